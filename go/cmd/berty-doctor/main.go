@@ -13,9 +13,11 @@ import (
 
 	"github.com/Masterminds/semver"
 	"github.com/fatih/color"
+	"github.com/libp2p/go-libp2p-core/pnet"
 	"moul.io/u"
 
 	"berty.tech/berty/v2/go/internal/config"
+	"berty.tech/berty/v2/go/internal/ipfsutil"
 )
 
 const (
@@ -25,11 +27,34 @@ const (
 	timeout             = time.Minute * 2
 )
 
-var verbose bool
+var (
+	verbose    bool
+	swarmKey   string
+	rdvp       string
+	rdvpQuorum int
+)
 
 func main() {
 	flag.BoolVar(&verbose, "v", false, "Enable verbose mode.")
+	flag.StringVar(&swarmKey, "swarm-key", "", "Path to a swarm.key file, test the RDVPs of this private network.")
+	flag.StringVar(&rdvp, "rdvp", "", "Comma separated list of RDVP maddrs to test instead of the default ones.")
+	flag.IntVar(&rdvpQuorum, "rdvp.quorum", 2, "Number of RDVPs the pool advertises on, 0 for every RDVP.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: berty-doctor [flags] [rdvp]\n\n  rdvp: only check the RDVPs\n\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+
+	onlyRDVP := false
+	switch flag.Arg(0) {
+	case "":
+	case "rdvp":
+		onlyRDVP = true
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	var wg sync.WaitGroup
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
@@ -37,6 +62,9 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if onlyRDVP {
+			return
+		}
 		for _, program := range strings.Split(mustHavePrograms, " ") {
 			path, err := exec.LookPath(program)
 			if err != nil {
@@ -59,6 +87,9 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if onlyRDVP {
+			return
+		}
 		goVersionOutput := u.SafeExec(exec.Command("go", "version"))
 		if strings.HasPrefix(goVersionOutput, "go version go1.") {
 			versionString := strings.Split(goVersionOutput, " ")[2][2:]
@@ -80,7 +111,7 @@ func main() {
 	}()
 
 	// check vendor dir
-	{
+	if !onlyRDVP {
 		if u.DirExists("./vendor") {
 			newWarn("'./vendor' directory exists, it may cause strange behavior during development.")
 		} else {
@@ -90,14 +121,38 @@ func main() {
 
 	// check if RDVPs are online.
 	{
-		wg.Add(1)
-		lenRDVPs := len(config.Config.P2P.RDVP)
-		maddrs := make([]string, lenRDVPs)
-		for lenRDVPs > 0 {
-			lenRDVPs--
-			maddrs[lenRDVPs] = config.Config.P2P.RDVP[lenRDVPs].Maddr
+		var maddrs []string
+		if rdvp != "" {
+			maddrs = strings.Split(rdvp, ",")
+		} else {
+			lenRDVPs := len(config.Config.P2P.RDVP)
+			maddrs = make([]string, lenRDVPs)
+			for lenRDVPs > 0 {
+				lenRDVPs--
+				maddrs[lenRDVPs] = config.Config.P2P.RDVP[lenRDVPs].Maddr
+			}
+		}
+
+		var psk pnet.PSK
+		if swarmKey != "" {
+			var err error
+			psk, err = ipfsutil.LoadSwarmKey(swarmKey)
+			if err != nil {
+				newErrorS("invalid swarm key: %s.", err)
+			} else {
+				newOkS("swarm key loaded (fingerprint %s), QUIC addrs are skipped.", ipfsutil.SwarmKeyFingerprint(psk))
+				maddrs = ipfsutil.FilterOutQUICAddrs(maddrs)
+				if rdvp == "" {
+					newWarn("testing the default RDVPs with a swarm key, they are part of the public network, use -rdvp to test the RDVPs of the private network.")
+				}
+			}
+		}
+
+		if psk != nil || swarmKey == "" {
+			wg.Add(2)
+			go testRDVPs(ctx, &wg, maddrs, psk)
+			go testRDVPPool(ctx, &wg, maddrs, psk, rdvpQuorum)
 		}
-		go testRDVPs(ctx, &wg, maddrs)
 	}
 
 	// FIXME: berty: if installed, make some checks
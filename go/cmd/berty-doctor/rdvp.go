@@ -16,8 +16,10 @@ import (
 	"github.com/denisbrodbeck/machineid"
 	p2p "github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p-core/discovery"
+	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/libp2p/go-libp2p-core/pnet"
 	quic "github.com/libp2p/go-libp2p-quic-transport"
 	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
 	ma "github.com/multiformats/go-multiaddr"
@@ -30,7 +32,53 @@ import (
 
 const timeRounding = time.Microsecond * 10
 
-func testRDVPs(ctx context.Context, gwg *sync.WaitGroup, addrs []string) {
+const connectTimeout = time.Second * 15
+
+// newDoctorHost creates the host used to test the rdvp servers, if psk is set the host joins the private network
+func newDoctorHost(ctx context.Context, psk pnet.PSK) (host.Host, error) {
+	opts := []p2p.Option{
+		p2p.DisableRelay(),
+		p2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		p2p.UserAgent("Berty Doctor"),
+		p2p.DefaultTransports,
+	}
+
+	if psk != nil {
+		// QUIC connections can't be protected by the pre-shared key
+		opts = append(opts, p2p.PrivateNetwork(psk))
+	} else {
+		opts = append(opts, p2p.Transport(quic.NewTransport))
+	}
+
+	return p2p.New(ctx, opts...)
+}
+
+// diagnoseConnect is called when the connection to a server failed, it connects using the other network (public or
+// private) to tell a swarm key mismatch apart from an unreachable server
+func diagnoseConnect(ctx context.Context, pi peer.AddrInfo, psk pnet.PSK, connectErr error) string {
+	if psk == nil {
+		return fmt.Sprintf("Unable to connect: %s (if this server is part of a private network, use -swarm-key)", connectErr)
+	}
+
+	fingerprint := ipfsutil.SwarmKeyFingerprint(psk)
+
+	h, err := newDoctorHost(ctx, nil)
+	if err != nil {
+		return fmt.Sprintf("Unable to connect with swarm key %s: %s", fingerprint, connectErr)
+	}
+	defer h.Close()
+
+	cctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	if err := h.Connect(cctx, pi); err == nil {
+		return fmt.Sprintf("PSK mismatch: the server is reachable without a swarm key, it is part of the public network and not of the private network %s", fingerprint)
+	}
+
+	return fmt.Sprintf("Unable to connect with swarm key %s: the server is unreachable or uses a different swarm key (%s)", fingerprint, connectErr)
+}
+
+func testRDVPs(ctx context.Context, gwg *sync.WaitGroup, addrs []string, psk pnet.PSK) {
 	defer (*gwg).Done()
 
 	// Isolate each server
@@ -119,13 +167,7 @@ func testRDVPs(ctx context.Context, gwg *sync.WaitGroup, addrs []string) {
 						}
 
 						// Setup host
-						host, err := p2p.New(ctx,
-							p2p.DisableRelay(),
-							p2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
-							p2p.UserAgent("Berty Doctor"),
-							p2p.DefaultTransports,
-							p2p.Transport(quic.NewTransport),
-						)
+						host, err := newDoctorHost(ctx, psk)
 						if err != nil {
 							rtr.message = fmt.Sprintf("Error creating host: %s", err)
 							return
@@ -134,6 +176,17 @@ func testRDVPs(ctx context.Context, gwg *sync.WaitGroup, addrs []string) {
 						// Check if that particular addr is fine.
 						host.Peerstore().AddAddrs(tgtPi.ID, tgtPi.Addrs, peerstore.PermanentAddrTTL)
 
+						// Connect first, a swarm key mismatch is reported as is instead of a discovery error
+						{
+							cctx, cancel := context.WithTimeout(ctx, connectTimeout)
+							err := host.Connect(cctx, *tgtPi)
+							cancel()
+							if err != nil {
+								rtr.message = diagnoseConnect(ctx, *tgtPi, psk, err)
+								return
+							}
+						}
+
 						// Ping if we are in verbose
 						if verbose {
 							pingChan := ping.Ping(ctx, host, tgtPi.ID)
@@ -151,25 +204,7 @@ func testRDVPs(ctx context.Context, gwg *sync.WaitGroup, addrs []string) {
 							rand.New(rand.NewSource(srand.SafeFast())), //nolint:gosec
 						)
 
-						// Generate a good key (mostly avoid collision with concurrent `make doctor` runs across the network).
-						var key string
-						{
-							// Using sha256 as this is not critical, probably still fast on most arch.
-							h := sha256.New()
-							buf := make([]byte, 8)
-							binary.LittleEndian.PutUint64(buf, uint64(srand.SafeFast()))
-							doWriteOnHash(h, buf)
-							id, err := machineid.ID()
-							if err == nil {
-								doWriteOnHash(h, []byte(id))
-							}
-							doWriteOnHash(h, []byte("Berty Doctor"))
-							buf = make([]byte, 8)
-							binary.LittleEndian.PutUint64(buf, uint64(runtime.NumCPU()))
-							doWriteOnHash(h, buf)
-							doWriteOnHash(h, []byte(runtime.Version()))
-							key = strconv.FormatUint(binary.LittleEndian.Uint64(h.Sum(nil)), 36)
-						}
+						key := generateDoctorKey()
 
 						// Advertise
 						{
@@ -331,6 +366,25 @@ type discReturn struct {
 	}
 }
 
+// generateDoctorKey generates a good key (mostly avoid collision with concurrent `make doctor` runs across the network).
+func generateDoctorKey() string {
+	// Using sha256 as this is not critical, probably still fast on most arch.
+	h := sha256.New()
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(srand.SafeFast()))
+	doWriteOnHash(h, buf)
+	id, err := machineid.ID()
+	if err == nil {
+		doWriteOnHash(h, []byte(id))
+	}
+	doWriteOnHash(h, []byte("Berty Doctor"))
+	buf = make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(runtime.NumCPU()))
+	doWriteOnHash(h, buf)
+	doWriteOnHash(h, []byte(runtime.Version()))
+	return strconv.FormatUint(binary.LittleEndian.Uint64(h.Sum(nil)), 36)
+}
+
 func doWriteOnHash(h io.Writer, buf []byte) {
 	toWrite := len(buf)
 	written := 0
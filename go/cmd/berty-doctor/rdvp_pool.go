@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/discovery"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/pnet"
+	"go.uber.org/zap"
+	"moul.io/srand"
+
+	"berty.tech/berty/v2/go/internal/ipfsutil"
+	"berty.tech/berty/v2/go/internal/tinder"
+)
+
+// testRDVPPool advertises through a pool of all the RDVPs, like a berty node does, and displays the state of the pool.
+func testRDVPPool(ctx context.Context, gwg *sync.WaitGroup, addrs []string, psk pnet.PSK, quorum int) {
+	defer gwg.Done()
+
+	var servers []peer.AddrInfo
+	for _, addr := range addrs {
+		pi, err := ipfsutil.ParseAndResolveIpfsAddr(ctx, addr)
+		if err != nil {
+			// already reported by testRDVPs
+			continue
+		}
+		servers = append(servers, *pi)
+	}
+	servers = mergeAddrInfos(servers)
+
+	if len(servers) == 0 {
+		newError("RDVP pool: no server found")
+		return
+	}
+
+	host, err := newDoctorHost(ctx, psk)
+	if err != nil {
+		newErrorS("RDVP pool: error creating host: %s", err)
+		return
+	}
+	defer host.Close()
+
+	pool, err := tinder.NewRendezvousPool(zap.NewNop(), host, servers, rand.New(rand.NewSource(srand.SafeFast())), &tinder.RendezvousPoolOpts{ //nolint:gosec
+		Quorum: quorum,
+	})
+	if err != nil {
+		newErrorS("RDVP pool: %s", err)
+		return
+	}
+
+	key := generateDoctorKey()
+	var message string
+	{
+		if _, err := pool.Advertise(ctx, key, discovery.TTL(timeout)); err != nil {
+			message = fmt.Sprintf("error advertising: %s", err)
+			goto Report
+		}
+
+		rc, err := pool.FindPeers(ctx, key)
+		if err != nil {
+			message = fmt.Sprintf("error finding peers: %s", err)
+			_ = pool.Unregister(ctx, key)
+			goto Report
+		}
+
+		found := false
+		for p := range rc {
+			if p.ID == host.ID() {
+				found = true
+			}
+		}
+		if !found {
+			message = "wasn't able to find us"
+		}
+
+		if err := pool.Unregister(ctx, key); err != nil && message == "" {
+			message = fmt.Sprintf("error unregistering us: %s", err)
+		}
+	}
+
+Report:
+	states := pool.State()
+
+	var healthy int
+	for _, state := range states {
+		if state.ConsecutiveFailures == 0 && !state.LastSuccess.IsZero() {
+			healthy++
+		}
+	}
+
+	if quorum <= 0 || quorum > len(states) {
+		quorum = len(states)
+	}
+
+	talkLock.Lock()
+	defer talkLock.Unlock()
+
+	switch {
+	case message != "":
+		fmt.Printf("[-] %s  RDVP pool (quorum %d, %d/%d healthy): %s\n", red("ERROR"), quorum, healthy, len(states), message)
+		errc++
+	case healthy < quorum:
+		fmt.Printf("[-] %s   RDVP pool (quorum %d, %d/%d healthy): quorum not reached\n", yellow("WARN"), quorum, healthy, len(states))
+		warnc++
+	default:
+		fmt.Printf("[+] %s     RDVP pool (quorum %d, %d/%d healthy)\n", green("OK"), quorum, healthy, len(states))
+		if !verbose {
+			return
+		}
+	}
+
+	for _, state := range states {
+		status := green("+")
+		if state.ConsecutiveFailures > 0 || state.LastSuccess.IsZero() {
+			status = red("-")
+		}
+
+		fmt.Printf("   [%s]     %s (connected: %t, failures: %d", status, state.ID, state.Connected, state.ConsecutiveFailures)
+		if !state.BackoffUntil.IsZero() {
+			fmt.Printf(", backoff: %s", time.Until(state.BackoffUntil).Round(time.Second))
+		}
+		if state.LastError != "" {
+			fmt.Printf(", last error: %s", state.LastError)
+		}
+		if state.QueryFailures > 0 {
+			fmt.Printf(", query failures: %d, last query error: %s", state.QueryFailures, state.LastQueryError)
+		}
+		fmt.Print(")\n")
+	}
+}
+
+// mergeAddrInfos groups the addrs of the same server
+func mergeAddrInfos(pis []peer.AddrInfo) []peer.AddrInfo {
+	index := make(map[peer.ID]int)
+	merged := []peer.AddrInfo{}
+	for _, pi := range pis {
+		if i, ok := index[pi.ID]; ok {
+			merged[i].Addrs = append(merged[i].Addrs, pi.Addrs...)
+			continue
+		}
+
+		index[pi.ID] = len(merged)
+		merged = append(merged, pi)
+	}
+
+	return merged
+}
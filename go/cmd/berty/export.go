@@ -9,11 +9,17 @@ import (
 
 	"github.com/peterbourgon/ff/v3/ffcli"
 
+	"berty.tech/berty/v2/go/pkg/bertyprotocol"
 	"berty.tech/berty/v2/go/pkg/messengertypes"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
 )
 
 func exportCommand() *ffcli.Command {
-	var exportPath *string
+	var (
+		exportPath       *string
+		exportPassphrase *string
+		incrementalFrom  *string
+	)
 
 	fsBuilder := func() (*flag.FlagSet, error) {
 		fs := flag.NewFlagSet("berty export", flag.ExitOnError)
@@ -22,6 +28,8 @@ func exportCommand() *ffcli.Command {
 		manager.SetupLocalMessengerServerFlags(fs) // by default, start a new local messenger server,
 		manager.SetupRemoteNodeFlags(fs)           // but allow to set a remote server instead
 		exportPath = fs.String("export-path", "", "path of the export tarball")
+		exportPassphrase = fs.String("export-passphrase", "", "passphrase used to encrypt the export (optional)")
+		incrementalFrom = fs.String("incremental-from", "", "path of a previous export, only the entries added since are exported (optional)")
 		return fs, nil
 	}
 
@@ -32,6 +40,7 @@ func exportCommand() *ffcli.Command {
 		FlagSetBuilder: fsBuilder,
 		Options:        ffSubcommandOptions(),
 		UsageFunc:      usageFunc,
+		Subcommands:    []*ffcli.Command{exportVerifyCommand()},
 		Exec: func(ctx context.Context, args []string) error {
 			if len(args) > 0 {
 				return flag.ErrHelp
@@ -41,6 +50,25 @@ func exportCommand() *ffcli.Command {
 				return fmt.Errorf("no export path specified")
 			}
 
+			var previousHeads []*protocoltypes.GroupHeadsExport
+			if *incrementalFrom != "" {
+				logger, err := manager.GetLogger()
+				if err != nil {
+					return err
+				}
+
+				previous, err := os.Open(*incrementalFrom)
+				if err != nil {
+					return err
+				}
+
+				previousHeads, err = bertyprotocol.ReadAccountExportHeads(previous, []byte(*exportPassphrase), logger)
+				_ = previous.Close()
+				if err != nil {
+					return fmt.Errorf("unable to read previous export: %w", err)
+				}
+			}
+
 			manager.DisableIPFSNetwork()
 
 			// messenger
@@ -56,7 +84,10 @@ func exportCommand() *ffcli.Command {
 
 			defer func() { _ = f.Close() }()
 
-			cl, err := messenger.InstanceExportData(ctx, &messengertypes.InstanceExportData_Request{})
+			cl, err := messenger.InstanceExportData(ctx, &messengertypes.InstanceExportData_Request{
+				Passphrase:    []byte(*exportPassphrase),
+				PreviousHeads: previousHeads,
+			})
 			if err != nil {
 				return err
 			}
@@ -78,3 +109,69 @@ func exportCommand() *ffcli.Command {
 		},
 	}
 }
+
+func exportVerifyCommand() *ffcli.Command {
+	var exportPassphrase *string
+
+	fsBuilder := func() (*flag.FlagSet, error) {
+		fs := flag.NewFlagSet("berty export verify", flag.ExitOnError)
+		manager.SetupLoggingFlags(fs) // also available at root level
+		exportPassphrase = fs.String("export-passphrase", "", "passphrase used to decrypt the exports (optional)")
+		return fs, nil
+	}
+
+	return &ffcli.Command{
+		Name:           "verify",
+		ShortUsage:     "berty [global flags] export verify [flags] <export> [previous exports...]",
+		ShortHelp:      "check the integrity of an export without restoring it",
+		LongHelp:       "previous exports are required to verify an incremental export",
+		FlagSetBuilder: fsBuilder,
+		Options:        ffSubcommandOptions(),
+		UsageFunc:      usageFunc,
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) == 0 {
+				return flag.ErrHelp
+			}
+
+			logger, err := manager.GetLogger()
+			if err != nil {
+				return err
+			}
+
+			files := make([]*os.File, len(args))
+			for i, path := range args {
+				if files[i], err = os.Open(path); err != nil {
+					return err
+				}
+
+				defer func(f *os.File) { _ = f.Close() }(files[i])
+			}
+
+			previous := make([]io.Reader, len(files)-1)
+			for i, f := range files[1:] {
+				previous[i] = f
+			}
+
+			report, err := bertyprotocol.VerifyAccountExport(files[0], []byte(*exportPassphrase), logger, previous...)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("keys: %d, groups: %d, entries: %d, incremental: %t\n", len(report.Keys), len(report.Groups), report.Entries, report.Incremental)
+
+			for _, id := range report.MissingEntries {
+				fmt.Printf("missing entry: %s\n", id)
+			}
+
+			for _, invalid := range report.InvalidEntries {
+				fmt.Printf("invalid entry: %s\n", invalid)
+			}
+
+			if !report.Valid() {
+				return fmt.Errorf("export is not valid")
+			}
+
+			return nil
+		},
+	}
+}
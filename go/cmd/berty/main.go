@@ -72,6 +72,7 @@ func runMain(args []string) error {
 				groupinitCommand(),
 				shareInviteCommand(),
 				tokenServerCommand(),
+				tokenCommand(),
 				replicationServerCommand(),
 				peersCommand(),
 				exportCommand(),
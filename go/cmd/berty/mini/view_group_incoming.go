@@ -348,13 +348,16 @@ func metadataEventHandler(ctx context.Context, v *groupView, e *protocoltypes.Gr
 		protocoltypes.EventTypeAccountContactRequestOutgoingSent:      handlerAccountContactRequestOutgoingSent,
 		protocoltypes.EventTypeAccountContactRequestReferenceReset:    handlerNoop,
 		protocoltypes.EventTypeAccountContactUnblocked:                nil, // do it later
+		protocoltypes.EventTypeAccountDeviceGroupKeyAdded:             handlerNoop,
 		protocoltypes.EventTypeAccountGroupJoined:                     handlerAccountGroupJoined,
 		protocoltypes.EventTypeAccountGroupLeft:                       handlerAccountGroupLeft,
 		protocoltypes.EventTypeContactAliasKeyAdded:                   handlerContactAliasKeyAdded,
+		protocoltypes.EventTypeGroupDeviceRevoked:                     nil, // do it later
 		protocoltypes.EventTypeGroupDeviceSecretAdded:                 handlerGroupDeviceSecretAdded,
 		protocoltypes.EventTypeGroupMemberDeviceAdded:                 handlerGroupMemberDeviceAdded,
 		protocoltypes.EventTypeGroupMetadataPayloadSent:               nil, // do it later
 		protocoltypes.EventTypeMultiMemberGroupAdminRoleGranted:       nil, // do it later
+		protocoltypes.EventTypeMultiMemberGroupMemberRemoved:          nil, // do it later
 		protocoltypes.EventTypeMultiMemberGroupAliasResolverAdded:     handlerMultiMemberGroupAliasResolverAdded,
 		protocoltypes.EventTypeMultiMemberGroupInitialMemberAnnounced: handlerMultiMemberGroupInitialMemberAnnounced,
 		protocoltypes.EventTypeAccountServiceTokenAdded:               handlerAccountServiceTokenAdded,
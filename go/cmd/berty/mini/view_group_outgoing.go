@@ -167,6 +167,11 @@ func commandList() []*command {
 			help:  "Lists registered services",
 			cmd:   servicesList,
 		},
+		{
+			title: "services remove",
+			help:  "Removes a service token from the account",
+			cmd:   servicesRemove,
+		},
 		{
 			title: "services auth init",
 			help:  "Inits authentication with a service provider",
@@ -314,6 +319,14 @@ func servicesList(ctx context.Context, v *groupView, _ string) error {
 	return nil
 }
 
+func servicesRemove(ctx context.Context, v *groupView, cmd string) error {
+	_, err := v.v.protocol.ServicesTokenRemove(ctx, &protocoltypes.ServicesTokenRemove_Request{
+		TokenID: strings.TrimSpace(cmd),
+	})
+
+	return err
+}
+
 func replGroup(ctx context.Context, v *groupView, cmd string) error {
 	if _, err := v.v.messenger.ReplicationServiceRegisterGroup(ctx, &messengertypes.ReplicationServiceRegisterGroup_Request{
 		TokenID:               strings.TrimSpace(cmd),
@@ -4,6 +4,8 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
+	"text/tabwriter"
 	"time"
 
 	"github.com/peterbourgon/ff/v3/ffcli"
@@ -15,6 +17,7 @@ import (
 
 func peersCommand() *ffcli.Command {
 	var refreshEveryFlag time.Duration = time.Second
+	var discoveryFlag = true
 	fsBuilder := func() (*flag.FlagSet, error) {
 		fs := flag.NewFlagSet("peers", flag.ExitOnError)
 		fs.String("config", "", "config file (optional)")
@@ -22,6 +25,7 @@ func peersCommand() *ffcli.Command {
 		manager.SetupLocalProtocolServerFlags(fs) // by default, start a new local messenger server,
 		manager.SetupRemoteNodeFlags(fs)          // but allow to set a remote server instead
 		fs.DurationVar(&refreshEveryFlag, "peers.refresh", refreshEveryFlag, "refresh every DURATION (0: no refresh)")
+		fs.BoolVar(&discoveryFlag, "peers.discovery", discoveryFlag, "display the health of the discovery drivers")
 		return fs, nil
 	}
 
@@ -49,8 +53,17 @@ func peersCommand() *ffcli.Command {
 					return errcode.TODO.Wrap(err)
 				}
 
+				var info *protocoltypes.SystemInfo_Reply
+				if discoveryFlag {
+					info, err = protocol.SystemInfo(ctx, &protocoltypes.SystemInfo_Request{})
+					if err != nil {
+						return errcode.TODO.Wrap(err)
+					}
+				}
+
 				if refreshEveryFlag == 0 {
 					fmt.Println(godev.PrettyJSONPB(ret))
+					printDiscoveryDrivers(info)
 					break
 				}
 
@@ -58,6 +71,7 @@ func peersCommand() *ffcli.Command {
 				print("\033[H\033[2J")
 				// FIXME: implement an ascii-table version of this view
 				fmt.Println(godev.PrettyJSONPB(ret))
+				printDiscoveryDrivers(info)
 				time.Sleep(refreshEveryFlag)
 			}
 
@@ -65,3 +79,37 @@ func peersCommand() *ffcli.Command {
 		},
 	}
 }
+
+func printDiscoveryDrivers(info *protocoltypes.SystemInfo_Reply) {
+	if info == nil || info.P2P == nil || len(info.P2P.DiscoveryDrivers) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "DISCOVERY DRIVER\tSCORE\tADVERTISE OK/KO\tFIND PEERS OK/KO\tLATENCY\tPEERS FOUND\tBACKOFF UNTIL\tLAST ERROR")
+	for _, d := range info.P2P.DiscoveryDrivers {
+		var peersFound int64
+		for _, ns := range d.PeersFound {
+			peersFound += ns.Peers
+		}
+
+		backoff := "-"
+		if until := time.Unix(0, d.BackoffUntil*int64(time.Millisecond)); d.BackoffUntil > 0 && until.After(time.Now()) {
+			backoff = until.Format(time.RFC3339)
+		}
+
+		lastError := d.LastError
+		if lastError == "" {
+			lastError = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%.2f\t%d/%d\t%d/%d\t%s\t%d\t%s\t%s\n",
+			d.Name, d.Score,
+			d.AdvertiseSuccess, d.AdvertiseFailure,
+			d.FindPeersSuccess, d.FindPeersFailure,
+			time.Duration(d.AverageLatencyMS)*time.Millisecond,
+			peersFound, backoff, lastError,
+		)
+	}
+	_ = tw.Flush()
+}
@@ -11,6 +11,11 @@ import (
 )
 
 func replicationServerCommand() *ffcli.Command {
+	var (
+		maxGroupsPerToken  *int
+		maxStoragePerToken *int64
+	)
+
 	fsBuilder := func() (*flag.FlagSet, error) {
 		fs := flag.NewFlagSet("berty repl-server", flag.ExitOnError)
 		fs.String("config", "", "config file (optional)")
@@ -18,6 +23,8 @@ func replicationServerCommand() *ffcli.Command {
 		manager.SetupProtocolAuth(fs)
 		manager.SetupLocalProtocolServerFlags(fs)
 		manager.SetupDefaultGRPCListenersFlags(fs)
+		maxGroupsPerToken = fs.Int("replication.max-groups-per-token", bertyprotocol.ReplicationServiceDefaultMaxGroupsPerToken, "maximum number of groups replicated for a single token")
+		maxStoragePerToken = fs.Int64("replication.max-storage-per-token", bertyprotocol.ReplicationServiceDefaultMaxStoragePerToken, "maximum size in bytes of the entries replicated for a single token")
 		return fs, nil
 	}
 
@@ -63,7 +70,16 @@ func replicationServerCommand() *ffcli.Command {
 				return err
 			}
 
-			replicationService, err := bertyprotocol.NewReplicationService(ctx, rootDS, odb, logger)
+			tokenVerifier, err := manager.GetAuthTokenVerifier()
+			if err != nil {
+				return err
+			}
+
+			replicationService, err := bertyprotocol.NewReplicationService(ctx, rootDS, odb, logger, &bertyprotocol.ReplicationServiceOpts{
+				TokenVerifier:      tokenVerifier,
+				MaxGroupsPerToken:  *maxGroupsPerToken,
+				MaxStoragePerToken: *maxStoragePerToken,
+			})
 			if err != nil {
 				return err
 			}
@@ -26,9 +26,12 @@ func TestPersistentIdentity(t *testing.T) {
 		defer os.RemoveAll(tempdir)
 	}
 	var (
-		pathBerty1 = filepath.Join(tempdir, "berty1")
-		pathBerty2 = filepath.Join(tempdir, "berty2")
-		pathExport = filepath.Join(tempdir, "export.tar")
+		pathBerty1          = filepath.Join(tempdir, "berty1")
+		pathBerty2          = filepath.Join(tempdir, "berty2")
+		pathBerty3          = filepath.Join(tempdir, "berty3")
+		pathExport          = filepath.Join(tempdir, "export.tar")
+		pathEncryptedExport = filepath.Join(tempdir, "export.tar.enc")
+		exportPassphrase    = "export passphrase"
 	)
 
 	// berty1: init a new account
@@ -74,4 +77,34 @@ func TestPersistentIdentity(t *testing.T) {
 		require.NotEmpty(t, key2)
 		require.Equal(t, key1, key2)
 	}
+
+	// berty1: export account using a passphrase
+	{
+		err := runMain([]string{
+			"export",
+			"-log.filters=none",
+			"-store.dir", pathBerty1,
+			"-export-path", pathEncryptedExport,
+			"-export-passphrase", exportPassphrase,
+		})
+		require.NoError(t, err)
+	}
+
+	// berty3: init a new account from the encrypted export
+	{
+		closer, err := u.CaptureStdoutAndStderr()
+		require.NoError(t, err)
+		err = runMain([]string{
+			"share-invite",
+			"-log.filters=none",
+			"-store.dir", pathBerty3,
+			"-node.restore-export-path", pathEncryptedExport,
+			"-node.restore-export-passphrase", exportPassphrase,
+			"-no-qr",
+		})
+		require.NoError(t, err)
+		key3 := strings.TrimSpace(closer())
+		require.NotEmpty(t, key3)
+		require.Equal(t, key1, key3)
+	}
 }
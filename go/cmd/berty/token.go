@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/oklog/run"
 	"github.com/peterbourgon/ff/v3/ffcli"
@@ -23,6 +24,10 @@ import (
 // end users. The value returned to the app also contains a map of the services
 // endpoints indexed by their identifiers.
 //
+// The issued tokens and the signing keys are recorded in a store, which allows
+// operators to list and revoke tokens and to rotate the signing key using the
+// `berty token` subcommands.
+//
 // For example the JSON response for /oauth/token can include:
 //  {
 //  "access_token":
@@ -47,13 +52,17 @@ func tokenServerCommand() *ffcli.Command {
 		authSKFlag    = ""
 		listenerFlag  = "127.0.0.1:8080"
 		supportedFlag = ""
+		storeFlag     = defaultAuthTokenStorePath
+		tokenTTLFlag  = time.Duration(0)
 	)
 	fsBuilder := func() (*flag.FlagSet, error) {
 		fs := flag.NewFlagSet("token issuer server p", flag.ExitOnError)
 		fs.String("config", "", "config file (optional)")
 		manager.SetupLoggingFlags(fs) // also available at root level
 		fs.StringVar(&secretFlag, "auth.secret", secretFlag, "base64 encoded secret")
-		fs.StringVar(&authSKFlag, "auth.sk", authSKFlag, "base64 encoded signature key")
+		fs.StringVar(&authSKFlag, "auth.sk", authSKFlag, "base64 encoded signature key, only used if the store has no signing key yet")
+		fs.StringVar(&storeFlag, "auth.store", storeFlag, "path of the SQLite store of the issued tokens and signing keys, the keys are stored unencrypted")
+		fs.DurationVar(&tokenTTLFlag, "auth.token-ttl", tokenTTLFlag, "validity duration of the issued tokens, tokens don't expire if zero")
 		fs.StringVar(&listenerFlag, "http.listener", listenerFlag, "http listener")
 		fs.StringVar(&supportedFlag, "svc", supportedFlag, "comma separated list of supported services as name@ip:port")
 		return fs, nil
@@ -92,16 +101,25 @@ func tokenServerCommand() *ffcli.Command {
 				return err
 			}
 
-			skBytes, err := base64.RawStdEncoding.DecodeString(authSKFlag)
-			if err != nil {
-				return err
-			}
+			var sk ed25519.PrivateKey
+			if authSKFlag != "" {
+				skBytes, err := base64.RawStdEncoding.DecodeString(authSKFlag)
+				if err != nil {
+					return err
+				}
+
+				if len(skBytes) != ed25519.SeedSize {
+					return fmt.Errorf("invalid sk size")
+				}
 
-			if len(skBytes) != ed25519.SeedSize {
-				return fmt.Errorf("invalid sk size")
+				sk = ed25519.NewKeyFromSeed(skBytes)
 			}
 
-			sk := ed25519.NewKeyFromSeed(skBytes)
+			store, err := bertyprotocol.NewAuthTokenSQLiteStore(storeFlag, logger)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
 
 			l, err := net.Listen("tcp", listenerFlag)
 			if err != nil {
@@ -118,7 +136,10 @@ func tokenServerCommand() *ffcli.Command {
 				services[values[0]] = values[1]
 			}
 
-			server, err := bertyprotocol.NewAuthTokenServer(secret, sk, services, logger)
+			server, err := bertyprotocol.NewAuthTokenServer(secret, sk, services, logger, &bertyprotocol.AuthTokenServerOpts{
+				Store:    store,
+				TokenTTL: tokenTTLFlag,
+			})
 			if err != nil {
 				return err
 			}
@@ -129,8 +150,12 @@ func tokenServerCommand() *ffcli.Command {
 				l.Close()
 			})
 
-			pk := sk.Public().(ed25519.PublicKey)
-			logger.Info(fmt.Sprintf("running server, corresponding pk is %s", base64.RawStdEncoding.EncodeToString(pk)))
+			activeKeys, err := store.ActiveSigningKeys()
+			if err != nil {
+				return err
+			}
+
+			logger.Info(fmt.Sprintf("running server, corresponding pks are %s", authTokenStorePublicKeys(activeKeys)))
 
 			return g.Run()
 		},
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+
+	"berty.tech/berty/v2/go/pkg/bertyprotocol"
+)
+
+const defaultAuthTokenStorePath = "token-server.sqlite"
+
+// authTokenStorePublicKeys returns the public keys of the signing keys in the format expected by node.auth-pk
+func authTokenStorePublicKeys(sks []ed25519.PrivateKey) string {
+	pks := make([]string, len(sks))
+	for i, sk := range sks {
+		pks[i] = base64.RawStdEncoding.EncodeToString(sk.Public().(ed25519.PublicKey))
+	}
+
+	return strings.Join(pks, ",")
+}
+
+func formatAuthTokenStoreDate(ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
+
+func tokenCommand() *ffcli.Command {
+	return &ffcli.Command{
+		Name:       "token",
+		ShortUsage: "berty [global flags] token <subcommand> [flags] [args...]",
+		ShortHelp:  "manage the tokens issued by a token server",
+		Options:    ffSubcommandOptions(),
+		UsageFunc:  usageFunc,
+		Subcommands: []*ffcli.Command{
+			tokenListCommand(),
+			tokenRevokeCommand(),
+			tokenRotateKeyCommand(),
+		},
+		Exec: func(context.Context, []string) error { return flag.ErrHelp },
+	}
+}
+
+func tokenStoreCommand(name, shortUsage, shortHelp string, exec func(store bertyprotocol.AuthTokenStore, args []string) error) *ffcli.Command {
+	storeFlag := defaultAuthTokenStorePath
+
+	fsBuilder := func() (*flag.FlagSet, error) {
+		fs := flag.NewFlagSet("berty token "+name, flag.ExitOnError)
+		manager.SetupLoggingFlags(fs) // also available at root level
+		fs.StringVar(&storeFlag, "auth.store", storeFlag, "path of the SQLite store of the token server")
+		return fs, nil
+	}
+
+	return &ffcli.Command{
+		Name:           name,
+		ShortUsage:     shortUsage,
+		ShortHelp:      shortHelp,
+		FlagSetBuilder: fsBuilder,
+		Options:        ffSubcommandOptions(),
+		UsageFunc:      usageFunc,
+		Exec: func(ctx context.Context, args []string) error {
+			logger, err := manager.GetLogger()
+			if err != nil {
+				return err
+			}
+
+			if _, err := os.Stat(storeFlag); err != nil {
+				return fmt.Errorf("unable to open store: %w", err)
+			}
+
+			store, err := bertyprotocol.NewAuthTokenSQLiteStore(storeFlag, logger)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			return exec(store, args)
+		},
+	}
+}
+
+func tokenListCommand() *ffcli.Command {
+	return tokenStoreCommand("list", "berty [global flags] token list [flags]", "list the issued tokens", func(store bertyprotocol.AuthTokenStore, args []string) error {
+		if len(args) > 0 {
+			return flag.ErrHelp
+		}
+
+		tokens, err := store.ListIssuedTokens()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+		fmt.Fprintln(tw, "TOKEN ID\tSERVICES\tISSUED\tEXPIRES\tREVOKED\tSIGNING KEY")
+		for _, t := range tokens {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.TokenID, t.Services, formatAuthTokenStoreDate(t.IssuedAt), formatAuthTokenStoreDate(t.Expiration), formatAuthTokenStoreDate(t.RevokedAt), t.SigningKey)
+		}
+
+		return tw.Flush()
+	})
+}
+
+func tokenRevokeCommand() *ffcli.Command {
+	return tokenStoreCommand("revoke", "berty [global flags] token revoke [flags] <token id> [token ids...]", "revoke issued tokens, replication servers using this store reject them", func(store bertyprotocol.AuthTokenStore, args []string) error {
+		if len(args) == 0 {
+			return flag.ErrHelp
+		}
+
+		for _, tokenID := range args {
+			if err := store.RevokeToken(tokenID); err != nil {
+				return fmt.Errorf("unable to revoke %s: %w", tokenID, err)
+			}
+
+			fmt.Printf("revoked %s\n", tokenID)
+		}
+
+		return nil
+	})
+}
+
+func tokenRotateKeyCommand() *ffcli.Command {
+	return tokenStoreCommand("rotate-key", "berty [global flags] token rotate-key [flags]", "generate a new signing key, the previous key stays active until the next rotation", func(store bertyprotocol.AuthTokenStore, args []string) error {
+		if len(args) > 0 {
+			return flag.ErrHelp
+		}
+
+		sk, err := bertyprotocol.AuthTokenStoreRotateKey(store)
+		if err != nil {
+			return err
+		}
+
+		active, err := store.ActiveSigningKeys()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("new signing key pk: %s\n", base64.RawStdEncoding.EncodeToString(sk.Public().(ed25519.PublicKey)))
+		fmt.Printf("active pks (node.auth-pk): %s\n", authTokenStorePublicKeys(active))
+		fmt.Println("restart the token server to sign with the new key")
+		fmt.Println("update node.auth-pk on the nodes which aren't using this store as their node.auth-token-store, they accept the retired keys until then")
+
+		return nil
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	// nolint:staticcheck
 	libp2p "github.com/libp2p/go-libp2p"
@@ -22,6 +23,7 @@ import (
 	libp2p_peer "github.com/libp2p/go-libp2p-core/peer"
 	libp2p_quic "github.com/libp2p/go-libp2p-quic-transport"
 	libp2p_rp "github.com/libp2p/go-libp2p-rendezvous"
+	libp2p_rpdbi "github.com/libp2p/go-libp2p-rendezvous/db"
 	libp2p_rpdb "github.com/libp2p/go-libp2p-rendezvous/db/sqlite"
 	"github.com/libp2p/go-libp2p/config"
 	ma "github.com/multiformats/go-multiaddr"
@@ -36,6 +38,7 @@ import (
 
 	"berty.tech/berty/v2/go/internal/ipfsutil"
 	"berty.tech/berty/v2/go/internal/logutil"
+	"berty.tech/berty/v2/go/internal/rdvpserver"
 	"berty.tech/berty/v2/go/pkg/errcode"
 )
 
@@ -53,6 +56,15 @@ func main() {
 		sharekeyPK            = ""
 		serveAnnounce         = ""
 		serveMetricsListeners = ""
+		serveSwarmKey         = ""
+		serveDBBackend        = "sqlite"
+		servePeerLimit        = 500
+		serveNSLimit          = 10000
+		serveMaxTTL           = 24 * time.Hour
+		serveRateLimit        = 10.0
+		serveRateBurst        = 50
+		serveAdminListener    = ""
+		serveAdminToken       = ""
 		genkeyType            = "Ed25519"
 		genkeyLength          = 2048
 	)
@@ -68,11 +80,20 @@ func main() {
 	globalFlags.StringVar(&logToFile, "log.file", logToFile, "if specified, will log everything in JSON into a file and nothing on stderr")
 	globalFlags.StringVar(&logFormat, "log.format", logFormat, "if specified, will override default log format")
 	serveFlags.StringVar(&serveURN, "db", serveURN, "rdvp sqlite URN")
+	serveFlags.StringVar(&serveDBBackend, "db.backend", serveDBBackend, "rdvp db backend, one of: sqlite, memory (the memory backend ignores -db and is intended for tests)")
 	serveFlags.StringVar(&serveListeners, "l", serveListeners, "lists of listeners of (m)addrs separate by a comma")
 	sharekeyFlags.StringVar(&sharekeyPK, "pk", sharekeyPK, "private key (generated by `rdvp genkey`)")
 	serveFlags.StringVar(&servePK, "pk", servePK, "private key (generated by `rdvp genkey`)")
 	serveFlags.StringVar(&serveAnnounce, "announce", serveAnnounce, "addrs that will be announce by this server")
 	serveFlags.StringVar(&serveMetricsListeners, "metrics", serveMetricsListeners, "metrics listener, if empty will disable metrics")
+	serveFlags.IntVar(&servePeerLimit, "limit.peer", servePeerLimit, "maximum number of namespaces a peer can be registered on, 0 to disable")
+	serveFlags.IntVar(&serveNSLimit, "limit.ns", serveNSLimit, "maximum number of peers registered on a namespace, 0 to disable")
+	serveFlags.DurationVar(&serveMaxTTL, "limit.ttl", serveMaxTTL, "maximum ttl of a registration, 0 to disable")
+	serveFlags.Float64Var(&serveRateLimit, "limit.rate", serveRateLimit, "maximum number of requests per second per peer, 0 to disable")
+	serveFlags.IntVar(&serveRateBurst, "limit.burst", serveRateBurst, "maximum burst of requests per peer")
+	serveFlags.StringVar(&serveAdminListener, "admin", serveAdminListener, "admin http api listener, if empty will disable the admin api")
+	serveFlags.StringVar(&serveAdminToken, "admin.token", serveAdminToken, "bearer token required by the admin api")
+	serveFlags.StringVar(&serveSwarmKey, "swarm-key", serveSwarmKey, "path to a swarm.key file, if set only the peers of this private network can connect (QUIC is disabled)")
 	genkeyFlags.StringVar(&genkeyType, "type", genkeyType, "Type of the private key generated, one of : Ed25519, ECDSA, Secp256k1, RSA")
 	genkeyFlags.IntVar(&genkeyLength, "length", genkeyLength, "The length (in bits) of the key generated.")
 	serveFlags.String("config", "", "config file (optional)")
@@ -80,8 +101,13 @@ func main() {
 	serve := &ffcli.Command{
 		Name:       "serve",
 		ShortUsage: "rdvp [global flags] serve [flags]",
-		LongHelp:   "EXAMPLE\n  rdvp genkey > rdvp.key\n  rdvp serve -pk `cat rdvp.key` -db ./rdvp-store",
-		FlagSet:    serveFlags,
+		LongHelp: "EXAMPLE\n  rdvp genkey > rdvp.key\n  rdvp serve -pk `cat rdvp.key` -db ./rdvp-store\n\n" +
+			"ADMIN API\n  rdvp serve -admin 127.0.0.1:8081 -admin.token `cat admin.token`\n" +
+			"  curl -H \"Authorization: Bearer $TOKEN\" http://127.0.0.1:8081/namespaces\n" +
+			"  curl -H \"Authorization: Bearer $TOKEN\" http://127.0.0.1:8081/registrations?ns=<namespace>\n" +
+			"  curl -H \"Authorization: Bearer $TOKEN\" http://127.0.0.1:8081/registrations?peer=<peer id>\n" +
+			"  curl -H \"Authorization: Bearer $TOKEN\" -X POST http://127.0.0.1:8081/evict?peer=<peer id>",
+		FlagSet: serveFlags,
 		Options: []ff.Option{
 			ff.WithEnvVarPrefix("RDVP"),
 			ff.WithConfigFileFlag("config"),
@@ -92,6 +118,10 @@ func main() {
 				return flag.ErrHelp
 			}
 
+			if serveAdminListener != "" && serveAdminToken == "" {
+				return errcode.TODO.Wrap(fmt.Errorf("the admin api requires an -admin.token"))
+			}
+
 			mrand.Seed(srand.MustSecure())
 			logger, cleanup, err := logutil.NewLogger(logFilters, logFormat, logToFile)
 			if err != nil {
@@ -111,6 +141,11 @@ func main() {
 			})
 
 			laddrs := strings.Split(serveListeners, ",")
+			if serveSwarmKey != "" {
+				// QUIC connections can't be protected by the pre-shared key
+				laddrs = ipfsutil.FilterOutQUICAddrs(laddrs)
+			}
+
 			listeners, err := ipfsutil.ParseAddrs(laddrs...)
 			if err != nil {
 				return errcode.TODO.Wrap(err)
@@ -148,17 +183,7 @@ func main() {
 
 			reporter := metrics.NewBandwidthCounter()
 
-			// init p2p host
-			host, err := libp2p.New(ctx,
-				// default tpt + quic
-				libp2p.DefaultTransports,
-				libp2p.Transport(libp2p_quic.NewTransport),
-
-				// Nat & Relay service
-				libp2p.EnableNATService(),
-				libp2p.DefaultStaticRelays(),
-				libp2p.EnableRelay(libp2p_cicuit.OptHop),
-
+			opts := []libp2p.Option{
 				// swarm listeners
 				libp2p.ListenAddrs(listeners...),
 
@@ -170,7 +195,37 @@ func main() {
 
 				// metrics
 				libp2p.BandwidthReporter(reporter),
-			)
+
+				// Nat & Relay service
+				libp2p.EnableNATService(),
+				libp2p.EnableRelay(libp2p_cicuit.OptHop),
+			}
+
+			if serveSwarmKey != "" {
+				psk, err := ipfsutil.LoadSwarmKey(serveSwarmKey)
+				if err != nil {
+					return errcode.TODO.Wrap(err)
+				}
+
+				// default tpt only, the default relays are part of the public network
+				opts = append(opts,
+					libp2p.DefaultTransports,
+					libp2p.PrivateNetwork(psk),
+				)
+
+				logger.Info("private network enabled", zap.String("swarm-key-fingerprint", ipfsutil.SwarmKeyFingerprint(psk)))
+			} else {
+				opts = append(opts,
+					// default tpt + quic
+					libp2p.DefaultTransports,
+					libp2p.Transport(libp2p_quic.NewTransport),
+
+					libp2p.DefaultStaticRelays(),
+				)
+			}
+
+			// init p2p host
+			host, err := libp2p.New(ctx, opts...)
 			if err != nil {
 				return errcode.TODO.Wrap(err)
 			}
@@ -178,15 +233,48 @@ func main() {
 			defer host.Close()
 			logHostInfo(logger, host)
 
-			db, err := libp2p_rpdb.OpenDB(ctx, serveURN)
+			var db libp2p_rpdbi.DB
+			switch serveDBBackend {
+			case "sqlite":
+				db, err = libp2p_rpdb.OpenDB(ctx, serveURN)
+			case "memory":
+				db, err = rdvpserver.NewMemoryDB()
+			default:
+				err = fmt.Errorf("unknown db backend: %q", serveDBBackend)
+			}
 			if err != nil {
 				return errcode.TODO.Wrap(err)
 			}
 
-			defer db.Close()
+			quotaDB := rdvpserver.NewQuotaDB(logger, db, rdvpserver.Limits{
+				MaxRegistrationsPerPeer:      servePeerLimit,
+				MaxRegistrationsPerNamespace: serveNSLimit,
+				MaxTTL:                       serveMaxTTL,
+			})
+			defer quotaDB.Close()
 
 			// start service
-			_ = libp2p_rp.NewRendezvousService(host, db)
+			limiter := rdvpserver.NewRateLimiter(serveRateLimit, serveRateBurst)
+			_ = libp2p_rp.NewRendezvousService(rdvpserver.NewRateLimitedHost(logger, host, limiter), quotaDB)
+
+			if serveAdminListener != "" {
+				handler, err := rdvpserver.NewAdminHandler(logger, quotaDB, serveAdminToken)
+				if err != nil {
+					return errcode.TODO.Wrap(err)
+				}
+
+				al, err := net.Listen("tcp", serveAdminListener)
+				if err != nil {
+					return errcode.TODO.Wrap(err)
+				}
+
+				gServe.Add(func() error {
+					logger.Info("admin listener", zap.String("listener", al.Addr().String()))
+					return http.Serve(al, handler)
+				}, func(error) {
+					al.Close()
+				})
+			}
 
 			if serveMetricsListeners != "" {
 				ml, err := net.Listen("tcp", serveMetricsListeners)
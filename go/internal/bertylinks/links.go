@@ -98,6 +98,12 @@ func MarshalLink(link *messengertypes.BertyLink) (internal string, web string, e
 			machine.Encrypted.GroupType = link.Encrypted.GroupType
 		}
 		*qrOptimized = *link
+	case messengertypes.BertyLink_DeviceLinkV1Kind:
+		kind = "device"
+		machine.DeviceLink = link.DeviceLink
+
+		// device links are one-time invitations which are not meant to be shared, there are no human-readable fields
+		*qrOptimized = *link
 	default:
 		return "", "", errcode.ErrInvalidInput
 	}
@@ -229,6 +235,11 @@ func UnmarshalLink(uri string, key []byte) (*messengertypes.BertyLink, error) {
 			if name := human.Get("name"); name != "" && link.BertyGroup.DisplayName == "" {
 				link.BertyGroup.DisplayName = name
 			}
+		case "device":
+			link.Kind = messengertypes.BertyLink_DeviceLinkV1Kind
+			if link.DeviceLink == nil {
+				return nil, errcode.ErrInvalidInput
+			}
 		case "enc":
 			link.Kind = messengertypes.BertyLink_EncryptedV1Kind
 			if link.Encrypted == nil {
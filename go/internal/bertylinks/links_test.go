@@ -2,11 +2,14 @@ package bertylinks_test
 
 import (
 	"bytes"
+	crand "crypto/rand"
 	"fmt"
 	"math/rand"
 	"os"
 	"testing"
 
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/mdp/qrterminal"
 	"github.com/stretchr/testify/require"
 	"github.com/tj/assert"
@@ -132,6 +135,45 @@ func TestMarshalLink(t *testing.T) {
 	}
 }
 
+func TestMarshalDeviceLink(t *testing.T) {
+	sk, pk, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	pid, err := peer.IDFromPrivateKey(sk)
+	require.NoError(t, err)
+
+	pkBytes, err := pk.Raw()
+	require.NoError(t, err)
+
+	link := &messengertypes.BertyLink{
+		Kind: messengertypes.BertyLink_DeviceLinkV1Kind,
+		DeviceLink: &protocoltypes.DeviceLinkInvitation{
+			PeerID:      pid.Pretty(),
+			Addrs:       []string{"/ip4/127.0.0.1/tcp/4242"},
+			ResponderPK: pkBytes,
+			Secret:      bytes.Repeat([]byte{7}, 32),
+		},
+	}
+
+	internal, web, err := bertylinks.MarshalLink(link)
+	require.NoError(t, err)
+
+	webLink, err := bertylinks.UnmarshalLink(web, nil)
+	require.NoError(t, err)
+	assert.True(t, webLink.IsDeviceLink())
+	assert.Equal(t, link, webLink)
+
+	internalLink, err := bertylinks.UnmarshalLink(internal, nil)
+	require.NoError(t, err)
+	assert.True(t, internalLink.IsDeviceLink())
+	assert.Equal(t, link, internalLink)
+
+	// secret is mandatory
+	link.DeviceLink.Secret = nil
+	_, _, err = bertylinks.MarshalLink(link)
+	require.Error(t, err)
+}
+
 func TestUnmarshalLink(t *testing.T) {
 	cases := []struct {
 		name               string
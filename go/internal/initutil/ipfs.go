@@ -19,12 +19,12 @@ import (
 	libp2p "github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
-	"github.com/libp2p/go-libp2p-core/peerstore"
 	discovery "github.com/libp2p/go-libp2p-discovery"
 	p2p_dht "github.com/libp2p/go-libp2p-kad-dht"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
+	"go.uber.org/zap"
 	"moul.io/srand"
 
 	ble "berty.tech/berty/v2/go/internal/ble-driver"
@@ -56,7 +56,11 @@ func (m *Manager) SetupLocalIPFSFlags(fs *flag.FlagSet) {
 	fs.DurationVar(&m.Node.Protocol.MinBackoff, "p2p.min-backoff", time.Second, "minimum p2p backoff duration")
 	fs.DurationVar(&m.Node.Protocol.MaxBackoff, "p2p.max-backoff", time.Minute, "maximum p2p backoff duration")
 	fs.DurationVar(&m.Node.Protocol.PollInterval, "p2p.poll-interval", pubsub.DiscoveryPollInterval, "how long the discovery system will waits for more peers")
+	fs.DurationVar(&m.Node.Protocol.PeerCacheTTL, "p2p.peer-cache-ttl", time.Hour*24*7, "how long the peers seen on the groups are kept to reconnect on the next start, 0 disables the peer cache")
 	fs.StringVar(&m.Node.Protocol.RdvpMaddrs, "p2p.rdvp", ":default:", `list of rendezvous point maddr, ":dev:" will add the default devs servers, ":none:" will disable rdvp`)
+	fs.StringVar(&m.Node.Protocol.SwarmKey, "p2p.swarm-key", "", "path to a swarm.key file, if set the node joins the private network protected by this pre-shared key")
+	fs.StringVar(&m.Node.Protocol.Bootstrap, "p2p.bootstrap", ":default:", `list of IPFS bootstrap maddrs, ":none:" will disable bootstrap`)
+	fs.IntVar(&m.Node.Protocol.RdvpQuorum, "p2p.rdvp-quorum", 2, "number of rendezvous points used to advertise, 0 will advertise on every rendezvous point")
 	fs.BoolVar(&m.Node.Protocol.Ble.Enable, "p2p.ble", ble.Supported, "if true Bluetooth Low Energy will be enabled")
 	fs.BoolVar(&m.Node.Protocol.MultipeerConnectivity, "p2p.multipeer-connectivity", mc.Supported, "if true Multipeer Connectivity will be enabled")
 	fs.StringVar(&m.Node.Protocol.Tor.Mode, "tor.mode", defaultTorMode, "changes the behavior of libp2p regarding tor, see advanced help for more details")
@@ -76,6 +80,14 @@ func (m *Manager) SetupLocalIPFSFlags(fs *flag.FlagSet) {
 		"",
 		"-> full list available at https://github.com/berty/berty/tree/master/config)",
 	})
+	m.longHelp = append(m.longHelp, [2]string{
+		"-p2p.bootstrap=:default:,CUSTOM",
+		"equivalent to -p2p.bootstrap=<IPFS default bootstrap peers>,CUSTOM, the defaults are skipped on a private network",
+	})
+	m.longHelp = append(m.longHelp, [2]string{
+		"-p2p.swarm-key=PATH",
+		"only peers sharing the same key can connect, QUIC is disabled since it can't be protected by the key",
+	})
 	m.longHelp = append(m.longHelp, [2]string{
 		"-tor.mode=" + TorDisabled,
 		"tor is completely disabled",
@@ -259,7 +271,7 @@ func (m *Manager) setupIPFSConfig(cfg *ipfs_cfg.Config) ([]libp2p.Option, error)
 	}
 
 	cfg.Addresses.Swarm = m.getSwarmAddrs()
-	cfg.Bootstrap = ipfs_cfg.DefaultBootstrapAddresses
+	cfg.Bootstrap = m.getBootstrapAddrs()
 
 	if m.Node.Protocol.IPFSAPIListeners != "" {
 		cfg.Addresses.API = strings.Split(m.Node.Protocol.IPFSAPIListeners, ",")
@@ -273,6 +285,26 @@ func (m *Manager) setupIPFSConfig(cfg *ipfs_cfg.Config) ([]libp2p.Option, error)
 		cfg.Addresses.NoAnnounce = strings.Split(m.Node.Protocol.NoAnnounce, ",")
 	}
 
+	// private network, also applied when the IP transports are disabled so the other transports are protected
+	if m.Node.Protocol.SwarmKey != "" {
+		psk, err := ipfsutil.LoadSwarmKey(m.Node.Protocol.SwarmKey)
+		if err != nil {
+			return nil, errcode.ErrIPFSSetupConfig.Wrap(err)
+		}
+
+		p2popts = append(p2popts, libp2p.PrivateNetwork(psk))
+
+		// QUIC connections can't be protected by the pre-shared key
+		cfg.Swarm.Transports.Network.QUIC = ipfs_cfg.False
+		cfg.Addresses.Swarm = ipfsutil.FilterOutQUICAddrs(cfg.Addresses.Swarm)
+
+		if m.hasDefaultRdvp() {
+			m.initLogger.Warn("the default rdvp servers are part of the public network, use -p2p.rdvp to set the rdvp servers of the private network")
+		}
+
+		m.initLogger.Info("private network enabled", zap.String("swarm-key-fingerprint", ipfsutil.SwarmKeyFingerprint(psk)))
+	}
+
 	if m.Node.Protocol.DisableIPFSNetwork {
 		// Disable IP transports
 		cfg.Swarm.Transports.Network.QUIC = ipfs_cfg.False
@@ -445,29 +477,53 @@ func (m *Manager) setupIPFSHost(h host.Host) error {
 	}
 
 	var rdvClients []tinder.AsyncableDriver
-	if lenrdvpeers := len(rdvpeers); lenrdvpeers > 0 {
-		drivers := make([]tinder.AsyncableDriver, lenrdvpeers)
-		for i, peer := range rdvpeers {
-			h.Peerstore().AddAddrs(peer.ID, peer.Addrs, peerstore.PermanentAddrTTL)
-			rng := mrand.New(mrand.NewSource(srand.MustSecure())) // nolint:gosec // we need to use math/rand here, but it is seeded from crypto/rand
-			disc := tinder.NewRendezvousDiscovery(logger, h, peer.ID, rng)
-
-			// monitor this driver
-			disc, err := tinder.MonitorDriverAsync(logger, h, disc)
-			if err != nil {
-				return errcode.ErrIPFSSetupHost.Wrap(err)
-			}
+	if len(rdvpeers) > 0 {
+		servers := make([]peer.AddrInfo, len(rdvpeers))
+		for i, pi := range rdvpeers {
+			servers[i] = *pi
+		}
 
-			drivers[i] = disc
+		// a single driver manages every rdvp, it advertises on a quorum of them and fails over to the healthy ones
+		rng := mrand.New(mrand.NewSource(srand.MustSecure())) // nolint:gosec // we need to use math/rand here, but it is seeded from crypto/rand
+		pool, err := tinder.NewRendezvousPool(logger, h, servers, rng, &tinder.RendezvousPoolOpts{
+			Quorum: m.Node.Protocol.RdvpQuorum,
+		})
+		if err != nil {
+			return errcode.ErrIPFSSetupHost.Wrap(err)
 		}
-		rdvClients = append(rdvClients, drivers...)
+
+		// monitor this driver
+		disc, err := tinder.MonitorDriverAsync(logger, h, pool)
+		if err != nil {
+			return errcode.ErrIPFSSetupHost.Wrap(err)
+		}
+
+		rdvClients = append(rdvClients, disc)
 	}
 
-	var rdvClient tinder.Driver
-	switch len(rdvClients) {
-	case 0:
+	if len(rdvClients) == 0 {
 		// FIXME: Check if this isn't called when DisableIPFSNetwork true.
 		return errcode.ErrIPFSSetupHost.Wrap(fmt.Errorf("can't create an IPFS node without any discovery"))
+	}
+
+	// serve the peers seen during the previous runs first, they are reachable even if the rdvp servers are down
+	if m.Node.Protocol.PeerCacheTTL > 0 {
+		rootDS, err := m.getRootDatastore()
+		if err != nil {
+			return errcode.ErrIPFSSetupHost.Wrap(err)
+		}
+
+		cacheDS := ipfsutil.NewNamespacedDatastore(rootDS, datastore.NewKey(bertyprotocol.NamespacePeerCache))
+		m.Node.Protocol.peerCache = tinder.NewPeerCache(logger, cacheDS, m.Node.Protocol.PeerCacheTTL)
+		if err := m.Node.Protocol.peerCache.Prune(); err != nil {
+			logger.Warn("unable to prune the peer cache", zap.Error(err))
+		}
+
+		rdvClients = append([]tinder.AsyncableDriver{tinder.NewPeerCacheDriver(logger, m.Node.Protocol.peerCache)}, rdvClients...)
+	}
+
+	var rdvClient tinder.Driver
+	switch len(rdvClients) {
 	case 1:
 		rdvClient = rdvClients[0]
 	default:
@@ -537,6 +593,37 @@ func (m *Manager) getRdvpMaddrs() ([]*peer.AddrInfo, error) {
 	return ipfsutil.ParseAndResolveRdvpMaddrs(m.getContext(), m.initLogger, addrs)
 }
 
+func (m *Manager) hasDefaultRdvp() bool {
+	for _, v := range strings.Split(m.Node.Protocol.RdvpMaddrs, ",") {
+		if v == ":default:" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) getBootstrapAddrs() []string {
+	if m.Node.Protocol.Bootstrap == "" {
+		return nil
+	}
+
+	bootstrapAddrs := []string{}
+	for _, addr := range strings.Split(m.Node.Protocol.Bootstrap, ",") {
+		switch addr {
+		case ":default:":
+			// the default bootstrap peers are part of the public network
+			if m.Node.Protocol.SwarmKey == "" {
+				bootstrapAddrs = append(bootstrapAddrs, ipfs_cfg.DefaultBootstrapAddresses...)
+			}
+		case ":none:":
+			return nil
+		default:
+			bootstrapAddrs = append(bootstrapAddrs, addr)
+		}
+	}
+	return bootstrapAddrs
+}
+
 func (m *Manager) getSwarmAddrs() []string {
 	if m.Node.Protocol.SwarmListeners == "" {
 		return nil
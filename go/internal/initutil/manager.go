@@ -80,9 +80,15 @@ type Manager struct {
 			MaxBackoff            time.Duration `json:"MaxBackoff,omitempty"`
 			DisableIPFSNetwork    bool          `json:"DisableIPFSNetwork,omitempty"`
 			RdvpMaddrs            string        `json:"RdvpMaddrs,omitempty"`
+			RdvpQuorum            int           `json:"RdvpQuorum,omitempty"`
+			SwarmKey              string        `json:"SwarmKey,omitempty"`
+			Bootstrap             string        `json:"Bootstrap,omitempty"`
 			AuthSecret            string        `json:"AuthSecret,omitempty"`
 			AuthPublicKey         string        `json:"AuthPublicKey,omitempty"`
+			AuthRevokedTokens     string        `json:"AuthRevokedTokens,omitempty"`
+			AuthTokenStore        string        `json:"AuthTokenStore,omitempty"`
 			PollInterval          time.Duration `json:"PollInterval,omitempty"`
+			PeerCacheTTL          time.Duration `json:"PeerCacheTTL,omitempty"`
 			Tor                   struct {
 				Mode       string `json:"Mode,omitempty"`
 				BinaryPath string `json:"BinaryPath,omitempty"`
@@ -96,6 +102,7 @@ type Manager struct {
 			ipfsAPI           ipfsutil.ExtendedCoreAPI
 			pubsub            *pubsub.PubSub
 			discovery         tinder.Driver
+			peerCache         *tinder.PeerCache
 			server            bertyprotocol.Service
 			ipfsAPIListeners  []net.Listener
 			ipfsWebUIListener net.Listener
@@ -103,6 +110,8 @@ type Manager struct {
 			requiredByClient  bool
 			ipfsWebUICleanup  func()
 			orbitDB           *bertyprotocol.BertyOrbitDB
+			authTokenVerifier *bertyprotocol.AuthTokenVerifier
+			authTokenStore    bertyprotocol.AuthTokenStore
 		}
 		Messenger struct {
 			DisableGroupMonitor  bool   `json:"DisableGroupMonitor,omitempty"`
@@ -111,6 +120,8 @@ type Manager struct {
 			RebuildSqlite        bool   `json:"RebuildSqlite,omitempty"`
 			MessengerSqliteOpts  string `json:"MessengerSqliteOpts,omitempty"`
 			ExportPathToRestore  string `json:"ExportPathToRestore,omitempty"`
+			ExportPassphrase     string `json:"-"` // not logged
+			DeviceLinkToJoin     string `json:"DeviceLinkToJoin,omitempty"`
 
 			// internal
 			protocolClient      bertyprotocol.Client
@@ -242,6 +253,7 @@ func (m *Manager) Close(prog *progress.Progress) error {
 	prog.AddStep("close-messenger-protocol-client")
 	prog.AddStep("cleanup-messenger-db")
 	prog.AddStep("close-protocol-server")
+	prog.AddStep("close-auth-token-store")
 	prog.AddStep("close-ipfs-node")
 	prog.AddStep("close-datastore")
 	prog.AddStep("cleanup-logging")
@@ -291,6 +303,11 @@ func (m *Manager) Close(prog *progress.Progress) error {
 		m.Node.Protocol.server.Close()
 	}
 
+	prog.Get("close-auth-token-store").SetAsCurrent()
+	if m.Node.Protocol.authTokenStore != nil {
+		m.Node.Protocol.authTokenStore.Close()
+	}
+
 	prog.Get("close-ipfs-node").SetAsCurrent()
 	if m.Node.Protocol.ipfsNode != nil {
 		m.Node.Protocol.ipfsNode.Close()
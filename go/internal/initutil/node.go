@@ -27,6 +27,7 @@ import (
 	"gorm.io/gorm"
 	"moul.io/zapgorm2"
 
+	"berty.tech/berty/v2/go/internal/bertylinks"
 	"berty.tech/berty/v2/go/internal/grpcutil"
 	"berty.tech/berty/v2/go/internal/ipfsutil"
 	"berty.tech/berty/v2/go/internal/lifecycle"
@@ -57,7 +58,9 @@ func (m *Manager) SetupLocalProtocolServerFlags(fs *flag.FlagSet) {
 
 func (m *Manager) SetupProtocolAuth(fs *flag.FlagSet) {
 	fs.StringVar(&m.Node.Protocol.AuthSecret, "node.auth-secret", "", "Protocol API Authentication Secret (base64 encoded)")
-	fs.StringVar(&m.Node.Protocol.AuthPublicKey, "node.auth-pk", "", "Protocol API Authentication Public Key (base64 encoded)")
+	fs.StringVar(&m.Node.Protocol.AuthPublicKey, "node.auth-pk", "", "Protocol API Authentication Public Keys (base64 encoded, comma separated, several keys are active while rotating)")
+	fs.StringVar(&m.Node.Protocol.AuthRevokedTokens, "node.auth-revoked-tokens", "", "Protocol API Authentication revoked token IDs (comma separated)")
+	fs.StringVar(&m.Node.Protocol.AuthTokenStore, "node.auth-token-store", "", "Protocol API Authentication token server store consulted for revoked tokens and retired signing keys (SQLite path)")
 }
 
 func (m *Manager) SetupEmptyGRPCListenersFlags(fs *flag.FlagSet) {
@@ -97,7 +100,9 @@ func (m *Manager) SetupLocalMessengerServerFlags(fs *flag.FlagSet) {
 	m.Node.Messenger.requiredByClient = true
 	m.SetupLocalProtocolServerFlags(fs)
 	m.SetupNotificationManagerFlags(fs)
-	fs.StringVar(&m.Node.Messenger.ExportPathToRestore, "node.restore-export-path", "", "inits node from a specified export path")
+	fs.StringVar(&m.Node.Messenger.ExportPathToRestore, "node.restore-export-path", "", "inits node from the specified export paths, comma separated, starting with a full export followed by its incremental exports")
+	fs.StringVar(&m.Node.Messenger.ExportPassphrase, "node.restore-export-passphrase", "", "passphrase used to decrypt the export, if encrypted")
+	fs.StringVar(&m.Node.Messenger.DeviceLinkToJoin, "node.link-device", "", "inits node by joining the account of the device which generated the specified device link")
 	fs.BoolVar(&m.Node.Messenger.RebuildSqlite, "node.rebuild-db", false, "reconstruct messenger DB from OrbitDB logs")
 	fs.BoolVar(&m.Node.Messenger.DisableGroupMonitor, "node.disable-group-monitor", false, "disable group monitoring")
 	fs.StringVar(&m.Node.Messenger.DisplayName, "node.display-name", safeDefaultDisplayName(), "display name")
@@ -181,6 +186,7 @@ func (m *Manager) getLocalProtocolServer() (bertyprotocol.Service, error) {
 			Host:           m.Node.Protocol.ipfsNode.PeerHost,
 			PubSub:         m.Node.Protocol.pubsub,
 			TinderDriver:   m.Node.Protocol.discovery,
+			PeerCache:      m.Node.Protocol.peerCache,
 			IpfsCoreAPI:    m.Node.Protocol.ipfsAPI,
 			Logger:         logger,
 			RootDatastore:  rootDS,
@@ -238,6 +244,11 @@ func (m *Manager) getGRPCClientConn() (*grpc.ClientConn, error) {
 				return nil, errcode.TODO.Wrap(err)
 			}
 
+			// join an existing account if provided
+			if err := m.linkDeviceFromLink(); err != nil {
+				return nil, errcode.TODO.Wrap(err)
+			}
+
 			if m.Node.Protocol.requiredByClient {
 				_, err := m.getLocalProtocolServer()
 				if err != nil {
@@ -394,7 +405,7 @@ func (m *Manager) getGRPCServer() (*grpc.Server, *grpcgw.ServeMux, error) {
 	authFunc := func(ctx context.Context) (context.Context, error) { return ctx, nil }
 
 	if m.Node.Protocol.AuthSecret != "" || m.Node.Protocol.AuthPublicKey != "" {
-		man, err := getAuthTokenVerifier(m.Node.Protocol.AuthSecret, m.Node.Protocol.AuthPublicKey)
+		man, err := m.getAuthTokenVerifier()
 		if err != nil {
 			return nil, nil, errcode.TODO.Wrap(err)
 		}
@@ -517,13 +528,10 @@ func (m *Manager) restoreMessengerDataFromExport() error {
 		return nil
 	}
 
-	f, err := os.Open(m.Node.Messenger.ExportPathToRestore)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = f.Close() }()
-
+	paths := strings.Split(m.Node.Messenger.ExportPathToRestore, ",")
+	passphrase := []byte(m.Node.Messenger.ExportPassphrase)
 	m.Node.Messenger.ExportPathToRestore = ""
+	m.Node.Messenger.ExportPassphrase = ""
 
 	logger, err := m.getLogger()
 	if err != nil {
@@ -542,7 +550,56 @@ func (m *Manager) restoreMessengerDataFromExport() error {
 
 	m.Node.Messenger.localDBState = &messengertypes.LocalDatabaseState{}
 
-	if err := bertymessenger.RestoreFromAccountExport(m.ctx, f, coreAPI, odb, m.Node.Messenger.localDBState, logger); err != nil {
+	// an incremental export is restored after the exports it is based on, the local state of the last one is kept
+	for _, p := range paths {
+		if err := func() error {
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+
+			return bertymessenger.RestoreFromAccountExport(m.ctx, f, passphrase, coreAPI, odb, m.Node.Messenger.localDBState, logger)
+		}(); err != nil {
+			return errcode.ErrInternal.Wrap(fmt.Errorf("unable to restore %s: %w", p, err))
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) linkDeviceFromLink() error {
+	if m.Node.Messenger.DeviceLinkToJoin == "" {
+		return nil
+	}
+
+	link, err := bertylinks.UnmarshalLink(m.Node.Messenger.DeviceLinkToJoin, nil)
+	if err != nil {
+		return errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	if !link.IsDeviceLink() {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("not a device link"))
+	}
+
+	m.Node.Messenger.DeviceLinkToJoin = ""
+
+	logger, err := m.getLogger()
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	ipfsAPI, _, err := m.getLocalIPFS()
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	odb, err := m.getOrbitDB()
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	if err := bertyprotocol.LinkDevice(m.getContext(), link.DeviceLink, ipfsAPI, odb, logger); err != nil {
 		return errcode.ErrInternal.Wrap(err)
 	}
 
@@ -565,6 +622,11 @@ func (m *Manager) getLocalMessengerServer() (messengertypes.MessengerServiceServ
 		return nil, errcode.TODO.Wrap(err)
 	}
 
+	// join an existing account if provided
+	if err := m.linkDeviceFromLink(); err != nil {
+		return nil, errcode.TODO.Wrap(err)
+	}
+
 	// logger
 	logger, err := m.getLogger()
 	if err != nil {
@@ -630,22 +692,65 @@ func (m *Manager) getLocalMessengerServer() (messengertypes.MessengerServiceServ
 	return m.Node.Messenger.server, nil
 }
 
-func getAuthTokenVerifier(secret, pk string) (*bertyprotocol.AuthTokenVerifier, error) {
-	rawSecret, err := base64.RawStdEncoding.DecodeString(secret)
+func (m *Manager) GetAuthTokenVerifier() (*bertyprotocol.AuthTokenVerifier, error) {
+	defer m.prepareForGetter()()
+
+	return m.getAuthTokenVerifier()
+}
+
+func (m *Manager) getAuthTokenVerifier() (*bertyprotocol.AuthTokenVerifier, error) {
+	if m.Node.Protocol.authTokenVerifier != nil {
+		return m.Node.Protocol.authTokenVerifier, nil
+	}
+
+	rawSecret, err := base64.RawStdEncoding.DecodeString(m.Node.Protocol.AuthSecret)
 	if err != nil {
 		return nil, err
 	}
 
-	rawPK, err := base64.RawStdEncoding.DecodeString(pk)
+	var rawPKs []ed25519.PublicKey
+	for _, pk := range strings.Split(m.Node.Protocol.AuthPublicKey, ",") {
+		rawPK, err := base64.RawStdEncoding.DecodeString(strings.TrimSpace(pk))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rawPK) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("empty or invalid pk size")
+		}
+
+		rawPKs = append(rawPKs, rawPK)
+	}
+
+	verifier, err := bertyprotocol.NewAuthTokenVerifier(rawSecret, rawPKs...)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(rawPK) != ed25519.PublicKeySize {
-		return nil, fmt.Errorf("empty or invalid pk size")
+	for _, tokenID := range strings.Split(m.Node.Protocol.AuthRevokedTokens, ",") {
+		if tokenID = strings.TrimSpace(tokenID); tokenID != "" {
+			verifier.RevokeToken(tokenID)
+		}
+	}
+
+	if m.Node.Protocol.AuthTokenStore != "" {
+		logger, err := m.getLogger()
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := bertyprotocol.NewAuthTokenSQLiteStore(m.Node.Protocol.AuthTokenStore, logger.Named("auth"))
+		if err != nil {
+			return nil, err
+		}
+
+		m.Node.Protocol.authTokenStore = store
+		verifier.SetRevocationList(store)
 	}
 
-	return bertyprotocol.NewAuthTokenVerifier(rawSecret, rawPK)
+	m.Node.Protocol.authTokenVerifier = verifier
+
+	return verifier, nil
 }
 
 func safeDefaultDisplayName() string {
@@ -0,0 +1,52 @@
+package ipfsutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/libp2p/go-libp2p-core/pnet"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// LoadSwarmKey reads a libp2p pre-shared key stored using the go-ipfs swarm.key format
+func LoadSwarmKey(path string) (pnet.PSK, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read swarm key: %w", err)
+	}
+
+	psk, err := pnet.DecodeV1PSK(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode swarm key %q: %w", path, err)
+	}
+
+	return psk, nil
+}
+
+// SwarmKeyFingerprint returns a short identifier of a pre-shared key, it allows to compare the keys of two nodes
+// without revealing them
+func SwarmKeyFingerprint(psk pnet.PSK) string {
+	sum := sha256.Sum256(psk)
+	return hex.EncodeToString(sum[:8])
+}
+
+// FilterOutQUICAddrs removes the QUIC addrs from a list of maddrs, QUIC connections can't be protected by a
+// pre-shared key
+func FilterOutQUICAddrs(addrs []string) []string {
+	filtered := []string{}
+	for _, addr := range addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err == nil {
+			if _, err := maddr.ValueForProtocol(ma.P_QUIC); err == nil {
+				continue
+			}
+		}
+
+		filtered = append(filtered, addr)
+	}
+
+	return filtered
+}
@@ -0,0 +1,73 @@
+package ipfsutil
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSwarmKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swarmkey")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyA := make([]byte, 32)
+	keyB := make([]byte, 32)
+	keyB[0] = 1
+
+	writeKey := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("unable to write swarm key: %s", err)
+		}
+		return path
+	}
+
+	pathA := writeKey("a.key", "/key/swarm/psk/1.0.0/\n/base16/\n"+hex.EncodeToString(keyA))
+	pathB := writeKey("b.key", "/key/swarm/psk/1.0.0/\n/base16/\n"+hex.EncodeToString(keyB))
+	pathInvalid := writeKey("invalid.key", "not a swarm key")
+
+	pskA, err := LoadSwarmKey(pathA)
+	if err != nil {
+		t.Fatalf("unable to load swarm key: %s", err)
+	}
+
+	if !reflect.DeepEqual([]byte(pskA), keyA) {
+		t.Fatalf("unexpected swarm key: %x", pskA)
+	}
+
+	pskB, err := LoadSwarmKey(pathB)
+	if err != nil {
+		t.Fatalf("unable to load swarm key: %s", err)
+	}
+
+	if SwarmKeyFingerprint(pskA) == SwarmKeyFingerprint(pskB) {
+		t.Fatalf("distinct keys should have distinct fingerprints")
+	}
+
+	if SwarmKeyFingerprint(pskA) != SwarmKeyFingerprint(pskA) {
+		t.Fatalf("fingerprints should be stable")
+	}
+
+	if _, err := LoadSwarmKey(pathInvalid); err == nil {
+		t.Fatalf("invalid swarm keys should be rejected")
+	}
+
+	if _, err := LoadSwarmKey(filepath.Join(dir, "missing.key")); err == nil {
+		t.Fatalf("missing swarm keys should be rejected")
+	}
+}
+
+func TestFilterOutQUICAddrs(t *testing.T) {
+	filtered := FilterOutQUICAddrs(DefaultSwarmListeners)
+	expected := []string{"/ip4/0.0.0.0/tcp/0", "/ip6/::/tcp/0"}
+
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Fatalf("expected %v, got %v", expected, filtered)
+	}
+}
@@ -0,0 +1,133 @@
+package rdvpserver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"go.uber.org/zap"
+)
+
+// EvictResult is returned by the admin api when a peer is evicted
+type EvictResult struct {
+	Peer          peer.ID `json:"peer"`
+	Registrations int     `json:"registrations"`
+}
+
+type adminHandler struct {
+	logger *zap.Logger
+	db     *QuotaDB
+	token  []byte
+	mux    *http.ServeMux
+}
+
+// NewAdminHandler returns the admin http api of a rendezvous server, every
+// request must provide the token using an `Authorization: Bearer <token>`
+// header.
+//
+//	GET  /namespaces                   lists the namespaces
+//	GET  /registrations?ns=<ns>        lists the registrations of a namespace
+//	GET  /registrations?peer=<peer id> lists the registrations of a peer
+//	POST /evict?peer=<peer id>         removes all the registrations of a peer
+func NewAdminHandler(logger *zap.Logger, db *QuotaDB, token string) (http.Handler, error) {
+	if token == "" {
+		return nil, fmt.Errorf("an admin token is required")
+	}
+
+	h := &adminHandler{
+		logger: logger.Named("rdvp/admin"),
+		db:     db,
+		token:  []byte(token),
+		mux:    http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("/namespaces", h.namespaces)
+	h.mux.HandleFunc("/registrations", h.registrations)
+	h.mux.HandleFunc("/evict", h.evict)
+
+	return h, nil
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *adminHandler) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	const prefix = "bearer "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), h.token) == 1
+}
+
+func (h *adminHandler) namespaces(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.writeJSON(w, h.db.Namespaces())
+}
+
+func (h *adminHandler) registrations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case query.Get("peer") != "":
+		p, err := peer.Decode(query.Get("peer"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid peer id: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		h.writeJSON(w, h.db.PeerRegistrations(p))
+	case query.Get("ns") != "":
+		h.writeJSON(w, h.db.Registrations(query.Get("ns")))
+	default:
+		http.Error(w, "a `ns` or `peer` parameter is required", http.StatusBadRequest)
+	}
+}
+
+func (h *adminHandler) evict(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, err := peer.Decode(r.URL.Query().Get("peer"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid peer id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.db.EvictPeer(p)
+	if err != nil {
+		h.logger.Error("unable to evict peer", zap.Stringer("peer", p), zap.Error(err))
+		http.Error(w, "unable to evict peer", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, &EvictResult{Peer: p, Registrations: count})
+}
+
+func (h *adminHandler) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Warn("unable to write response", zap.Error(err))
+	}
+}
@@ -0,0 +1,119 @@
+package rdvpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"berty.tech/berty/v2/go/internal/testutil"
+)
+
+func TestAdminHandler(t *testing.T) {
+	db := testQuotaDB(t, Limits{})
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	_, err := NewAdminHandler(logger, db, "")
+	require.Error(t, err)
+
+	handler, err := NewAdminHandler(logger, db, "secret")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	p := testPeerID(t)
+	_, err = db.Register(p, "ns1", nil, 60)
+	require.NoError(t, err)
+
+	do := func(method, path, token string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+
+		return res
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/namespaces", "").StatusCode)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/namespaces", "wrong").StatusCode)
+
+	res := do(http.MethodGet, "/namespaces", "secret")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var namespaces []*NamespaceInfo
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&namespaces))
+	require.Equal(t, []*NamespaceInfo{{Namespace: "ns1", Registrations: 1}}, namespaces)
+
+	res = do(http.MethodGet, "/registrations?ns=ns1", "secret")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var regs []*RegistrationInfo
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&regs))
+	require.Len(t, regs, 1)
+	require.Equal(t, p, regs[0].Peer)
+
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/registrations", "secret").StatusCode)
+	require.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/evict?peer="+p.String(), "secret").StatusCode)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/evict?peer=invalid", "secret").StatusCode)
+
+	res = do(http.MethodPost, "/evict?peer="+p.String(), "secret")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var evicted EvictResult
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&evicted))
+	require.Equal(t, EvictResult{Peer: p, Registrations: 1}, evicted)
+
+	require.Empty(t, db.Namespaces())
+	require.Empty(t, db.PeerRegistrations(p))
+
+	// evicting a peer without registrations is a no-op
+	res = do(http.MethodPost, "/evict?peer="+p.String(), "secret")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&evicted))
+	require.Equal(t, EvictResult{Peer: p, Registrations: 0}, evicted)
+}
+
+func TestAdminHandler_Auth(t *testing.T) {
+	db := testQuotaDB(t, Limits{})
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	handler, err := NewAdminHandler(logger, db, "secret")
+	require.NoError(t, err)
+
+	for _, c := range []struct {
+		header string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Basic secret", http.StatusUnauthorized},
+		{"Bearer", http.StatusUnauthorized},
+		{"Bearer ", http.StatusUnauthorized},
+		{"Bearer secre", http.StatusUnauthorized},
+		{"Bearer secrets", http.StatusUnauthorized},
+		{"Bearer  secret", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+		{"bearer secret", http.StatusOK},
+		{"BEARER secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/namespaces", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, c.status, rec.Code, "header %q", c.header)
+		if c.status == http.StatusUnauthorized {
+			require.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
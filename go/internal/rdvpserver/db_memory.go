@@ -0,0 +1,197 @@
+package rdvpserver
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	dbi "github.com/libp2p/go-libp2p-rendezvous/db"
+)
+
+// cookies are made of the db nonce, the last returned counter and the namespace
+const cookieHeaderLen = 16
+
+var _ dbi.DB = (*MemoryDB)(nil)
+
+type memoryRegistration struct {
+	counter uint64
+	addrs   [][]byte
+	expire  time.Time
+}
+
+// MemoryDB is a rendezvous db keeping the registrations in memory, it is
+// mainly intended for tests, everything is lost when the server stops
+type MemoryDB struct {
+	mu sync.Mutex
+
+	nonce   uint64
+	counter uint64
+
+	// ns -> peer -> registration
+	registrations map[string]map[peer.ID]*memoryRegistration
+}
+
+func NewMemoryDB() (*MemoryDB, error) {
+	var raw [8]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return nil, fmt.Errorf("unable to generate db nonce: %w", err)
+	}
+
+	return &MemoryDB{
+		nonce:         binary.BigEndian.Uint64(raw[:]),
+		registrations: make(map[string]map[peer.ID]*memoryRegistration),
+	}, nil
+}
+
+func (db *MemoryDB) Close() error { return nil }
+
+func (db *MemoryDB) Register(p peer.ID, ns string, addrs [][]byte, ttl int) (uint64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	peers, ok := db.registrations[ns]
+	if !ok {
+		peers = make(map[peer.ID]*memoryRegistration)
+		db.registrations[ns] = peers
+	}
+
+	// a new counter is used on each registration, so the peer is returned
+	// again to the clients using a cookie
+	db.counter++
+	peers[p] = &memoryRegistration{
+		counter: db.counter,
+		addrs:   addrs,
+		expire:  time.Now().Add(time.Duration(ttl) * time.Second),
+	}
+
+	return db.counter, nil
+}
+
+func (db *MemoryDB) Unregister(p peer.ID, ns string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if ns == "" {
+		for ns := range db.registrations {
+			db.unregister(p, ns)
+		}
+		return nil
+	}
+
+	db.unregister(p, ns)
+	return nil
+}
+
+func (db *MemoryDB) unregister(p peer.ID, ns string) {
+	peers, ok := db.registrations[ns]
+	if !ok {
+		return
+	}
+
+	delete(peers, p)
+	if len(peers) == 0 {
+		delete(db.registrations, ns)
+	}
+}
+
+func (db *MemoryDB) CountRegistrations(p peer.ID) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	count := 0
+	for _, peers := range db.registrations {
+		if reg, ok := peers[p]; ok && reg.expire.After(now) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (db *MemoryDB) Discover(ns string, cookie []byte, limit int) ([]dbi.RegistrationRecord, []byte, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var after uint64
+	if cookie != nil {
+		if !db.validCookie(ns, cookie) {
+			return nil, nil, fmt.Errorf("bad cookie")
+		}
+		after = binary.BigEndian.Uint64(cookie[8:cookieHeaderLen])
+	}
+
+	type record struct {
+		counter uint64
+		dbi.RegistrationRecord
+	}
+
+	now := time.Now()
+	records := []record{}
+	for rns, peers := range db.registrations {
+		if ns != "" && rns != ns {
+			continue
+		}
+
+		for p, reg := range peers {
+			if !reg.expire.After(now) {
+				// lazily remove the expired registrations
+				db.unregister(p, rns)
+				continue
+			}
+
+			if reg.counter <= after {
+				continue
+			}
+
+			records = append(records, record{
+				counter: reg.counter,
+				RegistrationRecord: dbi.RegistrationRecord{
+					Id:    p,
+					Addrs: reg.addrs,
+					Ns:    rns,
+					Ttl:   int(reg.expire.Sub(now) / time.Second),
+				},
+			})
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].counter < records[j].counter })
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	regs := make([]dbi.RegistrationRecord, len(records))
+	for i, rec := range records {
+		regs[i] = rec.RegistrationRecord
+		after = rec.counter
+	}
+
+	return regs, db.packCookie(ns, after), nil
+}
+
+func (db *MemoryDB) ValidCookie(ns string, cookie []byte) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.validCookie(ns, cookie)
+}
+
+func (db *MemoryDB) validCookie(ns string, cookie []byte) bool {
+	// the cookies of a previous instance are rejected
+	return len(cookie) >= cookieHeaderLen &&
+		binary.BigEndian.Uint64(cookie[:8]) == db.nonce &&
+		string(cookie[cookieHeaderLen:]) == ns
+}
+
+func (db *MemoryDB) packCookie(ns string, counter uint64) []byte {
+	cookie := make([]byte, cookieHeaderLen+len(ns))
+	binary.BigEndian.PutUint64(cookie[:8], db.nonce)
+	binary.BigEndian.PutUint64(cookie[8:cookieHeaderLen], counter)
+	copy(cookie[cookieHeaderLen:], ns)
+	return cookie
+}
@@ -0,0 +1,106 @@
+package rdvpserver
+
+import (
+	crand "crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func testPeerID(t *testing.T) peer.ID {
+	t.Helper()
+
+	_, pub, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	id, err := peer.IDFromPublicKey(pub)
+	require.NoError(t, err)
+
+	return id
+}
+
+func TestMemoryDB(t *testing.T) {
+	db, err := NewMemoryDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	p1, p2 := testPeerID(t), testPeerID(t)
+
+	_, err = db.Register(p1, "ns1", nil, 60)
+	require.NoError(t, err)
+	_, err = db.Register(p2, "ns1", nil, 60)
+	require.NoError(t, err)
+	_, err = db.Register(p1, "ns2", nil, 60)
+	require.NoError(t, err)
+
+	count, err := db.CountRegistrations(p1)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	// paginate using the cookie
+	regs, cookie, err := db.Discover("ns1", nil, 1)
+	require.NoError(t, err)
+	require.Len(t, regs, 1)
+	require.Equal(t, p1, regs[0].Id)
+	require.Equal(t, "ns1", regs[0].Ns)
+	require.True(t, db.ValidCookie("ns1", cookie))
+	require.False(t, db.ValidCookie("ns2", cookie))
+
+	regs, cookie, err = db.Discover("ns1", cookie, 10)
+	require.NoError(t, err)
+	require.Len(t, regs, 1)
+	require.Equal(t, p2, regs[0].Id)
+
+	regs, _, err = db.Discover("ns1", cookie, 10)
+	require.NoError(t, err)
+	require.Empty(t, regs)
+
+	// a renewed registration is returned again
+	_, err = db.Register(p1, "ns1", nil, 60)
+	require.NoError(t, err)
+	regs, _, err = db.Discover("ns1", cookie, 10)
+	require.NoError(t, err)
+	require.Len(t, regs, 1)
+	require.Equal(t, p1, regs[0].Id)
+
+	// the empty namespace matches all the registrations
+	regs, _, err = db.Discover("", nil, 10)
+	require.NoError(t, err)
+	require.Len(t, regs, 3)
+
+	require.NoError(t, db.Unregister(p1, ""))
+	count, err = db.CountRegistrations(p1)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	// the cookies of another db are rejected
+	other, err := NewMemoryDB()
+	require.NoError(t, err)
+	require.False(t, other.ValidCookie("ns1", cookie))
+	_, _, err = other.Discover("ns1", cookie, 10)
+	require.Error(t, err)
+}
+
+func TestMemoryDB_Expire(t *testing.T) {
+	db, err := NewMemoryDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	p := testPeerID(t)
+
+	_, err = db.Register(p, "ns", nil, 1)
+	require.NoError(t, err)
+
+	time.Sleep(time.Second + time.Millisecond*100)
+
+	count, err := db.CountRegistrations(p)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	regs, _, err := db.Discover("ns", nil, 10)
+	require.NoError(t, err)
+	require.Empty(t, regs)
+}
@@ -0,0 +1,352 @@
+package rdvpserver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	dbi "github.com/libp2p/go-libp2p-rendezvous/db"
+	ma "github.com/multiformats/go-multiaddr"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTooManyPeerRegistrations      = errors.New("too many registrations for this peer")
+	ErrTooManyNamespaceRegistrations = errors.New("too many registrations in this namespace")
+	ErrTTLTooLong                    = errors.New("registration ttl too long")
+)
+
+// Limits are the quotas applied by a QuotaDB, a zero value disables the limit
+type Limits struct {
+	MaxRegistrationsPerPeer      int
+	MaxRegistrationsPerNamespace int
+	MaxTTL                       time.Duration
+}
+
+// NamespaceInfo describes a namespace known by a QuotaDB
+type NamespaceInfo struct {
+	Namespace     string `json:"namespace"`
+	Registrations int    `json:"registrations"`
+}
+
+// RegistrationInfo describes a registration known by a QuotaDB
+type RegistrationInfo struct {
+	Peer      peer.ID   `json:"peer"`
+	Namespace string    `json:"namespace"`
+	Addrs     []string  `json:"addrs"`
+	Expire    time.Time `json:"expire"`
+}
+
+type quotaRegistration struct {
+	addrs  [][]byte
+	expire time.Time
+}
+
+const (
+	// quotaDBPruneInterval is the interval between two sweeps of the expired registrations of the index
+	quotaDBPruneInterval = time.Minute
+	// quotaDBIndexBatchSize is the amount of registrations read at once when indexing the wrapped db
+	quotaDBIndexBatchSize = 1000
+)
+
+var _ dbi.DB = (*QuotaDB)(nil)
+
+// QuotaDB wraps a rendezvous db to enforce the registration limits, it keeps
+// an index of the registrations, used by the admin api. The registrations
+// already stored in a persistent db are indexed when the QuotaDB is created.
+type QuotaDB struct {
+	dbi.DB
+
+	logger *zap.Logger
+	limits Limits
+
+	mu sync.Mutex
+	// ns -> peer -> registration
+	namespaces map[string]map[peer.ID]*quotaRegistration
+	// peer -> ns
+	peers map[peer.ID]map[string]struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewQuotaDB indexes the registrations of db and sweeps the expired ones
+// periodically until the QuotaDB is closed
+func NewQuotaDB(logger *zap.Logger, db dbi.DB, limits Limits) *QuotaDB {
+	q := &QuotaDB{
+		DB:         db,
+		logger:     logger.Named("rdvp/quota"),
+		limits:     limits,
+		namespaces: make(map[string]map[peer.ID]*quotaRegistration),
+		peers:      make(map[peer.ID]map[string]struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if err := q.index(); err != nil {
+		q.logger.Warn("unable to index the existing registrations", zap.Error(err))
+	}
+
+	go q.runPruner()
+
+	return q
+}
+
+// Close stops the periodic sweep of the index and closes the wrapped db
+func (q *QuotaDB) Close() error {
+	q.closeOnce.Do(func() { close(q.done) })
+
+	return q.DB.Close()
+}
+
+func (q *QuotaDB) Register(p peer.ID, ns string, addrs [][]byte, ttl int) (uint64, error) {
+	if q.limits.MaxTTL > 0 && time.Duration(ttl)*time.Second > q.limits.MaxTTL {
+		q.logger.Debug("registration rejected", zap.Stringer("peer", p), zap.String("ns", ns), zap.Int("ttl", ttl), zap.Error(ErrTTLTooLong))
+		return 0, fmt.Errorf("%w: %ds > %s", ErrTTLTooLong, ttl, q.limits.MaxTTL)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.pruneNamespace(ns, now)
+	q.prunePeer(p, now)
+
+	// renewing a registration doesn't count against the quotas
+	if _, renew := q.namespaces[ns][p]; !renew {
+		if max := q.limits.MaxRegistrationsPerPeer; max > 0 && len(q.peers[p]) >= max {
+			q.logger.Warn("registration rejected", zap.Stringer("peer", p), zap.String("ns", ns), zap.Error(ErrTooManyPeerRegistrations))
+			return 0, ErrTooManyPeerRegistrations
+		}
+
+		if max := q.limits.MaxRegistrationsPerNamespace; max > 0 && len(q.namespaces[ns]) >= max {
+			q.logger.Warn("registration rejected", zap.Stringer("peer", p), zap.String("ns", ns), zap.Error(ErrTooManyNamespaceRegistrations))
+			return 0, ErrTooManyNamespaceRegistrations
+		}
+	}
+
+	counter, err := q.DB.Register(p, ns, addrs, ttl)
+	if err != nil {
+		return 0, err
+	}
+
+	q.add(p, ns, addrs, now.Add(time.Duration(ttl)*time.Second))
+
+	return counter, nil
+}
+
+func (q *QuotaDB) Unregister(p peer.ID, ns string) error {
+	if err := q.DB.Unregister(p, ns); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if ns == "" {
+		for ns := range q.peers[p] {
+			q.remove(p, ns)
+		}
+		return nil
+	}
+
+	q.remove(p, ns)
+	return nil
+}
+
+// Namespaces lists the namespaces with at least one active registration
+func (q *QuotaDB) Namespaces() []*NamespaceInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	infos := []*NamespaceInfo{}
+	for ns := range q.namespaces {
+		q.pruneNamespace(ns, now)
+		if count := len(q.namespaces[ns]); count > 0 {
+			infos = append(infos, &NamespaceInfo{Namespace: ns, Registrations: count})
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Namespace < infos[j].Namespace })
+
+	return infos
+}
+
+// Registrations returns the active registrations of a namespace
+func (q *QuotaDB) Registrations(ns string) []*RegistrationInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pruneNamespace(ns, time.Now())
+
+	infos := []*RegistrationInfo{}
+	for p, reg := range q.namespaces[ns] {
+		infos = append(infos, newRegistrationInfo(p, ns, reg))
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Peer < infos[j].Peer })
+
+	return infos
+}
+
+// PeerRegistrations returns the active registrations of a peer
+func (q *QuotaDB) PeerRegistrations(p peer.ID) []*RegistrationInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.prunePeer(p, time.Now())
+
+	infos := []*RegistrationInfo{}
+	for ns := range q.peers[p] {
+		infos = append(infos, newRegistrationInfo(p, ns, q.namespaces[ns][p]))
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Namespace < infos[j].Namespace })
+
+	return infos
+}
+
+// EvictPeer removes all the registrations of a peer and returns their count
+func (q *QuotaDB) EvictPeer(p peer.ID) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.prunePeer(p, time.Now())
+	count := len(q.peers[p])
+
+	// the empty namespace unregisters the peer from all the namespaces,
+	// including the ones registered before the server started
+	if err := q.DB.Unregister(p, ""); err != nil {
+		return 0, err
+	}
+
+	for ns := range q.peers[p] {
+		q.remove(p, ns)
+	}
+
+	q.logger.Info("peer evicted", zap.Stringer("peer", p), zap.Int("registrations", count))
+
+	return count, nil
+}
+
+// index adds the registrations stored in the wrapped db to the index
+func (q *QuotaDB) index() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var cookie []byte
+	for {
+		regs, next, err := q.DB.Discover("", cookie, quotaDBIndexBatchSize)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, reg := range regs {
+			q.add(reg.Id, reg.Ns, reg.Addrs, now.Add(time.Duration(reg.Ttl)*time.Second))
+		}
+
+		if len(regs) < quotaDBIndexBatchSize {
+			q.logger.Debug("existing registrations indexed", zap.Int("namespaces", len(q.namespaces)), zap.Int("peers", len(q.peers)))
+			return nil
+		}
+
+		cookie = next
+	}
+}
+
+func (q *QuotaDB) runPruner() {
+	ticker := time.NewTicker(quotaDBPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.done:
+			return
+		case now := <-ticker.C:
+			q.prune(now)
+		}
+	}
+}
+
+// prune removes the expired registrations from the index, including the ones
+// of the namespaces and peers which aren't used anymore
+func (q *QuotaDB) prune(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for ns := range q.namespaces {
+		q.pruneNamespace(ns, now)
+	}
+}
+
+func (q *QuotaDB) add(p peer.ID, ns string, addrs [][]byte, expire time.Time) {
+	regs, ok := q.namespaces[ns]
+	if !ok {
+		regs = make(map[peer.ID]*quotaRegistration)
+		q.namespaces[ns] = regs
+	}
+	regs[p] = &quotaRegistration{
+		addrs:  addrs,
+		expire: expire,
+	}
+
+	nss, ok := q.peers[p]
+	if !ok {
+		nss = make(map[string]struct{})
+		q.peers[p] = nss
+	}
+	nss[ns] = struct{}{}
+}
+
+func (q *QuotaDB) pruneNamespace(ns string, now time.Time) {
+	for p, reg := range q.namespaces[ns] {
+		if !reg.expire.After(now) {
+			q.remove(p, ns)
+		}
+	}
+}
+
+func (q *QuotaDB) prunePeer(p peer.ID, now time.Time) {
+	for ns := range q.peers[p] {
+		if reg := q.namespaces[ns][p]; reg == nil || !reg.expire.After(now) {
+			q.remove(p, ns)
+		}
+	}
+}
+
+func (q *QuotaDB) remove(p peer.ID, ns string) {
+	if regs, ok := q.namespaces[ns]; ok {
+		delete(regs, p)
+		if len(regs) == 0 {
+			delete(q.namespaces, ns)
+		}
+	}
+
+	if nss, ok := q.peers[p]; ok {
+		delete(nss, ns)
+		if len(nss) == 0 {
+			delete(q.peers, p)
+		}
+	}
+}
+
+func newRegistrationInfo(p peer.ID, ns string, reg *quotaRegistration) *RegistrationInfo {
+	info := &RegistrationInfo{
+		Peer:      p,
+		Namespace: ns,
+		Addrs:     []string{},
+		Expire:    reg.expire,
+	}
+
+	for _, raw := range reg.addrs {
+		if addr, err := ma.NewMultiaddrBytes(raw); err == nil {
+			info.Addrs = append(info.Addrs, addr.String())
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,150 @@
+package rdvpserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"berty.tech/berty/v2/go/internal/testutil"
+)
+
+func testQuotaDB(t *testing.T, limits Limits) *QuotaDB {
+	t.Helper()
+
+	logger, cleanup := testutil.Logger(t)
+	t.Cleanup(cleanup)
+
+	db, err := NewMemoryDB()
+	require.NoError(t, err)
+
+	q := NewQuotaDB(logger, db, limits)
+	t.Cleanup(func() { q.Close() })
+
+	return q
+}
+
+func TestQuotaDB_Limits(t *testing.T) {
+	db := testQuotaDB(t, Limits{
+		MaxRegistrationsPerPeer:      2,
+		MaxRegistrationsPerNamespace: 2,
+		MaxTTL:                       time.Hour,
+	})
+
+	p1, p2, p3 := testPeerID(t), testPeerID(t), testPeerID(t)
+
+	_, err := db.Register(p1, "ns1", nil, int(time.Hour/time.Second)+1)
+	require.ErrorIs(t, err, ErrTTLTooLong)
+
+	_, err = db.Register(p1, "ns1", nil, 60)
+	require.NoError(t, err)
+	_, err = db.Register(p1, "ns2", nil, 60)
+	require.NoError(t, err)
+
+	_, err = db.Register(p1, "ns3", nil, 60)
+	require.ErrorIs(t, err, ErrTooManyPeerRegistrations)
+
+	// renewing doesn't count against the quota
+	_, err = db.Register(p1, "ns2", nil, 60)
+	require.NoError(t, err)
+
+	_, err = db.Register(p2, "ns1", nil, 60)
+	require.NoError(t, err)
+	_, err = db.Register(p3, "ns1", nil, 60)
+	require.ErrorIs(t, err, ErrTooManyNamespaceRegistrations)
+
+	// the rejected registrations aren't stored
+	count, err := db.CountRegistrations(p1)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	count, err = db.CountRegistrations(p3)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	// unregistering frees the quota
+	require.NoError(t, db.Unregister(p1, "ns2"))
+	_, err = db.Register(p1, "ns3", nil, 60)
+	require.NoError(t, err)
+}
+
+func TestQuotaDB_Evict(t *testing.T) {
+	db := testQuotaDB(t, Limits{})
+
+	p1, p2 := testPeerID(t), testPeerID(t)
+
+	for _, ns := range []string{"ns1", "ns2"} {
+		_, err := db.Register(p1, ns, nil, 60)
+		require.NoError(t, err)
+	}
+	_, err := db.Register(p2, "ns1", nil, 60)
+	require.NoError(t, err)
+
+	require.Equal(t, []*NamespaceInfo{
+		{Namespace: "ns1", Registrations: 2},
+		{Namespace: "ns2", Registrations: 1},
+	}, db.Namespaces())
+	require.Len(t, db.PeerRegistrations(p1), 2)
+
+	count, err := db.EvictPeer(p1)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	require.Equal(t, []*NamespaceInfo{{Namespace: "ns1", Registrations: 1}}, db.Namespaces())
+	require.Empty(t, db.PeerRegistrations(p1))
+
+	regs, _, err := db.Discover("", nil, 10)
+	require.NoError(t, err)
+	require.Len(t, regs, 1)
+	require.Equal(t, p2, regs[0].Id)
+}
+
+func TestQuotaDB_Index(t *testing.T) {
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	db, err := NewMemoryDB()
+	require.NoError(t, err)
+
+	// registrations stored before the quota db is created
+	p1, p2 := testPeerID(t), testPeerID(t)
+	for _, ns := range []string{"ns1", "ns2"} {
+		_, err := db.Register(p1, ns, nil, 60)
+		require.NoError(t, err)
+	}
+
+	q := NewQuotaDB(logger, db, Limits{MaxRegistrationsPerPeer: 2})
+	defer q.Close()
+
+	require.Equal(t, []*NamespaceInfo{
+		{Namespace: "ns1", Registrations: 1},
+		{Namespace: "ns2", Registrations: 1},
+	}, q.Namespaces())
+	require.Len(t, q.PeerRegistrations(p1), 2)
+
+	// the indexed registrations are counted by the quotas
+	_, err = q.Register(p1, "ns3", nil, 60)
+	require.ErrorIs(t, err, ErrTooManyPeerRegistrations)
+	_, err = q.Register(p2, "ns3", nil, 60)
+	require.NoError(t, err)
+}
+
+func TestQuotaDB_Prune(t *testing.T) {
+	db := testQuotaDB(t, Limits{})
+
+	p1, p2 := testPeerID(t), testPeerID(t)
+
+	_, err := db.Register(p1, "ns1", nil, 1)
+	require.NoError(t, err)
+	_, err = db.Register(p2, "ns2", nil, 60)
+	require.NoError(t, err)
+
+	db.prune(time.Now().Add(2 * time.Second))
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	require.NotContains(t, db.namespaces, "ns1")
+	require.NotContains(t, db.peers, p1)
+	require.Contains(t, db.namespaces, "ns2")
+	require.Contains(t, db.peers, p2)
+}
@@ -0,0 +1,3 @@
+// Package rdvpserver contains the helpers used by the rendezvous point server:
+// registration quotas, per peer rate limiting, an in-memory db and an admin api.
+package rdvpserver
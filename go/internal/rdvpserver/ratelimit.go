@@ -0,0 +1,186 @@
+package rdvpserver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"go.uber.org/zap"
+)
+
+// the buckets of the peers idle for this long are full again, they can be dropped
+const rateLimiterGCInterval = time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is a per peer token bucket, each request consumes a token and
+// the tokens are refilled at the given rate up to burst
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[peer.ID]*tokenBucket
+	lastGC  time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rate requests per second with
+// bursts of burst requests, a rate <= 0 disables the limiter
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[peer.ID]*tokenBucket),
+		lastGC:  time.Now(),
+	}
+}
+
+// Allow consumes a token of the peer, it returns false if none is left
+func (rl *RateLimiter) Allow(p peer.ID) bool {
+	if rl.rate <= 0 {
+		return true
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.gc(now)
+
+	b, ok := rl.buckets[p]
+	if !ok {
+		b = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[p] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * rl.rate
+	if b.tokens > rl.burst {
+		b.tokens = rl.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+func (rl *RateLimiter) gc(now time.Time) {
+	if now.Sub(rl.lastGC) < rateLimiterGCInterval {
+		return
+	}
+	rl.lastGC = now
+
+	refill := time.Duration(rl.burst / rl.rate * float64(time.Second))
+	for p, b := range rl.buckets {
+		if now.Sub(b.last) > refill {
+			delete(rl.buckets, p)
+		}
+	}
+}
+
+type rateLimitedHost struct {
+	host.Host
+
+	logger  *zap.Logger
+	limiter *RateLimiter
+}
+
+// NewRateLimitedHost wraps a host so each request of a peer consumes a token of
+// the limiter, the streams of a peer exceeding the limiter rate are reset before
+// reaching the protocol handlers or as soon as a request exceeds it
+func NewRateLimitedHost(logger *zap.Logger, h host.Host, limiter *RateLimiter) host.Host {
+	return &rateLimitedHost{
+		Host:    h,
+		logger:  logger.Named("rdvp/ratelimit"),
+		limiter: limiter,
+	}
+}
+
+func (h *rateLimitedHost) limit(handler network.StreamHandler) network.StreamHandler {
+	return func(s network.Stream) {
+		handler(newRateLimitedStream(h.logger, s, h.limiter))
+	}
+}
+
+func (h *rateLimitedHost) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
+	h.Host.SetStreamHandler(pid, h.limit(handler))
+}
+
+func (h *rateLimitedHost) SetStreamHandlerMatch(pid protocol.ID, match func(string) bool, handler network.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, match, h.limit(handler))
+}
+
+// ErrRateLimited is returned when reading a request exceeding the rate limit
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// rateLimitedStream consumes a token of the limiter for each request read
+// from the stream, the requests are varint length-prefixed messages
+type rateLimitedStream struct {
+	network.Stream
+
+	logger  *zap.Logger
+	limiter *RateLimiter
+	peer    peer.ID
+
+	// the length prefix of the next request, and what is left to read of the
+	// current one
+	prefix    uint64
+	shift     uint
+	remaining uint64
+}
+
+func newRateLimitedStream(logger *zap.Logger, s network.Stream, limiter *RateLimiter) *rateLimitedStream {
+	return &rateLimitedStream{
+		Stream:  s,
+		logger:  logger,
+		limiter: limiter,
+		peer:    s.Conn().RemotePeer(),
+	}
+}
+
+func (s *rateLimitedStream) Read(b []byte) (int, error) {
+	n, err := s.Stream.Read(b)
+
+	for _, c := range b[:n] {
+		if s.remaining > 0 {
+			s.remaining--
+			continue
+		}
+
+		// a new request starts with its length prefix
+		if s.shift == 0 && !s.limiter.Allow(s.peer) {
+			s.logger.Debug("rate limit exceeded", zap.Stringer("peer", s.peer), zap.String("protocol", string(s.Protocol())))
+			_ = s.Stream.Reset()
+			return 0, ErrRateLimited
+		}
+
+		s.prefix |= uint64(c&0x7f) << s.shift
+		if c&0x80 != 0 {
+			s.shift += 7
+			if s.shift >= 64 {
+				_ = s.Stream.Reset()
+				return 0, fmt.Errorf("invalid length prefix")
+			}
+			continue
+		}
+
+		s.remaining, s.prefix, s.shift = s.prefix, 0, 0
+	}
+
+	return n, err
+}
@@ -0,0 +1,91 @@
+package rdvpserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRateLimiter(t *testing.T) {
+	rl := NewRateLimiter(10, 3)
+	p1, p2 := testPeerID(t), testPeerID(t)
+
+	for i := 0; i < 3; i++ {
+		require.True(t, rl.Allow(p1))
+	}
+	require.False(t, rl.Allow(p1))
+
+	// the buckets are per peer
+	require.True(t, rl.Allow(p2))
+
+	// a token is refilled every 100ms
+	time.Sleep(time.Millisecond * 150)
+	require.True(t, rl.Allow(p1))
+	require.False(t, rl.Allow(p1))
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	rl := NewRateLimiter(0, 1)
+	p := testPeerID(t)
+
+	for i := 0; i < 100; i++ {
+		require.True(t, rl.Allow(p))
+	}
+}
+
+type testStream struct {
+	network.Stream
+
+	r     io.Reader
+	reset bool
+}
+
+func (s *testStream) Read(b []byte) (int, error) {
+	// small reads, so the requests are split across them
+	if len(b) > 7 {
+		b = b[:7]
+	}
+
+	return s.r.Read(b)
+}
+
+func (s *testStream) Reset() error {
+	s.reset = true
+	return nil
+}
+
+func (s *testStream) Protocol() protocol.ID { return "/test" }
+
+func TestRateLimitedStream(t *testing.T) {
+	requests := &bytes.Buffer{}
+	for _, size := range []int{10, 0, 300} {
+		prefix := make([]byte, binary.MaxVarintLen64)
+		requests.Write(prefix[:binary.PutUvarint(prefix, uint64(size))])
+		requests.Write(bytes.Repeat([]byte{0x80}, size))
+	}
+
+	// each request consumes a token, whatever the number of reads
+	stream := &testStream{r: bytes.NewReader(requests.Bytes())}
+	limited := &rateLimitedStream{Stream: stream, logger: zap.NewNop(), limiter: NewRateLimiter(0.001, 3), peer: testPeerID(t)}
+
+	data, err := ioutil.ReadAll(limited)
+	require.NoError(t, err)
+	require.Equal(t, requests.Bytes(), data)
+	require.False(t, stream.reset)
+
+	// the stream is reset once a request exceeds the limit
+	stream = &testStream{r: bytes.NewReader(requests.Bytes())}
+	limited = &rateLimitedStream{Stream: stream, logger: zap.NewNop(), limiter: NewRateLimiter(0.001, 2), peer: testPeerID(t)}
+
+	_, err = ioutil.ReadAll(limited)
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.True(t, stream.reset)
+}
@@ -2,6 +2,7 @@ package tinder
 
 import (
 	"context"
+	"errors"
 
 	p2p_discovery "github.com/libp2p/go-libp2p-core/discovery"
 )
@@ -9,6 +10,10 @@ import (
 // Driver is a p2p_discovery.Discovery
 var _ p2p_discovery.Discovery = (Driver)(nil)
 
+// ErrAdvertiseNotSupported is returned by the drivers which only find peers,
+// a MultiDriver doesn't count it as an advertise failure
+var ErrAdvertiseNotSupported = errors.New("the driver can't advertise")
+
 type Unregisterer interface {
 	Unregister(ctx context.Context, ns string) error
 }
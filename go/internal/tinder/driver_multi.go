@@ -2,6 +2,7 @@ package tinder
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
@@ -11,12 +12,14 @@ import (
 	"go.uber.org/zap"
 )
 
-// MultiDriver is a simple driver manager, that forward request across multiple driver
+// MultiDriver is a simple driver manager, that forward request across multiple driver.
+// The failing drivers are backed off, see DriversStats.
 type MultiDriver struct {
 	logger *zap.Logger
 	// Stores AsyncableDriver for ease with AsyncMultiDriver.
 	// That mean they must be bundled in a noop.
 	drivers []AsyncableDriver
+	health  []*driverHealth
 
 	mapc map[string]context.CancelFunc
 	muc  sync.Mutex
@@ -36,6 +39,7 @@ func NewMultiDriver(logger *zap.Logger, drivers ...Driver) Driver {
 		return &MultiDriver{
 			logger:  logger.Named("tinder/multi"),
 			drivers: noopedDrivers,
+			health:  newDriversHealth(noopedDrivers),
 			mapc:    make(map[string]context.CancelFunc),
 		}
 	}
@@ -59,12 +63,13 @@ func NewAsyncMultiDriver(logger *zap.Logger, drivers ...AsyncableDriver) Asyncab
 		return &AsyncMultiDriver{MultiDriver{
 			logger:  logger.Named("tinder/multi"),
 			drivers: drivers,
+			health:  newDriversHealth(drivers),
 			mapc:    make(map[string]context.CancelFunc),
 		}}
 	}
 }
 
-// Advertise simply dispatch Advertise request across all the drivers
+// Advertise simply dispatch Advertise request across all the available drivers
 func (md *MultiDriver) Advertise(ctx context.Context, ns string, opts ...p2p_discovery.Option) (time.Duration, error) {
 	// Get options
 	var options p2p_discovery.Options
@@ -84,7 +89,8 @@ func (md *MultiDriver) Advertise(ctx context.Context, ns string, opts ...p2p_dis
 	md.mapc[ns] = cf
 	md.muc.Unlock()
 
-	ndrivers := len(md.drivers)
+	available := md.availableDrivers()
+	ndrivers := len(available)
 	// We don't use a lock array because what we execute next is so cheap it's not worth the extra context switches that generates.
 	var wg sync.WaitGroup
 	wg.Add(ndrivers)
@@ -93,7 +99,21 @@ func (md *MultiDriver) Advertise(ctx context.Context, ns string, opts ...p2p_dis
 	for i := 0; i < ndrivers; i++ {
 		go func(j int) {
 			defer wg.Done()
-			durationReturn[j], errReturn[j] = md.drivers[j].Advertise(ctx, ns, opts...)
+			driver, health := md.drivers[available[j]], md.health[available[j]]
+
+			start := time.Now()
+			durationReturn[j], errReturn[j] = driver.Advertise(ctx, ns, opts...)
+			switch {
+			case errors.Is(errReturn[j], ErrAdvertiseNotSupported):
+				// the driver only finds peers, it must not be backed off
+				return
+			case errReturn[j] != nil:
+				health.recordFailure(ctx, true, errReturn[j])
+				return
+			}
+
+			health.recordSuccess(true)
+			health.recordLatency(time.Since(start))
 		}(i)
 	}
 	wg.Wait()
@@ -116,7 +136,18 @@ func (md *MultiDriver) Advertise(ctx context.Context, ns string, opts ...p2p_dis
 		}
 	}
 
-	return 0, multierr.Combine(errReturn...)
+	var errs []error
+	for _, err := range errReturn {
+		if !errors.Is(err, ErrAdvertiseNotSupported) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return 0, ErrAdvertiseNotSupported
+	}
+
+	return 0, multierr.Combine(errs...)
 }
 
 const maxLimit = 1000
@@ -137,7 +168,8 @@ func (md *MultiDriver) FindPeers(ctx context.Context, ns string, opts ...p2p_dis
 	ctx, cancelSearch := context.WithCancel(ctx)
 	// This wait group is used to close outPeers when we are finished.
 	var wg sync.WaitGroup
-	ndrivers := len(md.drivers)
+	available := md.availableDrivers()
+	ndrivers := len(available)
 	wg.Add(ndrivers)
 	errReturn := make([]error, ndrivers)
 	// This wait group is used to know when to check for errors.
@@ -151,13 +183,27 @@ func (md *MultiDriver) FindPeers(ctx context.Context, ns string, opts ...p2p_dis
 			defer wg.Done()
 			ctx, cancel := context.WithCancel(ctx)
 			defer cancel()
-			var inPeers <-chan p2p_peer.AddrInfo
-			inPeers, errReturn[j] = md.drivers[j].FindPeers(ctx, ns, opts...)
+			driver, health := md.drivers[available[j]], md.health[available[j]]
+			start := time.Now()
+			inPeers, err := driver.FindPeers(ctx, ns, opts...)
+			// record the result before the search could be canceled
+			if err != nil {
+				health.recordFailure(ctx, false, err)
+			} else {
+				health.recordSuccess(false)
+			}
+			errReturn[j] = err
 			errWg.Done()
-			if errReturn[j] != nil {
+			if err != nil {
 				return
 			}
+			firstPeer := true
 			for v := range inPeers {
+				if firstPeer {
+					health.recordLatency(time.Since(start))
+					firstPeer = false
+				}
+
 				id := v.ID
 				seenLock.Lock()
 				if peerCount == limit {
@@ -172,6 +218,7 @@ func (md *MultiDriver) FindPeers(ctx context.Context, ns string, opts ...p2p_dis
 				alreadySeen[id] = struct{}{}
 				peerCount++
 				seenLock.Unlock()
+				health.recordPeerFound(ns)
 				select {
 				case outPeers <- v:
 				case <-ctx.Done():
@@ -211,14 +258,15 @@ func (md *MultiDriver) FindPeersAsync(ctx context.Context, outPeers chan<- p2p_p
 	}
 
 	// Buffer to try to limit context switch while returning peers.
-	inPeers := make(chan p2p_peer.AddrInfo, limit)
+	inPeers := make(chan foundPeer, limit)
 
 	// Start the fetchers first.
 	ctx, cancelSearch := context.WithCancel(ctx)
-	ndrivers := len(md.drivers)
+	available := md.availableDrivers()
+	ndrivers := len(available)
 	errReturn := make([]error, ndrivers)
 	for i := 0; i < ndrivers; i++ {
-		errReturn[i] = md.drivers[i].FindPeersAsync(ctx, inPeers, ns, opts...)
+		errReturn[i] = md.findPeersAsync(ctx, inPeers, available[i], ns, opts...)
 	}
 	for _, v := range errReturn {
 		if v == nil {
@@ -229,15 +277,16 @@ func (md *MultiDriver) FindPeersAsync(ctx context.Context, outPeers chan<- p2p_p
 				var peerCount int
 				for peerCount < limit {
 					select {
-					case info := <-inPeers:
-						id := info.ID
+					case found := <-inPeers:
+						id := found.info.ID
 						_, ok := alreadySeen[id]
 						if ok {
 							continue
 						}
 						alreadySeen[id] = struct{}{}
+						md.health[found.driver].recordPeerFound(ns)
 						select {
-						case outPeers <- info:
+						case outPeers <- found.info:
 						case <-ctx.Done():
 							return
 						}
@@ -255,6 +304,47 @@ func (md *MultiDriver) FindPeersAsync(ctx context.Context, outPeers chan<- p2p_p
 	return multierr.Combine(errReturn...)
 }
 
+type foundPeer struct {
+	info   p2p_peer.AddrInfo
+	driver int
+}
+
+// findPeersAsync starts the search of a driver, the peers are tagged with the driver index to track their origin
+func (md *MultiDriver) findPeersAsync(ctx context.Context, inPeers chan<- foundPeer, driver int, ns string, opts ...p2p_discovery.Option) error {
+	health := md.health[driver]
+	driverPeers := make(chan p2p_peer.AddrInfo)
+
+	start := time.Now()
+	if err := md.drivers[driver].FindPeersAsync(ctx, driverPeers, ns, opts...); err != nil {
+		health.recordFailure(ctx, false, err)
+		return err
+	}
+	health.recordSuccess(false)
+
+	go func() {
+		firstPeer := true
+		for {
+			select {
+			case info := <-driverPeers:
+				if firstPeer {
+					health.recordLatency(time.Since(start))
+					firstPeer = false
+				}
+
+				select {
+				case inPeers <- foundPeer{info: info, driver: driver}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
 func (md *MultiDriver) Unregister(ctx context.Context, ns string) error {
 	// first cancel advertiser
 	md.muc.Lock()
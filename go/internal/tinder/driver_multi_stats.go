@@ -0,0 +1,235 @@
+package tinder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// the backoff applied to a failing driver doubles after each consecutive failure
+	driverBackoffMin = time.Second * 5
+	driverBackoffMax = time.Minute * 5
+
+	// keep the stats memory bounded, the rendezvous points of the contact requests rotate
+	maxNamespaceStats = 256
+	otherNamespaces   = ":other:"
+)
+
+// DriverStats is a snapshot of the health of a driver managed by a MultiDriver
+type DriverStats struct {
+	Name string
+
+	AdvertiseSuccess int64
+	AdvertiseFailure int64
+	FindPeersSuccess int64
+	FindPeersFailure int64
+
+	// AverageLatency is the average duration of the successful advertises and
+	// the time needed to find the first peer
+	AverageLatency time.Duration
+
+	ConsecutiveFailures int64
+
+	// BackoffUntil is set when the driver is skipped because of its failures
+	BackoffUntil time.Time
+	LastError    string
+
+	// PeersFound is the number of peers found first by this driver, per namespace
+	PeersFound map[string]int64
+}
+
+// Score is the success rate of the driver, drivers without any request score 1
+func (s *DriverStats) Score() float64 {
+	success := s.AdvertiseSuccess + s.FindPeersSuccess
+	total := success + s.AdvertiseFailure + s.FindPeersFailure
+	if total == 0 {
+		return 1
+	}
+
+	return float64(success) / float64(total)
+}
+
+// StatsProvider is implemented by the drivers tracking the health of their sub drivers
+type StatsProvider interface {
+	DriversStats() []*DriverStats
+}
+
+type driverHealth struct {
+	mu sync.Mutex
+
+	name string
+
+	advertiseSuccess int64
+	advertiseFailure int64
+	findPeersSuccess int64
+	findPeersFailure int64
+
+	latencyTotal time.Duration
+	latencyCount int64
+
+	consecutiveFailures int64
+	backoffUntil        time.Time
+	lastError           error
+
+	peersFound map[string]int64
+}
+
+func newDriverHealth(name string) *driverHealth {
+	return &driverHealth{
+		name:       name,
+		peersFound: make(map[string]int64),
+	}
+}
+
+// available returns false while the driver is backed off
+func (h *driverHealth) available(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return !now.Before(h.backoffUntil)
+}
+
+func (h *driverHealth) recordLatency(latency time.Duration) {
+	h.mu.Lock()
+	h.latencyTotal += latency
+	h.latencyCount++
+	h.mu.Unlock()
+}
+
+func (h *driverHealth) recordSuccess(advertise bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if advertise {
+		h.advertiseSuccess++
+	} else {
+		h.findPeersSuccess++
+	}
+
+	h.consecutiveFailures = 0
+	h.backoffUntil = time.Time{}
+}
+
+func (h *driverHealth) recordFailure(ctx context.Context, advertise bool, err error) {
+	// the caller gave up, this doesn't tell anything about the driver
+	if ctx.Err() != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if advertise {
+		h.advertiseFailure++
+	} else {
+		h.findPeersFailure++
+	}
+
+	h.lastError = err
+	h.consecutiveFailures++
+
+	backoff := driverBackoffMin
+	for i := int64(1); i < h.consecutiveFailures && backoff < driverBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > driverBackoffMax {
+		backoff = driverBackoffMax
+	}
+
+	h.backoffUntil = time.Now().Add(backoff)
+}
+
+func (h *driverHealth) recordPeerFound(ns string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.peersFound[ns]; !ok && len(h.peersFound) >= maxNamespaceStats {
+		ns = otherNamespaces
+	}
+
+	h.peersFound[ns]++
+}
+
+func (h *driverHealth) stats() *DriverStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := &DriverStats{
+		Name:                h.name,
+		AdvertiseSuccess:    h.advertiseSuccess,
+		AdvertiseFailure:    h.advertiseFailure,
+		FindPeersSuccess:    h.findPeersSuccess,
+		FindPeersFailure:    h.findPeersFailure,
+		ConsecutiveFailures: h.consecutiveFailures,
+		BackoffUntil:        h.backoffUntil,
+		PeersFound:          make(map[string]int64, len(h.peersFound)),
+	}
+
+	if h.latencyCount > 0 {
+		s.AverageLatency = h.latencyTotal / time.Duration(h.latencyCount)
+	}
+
+	if h.lastError != nil {
+		s.LastError = h.lastError.Error()
+	}
+
+	for ns, count := range h.peersFound {
+		s.PeersFound[ns] = count
+	}
+
+	return s
+}
+
+func newDriversHealth(drivers []AsyncableDriver) []*driverHealth {
+	names := make(map[string]int)
+	for _, d := range drivers {
+		names[d.Name()]++
+	}
+
+	// several drivers of the same kind are usually used, e.g. one per rdvp
+	health := make([]*driverHealth, len(drivers))
+	seen := make(map[string]int)
+	for i, d := range drivers {
+		name := d.Name()
+		if names[name] > 1 {
+			seen[name]++
+			name = fmt.Sprintf("%s#%d", name, seen[name])
+		}
+
+		health[i] = newDriverHealth(name)
+	}
+
+	return health
+}
+
+// availableDrivers returns the indexes of the drivers which aren't backed
+// off, all the drivers are returned if every one of them is failing
+func (md *MultiDriver) availableDrivers() []int {
+	now := time.Now()
+
+	available := make([]int, 0, len(md.drivers))
+	for i, h := range md.health {
+		if h.available(now) {
+			available = append(available, i)
+		}
+	}
+
+	if len(available) == 0 {
+		for i := range md.drivers {
+			available = append(available, i)
+		}
+	}
+
+	return available
+}
+
+func (md *MultiDriver) DriversStats() []*DriverStats {
+	stats := make([]*DriverStats, len(md.health))
+	for i, h := range md.health {
+		stats[i] = h.stats()
+	}
+
+	return stats
+}
@@ -2,6 +2,7 @@ package tinder
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -269,3 +270,94 @@ func TestMultiDriver_Unregister(t *testing.T) {
 		})
 	}
 }
+
+type failingDriver struct {
+	calls int
+}
+
+func (d *failingDriver) Advertise(context.Context, string, ...p2p_discovery.Option) (time.Duration, error) {
+	d.calls++
+	return 0, fmt.Errorf("unreachable")
+}
+
+func (d *failingDriver) FindPeers(context.Context, string, ...p2p_discovery.Option) (<-chan p2p_peer.AddrInfo, error) {
+	d.calls++
+	return nil, fmt.Errorf("unreachable")
+}
+
+func (d *failingDriver) Unregister(context.Context, string) error { return nil }
+
+func (d *failingDriver) Name() string { return "failing" }
+
+func TestMultiDriver_Stats(t *testing.T) {
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	ms := NewMockedDriverServer()
+	mn := p2p_mock.New(ctx)
+
+	peers := testingPeers(t, mn, 2)
+	drivers := testingMockedDriverClients(t, ms, peers...)
+	failing := &failingDriver{}
+	md := NewMultiDriver(logger, append(drivers, failing)...)
+
+	const testKey = "testkey"
+	_, err := md.Advertise(ctx, testKey, p2p_discovery.TTL(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, failing.calls)
+
+	// the failing driver is backed off
+	ps, err := p2p_disc.FindPeers(ctx, md, testKey)
+	require.NoError(t, err)
+	require.Len(t, ps, 2)
+	require.Equal(t, 1, failing.calls)
+
+	stats := md.(StatsProvider).DriversStats()
+	require.Len(t, stats, 3)
+
+	// drivers sharing the same name are numbered
+	require.Equal(t, "mock#1", stats[0].Name)
+	require.Equal(t, "mock#2", stats[1].Name)
+	require.Equal(t, "failing", stats[2].Name)
+
+	var peersFound int64
+	for _, s := range stats[:2] {
+		require.Equal(t, int64(1), s.AdvertiseSuccess)
+		require.Equal(t, int64(1), s.FindPeersSuccess)
+		require.Equal(t, float64(1), s.Score())
+		require.True(t, s.BackoffUntil.IsZero())
+		peersFound += s.PeersFound[testKey]
+	}
+	// each peer is only counted for the driver which found it first
+	require.Equal(t, int64(2), peersFound)
+
+	require.Equal(t, int64(1), stats[2].AdvertiseFailure)
+	require.Equal(t, int64(1), stats[2].ConsecutiveFailures)
+	require.Equal(t, float64(0), stats[2].Score())
+	require.Equal(t, "unreachable", stats[2].LastError)
+	require.True(t, stats[2].BackoffUntil.After(time.Now()))
+}
+
+func TestMultiDriver_AllDriversBackedOff(t *testing.T) {
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	failingA, failingB := &failingDriver{}, &failingDriver{}
+	md := NewMultiDriver(logger, failingA, failingB)
+
+	_, err := md.Advertise(ctx, "testkey")
+	require.Error(t, err)
+
+	// every driver is failing, they are all tried instead of none
+	_, err = md.FindPeers(ctx, "testkey")
+	require.Error(t, err)
+	require.Equal(t, 2, failingA.calls)
+	require.Equal(t, 2, failingB.calls)
+
+	for _, s := range md.(StatsProvider).DriversStats() {
+		require.Equal(t, int64(2), s.ConsecutiveFailures)
+		require.Equal(t, int64(1), s.FindPeersFailure)
+	}
+}
@@ -0,0 +1,256 @@
+package tinder
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	p2p_discovery "github.com/libp2p/go-libp2p-core/discovery"
+	p2p_peer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"go.uber.org/zap"
+)
+
+// PeerCache persists the peers seen on pubsub topics, so they can be dialed
+// again after a restart without waiting for the rendezvous points
+type PeerCache struct {
+	logger *zap.Logger
+	ds     datastore.Datastore
+	ttl    time.Duration
+	mu     sync.Mutex
+}
+
+func NewPeerCache(logger *zap.Logger, ds datastore.Datastore, ttl time.Duration) *PeerCache {
+	return &PeerCache{
+		logger: logger.Named("tinder/peercache"),
+		ds:     ds,
+		ttl:    ttl,
+	}
+}
+
+func peerCacheTopicKey(topic string) datastore.Key {
+	// topics are orbitdb addresses, escape them to keep a single namespace per topic
+	return datastore.NewKey(base64.RawURLEncoding.EncodeToString([]byte(topic)))
+}
+
+func peerCacheKey(topic string, id p2p_peer.ID) datastore.Key {
+	return peerCacheTopicKey(topic).ChildString(id.String())
+}
+
+// Record saves the addrs of a peer seen on the given topic, the previously
+// known addrs are kept if none are provided
+func (c *PeerCache) Record(topic string, info p2p_peer.AddrInfo) error {
+	topic = strings.TrimPrefix(topic, "floodsub:")
+	key := peerCacheKey(topic, info.ID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec := &PeerCacheRecord{}
+	if len(info.Addrs) > 0 {
+		rec.Addrs = make([][]byte, len(info.Addrs))
+		for i, addr := range info.Addrs {
+			rec.Addrs[i] = addr.Bytes()
+		}
+	} else {
+		data, err := c.ds.Get(key)
+		switch err {
+		case nil:
+			if err := rec.Unmarshal(data); err != nil {
+				c.logger.Warn("unable to decode peer record", zap.String("key", key.String()), zap.Error(err))
+				rec = &PeerCacheRecord{}
+			}
+		case datastore.ErrNotFound:
+		default:
+			return err
+		}
+	}
+
+	rec.LastSeen = time.Now().UnixNano()
+
+	data, err := rec.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return c.ds.Put(key, data)
+}
+
+// Peers returns the peers seen on the given topic, most recently seen first,
+// the stale entries are removed
+func (c *PeerCache) Peers(topic string) ([]p2p_peer.AddrInfo, error) {
+	topic = strings.TrimPrefix(topic, "floodsub:")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type seenPeer struct {
+		info     p2p_peer.AddrInfo
+		lastSeen int64
+	}
+
+	topicKey := peerCacheTopicKey(topic)
+
+	var peers []seenPeer
+	err := c.forEach(topicKey.String(), func(key datastore.Key, rec *PeerCacheRecord) {
+		// the prefix may also match a topic sharing the same beginning
+		if !key.Parent().Equal(topicKey) {
+			return
+		}
+
+		id, err := p2p_peer.Decode(key.BaseNamespace())
+		if err != nil {
+			c.logger.Warn("invalid peer id in cache", zap.String("key", key.String()), zap.Error(err))
+			return
+		}
+
+		info := p2p_peer.AddrInfo{ID: id}
+		for _, raw := range rec.Addrs {
+			addr, err := ma.NewMultiaddrBytes(raw)
+			if err != nil {
+				continue
+			}
+			info.Addrs = append(info.Addrs, addr)
+		}
+
+		if len(info.Addrs) > 0 {
+			peers = append(peers, seenPeer{info: info, lastSeen: rec.LastSeen})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].lastSeen > peers[j].lastSeen })
+
+	infos := make([]p2p_peer.AddrInfo, len(peers))
+	for i, p := range peers {
+		infos[i] = p.info
+	}
+
+	return infos, nil
+}
+
+// Prune removes the entries which haven't been seen during the cache ttl
+func (c *PeerCache) Prune() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.forEach("/", func(datastore.Key, *PeerCacheRecord) {})
+}
+
+// forEach calls fn for each valid entry under the prefix, stale and
+// undecodable entries are deleted
+func (c *PeerCache) forEach(prefix string, fn func(key datastore.Key, rec *PeerCacheRecord)) error {
+	res, err := c.ds.Query(query.Query{Prefix: prefix})
+	if err != nil {
+		return err
+	}
+
+	entries, err := res.Rest()
+	if err != nil {
+		return err
+	}
+
+	staleBefore := time.Now().Add(-c.ttl).UnixNano()
+	for _, entry := range entries {
+		key := datastore.NewKey(entry.Key)
+
+		rec := &PeerCacheRecord{}
+		if err := rec.Unmarshal(entry.Value); err != nil || rec.LastSeen < staleBefore {
+			if err := c.ds.Delete(key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fn(key, rec)
+	}
+
+	return nil
+}
+
+// peerCacheDriver is an AsyncableDriver
+var _ AsyncableDriver = (*peerCacheDriver)(nil)
+
+type peerCacheDriver struct {
+	logger *zap.Logger
+	cache  *PeerCache
+}
+
+// NewPeerCacheDriver returns a driver serving the peers of a PeerCache, it
+// answers immediately so the cached peers are dialed before the other drivers
+// respond
+func NewPeerCacheDriver(logger *zap.Logger, cache *PeerCache) AsyncableDriver {
+	return &peerCacheDriver{
+		logger: logger.Named("tinder/peercache"),
+		cache:  cache,
+	}
+}
+
+func (d *peerCacheDriver) Advertise(context.Context, string, ...p2p_discovery.Option) (time.Duration, error) {
+	return 0, ErrAdvertiseNotSupported
+}
+
+func (d *peerCacheDriver) findPeers(ns string, opts ...p2p_discovery.Option) ([]p2p_peer.AddrInfo, error) {
+	var options p2p_discovery.Options
+	if err := options.Apply(opts...); err != nil {
+		return nil, err
+	}
+
+	peers, err := d.cache.Peers(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Limit > 0 && len(peers) > options.Limit {
+		peers = peers[:options.Limit]
+	}
+
+	d.logger.Debug("found cached peers", zap.String("ns", ns), zap.Int("count", len(peers)))
+
+	return peers, nil
+}
+
+func (d *peerCacheDriver) FindPeers(ctx context.Context, ns string, opts ...p2p_discovery.Option) (<-chan p2p_peer.AddrInfo, error) {
+	peers, err := d.findPeers(ns, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan p2p_peer.AddrInfo, len(peers))
+	for _, p := range peers {
+		ch <- p
+	}
+	close(ch)
+
+	return ch, nil
+}
+
+func (d *peerCacheDriver) FindPeersAsync(ctx context.Context, outChan chan<- p2p_peer.AddrInfo, ns string, opts ...p2p_discovery.Option) error {
+	peers, err := d.findPeers(ns, opts...)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for _, p := range peers {
+			select {
+			case outChan <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (d *peerCacheDriver) Unregister(context.Context, string) error { return nil }
+
+func (d *peerCacheDriver) Name() string { return "peercache" }
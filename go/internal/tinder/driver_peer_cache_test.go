@@ -0,0 +1,146 @@
+package tinder
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	ds_sync "github.com/ipfs/go-datastore/sync"
+	p2p_discovery "github.com/libp2p/go-libp2p-core/discovery"
+	p2p_peer "github.com/libp2p/go-libp2p-core/peer"
+	p2p_mock "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"berty.tech/berty/v2/go/internal/testutil"
+)
+
+func TestPeerCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	mn := p2p_mock.New(ctx)
+	hs := testingPeers(t, mn, 3)
+
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	cache := NewPeerCache(logger, ds, time.Hour)
+
+	const topicA = "/orbitdb/topic/a"
+	const topicB = "/orbitdb/topic/b"
+
+	require.NoError(t, cache.Record(topicA, p2p_peer.AddrInfo{ID: hs[0].ID(), Addrs: hs[0].Addrs()}))
+	require.NoError(t, cache.Record("floodsub:"+topicA, p2p_peer.AddrInfo{ID: hs[1].ID(), Addrs: hs[1].Addrs()}))
+	require.NoError(t, cache.Record(topicB, p2p_peer.AddrInfo{ID: hs[2].ID(), Addrs: hs[2].Addrs()}))
+
+	peers, err := cache.Peers(topicA)
+	require.NoError(t, err)
+	require.Len(t, peers, 2)
+	// most recently seen first
+	require.Equal(t, hs[1].ID(), peers[0].ID)
+	require.Equal(t, hs[0].ID(), peers[1].ID)
+	require.Equal(t, hs[0].Addrs(), peers[1].Addrs)
+
+	// recording a peer without addrs keeps the known ones
+	require.NoError(t, cache.Record(topicA, p2p_peer.AddrInfo{ID: hs[0].ID()}))
+	peers, err = cache.Peers(topicA)
+	require.NoError(t, err)
+	require.Len(t, peers, 2)
+	require.Equal(t, hs[0].ID(), peers[0].ID)
+	require.Equal(t, hs[0].Addrs(), peers[0].Addrs)
+
+	// the cache is persisted in the datastore
+	cache = NewPeerCache(logger, ds, time.Hour)
+	peers, err = cache.Peers("floodsub:" + topicB)
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	require.Equal(t, hs[2].ID(), peers[0].ID)
+
+	// stale entries are pruned
+	time.Sleep(time.Millisecond * 10)
+	cache = NewPeerCache(logger, ds, time.Millisecond)
+	require.NoError(t, cache.Prune())
+
+	peers, err = cache.Peers(topicA)
+	require.NoError(t, err)
+	require.Empty(t, peers)
+
+	keys, err := ds.Query(query.Query{})
+	require.NoError(t, err)
+	entries, err := keys.Rest()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestPeerCacheDriver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	mn := p2p_mock.New(ctx)
+	hs := testingPeers(t, mn, 4)
+
+	const topic = "/orbitdb/topic"
+
+	cache := NewPeerCache(logger, ds_sync.MutexWrap(datastore.NewMapDatastore()), time.Hour)
+	for _, h := range hs[1:] {
+		require.NoError(t, cache.Record(topic, p2p_peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()}))
+	}
+
+	driver := NewPeerCacheDriver(logger, cache)
+
+	_, err := driver.Advertise(ctx, topic)
+	require.True(t, errors.Is(err, ErrAdvertiseNotSupported))
+
+	cc, err := driver.FindPeers(ctx, "floodsub:"+topic)
+	require.NoError(t, err)
+
+	found := []p2p_peer.ID{}
+	for p := range cc {
+		found = append(found, p.ID)
+	}
+	require.ElementsMatch(t, []p2p_peer.ID{hs[1].ID(), hs[2].ID(), hs[3].ID()}, found)
+
+	cc, err = driver.FindPeers(ctx, topic, p2p_discovery.Limit(1))
+	require.NoError(t, err)
+	require.Len(t, cc, 1)
+
+	// the cached peers are served along with the peers of the other drivers
+	server := NewMockedDriverServer()
+	multi := NewAsyncMultiDriver(logger, driver, NewMockedDriverClient(hs[0], server))
+
+	cc, err = multi.FindPeers(ctx, topic)
+	require.NoError(t, err)
+
+	found = []p2p_peer.ID{}
+	for p := range cc {
+		found = append(found, p.ID)
+	}
+	require.ElementsMatch(t, []p2p_peer.ID{hs[1].ID(), hs[2].ID(), hs[3].ID()}, found)
+
+	// advertising doesn't back off the peer cache, it keeps serving the cached peers
+	for i := 0; i < 2; i++ {
+		_, err = multi.Advertise(ctx, topic)
+		require.NoError(t, err)
+	}
+
+	stats := multi.(StatsProvider).DriversStats()
+	require.Equal(t, "peercache", stats[0].Name)
+	require.Zero(t, stats[0].AdvertiseFailure)
+	require.True(t, stats[0].BackoffUntil.IsZero())
+
+	cc, err = multi.FindPeers(ctx, topic)
+	require.NoError(t, err)
+
+	found = []p2p_peer.ID{}
+	for p := range cc {
+		found = append(found, p.ID)
+	}
+	require.ElementsMatch(t, []p2p_peer.ID{hs[1].ID(), hs[2].ID(), hs[3].ID()}, found)
+}
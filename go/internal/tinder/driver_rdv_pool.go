@@ -0,0 +1,510 @@
+package tinder
+
+import (
+	"context"
+	"fmt"
+	mrand "math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/discovery"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRendezvousPoolQuorum     = 2
+	defaultRendezvousPoolBackoffMin = time.Second * 10
+	defaultRendezvousPoolBackoffMax = time.Minute * 10
+	rendezvousPoolConnectTimeout    = time.Second * 15
+)
+
+type RendezvousPoolOpts struct {
+	// Quorum is the number of servers an advertise is sent to, 0 means every
+	// server of the pool
+	Quorum int
+
+	// BackoffMin and BackoffMax bound the delay before using a failing server
+	// again, the delay doubles after each consecutive failure
+	BackoffMin time.Duration
+	BackoffMax time.Duration
+}
+
+func (opts *RendezvousPoolOpts) applyDefaults(servers int) {
+	if opts.Quorum <= 0 || opts.Quorum > servers {
+		opts.Quorum = servers
+	}
+
+	if opts.BackoffMin <= 0 {
+		opts.BackoffMin = defaultRendezvousPoolBackoffMin
+	}
+
+	if opts.BackoffMax < opts.BackoffMin {
+		opts.BackoffMax = defaultRendezvousPoolBackoffMax
+		if opts.BackoffMax < opts.BackoffMin {
+			opts.BackoffMax = opts.BackoffMin
+		}
+	}
+}
+
+// RendezvousServerState is a snapshot of the state of a server of a RendezvousPool,
+// the failures and backoff are the ones of the advertises
+type RendezvousServerState struct {
+	ID        peer.ID
+	Connected bool
+
+	ConsecutiveFailures int
+	BackoffUntil        time.Time
+	LastError           string
+	LastSuccess         time.Time
+
+	// the queries are tracked separately, a server answering the queries
+	// isn't necessarily accepting the registrations
+	QueryFailures     int
+	QueryBackoffUntil time.Time
+	LastQueryError    string
+
+	// Namespaces is the number of namespaces currently advertised on this server
+	Namespaces int
+}
+
+type rendezvousHealth struct {
+	consecutiveFailures int
+	backoffUntil        time.Time
+	lastError           error
+	lastSuccess         time.Time
+}
+
+type rendezvousServer struct {
+	info   peer.AddrInfo
+	driver AsyncableDriver
+
+	mu         sync.Mutex
+	advertise  rendezvousHealth
+	query      rendezvousHealth
+	registered map[string]struct{}
+}
+
+// health returns the advertise or query health, s.mu must be held
+func (s *rendezvousServer) health(advertise bool) *rendezvousHealth {
+	if advertise {
+		return &s.advertise
+	}
+
+	return &s.query
+}
+
+// RendezvousPool is a driver managing several rendezvous servers, the
+// advertises are sent to a quorum of servers and the peers are searched on
+// every server. The failing servers are backed off and replaced by the next
+// healthy ones, the advertises and the queries are backed off independently.
+type RendezvousPool struct {
+	logger  *zap.Logger
+	host    host.Host
+	servers []*rendezvousServer
+	opts    RendezvousPoolOpts
+}
+
+// RendezvousPool is an AsyncableDriver
+var _ AsyncableDriver = (*RendezvousPool)(nil)
+
+func NewRendezvousPool(logger *zap.Logger, h host.Host, servers []peer.AddrInfo, rng *mrand.Rand, opts *RendezvousPoolOpts) (*RendezvousPool, error) {
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("a rendezvous pool requires at least one server")
+	}
+
+	if opts == nil {
+		opts = &RendezvousPoolOpts{}
+	}
+	opts.applyDefaults(len(servers))
+
+	pool := &RendezvousPool{
+		logger:  logger.Named("tinder/rdvp-pool"),
+		host:    h,
+		servers: make([]*rendezvousServer, len(servers)),
+		opts:    *opts,
+	}
+
+	for i, info := range servers {
+		h.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+
+		// each driver gets its own rng, they aren't safe for a concurrent use
+		driverRng := mrand.New(mrand.NewSource(rng.Int63())) // nolint:gosec
+		pool.servers[i] = &rendezvousServer{
+			info:       info,
+			driver:     NewRendezvousDiscovery(logger, h, info.ID, driverRng),
+			registered: make(map[string]struct{}),
+		}
+	}
+
+	return pool, nil
+}
+
+func (p *RendezvousPool) recordSuccess(s *rendezvousServer, advertise bool) {
+	s.mu.Lock()
+	h := s.health(advertise)
+	h.consecutiveFailures = 0
+	h.backoffUntil = time.Time{}
+	h.lastSuccess = time.Now()
+	s.mu.Unlock()
+}
+
+func (p *RendezvousPool) recordFailure(ctx context.Context, s *rendezvousServer, advertise bool, err error) {
+	// the caller gave up, this doesn't tell anything about the server
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.health(advertise)
+	h.consecutiveFailures++
+	h.lastError = err
+
+	backoff := p.opts.BackoffMin
+	for i := 1; i < h.consecutiveFailures && backoff < p.opts.BackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > p.opts.BackoffMax {
+		backoff = p.opts.BackoffMax
+	}
+	h.backoffUntil = time.Now().Add(backoff)
+
+	p.logger.Debug("rendezvous server failed",
+		zap.String("server", s.info.ID.String()),
+		zap.Bool("advertise", advertise),
+		zap.Int("consecutive-failures", h.consecutiveFailures),
+		zap.Duration("backoff", backoff),
+		zap.Error(err))
+}
+
+// connect makes sure a connection to the server is opened, it reconnects the
+// servers coming back from a backoff
+func (p *RendezvousPool) connect(ctx context.Context, s *rendezvousServer) error {
+	if p.host.Network().Connectedness(s.info.ID) == network.Connected {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rendezvousPoolConnectTimeout)
+	defer cancel()
+
+	if err := p.host.Connect(ctx, s.info); err != nil {
+		return fmt.Errorf("unable to connect: %w", err)
+	}
+
+	return nil
+}
+
+// orderedServers returns the servers which aren't backed off for advertises or
+// queries, the servers with the fewest failures first, or every server if all
+// of them are failing
+func (p *RendezvousPool) orderedServers(advertise bool) []*rendezvousServer {
+	now := time.Now()
+
+	type candidate struct {
+		server   *rendezvousServer
+		failures int
+	}
+
+	candidates := []candidate{}
+	for _, s := range p.servers {
+		s.mu.Lock()
+		h := s.health(advertise)
+		available := !now.Before(h.backoffUntil)
+		failures := h.consecutiveFailures
+		s.mu.Unlock()
+
+		if available {
+			candidates = append(candidates, candidate{server: s, failures: failures})
+		}
+	}
+
+	if len(candidates) == 0 {
+		for _, s := range p.servers {
+			candidates = append(candidates, candidate{server: s})
+		}
+	}
+
+	// keep the pool order between servers as healthy as each other, so the
+	// same servers are used for successive advertises
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].failures < candidates[j].failures })
+
+	servers := make([]*rendezvousServer, len(candidates))
+	for i, c := range candidates {
+		servers[i] = c.server
+	}
+
+	return servers
+}
+
+// Advertise registers on a quorum of servers, a failing server is replaced by
+// the next available one. It fails only if no server accepted the
+// registration.
+func (p *RendezvousPool) Advertise(ctx context.Context, ns string, opts ...discovery.Option) (time.Duration, error) {
+	servers := p.orderedServers(true)
+
+	var (
+		mu      sync.Mutex
+		ttl     time.Duration
+		success int
+		errs    error
+	)
+
+	next := 0
+	for success < p.opts.Quorum && next < len(servers) {
+		// register on the missing servers in parallel
+		missing := p.opts.Quorum - success
+		if remaining := len(servers) - next; missing > remaining {
+			missing = remaining
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(missing)
+		for _, s := range servers[next : next+missing] {
+			go func(s *rendezvousServer) {
+				defer wg.Done()
+
+				rttl, err := p.advertiseOn(ctx, s, ns, opts...)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err != nil {
+					errs = multierr.Append(errs, fmt.Errorf("%s: %w", s.info.ID.ShortString(), err))
+					return
+				}
+
+				if success == 0 || rttl < ttl {
+					ttl = rttl
+				}
+				success++
+			}(s)
+		}
+		wg.Wait()
+		next += missing
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if success == 0 {
+		return 0, fmt.Errorf("unable to advertise on any rendezvous server: %w", errs)
+	}
+
+	if success < p.opts.Quorum {
+		p.logger.Warn("advertise quorum not reached",
+			zap.String("ns", ns),
+			zap.Int("success", success),
+			zap.Int("quorum", p.opts.Quorum),
+			zap.Error(errs))
+	}
+
+	return ttl, nil
+}
+
+func (p *RendezvousPool) advertiseOn(ctx context.Context, s *rendezvousServer, ns string, opts ...discovery.Option) (time.Duration, error) {
+	if err := p.connect(ctx, s); err != nil {
+		p.recordFailure(ctx, s, true, err)
+		return 0, err
+	}
+
+	ttl, err := s.driver.Advertise(ctx, ns, opts...)
+	if err != nil {
+		p.recordFailure(ctx, s, true, err)
+		return 0, err
+	}
+
+	p.recordSuccess(s, true)
+
+	s.mu.Lock()
+	s.registered[ns] = struct{}{}
+	s.mu.Unlock()
+
+	return ttl, nil
+}
+
+// FindPeers queries the available servers in parallel, the peers are deduplicated
+func (p *RendezvousPool) FindPeers(ctx context.Context, ns string, opts ...discovery.Option) (<-chan peer.AddrInfo, error) {
+	var options discovery.Options
+	if err := options.Apply(opts...); err != nil {
+		return nil, err
+	}
+
+	limit := options.Limit
+	if limit == 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+
+	servers := p.orderedServers(false)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  error
+		seen  = make(map[peer.ID]struct{})
+		found []peer.AddrInfo
+	)
+
+	wg.Add(len(servers))
+	for _, s := range servers {
+		go func(s *rendezvousServer) {
+			defer wg.Done()
+
+			peers, err := p.findPeersOn(ctx, s, ns, opts...)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = multierr.Append(errs, fmt.Errorf("%s: %w", s.info.ID.ShortString(), err))
+				return
+			}
+
+			for _, info := range peers {
+				if _, ok := seen[info.ID]; ok || len(found) >= limit {
+					continue
+				}
+
+				seen[info.ID] = struct{}{}
+				found = append(found, info)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	if len(multierr.Errors(errs)) == len(servers) {
+		return nil, fmt.Errorf("unable to find peers on any rendezvous server: %w", errs)
+	}
+
+	ch := make(chan peer.AddrInfo, len(found))
+	for _, info := range found {
+		ch <- info
+	}
+	close(ch)
+
+	return ch, nil
+}
+
+// findPeersOn queries a server, its result only affects the query health so a
+// server answering the queries doesn't leave the advertise backoff
+func (p *RendezvousPool) findPeersOn(ctx context.Context, s *rendezvousServer, ns string, opts ...discovery.Option) ([]peer.AddrInfo, error) {
+	if err := p.connect(ctx, s); err != nil {
+		p.recordFailure(ctx, s, false, err)
+		return nil, err
+	}
+
+	cc, err := s.driver.FindPeers(ctx, ns, opts...)
+	if err != nil {
+		p.recordFailure(ctx, s, false, err)
+		return nil, err
+	}
+
+	p.recordSuccess(s, false)
+
+	peers := []peer.AddrInfo{}
+	for info := range cc {
+		peers = append(peers, info)
+	}
+
+	return peers, nil
+}
+
+// FindPeersAsync starts a search on the available servers, the peers are deduplicated
+func (p *RendezvousPool) FindPeersAsync(ctx context.Context, outChan chan<- peer.AddrInfo, ns string, opts ...discovery.Option) error {
+	var options discovery.Options
+	if err := options.Apply(opts...); err != nil {
+		return err
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[peer.ID]struct{})
+	)
+
+	for _, s := range p.orderedServers(false) {
+		go func(s *rendezvousServer) {
+			peers, err := p.findPeersOn(ctx, s, ns, opts...)
+			if err != nil {
+				return
+			}
+
+			for _, info := range peers {
+				mu.Lock()
+				_, ok := seen[info.ID]
+				seen[info.ID] = struct{}{}
+				mu.Unlock()
+
+				if ok {
+					continue
+				}
+
+				select {
+				case outChan <- info:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(s)
+	}
+
+	return nil
+}
+
+// Unregister removes the registrations from the servers which accepted them
+func (p *RendezvousPool) Unregister(ctx context.Context, ns string) error {
+	var errs error
+	for _, s := range p.servers {
+		s.mu.Lock()
+		_, ok := s.registered[ns]
+		delete(s.registered, ns)
+		s.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		if err := s.driver.Unregister(ctx, ns); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%s: %w", s.info.ID.ShortString(), err))
+		}
+	}
+
+	return errs
+}
+
+func (*RendezvousPool) Name() string { return "rdvp-pool" }
+
+// State returns a snapshot of the state of the servers, in the pool order
+func (p *RendezvousPool) State() []*RendezvousServerState {
+	states := make([]*RendezvousServerState, len(p.servers))
+	for i, s := range p.servers {
+		s.mu.Lock()
+		state := &RendezvousServerState{
+			ID:                  s.info.ID,
+			Connected:           p.host.Network().Connectedness(s.info.ID) == network.Connected,
+			ConsecutiveFailures: s.advertise.consecutiveFailures,
+			BackoffUntil:        s.advertise.backoffUntil,
+			LastSuccess:         s.advertise.lastSuccess,
+			QueryFailures:       s.query.consecutiveFailures,
+			QueryBackoffUntil:   s.query.backoffUntil,
+			Namespaces:          len(s.registered),
+		}
+		if s.advertise.lastError != nil {
+			state.LastError = s.advertise.lastError.Error()
+		}
+		if s.query.lastError != nil {
+			state.LastQueryError = s.query.lastError.Error()
+		}
+		s.mu.Unlock()
+
+		states[i] = state
+	}
+
+	return states
+}
@@ -0,0 +1,230 @@
+package tinder
+
+import (
+	"context"
+	"fmt"
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	p2p_host "github.com/libp2p/go-libp2p-core/host"
+	p2p_peer "github.com/libp2p/go-libp2p-core/peer"
+	p2p_rp "github.com/libp2p/go-libp2p-rendezvous"
+	p2p_rpdbi "github.com/libp2p/go-libp2p-rendezvous/db"
+	p2p_rpdb "github.com/libp2p/go-libp2p-rendezvous/db/sqlite"
+	p2p_mock "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"berty.tech/berty/v2/go/internal/testutil"
+)
+
+func testingRendezvousServer(ctx context.Context, t *testing.T, h p2p_host.Host) {
+	t.Helper()
+
+	testingRendezvousServerWithDB(ctx, t, h, func(db p2p_rpdbi.DB) p2p_rpdbi.DB { return db })
+}
+
+func testingRendezvousServerWithDB(ctx context.Context, t *testing.T, h p2p_host.Host, wrap func(p2p_rpdbi.DB) p2p_rpdbi.DB) {
+	t.Helper()
+
+	db, err := p2p_rpdb.OpenDB(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_ = p2p_rp.NewRendezvousService(h, wrap(db))
+}
+
+// testingFailingRendezvousDB makes the registrations or the queries of a
+// rendezvous server fail
+type testingFailingRendezvousDB struct {
+	p2p_rpdbi.DB
+
+	failRegister bool
+	failDiscover bool
+}
+
+func (db *testingFailingRendezvousDB) Register(p p2p_peer.ID, ns string, addrs [][]byte, ttl int) (uint64, error) {
+	if db.failRegister {
+		return 0, fmt.Errorf("register failed")
+	}
+
+	return db.DB.Register(p, ns, addrs, ttl)
+}
+
+func (db *testingFailingRendezvousDB) Discover(ns string, cookie []byte, limit int) ([]p2p_rpdbi.RegistrationRecord, []byte, error) {
+	if db.failDiscover {
+		return nil, nil, fmt.Errorf("discover failed")
+	}
+
+	return db.DB.Discover(ns, cookie, limit)
+}
+
+func TestRendezvousPool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	mn := p2p_mock.New(ctx)
+	hs := make([]p2p_host.Host, 5)
+	for i := range hs {
+		var err error
+		hs[i], err = mn.GenPeer()
+		require.NoError(t, err)
+	}
+
+	// the first server is unreachable
+	unreachable, rdvA, rdvB := hs[0], hs[1], hs[2]
+	clientA, clientB := hs[3], hs[4]
+	testingRendezvousServer(ctx, t, rdvA)
+	testingRendezvousServer(ctx, t, rdvB)
+	for _, client := range []p2p_host.Host{clientA, clientB} {
+		for _, server := range []p2p_host.Host{rdvA, rdvB} {
+			_, err := mn.LinkPeers(client.ID(), server.ID())
+			require.NoError(t, err)
+		}
+	}
+
+	servers := []p2p_peer.AddrInfo{}
+	for _, server := range []p2p_host.Host{unreachable, rdvA, rdvB} {
+		servers = append(servers, *p2p_host.InfoFromHost(server))
+	}
+
+	rng := mrand.New(mrand.NewSource(1))
+	poolA, err := NewRendezvousPool(logger, clientA, servers, rng, &RendezvousPoolOpts{Quorum: 2})
+	require.NoError(t, err)
+	poolB, err := NewRendezvousPool(logger, clientB, servers, rng, nil)
+	require.NoError(t, err)
+
+	const testKey = "testkey"
+
+	// the unreachable server is replaced by the next one to reach the quorum
+	_, err = poolA.Advertise(ctx, testKey)
+	require.NoError(t, err)
+
+	states := poolA.State()
+	require.Len(t, states, 3)
+	require.Equal(t, 1, states[0].ConsecutiveFailures)
+	require.True(t, states[0].BackoffUntil.After(time.Now()))
+	require.NotEmpty(t, states[0].LastError)
+	require.Equal(t, 0, states[0].Namespaces)
+	for _, state := range states[1:] {
+		require.True(t, state.Connected)
+		require.Equal(t, 0, state.ConsecutiveFailures)
+		require.False(t, state.LastSuccess.IsZero())
+		require.Equal(t, 1, state.Namespaces)
+	}
+
+	// the peers are deduplicated across the servers
+	cc, err := poolB.FindPeers(ctx, testKey)
+	require.NoError(t, err)
+
+	found := []p2p_peer.ID{}
+	for p := range cc {
+		found = append(found, p.ID)
+	}
+	require.Equal(t, []p2p_peer.ID{clientA.ID()}, found)
+
+	// the backed off server isn't used anymore
+	_, err = poolA.Advertise(ctx, testKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, poolA.State()[0].ConsecutiveFailures)
+
+	require.NoError(t, poolA.Unregister(ctx, testKey))
+	for _, state := range poolA.State() {
+		require.Equal(t, 0, state.Namespaces)
+	}
+}
+
+func TestRendezvousPoolUnreachable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	mn := p2p_mock.New(ctx)
+	server, err := mn.GenPeer()
+	require.NoError(t, err)
+	client, err := mn.GenPeer()
+	require.NoError(t, err)
+
+	pool, err := NewRendezvousPool(logger, client, []p2p_peer.AddrInfo{*p2p_host.InfoFromHost(server)}, mrand.New(mrand.NewSource(1)), nil)
+	require.NoError(t, err)
+
+	_, err = pool.Advertise(ctx, "testkey")
+	require.Error(t, err)
+
+	// every server is backed off, they are tried anyway
+	_, err = pool.Advertise(ctx, "testkey")
+	require.Error(t, err)
+	require.Equal(t, 2, pool.State()[0].ConsecutiveFailures)
+
+	// the queries are tracked separately
+	_, err = pool.FindPeers(ctx, "testkey")
+	require.Error(t, err)
+	require.Equal(t, 1, pool.State()[0].QueryFailures)
+	require.Equal(t, 2, pool.State()[0].ConsecutiveFailures)
+
+	_, err = NewRendezvousPool(logger, client, nil, mrand.New(mrand.NewSource(1)), nil)
+	require.Error(t, err)
+}
+
+func TestRendezvousPoolQueryHealth(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	mn := p2p_mock.New(ctx)
+	hs := make([]p2p_host.Host, 3)
+	for i := range hs {
+		var err error
+		hs[i], err = mn.GenPeer()
+		require.NoError(t, err)
+	}
+
+	// the first server refuses the registrations, the second one fails the queries
+	rdvNoRegister, rdvNoDiscover, client := hs[0], hs[1], hs[2]
+	testingRendezvousServerWithDB(ctx, t, rdvNoRegister, func(db p2p_rpdbi.DB) p2p_rpdbi.DB {
+		return &testingFailingRendezvousDB{DB: db, failRegister: true}
+	})
+	testingRendezvousServerWithDB(ctx, t, rdvNoDiscover, func(db p2p_rpdbi.DB) p2p_rpdbi.DB {
+		return &testingFailingRendezvousDB{DB: db, failDiscover: true}
+	})
+	for _, server := range []p2p_host.Host{rdvNoRegister, rdvNoDiscover} {
+		_, err := mn.LinkPeers(client.ID(), server.ID())
+		require.NoError(t, err)
+	}
+
+	servers := []p2p_peer.AddrInfo{*p2p_host.InfoFromHost(rdvNoRegister), *p2p_host.InfoFromHost(rdvNoDiscover)}
+	pool, err := NewRendezvousPool(logger, client, servers, mrand.New(mrand.NewSource(1)), nil)
+	require.NoError(t, err)
+
+	const testKey = "testkey"
+
+	_, err = pool.Advertise(ctx, testKey)
+	require.NoError(t, err)
+
+	// the query errors are reported, a successful query doesn't clear the advertise backoff
+	_, err = pool.FindPeers(ctx, testKey)
+	require.NoError(t, err)
+
+	states := pool.State()
+	require.Equal(t, 1, states[0].ConsecutiveFailures)
+	require.True(t, states[0].BackoffUntil.After(time.Now()))
+	require.Equal(t, 0, states[0].QueryFailures)
+
+	require.Equal(t, 0, states[1].ConsecutiveFailures)
+	require.Equal(t, 1, states[1].QueryFailures)
+	require.True(t, states[1].QueryBackoffUntil.After(time.Now()))
+	require.NotEmpty(t, states[1].LastQueryError)
+
+	// the server failing the queries is still used to advertise
+	_, err = pool.Advertise(ctx, testKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, pool.State()[0].ConsecutiveFailures)
+	require.Equal(t, 1, pool.State()[1].Namespaces)
+}
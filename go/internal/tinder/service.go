@@ -10,11 +10,32 @@ type Service interface {
 	Driver
 }
 
+// service is a StatsProvider when the underlying driver is one
+var _ StatsProvider = (*service)(nil)
+
+type service struct {
+	Driver
+
+	driver Driver
+}
+
 func NewService(logger *zap.Logger, driver Driver, stratFactory p2p_discovery.BackoffFactory, opts ...p2p_discovery.BackoffDiscoveryOption) (Service, error) {
 	disc, err := p2p_discovery.NewBackoffDiscovery(driver, stratFactory, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return ComposeDriver("tinder", disc, disc, driver), nil
+	return &service{
+		Driver: ComposeDriver("tinder", disc, disc, driver),
+		driver: driver,
+	}, nil
+}
+
+// DriversStats returns the stats of the underlying driver, if it tracks them
+func (s *service) DriversStats() []*DriverStats {
+	if sp, ok := s.driver.(StatsProvider); ok {
+		return sp.DriversStats()
+	}
+
+	return nil
 }
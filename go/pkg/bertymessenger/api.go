@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/url"
 	"os"
 	"strings"
@@ -171,6 +170,32 @@ func (svc *service) ShareableBertyGroup(ctx context.Context, req *messengertypes
 	return &rep, nil
 }
 
+func (svc *service) DeviceLinkCreate(ctx context.Context, req *messengertypes.DeviceLinkCreate_Request) (*messengertypes.DeviceLinkCreate_Reply, error) {
+	if req == nil {
+		return nil, errcode.ErrInvalidInput
+	}
+
+	res, err := svc.protocolClient.DeviceLinkInvitationCreate(ctx, &protocoltypes.DeviceLinkInvitationCreate_Request{})
+	if err != nil {
+		return nil, err
+	}
+
+	link := &messengertypes.BertyLink{
+		Kind:       messengertypes.BertyLink_DeviceLinkV1Kind,
+		DeviceLink: res.Invitation,
+	}
+	internal, web, err := bertylinks.MarshalLink(link)
+	if err != nil {
+		return nil, errcode.TODO.Wrap(err)
+	}
+
+	return &messengertypes.DeviceLinkCreate_Reply{
+		Link:        link,
+		InternalURL: internal,
+		WebURL:      web,
+	}, nil
+}
+
 // maybe we should preserve the previous generic api
 func (svc *service) SendContactRequest(ctx context.Context, req *messengertypes.SendContactRequest_Request) (*messengertypes.SendContactRequest_Reply, error) {
 	if req == nil || req.BertyID == nil || req.BertyID.AccountPK == nil || req.BertyID.PublicRendezvousSeed == nil {
@@ -660,7 +685,7 @@ func (svc *service) ConversationCreate(ctx context.Context, req *messengertypes.
 	// Try to put group name in group metadata
 	{
 		err := func() error {
-			am, err := messengertypes.AppMessage_TypeSetGroupInfo.MarshalPayload(0, nil, &messengertypes.AppMessage_SetGroupInfo{DisplayName: dn})
+			am, err := messengertypes.AppMessage_TypeSetGroupInfo.MarshalPayload(timestampMs(time.Now()), nil, &messengertypes.AppMessage_SetGroupInfo{DisplayName: dn})
 			if err != nil {
 				return err
 			}
@@ -783,6 +808,168 @@ func (svc *service) ConversationJoin(ctx context.Context, req *messengertypes.Co
 	return &messengertypes.ConversationJoin_Reply{}, nil
 }
 
+func (svc *service) ConversationUpdate(ctx context.Context, req *messengertypes.ConversationUpdate_Request) (*messengertypes.ConversationUpdate_Reply, error) {
+	gpk := req.GetConversationPublicKey()
+	if gpk == "" {
+		return nil, errcode.ErrMissingInput
+	}
+
+	gpkb, err := b64DecodeBytes(gpk)
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	avatarCID := req.GetAvatarCID()
+	if avatarCID != "" {
+		if err := ensureValidBase64CID(avatarCID); err != nil {
+			return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("couldn't ensure the avatar cid is a valid ipfs cid: %s", err))
+		}
+	}
+
+	svc.handlerMutex.Lock()
+	defer svc.handlerMutex.Unlock()
+
+	conv, err := svc.db.getConversationByPK(gpk)
+	if err != nil {
+		return nil, errcode.ErrDBRead.Wrap(err)
+	}
+
+	if conv.GetType() != messengertypes.Conversation_MultiMemberType {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("only multi-member conversations can be updated"))
+	}
+
+	dn := strings.TrimSpace(req.GetDisplayName())
+	if dn == "" {
+		dn = conv.GetDisplayName()
+	}
+
+	var (
+		attachmentCIDs [][]byte
+		medias         []*messengertypes.Media
+	)
+
+	if avatarCID != "" {
+		avatarCIDBytes, err := b64DecodeBytes(avatarCID)
+		if err != nil {
+			return nil, errcode.ErrDeserialization.Wrap(err)
+		}
+		attachmentCIDs = [][]byte{avatarCIDBytes}
+
+		if medias, err = svc.db.getMedias([]string{avatarCID}); err != nil {
+			return nil, errcode.ErrDBRead.Wrap(err)
+		}
+	} else {
+		avatarCID = conv.GetAvatarCID()
+	}
+
+	adminOnly := conv.GetInfoAdminOnly()
+	switch req.GetInfoAdminOnly() {
+	case messengertypes.ConversationUpdate_Enabled:
+		adminOnly = true
+	case messengertypes.ConversationUpdate_Disabled:
+		adminOnly = false
+	}
+
+	if dn == conv.GetDisplayName() && avatarCID == conv.GetAvatarCID() && adminOnly == conv.GetInfoAdminOnly() {
+		svc.logger.Debug("ConversationUpdate: nothing to do")
+		return &messengertypes.ConversationUpdate_Reply{}, nil
+	}
+
+	// the other members would ignore the update anyway
+	if adminOnly || conv.GetInfoAdminOnly() {
+		gi, err := svc.protocolClient.GroupInfo(ctx, &protocoltypes.GroupInfo_Request{GroupPK: gpkb})
+		if err != nil {
+			return nil, errcode.ErrGroupInfo.Wrap(err)
+		}
+
+		isAdmin, err := svc.db.isMemberAdmin(b64EncodeBytes(gi.GetMemberPK()), gpk)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isAdmin {
+			return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("only admins can update this conversation"))
+		}
+	}
+
+	am, err := messengertypes.AppMessage_TypeSetGroupInfo.MarshalPayload(
+		timestampMs(time.Now()),
+		medias,
+		&messengertypes.AppMessage_SetGroupInfo{DisplayName: dn, AvatarCID: avatarCID, AdminOnly: adminOnly},
+	)
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	if _, err := svc.protocolClient.AppMetadataSend(ctx, &protocoltypes.AppMetadataSend_Request{GroupPK: gpkb, Payload: am, AttachmentCIDs: attachmentCIDs}); err != nil {
+		return nil, errcode.ErrProtocolSend.Wrap(err)
+	}
+
+	return &messengertypes.ConversationUpdate_Reply{}, nil
+}
+
+func (svc *service) ConversationAdminGrant(ctx context.Context, req *messengertypes.ConversationAdminGrant_Request) (*messengertypes.ConversationAdminGrant_Reply, error) {
+	if req.GetConversationPublicKey() == "" || req.GetMemberPublicKey() == "" {
+		return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a conversation and a member public key are required"))
+	}
+
+	conv, err := svc.db.getConversationByPK(req.GetConversationPublicKey())
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	if conv.GetType() != messengertypes.Conversation_MultiMemberType {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("admin roles are only available in multi-member conversations"))
+	}
+
+	gpkb, err := b64DecodeBytes(conv.GetPublicKey())
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	mpkb, err := b64DecodeBytes(req.GetMemberPublicKey())
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	if _, err := svc.protocolClient.MultiMemberGroupAdminRoleGrant(ctx, &protocoltypes.MultiMemberGroupAdminRoleGrant_Request{GroupPK: gpkb, MemberPK: mpkb}); err != nil {
+		return nil, errcode.ErrProtocolSend.Wrap(err)
+	}
+
+	return &messengertypes.ConversationAdminGrant_Reply{}, nil
+}
+
+func (svc *service) ConversationMemberRemove(ctx context.Context, req *messengertypes.ConversationMemberRemove_Request) (*messengertypes.ConversationMemberRemove_Reply, error) {
+	if req.GetConversationPublicKey() == "" || req.GetMemberPublicKey() == "" {
+		return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a conversation and a member public key are required"))
+	}
+
+	conv, err := svc.db.getConversationByPK(req.GetConversationPublicKey())
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	if conv.GetType() != messengertypes.Conversation_MultiMemberType {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("members can only be removed from multi-member conversations"))
+	}
+
+	gpkb, err := b64DecodeBytes(conv.GetPublicKey())
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	mpkb, err := b64DecodeBytes(req.GetMemberPublicKey())
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	if _, err := svc.protocolClient.MultiMemberGroupMemberRemove(ctx, &protocoltypes.MultiMemberGroupMemberRemove_Request{GroupPK: gpkb, MemberPK: mpkb}); err != nil {
+		return nil, errcode.ErrProtocolSend.Wrap(err)
+	}
+
+	return &messengertypes.ConversationMemberRemove_Reply{}, nil
+}
+
 func ensureValidBase64CID(str string) error {
 	cidBytes, err := b64DecodeBytes(str)
 	if err != nil {
@@ -954,6 +1141,76 @@ func (svc *service) ContactAccept(ctx context.Context, req *messengertypes.Conta
 	return &messengertypes.ContactAccept_Reply{}, nil
 }
 
+func (svc *service) ContactRequestDiscard(ctx context.Context, req *messengertypes.ContactRequestDiscard_Request) (*messengertypes.ContactRequestDiscard_Reply, error) {
+	c, pkb, err := svc.getContactForRequest(req.GetPublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.State != messengertypes.Contact_IncomingRequest {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("contact request status is not IncomingRequest %s)", c.State.String()))
+	}
+
+	if _, err := svc.protocolClient.ContactRequestDiscard(ctx, &protocoltypes.ContactRequestDiscard_Request{ContactPK: pkb}); err != nil {
+		return nil, errcode.TODO.Wrap(err)
+	}
+
+	return &messengertypes.ContactRequestDiscard_Reply{}, nil
+}
+
+func (svc *service) ContactBlock(ctx context.Context, req *messengertypes.ContactBlock_Request) (*messengertypes.ContactBlock_Reply, error) {
+	c, pkb, err := svc.getContactForRequest(req.GetPublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.State == messengertypes.Contact_Blocked {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("contact is already blocked"))
+	}
+
+	if _, err := svc.protocolClient.ContactBlock(ctx, &protocoltypes.ContactBlock_Request{ContactPK: pkb}); err != nil {
+		return nil, errcode.TODO.Wrap(err)
+	}
+
+	return &messengertypes.ContactBlock_Reply{}, nil
+}
+
+func (svc *service) ContactUnblock(ctx context.Context, req *messengertypes.ContactUnblock_Request) (*messengertypes.ContactUnblock_Reply, error) {
+	c, pkb, err := svc.getContactForRequest(req.GetPublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.State != messengertypes.Contact_Blocked {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("contact is not blocked"))
+	}
+
+	if _, err := svc.protocolClient.ContactUnblock(ctx, &protocoltypes.ContactUnblock_Request{ContactPK: pkb}); err != nil {
+		return nil, errcode.TODO.Wrap(err)
+	}
+
+	return &messengertypes.ContactUnblock_Reply{}, nil
+}
+
+// getContactForRequest retrieves a contact by its public key, the decoded public key is also returned
+func (svc *service) getContactForRequest(pk string) (*messengertypes.Contact, []byte, error) {
+	if pk == "" {
+		return nil, nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("no public key supplied"))
+	}
+
+	pkb, err := b64DecodeBytes(pk)
+	if err != nil {
+		return nil, nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	c, err := svc.db.getContactByPK(pk)
+	if err != nil {
+		return nil, nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	return c, pkb, nil
+}
+
 func (svc *service) Interact(ctx context.Context, req *messengertypes.Interact_Request) (*messengertypes.Interact_Reply, error) {
 	gpk := req.GetConversationPublicKey()
 	if gpk == "" {
@@ -994,6 +1251,71 @@ func (svc *service) Interact(ctx context.Context, req *messengertypes.Interact_R
 		if err != nil {
 			return nil, err
 		}
+	case messengertypes.AppMessage_TypeUserReaction:
+		var p messengertypes.AppMessage_UserReaction
+		if err := proto.Unmarshal(req.GetPayload(), &p); err != nil {
+			return nil, errcode.ErrInvalidInput.Wrap(err)
+		}
+		if p.GetTarget() == "" || p.GetEmoji() == "" {
+			return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a target and an emoji are required"))
+		}
+		fp, err := messengertypes.AppMessage_TypeUserReaction.MarshalPayload(timestampMs(time.Now()), nil, &p)
+		if err != nil {
+			return nil, errcode.ErrInternal.Wrap(err)
+		}
+		_, err = svc.protocolClient.AppMessageSend(ctx, &protocoltypes.AppMessageSend_Request{GroupPK: gpkb, Payload: fp})
+		if err != nil {
+			return nil, err
+		}
+	case messengertypes.AppMessage_TypeEditUserMessage:
+		var p messengertypes.AppMessage_EditUserMessage
+		if err := proto.Unmarshal(req.GetPayload(), &p); err != nil {
+			return nil, errcode.ErrInvalidInput.Wrap(err)
+		}
+		if p.GetTarget() == "" {
+			return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a target is required"))
+		}
+		target, err := svc.db.getInteractionByCID(p.GetTarget())
+		if err != nil {
+			return nil, errcode.ErrInvalidInput.Wrap(err)
+		}
+		if !target.GetIsMine() || target.GetType() != messengertypes.AppMessage_TypeUserMessage || target.GetConversationPublicKey() != gpk {
+			return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("only your own messages can be edited"))
+		}
+		fp, err := messengertypes.AppMessage_TypeEditUserMessage.MarshalPayload(timestampMs(time.Now()), nil, &p)
+		if err != nil {
+			return nil, errcode.ErrInternal.Wrap(err)
+		}
+		_, err = svc.protocolClient.AppMessageSend(ctx, &protocoltypes.AppMessageSend_Request{GroupPK: gpkb, Payload: fp})
+		if err != nil {
+			return nil, err
+		}
+	case messengertypes.AppMessage_TypeRetractUserMessage:
+		var p messengertypes.AppMessage_RetractUserMessage
+		if err := proto.Unmarshal(req.GetPayload(), &p); err != nil {
+			return nil, errcode.ErrInvalidInput.Wrap(err)
+		}
+		if p.GetTarget() == "" {
+			return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a target is required"))
+		}
+		target, err := svc.db.getInteractionByCID(p.GetTarget())
+		if err != nil {
+			return nil, errcode.ErrInvalidInput.Wrap(err)
+		}
+		if !target.GetIsMine() || target.GetType() != messengertypes.AppMessage_TypeUserMessage || target.GetConversationPublicKey() != gpk {
+			return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("only your own messages can be retracted"))
+		}
+		if target.GetRetracted() {
+			return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("message already retracted"))
+		}
+		fp, err := messengertypes.AppMessage_TypeRetractUserMessage.MarshalPayload(timestampMs(time.Now()), nil, &p)
+		if err != nil {
+			return nil, errcode.ErrInternal.Wrap(err)
+		}
+		_, err = svc.protocolClient.AppMessageSend(ctx, &protocoltypes.AppMessageSend_Request{GroupPK: gpkb, Payload: fp})
+		if err != nil {
+			return nil, err
+		}
 	case messengertypes.AppMessage_TypeAcknowledge:
 		// trick gocritic
 	}
@@ -1058,7 +1380,13 @@ func (svc *service) ConversationOpen(ctx context.Context, req *messengertypes.Co
 
 	if err != nil {
 		return nil, err
-	} else if !updated {
+	}
+
+	if err := svc.sendReadReceipts(ctx, req.GetGroupPK()); err != nil {
+		svc.logger.Error("unable to send read receipts", zap.String("public-key", req.GetGroupPK()), zap.Error(err))
+	}
+
+	if !updated {
 		return &ret, nil
 	}
 
@@ -1069,6 +1397,131 @@ func (svc *service) ConversationOpen(ctx context.Context, req *messengertypes.Co
 	return &ret, nil
 }
 
+// readReceiptMaxTargets is the maximum amount of messages marked as read by a single read receipt
+const readReceiptMaxTargets = 100
+
+// sendReadReceipts marks the received messages of a conversation as read if the account allows it,
+// the receipts are sent by batches and recorded locally so a message is only marked as read once
+func (svc *service) sendReadReceipts(ctx context.Context, conversationPK string) error {
+	acc, err := svc.db.getAccount()
+	if err != nil {
+		return err
+	}
+
+	if !acc.GetSendReadReceipts() {
+		return nil
+	}
+
+	gpkb, err := b64DecodeBytes(conversationPK)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	var memberPK string
+	for {
+		cids, err := svc.db.getCIDsToMarkAsRead(conversationPK, readReceiptMaxTargets)
+		if err != nil {
+			return err
+		}
+
+		if len(cids) == 0 {
+			return nil
+		}
+
+		// own receipts are attributed to the account member, like the ones received from our other devices
+		if memberPK == "" {
+			gi, err := svc.protocolClient.GroupInfo(ctx, &protocoltypes.GroupInfo_Request{GroupPK: gpkb})
+			if err != nil {
+				return errcode.ErrGroupInfo.Wrap(err)
+			}
+			memberPK = b64EncodeBytes(gi.GetMemberPK())
+		}
+
+		readDate := timestampMs(time.Now())
+		payload, err := messengertypes.AppMessage_TypeReadReceipt.MarshalPayload(readDate, nil, &messengertypes.AppMessage_ReadReceipt{Targets: cids})
+		if err != nil {
+			return errcode.ErrSerialization.Wrap(err)
+		}
+
+		if _, err := svc.protocolClient.AppMessageSend(ctx, &protocoltypes.AppMessageSend_Request{GroupPK: gpkb, Payload: payload}); err != nil {
+			return errcode.ErrProtocolSend.Wrap(err)
+		}
+
+		if err := svc.db.tx(func(tx *dbWrapper) error {
+			for _, cid := range cids {
+				if _, err := tx.upsertInteractionReceipt(messengertypes.InteractionReceipt{
+					InteractionCID:  cid,
+					MemberPublicKey: memberPK,
+					IsMine:          true,
+					ReadDate:        readDate,
+				}); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(cids) < readReceiptMaxTargets {
+			return nil
+		}
+	}
+}
+
+func (svc *service) ConversationRetentionSet(ctx context.Context, req *messengertypes.ConversationRetentionSet_Request) (*messengertypes.ConversationRetentionSet_Reply, error) {
+	if req.GetConversationPublicKey() == "" {
+		return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a conversation public key is required"))
+	}
+
+	if req.GetPeriod() < 0 {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a retention period can't be negative"))
+	}
+
+	if _, err := svc.db.getConversationByPK(req.GetConversationPublicKey()); err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	gpkb, err := b64DecodeBytes(req.GetConversationPublicKey())
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	payload, err := messengertypes.AppMessage_TypeSetRetention.MarshalPayload(timestampMs(time.Now()), nil, &messengertypes.AppMessage_SetRetention{Period: req.GetPeriod()})
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	if _, err := svc.protocolClient.AppMessageSend(ctx, &protocoltypes.AppMessageSend_Request{GroupPK: gpkb, Payload: payload}); err != nil {
+		return nil, errcode.ErrProtocolSend.Wrap(err)
+	}
+
+	return &messengertypes.ConversationRetentionSet_Reply{}, nil
+}
+
+func (svc *service) ConversationTypingSet(ctx context.Context, req *messengertypes.ConversationTypingSet_Request) (*messengertypes.ConversationTypingSet_Reply, error) {
+	if req.GetConversationPublicKey() == "" {
+		return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a conversation public key is required"))
+	}
+
+	gpkb, err := b64DecodeBytes(req.GetConversationPublicKey())
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	payload, err := messengertypes.AppMessage_TypeTyping.MarshalPayload(timestampMs(time.Now()), nil, &messengertypes.AppMessage_Typing{State: req.GetTyping()})
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	if _, err := svc.protocolClient.EphemeralMessageSend(ctx, &protocoltypes.EphemeralMessageSend_Request{GroupPK: gpkb, Payload: payload}); err != nil {
+		return nil, errcode.ErrProtocolSend.Wrap(err)
+	}
+
+	return &messengertypes.ConversationTypingSet_Reply{}, nil
+}
+
 func (svc *service) ConversationClose(ctx context.Context, req *messengertypes.ConversationClose_Request) (*messengertypes.ConversationClose_Reply, error) {
 	// check input
 	if req.GroupPK == "" {
@@ -1093,6 +1546,32 @@ func (svc *service) ConversationClose(ctx context.Context, req *messengertypes.C
 	return &ret, nil
 }
 
+func (svc *service) ConversationLeave(ctx context.Context, req *messengertypes.ConversationLeave_Request) (*messengertypes.ConversationLeave_Reply, error) {
+	if req.GetConversationPublicKey() == "" {
+		return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a conversation public key is required"))
+	}
+
+	conv, err := svc.db.getConversationByPK(req.GetConversationPublicKey())
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	if conv.GetType() != messengertypes.Conversation_MultiMemberType {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("only multi-member conversations can be left"))
+	}
+
+	gpkb, err := b64DecodeBytes(conv.GetPublicKey())
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if _, err := svc.protocolClient.MultiMemberGroupLeave(ctx, &protocoltypes.MultiMemberGroupLeave_Request{GroupPK: gpkb}); err != nil {
+		return nil, errcode.TODO.Wrap(err)
+	}
+
+	return &messengertypes.ConversationLeave_Reply{}, nil
+}
+
 func (svc *service) ServicesTokenList(req *protocoltypes.ServicesTokenList_Request, server messengertypes.MessengerService_ServicesTokenListServer) error {
 	cl, err := svc.protocolClient.ServicesTokenList(server.Context(), req)
 	if err != nil {
@@ -1119,6 +1598,19 @@ func (svc *service) ServicesTokenList(req *protocoltypes.ServicesTokenList_Reque
 	return nil
 }
 
+func (svc *service) ServicesTokenRemove(ctx context.Context, req *protocoltypes.ServicesTokenRemove_Request) (*protocoltypes.ServicesTokenRemove_Reply, error) {
+	if req.GetTokenID() == "" {
+		return nil, errcode.ErrMissingInput
+	}
+
+	if _, err := svc.protocolClient.ServicesTokenRemove(ctx, req); err != nil {
+		svc.logger.Error("failed to remove service token", zap.String("token-id", req.TokenID), zap.Error(err))
+		return nil, err
+	}
+
+	return &protocoltypes.ServicesTokenRemove_Reply{}, nil
+}
+
 func (svc *service) ReplicationServiceRegisterGroup(ctx context.Context, req *messengertypes.ReplicationServiceRegisterGroup_Request) (*messengertypes.ReplicationServiceRegisterGroup_Reply, error) {
 	gpk := req.GetConversationPublicKey()
 	if gpk == "" {
@@ -1147,6 +1639,46 @@ func (svc *service) ReplicationServiceRegisterGroup(ctx context.Context, req *me
 	return &messengertypes.ReplicationServiceRegisterGroup_Reply{}, nil
 }
 
+func (svc *service) ReplicationServiceUnregisterGroup(ctx context.Context, req *messengertypes.ReplicationServiceUnregisterGroup_Request) (*messengertypes.ReplicationServiceUnregisterGroup_Reply, error) {
+	gpk := req.GetConversationPublicKey()
+	if gpk == "" {
+		return nil, errcode.ErrMissingInput
+	}
+
+	gpkb, err := b64DecodeBytes(gpk)
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	if _, err := svc.protocolClient.ReplicationServiceUnregisterGroup(ctx, &protocoltypes.ReplicationServiceUnregisterGroup_Request{
+		TokenID: req.TokenID,
+		GroupPK: gpkb,
+	}); err != nil {
+		svc.logger.Error("failed to stop group replication", zap.String("public-key", gpk), zap.String("token-id", req.TokenID), zap.Error(err))
+		return nil, err
+	}
+
+	svc.logger.Info("group replication stopped", zap.String("public-key", gpk), zap.String("token-id", req.TokenID))
+
+	return &messengertypes.ReplicationServiceUnregisterGroup_Reply{}, nil
+}
+
+func (svc *service) ReplicationServiceListGroups(ctx context.Context, req *messengertypes.ReplicationServiceListGroups_Request) (*messengertypes.ReplicationServiceListGroups_Reply, error) {
+	res, err := svc.protocolClient.ReplicationServiceListGroups(ctx, &protocoltypes.ReplicationServiceListGroups_Request{
+		TokenID: req.TokenID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conversationPKs := make([]string, len(res.GroupPKs))
+	for i, groupPK := range res.GroupPKs {
+		conversationPKs[i] = b64EncodeBytes(groupPK)
+	}
+
+	return &messengertypes.ReplicationServiceListGroups_Reply{ConversationPublicKeys: conversationPKs}, nil
+}
+
 func (svc *service) BannerQuote(ctx context.Context, req *messengertypes.BannerQuote_Request) (*messengertypes.BannerQuote_Reply, error) {
 	var quote banner.Quote
 	if req != nil && req.Random {
@@ -1207,61 +1739,134 @@ func (svc *service) ReplicationSetAutoEnable(ctx context.Context, req *messenger
 	return &messengertypes.ReplicationSetAutoEnable_Reply{}, nil
 }
 
-func (svc *service) InstanceExportData(_ *messengertypes.InstanceExportData_Request, server messengertypes.MessengerService_InstanceExportDataServer) error {
-	tmpFile, err := ioutil.TempFile(os.TempDir(), "export-")
+func (svc *service) ReadReceiptsSetEnable(ctx context.Context, req *messengertypes.ReadReceiptsSetEnable_Request) (*messengertypes.ReadReceiptsSetEnable_Reply, error) {
+	config, err := svc.protocolClient.InstanceGetConfiguration(svc.ctx, &protocoltypes.InstanceGetConfiguration_Request{})
 	if err != nil {
-		return errcode.ErrInternal.Wrap(err)
+		return nil, err
 	}
 
-	defer os.Remove(tmpFile.Name())
+	if err := svc.db.accountSetReadReceiptsEnable(b64EncodeBytes(config.AccountPK), req.Enabled); err != nil {
+		return nil, err
+	}
 
-	cl, err := svc.protocolClient.InstanceExportData(server.Context(), &protocoltypes.InstanceExportData_Request{})
+	acc, err := svc.db.getAccount()
 	if err != nil {
-		return errcode.ErrInternal.Wrap(err)
+		return nil, err
 	}
 
-	for {
-		chunk, err := cl.Recv()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return errcode.ErrInternal.Wrap(err)
+	// dispatch event
+	if err := svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeAccountUpdated, &messengertypes.StreamEvent_AccountUpdated{Account: acc}, false); err != nil {
+		return nil, errcode.TODO.Wrap(err)
+	}
+
+	return &messengertypes.ReadReceiptsSetEnable_Reply{}, nil
+}
+
+func (svc *service) InteractionReceipts(ctx context.Context, req *messengertypes.InteractionReceipts_Request) (*messengertypes.InteractionReceipts_Reply, error) {
+	if req.GetCID() == "" {
+		return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("an interaction cid is required"))
+	}
+
+	i, err := svc.db.getInteractionByCID(req.GetCID())
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	receipts, err := svc.db.getInteractionReceipts(i.GetCID())
+	if err != nil {
+		return nil, err
+	}
+
+	membersCount, err := svc.db.countInteractionRecipients(i)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &messengertypes.InteractionReceipts_Reply{MembersCount: membersCount}
+	authorPK := i.GetMemberPublicKey()
+	if i.GetConversation().GetType() == messengertypes.Conversation_ContactType {
+		authorPK = i.GetConversation().GetContactPublicKey()
+	}
+
+	for _, r := range receipts {
+		// receipts from the author are not relevant, our own receipts are merged under the account member
+		if (i.GetIsMine() && r.GetIsMine()) || (!i.GetIsMine() && r.GetMemberPublicKey() == authorPK) {
+			continue
 		}
 
-		if _, err := tmpFile.Write(chunk.ExportedData); err != nil {
-			return errcode.ErrInternal.Wrap(err)
+		reply.Receipts = append(reply.Receipts, r)
+
+		if r.GetDeliveredDate() != 0 {
+			reply.DeliveredCount++
+		}
+
+		if r.GetReadDate() != 0 {
+			reply.ReadCount++
 		}
 	}
 
-	// Remove trailing headers to append messenger data
-	_, err = tmpFile.Seek(-1024, io.SeekEnd)
+	return reply, nil
+}
+
+func (svc *service) InstanceExportData(req *messengertypes.InstanceExportData_Request, server messengertypes.MessengerService_InstanceExportDataServer) error {
+	cl, err := svc.protocolClient.InstanceExportData(server.Context(), &protocoltypes.InstanceExportData_Request{PreviousHeads: req.PreviousHeads})
 	if err != nil {
 		return errcode.ErrInternal.Wrap(err)
 	}
 
-	svc.handlerMutex.Lock()
-	defer svc.handlerMutex.Unlock()
+	r, w := io.Pipe()
+	defer r.Close()
 
-	if err := exportMessengerData(tmpFile, svc.db.db, svc.logger); err != nil {
-		return errcode.ErrInternal.Wrap(err)
+	// the protocol export is not encrypted as messenger data are appended to it, the whole export is encrypted here
+	var exported io.ReadCloser = r
+	if len(req.Passphrase) > 0 {
+		exported, err = bertyprotocol.SealExport(r, req.Passphrase, svc.logger)
+		if err != nil {
+			return errcode.ErrInternal.Wrap(err)
+		}
+		defer exported.Close()
 	}
 
-	if _, err = tmpFile.Seek(0, io.SeekStart); err != nil {
-		return errcode.ErrInternal.Wrap(err)
-	}
+	go func() {
+		err := func() error {
+			// remove trailing headers to append messenger data
+			tw := &tarTrailerWriter{w: w}
+
+			for {
+				chunk, err := cl.Recv()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return errcode.ErrInternal.Wrap(err)
+				}
+
+				if _, err := tw.Write(chunk.ExportedData); err != nil {
+					return errcode.ErrStreamWrite.Wrap(err)
+				}
+			}
+
+			svc.handlerMutex.Lock()
+			defer svc.handlerMutex.Unlock()
+
+			return exportMessengerData(w, svc.db.db, svc.logger)
+		}()
+		streamutil.ClosePipeOut(w, err, "InstanceExportData: close pipe out", svc.logger)
+	}()
 
 	buffer := make([]byte, 1024)
 	for {
-		_, err := tmpFile.Read(buffer)
+		n, err := exported.Read(buffer)
+		if n > 0 {
+			if err := server.Send(&messengertypes.InstanceExportData_Reply{ExportedData: buffer[:n]}); err != nil {
+				return errcode.ErrInternal.Wrap(err)
+			}
+		}
+
 		if err == io.EOF {
 			return nil
 		} else if err != nil {
 			return errcode.ErrInternal.Wrap(err)
 		}
-
-		if err := server.Send(&messengertypes.InstanceExportData_Reply{ExportedData: buffer}); err != nil {
-			return errcode.ErrInternal.Wrap(err)
-		}
 	}
 }
 
@@ -1423,3 +2028,12 @@ func (svc *service) ConversationLoad(ctx context.Context, request *messengertype
 
 	return &messengertypes.ConversationLoad_Reply{}, nil
 }
+
+func (svc *service) InteractionSearch(ctx context.Context, req *messengertypes.InteractionSearch_Request) (*messengertypes.InteractionSearch_Reply, error) {
+	results, medias, err := svc.db.searchInteractions(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &messengertypes.InteractionSearch_Reply{Results: results, Medias: medias}, nil
+}
@@ -21,6 +21,9 @@ import (
 type dbWrapper struct {
 	db  *gorm.DB
 	log *zap.Logger
+
+	// searchIndex is true once the full-text search index has been set up by initSearchIndex
+	searchIndex bool
 }
 
 func newDBWrapper(db *gorm.DB, log *zap.Logger) *dbWrapper {
@@ -49,6 +52,10 @@ func getDBModels() []interface{} {
 		&messengertypes.Device{},
 		&messengertypes.ConversationReplicationInfo{},
 		&messengertypes.Media{},
+		&messengertypes.MediaReference{},
+		&messengertypes.Reaction{},
+		&messengertypes.InteractionEdit{},
+		&messengertypes.InteractionReceipt{},
 	}
 }
 
@@ -57,7 +64,9 @@ func (d *dbWrapper) initDB(replayer func(d *dbWrapper) error) error {
 		return err
 	}
 
-	return nil
+	// the search index is dropped along with the other tables when the db is replayed,
+	// it is then created and populated once all the interactions are stored
+	return d.initSearchIndex()
 }
 
 func (d *dbWrapper) getUpdatedDB(models []interface{}, replayer func(db *dbWrapper) error, logger *zap.Logger) error {
@@ -103,7 +112,7 @@ func isSQLiteError(err error, sqliteErr sqlite3.ErrNo) bool {
 func (d *dbWrapper) tx(txFunc func(*dbWrapper) error) error {
 	// Use this to propagate scope, ie. opened account
 	return d.db.Transaction(func(tx *gorm.DB) error {
-		return txFunc(&dbWrapper{db: tx})
+		return txFunc(&dbWrapper{db: tx, searchIndex: d.searchIndex})
 	})
 }
 
@@ -224,6 +233,100 @@ func (d *dbWrapper) updateConversation(c messengertypes.Conversation) (bool, err
 	return isNew, nil
 }
 
+// updateConversationInfo applies a group info to a conversation if it is more recent than the current one.
+// It returns true if the conversation has been updated.
+func (d *dbWrapper) updateConversationInfo(pk, displayName, avatarCID string, adminOnly bool, infoDate int64) (bool, error) {
+	if pk == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a conversation public key is required"))
+	}
+
+	tx := d.db.
+		Model(&messengertypes.Conversation{}).
+		Where("public_key = ? AND info_date < ?", pk, infoDate).
+		Updates(map[string]interface{}{
+			"display_name":    displayName,
+			"avatar_cid":      avatarCID,
+			"info_admin_only": adminOnly,
+			"info_date":       infoDate,
+		})
+
+	if tx.Error != nil {
+		return false, errcode.ErrDBWrite.Wrap(tx.Error)
+	}
+
+	return tx.RowsAffected > 0, nil
+}
+
+// updateConversationRetention applies a retention setting to a conversation if it is more recent than the current one.
+// It returns true if the conversation has been updated.
+func (d *dbWrapper) updateConversationRetention(pk string, period int64, retentionDate int64) (bool, error) {
+	if pk == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a conversation public key is required"))
+	}
+
+	if period < 0 {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a retention period can't be negative"))
+	}
+
+	updated := false
+	if err := d.tx(func(tx *dbWrapper) error {
+		res := tx.db.
+			Model(&messengertypes.Conversation{}).
+			Where("public_key = ? AND retention_date < ?", pk, retentionDate).
+			Updates(map[string]interface{}{
+				"retention_period": period,
+				"retention_date":   retentionDate,
+			})
+
+		if res.Error != nil {
+			return errcode.ErrDBWrite.Wrap(res.Error)
+		}
+
+		if updated = res.RowsAffected > 0; !updated {
+			return nil
+		}
+
+		// the interactions already stored expire according to the new setting, see getInteractionExpirationDate
+		expirationDate := gorm.Expr("0")
+		if period > 0 {
+			expirationDate = gorm.Expr("CASE WHEN sent_date > 0 AND sent_date >= ? THEN sent_date + ? ELSE 0 END", retentionDate, period)
+		}
+
+		if err := tx.db.
+			Model(&messengertypes.Interaction{}).
+			Where("conversation_public_key = ?", pk).
+			Update("expiration_date", expirationDate).
+			Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	return updated, nil
+}
+
+// setConversationArchived archives or unarchives a conversation, it is a no-op for an empty public key.
+// It returns true if the conversation has been updated.
+func (d *dbWrapper) setConversationArchived(pk string, archived bool) (bool, error) {
+	if pk == "" {
+		return false, nil
+	}
+
+	tx := d.db.
+		Model(&messengertypes.Conversation{}).
+		Where("public_key = ? AND is_archived = ?", pk, !archived).
+		Update("is_archived", archived)
+
+	if tx.Error != nil {
+		return false, errcode.ErrDBWrite.Wrap(tx.Error)
+	}
+
+	return tx.RowsAffected > 0, nil
+}
+
 func (d *dbWrapper) updateConversationReadState(pk string, newUnread bool, eventDate time.Time) error {
 	if pk == "" {
 		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("a conversation public key is required"))
@@ -410,7 +513,11 @@ func (d *dbWrapper) getContactsByState(state messengertypes.Contact_State) ([]*m
 func (d *dbWrapper) getAllInteractions() ([]*messengertypes.Interaction, error) {
 	interactions := []*messengertypes.Interaction(nil)
 
-	return interactions, d.db.Preload(clause.Associations).Find(&interactions).Error
+	if err := d.db.Preload(clause.Associations).Where("retracted = false").Find(&interactions).Error; err != nil {
+		return nil, err
+	}
+
+	return interactions, d.attachReactionsToInteractions(interactions)
 }
 
 func (d *dbWrapper) getPaginatedInteractions(opts *messengertypes.PaginatedInteractionsOptions) ([]*messengertypes.Interaction, []*messengertypes.Media, error) {
@@ -451,7 +558,8 @@ func (d *dbWrapper) getPaginatedInteractions(opts *messengertypes.PaginatedInter
 		var cidsForConv []string
 		query := d.db.
 			Model(&messengertypes.Interaction{}).
-			Where(&messengertypes.Interaction{ConversationPublicKey: pk})
+			Where(&messengertypes.Interaction{ConversationPublicKey: pk}).
+			Where("retracted = false")
 
 		if previousInteraction != nil {
 			if opts.OldestToNewest {
@@ -489,6 +597,10 @@ func (d *dbWrapper) getPaginatedInteractions(opts *messengertypes.PaginatedInter
 		return nil, nil, errcode.ErrDBRead.Wrap(fmt.Errorf("unable to fetch interactions: %w", err))
 	}
 
+	if err := d.attachReactionsToInteractions(interactions); err != nil {
+		return nil, nil, errcode.ErrDBRead.Wrap(fmt.Errorf("unable to fetch reactions: %w", err))
+	}
+
 	if !opts.ExcludeMedias {
 		if err := d.db.
 			Preload(clause.Associations).
@@ -509,7 +621,11 @@ func (d *dbWrapper) getInteractionByCID(cid string) (*messengertypes.Interaction
 	}
 
 	interaction := &messengertypes.Interaction{}
-	return interaction, d.db.Preload(clause.Associations).First(&interaction, &messengertypes.Interaction{CID: cid}).Error
+	if err := d.db.Preload(clause.Associations).First(&interaction, &messengertypes.Interaction{CID: cid}).Error; err != nil {
+		return interaction, err
+	}
+
+	return interaction, d.attachReactionsToInteractions([]*messengertypes.Interaction{interaction})
 }
 
 func (d *dbWrapper) addContactRequestOutgoingEnqueued(contactPK, displayName, convPK string) (*messengertypes.Contact, error) {
@@ -572,7 +688,25 @@ func (d *dbWrapper) addContactRequestIncomingReceived(contactPK, displayName, gr
 	}
 
 	ec, err := d.getContactByPK(contactPK)
-	if err == nil {
+	if err == nil && ec.State == messengertypes.Contact_Discarded {
+		// a new request can be received after discarding the previous one
+		if err := d.db.
+			Model(&messengertypes.Contact{}).
+			Where(&messengertypes.Contact{PublicKey: contactPK}).
+			Updates(map[string]interface{}{
+				"state":        messengertypes.Contact_IncomingRequest,
+				"display_name": displayName,
+			}).
+			Error; err != nil {
+			return nil, errcode.ErrDBWrite.Wrap(err)
+		}
+
+		if _, err := d.setConversationArchived(ec.GetConversationPublicKey(), false); err != nil {
+			return nil, err
+		}
+
+		return d.getContactByPK(contactPK)
+	} else if err == nil {
 		return ec, errcode.ErrDBEntryAlreadyExists
 	} else if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
@@ -632,6 +766,127 @@ func (d *dbWrapper) addContactRequestIncomingAccepted(contactPK, groupPK string)
 	return contact, nil
 }
 
+// discardContactRequest marks an incoming contact request as discarded and archives its conversation
+func (d *dbWrapper) discardContactRequest(contactPK string) (*messengertypes.Contact, error) {
+	if contactPK == "" {
+		return nil, errcode.ErrInvalidInput.Wrap(errors.New("a contact public key is required"))
+	}
+
+	if err := d.tx(func(tx *dbWrapper) error {
+		contact, err := tx.getContactByPK(contactPK)
+		if err != nil {
+			return err
+		}
+
+		if contact.State != messengertypes.Contact_IncomingRequest {
+			return errcode.ErrInvalidInput.Wrap(errors.New("no incoming request"))
+		}
+
+		if err := tx.db.
+			Model(&messengertypes.Contact{}).
+			Where(&messengertypes.Contact{PublicKey: contactPK}).
+			Update("state", messengertypes.Contact_Discarded).
+			Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		_, err = tx.setConversationArchived(contact.GetConversationPublicKey(), true)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return d.getContactByPK(contactPK)
+}
+
+// blockContact marks a contact as blocked and archives its conversation, the previous state is kept to be restored
+// when the contact is unblocked.
+// It returns the contact and whether it has been updated.
+func (d *dbWrapper) blockContact(contactPK string) (*messengertypes.Contact, bool, error) {
+	if contactPK == "" {
+		return nil, false, errcode.ErrInvalidInput.Wrap(errors.New("a contact public key is required"))
+	}
+
+	updated := false
+	if err := d.tx(func(tx *dbWrapper) error {
+		contact, err := tx.getContactByPK(contactPK)
+		if err != nil {
+			return err
+		}
+
+		if contact.State == messengertypes.Contact_Blocked {
+			return nil
+		}
+
+		if err := tx.db.
+			Model(&messengertypes.Contact{}).
+			Where(&messengertypes.Contact{PublicKey: contactPK}).
+			Updates(map[string]interface{}{
+				"state":              messengertypes.Contact_Blocked,
+				"state_before_block": contact.State,
+			}).
+			Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		updated = true
+
+		_, err = tx.setConversationArchived(contact.GetConversationPublicKey(), true)
+		return err
+	}); err != nil {
+		return nil, false, err
+	}
+
+	contact, err := d.getContactByPK(contactPK)
+	return contact, updated, err
+}
+
+// unblockContact restores the state a contact had before being blocked, its conversation is unarchived unless
+// its request had been discarded.
+// It returns the contact and whether it has been updated.
+func (d *dbWrapper) unblockContact(contactPK string) (*messengertypes.Contact, bool, error) {
+	if contactPK == "" {
+		return nil, false, errcode.ErrInvalidInput.Wrap(errors.New("a contact public key is required"))
+	}
+
+	updated := false
+	if err := d.tx(func(tx *dbWrapper) error {
+		contact, err := tx.getContactByPK(contactPK)
+		if err != nil {
+			return err
+		}
+
+		if contact.State != messengertypes.Contact_Blocked {
+			return nil
+		}
+
+		if err := tx.db.
+			Model(&messengertypes.Contact{}).
+			Where(&messengertypes.Contact{PublicKey: contactPK}).
+			Updates(map[string]interface{}{
+				"state":              contact.StateBeforeBlock,
+				"state_before_block": messengertypes.Contact_Undefined,
+			}).
+			Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		updated = true
+
+		if contact.StateBeforeBlock == messengertypes.Contact_Discarded {
+			return nil
+		}
+
+		_, err = tx.setConversationArchived(contact.GetConversationPublicKey(), false)
+		return err
+	}); err != nil {
+		return nil, false, err
+	}
+
+	contact, err := d.getContactByPK(contactPK)
+	return contact, updated, err
+}
+
 func (d *dbWrapper) markInteractionAsAcknowledged(cid string) (*messengertypes.Interaction, error) {
 	if cid == "" {
 		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("an interaction cid is required"))
@@ -677,11 +932,33 @@ func (d *dbWrapper) getAcknowledgementsCIDsForInteraction(cid string) ([]string,
 	return cids, nil
 }
 
+func (d *dbWrapper) getInteractionsTargeting(cid string, interactionType messengertypes.AppMessage_Type) ([]*messengertypes.Interaction, error) {
+	if cid == "" {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("an interaction cid is required"))
+	}
+
+	interactions := []*messengertypes.Interaction(nil)
+
+	if err := d.db.
+		Where(&messengertypes.Interaction{Type: interactionType, TargetCID: cid}).
+		Order("sent_date asc").
+		Find(&interactions).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return interactions, nil
+}
+
 func (d *dbWrapper) deleteInteractions(cids []string) error {
 	if len(cids) == 0 {
 		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("a list of cids is required"))
 	}
 
+	if err := d.unindexInteractions(cids); err != nil {
+		return err
+	}
+
 	return d.db.Model(&messengertypes.Interaction{}).Delete(&messengertypes.Interaction{}, &cids).Error
 }
 
@@ -713,6 +990,12 @@ func (d *dbWrapper) getDBInfo() (*messengertypes.SystemInfo_DB, error) {
 	infos.ConversationReplicationInfo, err = d.dbModelRowsCount(messengertypes.ConversationReplicationInfo{})
 	errs = multierr.Append(errs, err)
 
+	infos.Reactions, err = d.dbModelRowsCount(messengertypes.Reaction{})
+	errs = multierr.Append(errs, err)
+
+	infos.InteractionReceipts, err = d.dbModelRowsCount(messengertypes.InteractionReceipt{})
+	errs = multierr.Append(errs, err)
+
 	return infos, errs
 }
 
@@ -782,10 +1065,20 @@ func (d *dbWrapper) addInteraction(rawInte messengertypes.Interaction) (*messeng
 	_, err := d.getInteractionByCID(rawInte.CID)
 	isNew := false
 	if err == gorm.ErrRecordNotFound {
+		if rawInte.ExpirationDate == 0 {
+			if rawInte.ExpirationDate, err = d.getInteractionExpirationDate(&rawInte); err != nil {
+				return nil, false, err
+			}
+		}
+
 		if err := d.db.Create(&rawInte).Error; err != nil {
 			return nil, true, err
 		}
 		isNew = true
+
+		if err := d.indexInteractions([]string{rawInte.CID}); err != nil {
+			return nil, true, err
+		}
 	} else if err != nil {
 		return nil, false, err
 	}
@@ -794,6 +1087,143 @@ func (d *dbWrapper) addInteraction(rawInte messengertypes.Interaction) (*messeng
 	return i, isNew, err
 }
 
+// getInteractionExpirationDate returns the expiration date of an interaction according to the retention
+// setting of its conversation, interactions sent before the setting was applied don't expire
+func (d *dbWrapper) getInteractionExpirationDate(i *messengertypes.Interaction) (int64, error) {
+	if i.GetConversationPublicKey() == "" || i.GetSentDate() == 0 {
+		return 0, nil
+	}
+
+	conv := &messengertypes.Conversation{}
+	err := d.db.
+		Model(&messengertypes.Conversation{}).
+		Select("retention_period", "retention_date").
+		Where("public_key = ?", i.GetConversationPublicKey()).
+		Take(conv).Error
+
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return 0, nil
+	case err != nil:
+		return 0, errcode.ErrDBRead.Wrap(err)
+	case conv.RetentionPeriod <= 0 || i.GetSentDate() < conv.RetentionDate:
+		return 0, nil
+	}
+
+	return i.GetSentDate() + conv.RetentionPeriod, nil
+}
+
+// getNextInteractionExpirationDate returns the earliest expiration date of the stored interactions, zero if none expires
+func (d *dbWrapper) getNextInteractionExpirationDate() (int64, error) {
+	dates := []int64(nil)
+
+	if err := d.db.
+		Model(&messengertypes.Interaction{}).
+		Where("expiration_date > 0").
+		Order("expiration_date ASC").
+		Limit(1).
+		Pluck("expiration_date", &dates).Error; err != nil {
+		return 0, errcode.ErrDBRead.Wrap(err)
+	}
+
+	if len(dates) == 0 {
+		return 0, nil
+	}
+
+	return dates[0], nil
+}
+
+// deleteExpiredInteractions deletes the interactions expired at the given date along with their medias,
+// reactions, edits and receipts. The medias also attached to a remaining interaction are kept.
+// It returns the cids of the deleted interactions and the deleted medias.
+func (d *dbWrapper) deleteExpiredInteractions(now int64) ([]string, []*messengertypes.Media, error) {
+	var (
+		cids   []string
+		medias []*messengertypes.Media
+	)
+
+	if err := d.tx(func(tx *dbWrapper) error {
+		if err := tx.db.
+			Model(&messengertypes.Interaction{}).
+			Where("expiration_date > 0 AND expiration_date <= ?", now).
+			Pluck("cid", &cids).Error; err != nil {
+			return errcode.ErrDBRead.Wrap(err)
+		}
+
+		if len(cids) == 0 {
+			return nil
+		}
+
+		// the medias prepared by the current account aren't attached to the interaction they were sent with
+		mediaCIDs := []string(nil)
+		if err := tx.db.
+			Model(&messengertypes.MediaReference{}).
+			Where("interaction_cid IN ?", cids).
+			Pluck("media_cid", &mediaCIDs).Error; err != nil {
+			return errcode.ErrDBRead.Wrap(err)
+		}
+
+		if err := tx.unindexInteractions(cids); err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{&messengertypes.MediaReference{}, &messengertypes.InteractionReceipt{}} {
+			if err := tx.db.Where("interaction_cid IN ?", cids).Delete(model).Error; err != nil {
+				return errcode.ErrDBWrite.Wrap(err)
+			}
+		}
+
+		// the medias still attached to another interaction are kept and moved to one of them
+		stillReferenced := tx.db.Model(&messengertypes.MediaReference{}).Select("media_cid")
+
+		if err := tx.db.
+			Where("cid IN ? OR interaction_cid IN ?", mediaCIDs, cids).
+			Where("cid NOT IN (?)", stillReferenced).
+			Find(&medias).Error; err != nil {
+			return errcode.ErrDBRead.Wrap(err)
+		}
+
+		if err := tx.db.
+			Model(&messengertypes.Media{}).
+			Where("interaction_cid IN ?", cids).
+			Update("interaction_cid", tx.db.
+				Model(&messengertypes.MediaReference{}).
+				Select("interaction_cid").
+				Where("media_cid = media.cid").
+				Limit(1)).
+			Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		if len(medias) > 0 {
+			deleted := make([]string, len(medias))
+			for i, m := range medias {
+				deleted[i] = m.GetCID()
+			}
+
+			if err := tx.db.Where("cid IN ?", deleted).Delete(&messengertypes.Media{}).Error; err != nil {
+				return errcode.ErrDBWrite.Wrap(err)
+			}
+		}
+
+		for _, model := range []interface{}{&messengertypes.Reaction{}, &messengertypes.InteractionEdit{}} {
+			if err := tx.db.Where("target_cid IN ?", cids).Delete(model).Error; err != nil {
+				return errcode.ErrDBWrite.Wrap(err)
+			}
+		}
+
+		if err := tx.db.Where("cid IN ?", cids).Delete(&messengertypes.Interaction{}).Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return cids, medias, nil
+}
+
 func (d *dbWrapper) getReplyOptionsCIDForConversation(pk string) (string, error) {
 	if pk == "" {
 		return "", errcode.ErrInvalidInput.Wrap(fmt.Errorf("a conversation public key is required"))
@@ -887,6 +1317,7 @@ func (d *dbWrapper) addMember(memberPK, groupPK, displayName, avatarCID string,
 		ConversationPublicKey: groupPK,
 		AvatarCID:             avatarCID,
 		IsCreator:             isCreator,
+		IsAdmin:               isCreator,
 		IsMe:                  isMe,
 	}
 
@@ -919,6 +1350,46 @@ func (d *dbWrapper) addMember(memberPK, groupPK, displayName, avatarCID string,
 	return member, nil
 }
 
+// setMemberAdmin grants the admin role to an existing member of a conversation.
+// It returns true if the member has been updated.
+func (d *dbWrapper) setMemberAdmin(memberPK, convPK string) (bool, error) {
+	if memberPK == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("member public key cannot be empty"))
+	}
+	if convPK == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("conversation public key cannot be empty"))
+	}
+
+	tx := d.db.
+		Model(&messengertypes.Member{}).
+		Where("public_key = ? AND conversation_public_key = ? AND is_admin = ?", memberPK, convPK, false).
+		Update("is_admin", true)
+
+	if tx.Error != nil {
+		return false, errcode.ErrDBWrite.Wrap(tx.Error)
+	}
+
+	return tx.RowsAffected > 0, nil
+}
+
+// isMemberAdmin returns true if the member is an admin of the conversation, unknown members are not admins
+func (d *dbWrapper) isMemberAdmin(memberPK, convPK string) (bool, error) {
+	if memberPK == "" || convPK == "" {
+		return false, nil
+	}
+
+	var count int64
+	if err := d.db.
+		Model(&messengertypes.Member{}).
+		Where("public_key = ? AND conversation_public_key = ? AND is_admin = ?", memberPK, convPK, true).
+		Count(&count).
+		Error; err != nil {
+		return false, errcode.ErrDBRead.Wrap(err)
+	}
+
+	return count > 0, nil
+}
+
 func (d *dbWrapper) upsertMember(memberPK, groupPK string, m messengertypes.Member) (*messengertypes.Member, bool, error) {
 	if memberPK == "" {
 		return nil, false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("member public key cannot be empty"))
@@ -1058,6 +1529,18 @@ func (d *dbWrapper) addServiceToken(accountPK string, serviceToken *protocoltype
 	return nil
 }
 
+func (d *dbWrapper) deleteServiceToken(tokenID string) error {
+	if tokenID == "" {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("a token id is required"))
+	}
+
+	if err := d.db.Where("token_id = ?", tokenID).Delete(&messengertypes.ServiceToken{}).Error; err != nil {
+		return errcode.ErrDBWrite.Wrap(err)
+	}
+
+	return nil
+}
+
 func (d *dbWrapper) accountSetReplicationAutoEnable(pk string, enabled bool) error {
 	updates := map[string]interface{}{
 		"replicate_new_groups_automatically": enabled,
@@ -1077,6 +1560,25 @@ func (d *dbWrapper) accountSetReplicationAutoEnable(pk string, enabled bool) err
 	return nil
 }
 
+func (d *dbWrapper) accountSetReadReceiptsEnable(pk string, enabled bool) error {
+	updates := map[string]interface{}{
+		"send_read_receipts": enabled,
+	}
+
+	// db update
+	tx := d.db.Model(&messengertypes.Account{}).Where(&messengertypes.Account{PublicKey: pk}).Updates(updates)
+
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return errcode.ErrDBWrite.Wrap(fmt.Errorf("record not found"))
+	}
+
+	return nil
+}
+
 func (d *dbWrapper) saveConversationReplicationInfo(c messengertypes.ConversationReplicationInfo) error {
 	if c.CID == "" {
 		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("an interaction cid is required"))
@@ -1090,6 +1592,18 @@ func (d *dbWrapper) saveConversationReplicationInfo(c messengertypes.Conversatio
 	return nil
 }
 
+func (d *dbWrapper) deleteConversationReplicationInfo(conversationPK, replicationServer string) error {
+	if conversationPK == "" {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("a conversation public key is required"))
+	}
+
+	if err := d.db.Where("conversation_public_key = ? AND replication_server = ?", conversationPK, replicationServer).Delete(&messengertypes.ConversationReplicationInfo{}).Error; err != nil {
+		return errcode.ErrDBWrite.Wrap(err)
+	}
+
+	return nil
+}
+
 func (d *dbWrapper) addMedias(medias []*messengertypes.Media) ([]bool, error) {
 	if len(medias) == 0 {
 		return []bool{}, nil
@@ -1128,6 +1642,31 @@ func (d *dbWrapper) addMedias(medias []*messengertypes.Media) ([]bool, error) {
 		return nil, errcode.ErrDBWrite.Wrap(err)
 	}
 
+	// a media row is only attached to the first interaction it was received with, the other ones are referenced
+	references := []*messengertypes.MediaReference(nil)
+	for _, m := range medias {
+		if m.GetInteractionCID() != "" {
+			references = append(references, &messengertypes.MediaReference{MediaCID: m.GetCID(), InteractionCID: m.GetInteractionCID()})
+		}
+	}
+
+	if len(references) > 0 {
+		if err := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(references).Error; err != nil {
+			return nil, errcode.ErrDBWrite.Wrap(err)
+		}
+	}
+
+	interactionCIDs := []string(nil)
+	for i, m := range medias {
+		if willAdd[i] && m.GetInteractionCID() != "" {
+			interactionCIDs = append(interactionCIDs, m.GetInteractionCID())
+		}
+	}
+
+	if err := d.indexInteractions(interactionCIDs); err != nil {
+		return nil, err
+	}
+
 	return willAdd, nil
 }
 
@@ -1173,13 +1712,378 @@ func (d *dbWrapper) getMemberPKFromDevicePK(dpk string) (string, error) {
 	err := d.db.Where("public_key = ?", dpk).First(&dev).Error
 	switch err {
 	case nil:
-		if len(dev.PublicKey) == 0 {
+		if len(dev.MemberPublicKey) == 0 {
 			return "", errcode.ErrNotFound
 		}
-		return dev.PublicKey, nil
+		return dev.MemberPublicKey, nil
 	case gorm.ErrRecordNotFound:
 		return "", errcode.ErrNotFound
 	default:
 		return "", errcode.ErrDBRead.Wrap(err)
 	}
 }
+
+// upsertReaction stores the state of a reaction, the most recent state wins.
+// It returns true if the visible state of the reaction has changed.
+func (d *dbWrapper) upsertReaction(reaction messengertypes.Reaction) (bool, error) {
+	if reaction.TargetCID == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a target cid is required"))
+	}
+
+	if reaction.MemberPublicKey == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a member public key is required"))
+	}
+
+	if reaction.Emoji == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("an emoji is required"))
+	}
+
+	existing := &messengertypes.Reaction{}
+	err := d.db.First(&existing, &messengertypes.Reaction{
+		TargetCID:       reaction.TargetCID,
+		MemberPublicKey: reaction.MemberPublicKey,
+		Emoji:           reaction.Emoji,
+	}).Error
+
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		// removals are stored as well, so an older addition received later won't be applied
+		if err := d.db.Create(&reaction).Error; err != nil {
+			return false, errcode.ErrDBWrite.Wrap(err)
+		}
+
+		return reaction.State, nil
+
+	case err != nil:
+		return false, errcode.ErrDBRead.Wrap(err)
+	}
+
+	if existing.StateDate >= reaction.StateDate {
+		return false, nil
+	}
+
+	if err := d.db.
+		Model(&messengertypes.Reaction{}).
+		Where("target_cid = ? AND member_public_key = ? AND emoji = ?", reaction.TargetCID, reaction.MemberPublicKey, reaction.Emoji).
+		Updates(map[string]interface{}{
+			"state":      reaction.State,
+			"state_date": reaction.StateDate,
+		}).
+		Error; err != nil {
+		return false, errcode.ErrDBWrite.Wrap(err)
+	}
+
+	return existing.State != reaction.State, nil
+}
+
+func (d *dbWrapper) getReactionsViewsForInteractions(cids []string) (map[string][]*messengertypes.Interaction_ReactionView, error) {
+	views := map[string][]*messengertypes.Interaction_ReactionView{}
+	if len(cids) == 0 {
+		return views, nil
+	}
+
+	var counts []struct {
+		TargetCID string `gorm:"column:target_cid"`
+		Emoji     string
+		Count     uint64
+		OwnState  bool
+	}
+
+	if err := d.db.
+		Model(&messengertypes.Reaction{}).
+		Select("target_cid, emoji, COUNT(*) AS count, MAX(is_mine) AS own_state").
+		Where("state = true AND target_cid IN ?", cids).
+		Group("target_cid, emoji").
+		Order("MIN(state_date)").
+		Scan(&counts).
+		Error; err != nil {
+		return nil, err
+	}
+
+	for _, c := range counts {
+		views[c.TargetCID] = append(views[c.TargetCID], &messengertypes.Interaction_ReactionView{
+			Emoji:    c.Emoji,
+			OwnState: c.OwnState,
+			Count:    c.Count,
+		})
+	}
+
+	return views, nil
+}
+
+func (d *dbWrapper) attachReactionsToInteractions(interactions []*messengertypes.Interaction) error {
+	if len(interactions) == 0 {
+		return nil
+	}
+
+	cids := make([]string, len(interactions))
+	for i, inte := range interactions {
+		cids[i] = inte.CID
+	}
+
+	views, err := d.getReactionsViewsForInteractions(cids)
+	if err != nil {
+		return err
+	}
+
+	for _, inte := range interactions {
+		inte.Reactions = views[inte.CID]
+	}
+
+	return nil
+}
+
+// addInteractionEdit records a new version of a user message body and applies it
+// to the edited interaction if it is the most recent one.
+// It returns the edited interaction and whether it has been updated.
+func (d *dbWrapper) addInteractionEdit(target *messengertypes.Interaction, edit messengertypes.InteractionEdit) (*messengertypes.Interaction, bool, error) {
+	if target == nil || target.CID == "" {
+		return nil, false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("an edited interaction is required"))
+	}
+
+	if edit.CID == "" {
+		return nil, false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("an edit cid is required"))
+	}
+
+	edit.TargetCID = target.CID
+
+	if err := d.tx(func(tx *dbWrapper) error {
+		// keep the original version in history before its first edit
+		if target.EditedAt == 0 {
+			if err := tx.db.
+				Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&messengertypes.InteractionEdit{
+					CID:       target.CID,
+					TargetCID: target.CID,
+					Payload:   target.Payload,
+					EditDate:  target.SentDate,
+				}).
+				Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&edit).Error; err != nil {
+			return err
+		}
+
+		if edit.EditDate <= target.EditedAt {
+			return nil
+		}
+
+		if err := tx.db.
+			Model(&messengertypes.Interaction{}).
+			Where(&messengertypes.Interaction{CID: target.CID}).
+			Updates(map[string]interface{}{
+				"payload":   edit.Payload,
+				"edited_at": edit.EditDate,
+			}).
+			Error; err != nil {
+			return err
+		}
+
+		return tx.indexInteractions([]string{target.CID})
+	}); err != nil {
+		return nil, false, errcode.ErrDBWrite.Wrap(err)
+	}
+
+	if edit.EditDate <= target.EditedAt {
+		return target, false, nil
+	}
+
+	i, err := d.getInteractionByCID(target.CID)
+	if err != nil {
+		return nil, false, errcode.ErrDBRead.Wrap(err)
+	}
+
+	return i, true, nil
+}
+
+// retractInteraction keeps a tombstone of an interaction, its payload, medias, reactions and edit history are dropped
+func (d *dbWrapper) retractInteraction(cid string) error {
+	if cid == "" {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("an interaction cid is required"))
+	}
+
+	return d.tx(func(tx *dbWrapper) error {
+		res := tx.db.
+			Model(&messengertypes.Interaction{}).
+			Where(&messengertypes.Interaction{CID: cid}).
+			Updates(map[string]interface{}{
+				"retracted": true,
+				"payload":   nil,
+			})
+
+		if res.Error != nil {
+			return errcode.ErrDBWrite.Wrap(res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return errcode.ErrDBWrite.Wrap(fmt.Errorf("record not found"))
+		}
+
+		if err := tx.db.Where("interaction_cid = ?", cid).Delete(&messengertypes.Media{}).Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		if err := tx.db.Where("target_cid = ?", cid).Delete(&messengertypes.Reaction{}).Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		if err := tx.db.Where("target_cid = ?", cid).Delete(&messengertypes.InteractionEdit{}).Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		if err := tx.db.Where("interaction_cid = ?", cid).Delete(&messengertypes.InteractionReceipt{}).Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		if err := tx.db.Where("interaction_cid = ?", cid).Delete(&messengertypes.MediaReference{}).Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		return tx.unindexInteractions([]string{cid})
+	})
+}
+
+// upsertInteractionReceipt merges a receipt with the existing one, the earliest dates are kept
+// and a read interaction is considered as delivered.
+// It returns whether the stored receipt has been updated.
+func (d *dbWrapper) upsertInteractionReceipt(receipt messengertypes.InteractionReceipt) (bool, error) {
+	if receipt.InteractionCID == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("an interaction cid is required"))
+	}
+
+	if receipt.MemberPublicKey == "" {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a member public key is required"))
+	}
+
+	if receipt.DeliveredDate == 0 && receipt.ReadDate == 0 {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a delivered or read date is required"))
+	}
+
+	if receipt.DeliveredDate == 0 || (receipt.ReadDate != 0 && receipt.ReadDate < receipt.DeliveredDate) {
+		receipt.DeliveredDate = receipt.ReadDate
+	}
+
+	existing := &messengertypes.InteractionReceipt{}
+	err := d.db.First(&existing, &messengertypes.InteractionReceipt{
+		InteractionCID:  receipt.InteractionCID,
+		MemberPublicKey: receipt.MemberPublicKey,
+	}).Error
+
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		if err := d.db.Create(&receipt).Error; err != nil {
+			return false, errcode.ErrDBWrite.Wrap(err)
+		}
+
+		return true, nil
+
+	case err != nil:
+		return false, errcode.ErrDBRead.Wrap(err)
+	}
+
+	earliest := func(existing, received int64) int64 {
+		if existing == 0 || (received != 0 && received < existing) {
+			return received
+		}
+		return existing
+	}
+
+	deliveredDate := earliest(existing.DeliveredDate, receipt.DeliveredDate)
+	readDate := earliest(existing.ReadDate, receipt.ReadDate)
+
+	if deliveredDate == existing.DeliveredDate && readDate == existing.ReadDate {
+		return false, nil
+	}
+
+	if err := d.db.
+		Model(&messengertypes.InteractionReceipt{}).
+		Where("interaction_cid = ? AND member_public_key = ?", receipt.InteractionCID, receipt.MemberPublicKey).
+		Updates(map[string]interface{}{
+			"delivered_date": deliveredDate,
+			"read_date":      readDate,
+		}).
+		Error; err != nil {
+		return false, errcode.ErrDBWrite.Wrap(err)
+	}
+
+	return true, nil
+}
+
+func (d *dbWrapper) getInteractionReceipts(cid string) ([]*messengertypes.InteractionReceipt, error) {
+	if cid == "" {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("an interaction cid is required"))
+	}
+
+	receipts := []*messengertypes.InteractionReceipt(nil)
+	if err := d.db.
+		Where(&messengertypes.InteractionReceipt{InteractionCID: cid}).
+		Order("read_date, delivered_date").
+		Find(&receipts).
+		Error; err != nil {
+		return nil, errcode.ErrDBRead.Wrap(err)
+	}
+
+	return receipts, nil
+}
+
+// getCIDsToMarkAsRead returns the received user messages of a conversation that haven't been marked as read yet,
+// at most limit of them starting with the oldest ones
+func (d *dbWrapper) getCIDsToMarkAsRead(conversationPK string, limit int) ([]string, error) {
+	if conversationPK == "" {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a conversation public key is required"))
+	}
+
+	cids := []string(nil)
+	if err := d.db.
+		Model(&messengertypes.Interaction{}).
+		Where(&messengertypes.Interaction{ConversationPublicKey: conversationPK, Type: messengertypes.AppMessage_TypeUserMessage}).
+		Where("is_mine = false AND retracted = false").
+		Where("cid NOT IN (?)", d.db.
+			Model(&messengertypes.InteractionReceipt{}).
+			Select("interaction_cid").
+			Where("is_mine = true AND read_date != 0")).
+		Order("sent_date").
+		Limit(limit).
+		Pluck("cid", &cids).
+		Error; err != nil {
+		return nil, errcode.ErrDBRead.Wrap(err)
+	}
+
+	return cids, nil
+}
+
+// countInteractionRecipients returns the number of members expected to receive an interaction
+func (d *dbWrapper) countInteractionRecipients(i *messengertypes.Interaction) (int64, error) {
+	if i == nil || i.ConversationPublicKey == "" {
+		return 0, errcode.ErrInvalidInput.Wrap(fmt.Errorf("an interaction is required"))
+	}
+
+	conv, err := d.getConversationByPK(i.ConversationPublicKey)
+	if err != nil {
+		return 0, errcode.ErrDBRead.Wrap(err)
+	}
+
+	if conv.Type == messengertypes.Conversation_ContactType {
+		return 1, nil
+	}
+
+	query := d.db.
+		Model(&messengertypes.Member{}).
+		Where(&messengertypes.Member{ConversationPublicKey: i.ConversationPublicKey})
+
+	if i.IsMine {
+		query = query.Where("is_me = false")
+	} else {
+		query = query.Where("public_key != ?", i.MemberPublicKey)
+	}
+
+	count := int64(0)
+	if err := query.Count(&count).Error; err != nil {
+		return 0, errcode.ErrDBRead.Wrap(err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,285 @@
+package bertymessenger
+
+import (
+	"fmt"
+	"strings"
+
+	// nolint:staticcheck // cannot use the new protobuf API while keeping gogoproto
+	"github.com/golang/protobuf/proto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"berty.tech/berty/v2/go/pkg/errcode"
+	"berty.tech/berty/v2/go/pkg/messengertypes"
+)
+
+// searchIndexTable is an FTS5 virtual table, it is not managed by gorm and is
+// excluded from the schema comparison along with its shadow tables
+const searchIndexTable = "interactions_fts"
+
+const (
+	searchSnippetHighlightStart = "<b>"
+	searchSnippetHighlightEnd   = "</b>"
+	searchDefaultAmount         = 20
+)
+
+// isSearchIndexTable returns true for the search index and its shadow tables
+func isSearchIndexTable(name string) bool {
+	return name == searchIndexTable || strings.HasPrefix(name, searchIndexTable+"_")
+}
+
+// isSearchIndexSupported checks if sqlite has been built with FTS5, ie. with the sqlite_fts5 build tag
+func (d *dbWrapper) isSearchIndexSupported() bool {
+	supported := false
+	if err := d.db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&supported).Error; err != nil {
+		return false
+	}
+
+	return supported
+}
+
+// initSearchIndex creates the search index if needed, it is populated with the
+// existing interactions when it has just been created
+func (d *dbWrapper) initSearchIndex() error {
+	if !d.isSearchIndexSupported() {
+		if d.log != nil {
+			d.log.Warn("full-text search is unavailable, sqlite has been built without fts5")
+		}
+		return nil
+	}
+
+	count := int64(0)
+	if err := d.db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = ?", searchIndexTable).Scan(&count).Error; err != nil {
+		return errcode.ErrDBRead.Wrap(err)
+	}
+
+	if count != 0 {
+		d.searchIndex = true
+		return nil
+	}
+
+	if err := d.db.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(cid UNINDEXED, body, filenames, tokenize = 'unicode61 remove_diacritics 2')", searchIndexTable)).Error; err != nil {
+		return errcode.ErrDBWrite.Wrap(err)
+	}
+
+	d.searchIndex = true
+
+	return d.rebuildSearchIndex()
+}
+
+// rebuildSearchIndex drops the content of the search index and indexes all the user messages
+func (d *dbWrapper) rebuildSearchIndex() error {
+	if !d.searchIndex {
+		return nil
+	}
+
+	return d.tx(func(tx *dbWrapper) error {
+		if err := tx.db.Exec(fmt.Sprintf("DELETE FROM %s", searchIndexTable)).Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+
+		cids := []string(nil)
+		if err := tx.db.
+			Model(&messengertypes.Interaction{}).
+			Where(&messengertypes.Interaction{Type: messengertypes.AppMessage_TypeUserMessage}).
+			Where("retracted = false").
+			Pluck("cid", &cids).
+			Error; err != nil {
+			return errcode.ErrDBRead.Wrap(err)
+		}
+
+		return tx.indexInteractions(cids)
+	})
+}
+
+// indexInteractions updates the search index entries of the given interactions,
+// interactions that can't be searched are removed from the index
+func (d *dbWrapper) indexInteractions(cids []string) error {
+	if len(cids) == 0 || !d.searchIndex {
+		return nil
+	}
+
+	if err := d.unindexInteractions(cids); err != nil {
+		return err
+	}
+
+	interactions := []*messengertypes.Interaction(nil)
+	if err := d.db.
+		Where("cid IN ?", cids).
+		Where(&messengertypes.Interaction{Type: messengertypes.AppMessage_TypeUserMessage}).
+		Where("retracted = false").
+		Find(&interactions).
+		Error; err != nil {
+		return errcode.ErrDBRead.Wrap(err)
+	}
+
+	for _, i := range interactions {
+		// an undecodable message is not searchable but must not prevent it from being stored
+		var payload messengertypes.AppMessage_UserMessage
+		if err := proto.Unmarshal(i.GetPayload(), &payload); err != nil {
+			continue
+		}
+
+		filenames := []string(nil)
+		if err := d.db.
+			Model(&messengertypes.Media{}).
+			Where("interaction_cid = ?", i.GetCID()).
+			Pluck("filename", &filenames).
+			Error; err != nil {
+			return errcode.ErrDBRead.Wrap(err)
+		}
+
+		if payload.GetBody() == "" && len(filenames) == 0 {
+			continue
+		}
+
+		if err := d.db.Exec(
+			fmt.Sprintf("INSERT INTO %s (cid, body, filenames) VALUES (?, ?, ?)", searchIndexTable),
+			i.GetCID(), payload.GetBody(), strings.Join(filenames, " "),
+		).Error; err != nil {
+			return errcode.ErrDBWrite.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func (d *dbWrapper) unindexInteractions(cids []string) error {
+	if len(cids) == 0 || !d.searchIndex {
+		return nil
+	}
+
+	if err := d.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE cid IN ?", searchIndexTable), cids).Error; err != nil {
+		return errcode.ErrDBWrite.Wrap(err)
+	}
+
+	return nil
+}
+
+// searchQueryToMatchExpression turns user input into an FTS5 expression, each term is
+// quoted to escape the FTS5 syntax and matched as a prefix
+func searchQueryToMatchExpression(query string) string {
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		terms[i] = fmt.Sprintf(`"%s"*`, strings.ReplaceAll(term, `"`, `""`))
+	}
+
+	return strings.Join(terms, " ")
+}
+
+func (d *dbWrapper) searchInteractions(opts *messengertypes.InteractionSearch_Request) ([]*messengertypes.InteractionSearch_Result, []*messengertypes.Media, error) {
+	if opts == nil || strings.TrimSpace(opts.Query) == "" {
+		return nil, nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("a search query is required"))
+	}
+
+	if !d.searchIndex {
+		return nil, nil, errcode.ErrNotImplemented.Wrap(fmt.Errorf("full-text search is unavailable, sqlite has been built without fts5"))
+	}
+
+	amount := int(opts.Amount)
+	if amount <= 0 {
+		amount = searchDefaultAmount
+	}
+
+	query := d.db.
+		Table(searchIndexTable).
+		Select(fmt.Sprintf("%s.cid AS cid, snippet(%s, -1, ?, ?, '…', 16) AS snippet", searchIndexTable, searchIndexTable), searchSnippetHighlightStart, searchSnippetHighlightEnd).
+		Joins(fmt.Sprintf("JOIN interactions ON interactions.cid = %s.cid", searchIndexTable)).
+		Where(fmt.Sprintf("%s MATCH ?", searchIndexTable), searchQueryToMatchExpression(opts.Query)).
+		Where("interactions.retracted = false")
+
+	if opts.ConversationPK != "" {
+		query = query.Where("interactions.conversation_public_key = ?", opts.ConversationPK)
+	}
+
+	// own interactions and interactions from contacts aren't attributed to a member, see interactionMemberPK,
+	// in 1:1 conversations the member keys are the account keys
+	if opts.MemberPK != "" {
+		query = query.
+			Joins("LEFT JOIN conversations ON conversations.public_key = interactions.conversation_public_key").
+			Where(
+				"(interactions.member_public_key = ? OR "+
+					"(interactions.is_mine = true AND (conversations.account_member_public_key = ? OR (conversations.type = ? AND ? IN (SELECT public_key FROM accounts)))) OR "+
+					"(interactions.is_mine = false AND conversations.type = ? AND conversations.contact_public_key = ?))",
+				opts.MemberPK,
+				opts.MemberPK, messengertypes.Conversation_ContactType, opts.MemberPK,
+				messengertypes.Conversation_ContactType, opts.MemberPK,
+			)
+	}
+
+	if opts.SinceDate != 0 {
+		query = query.Where("interactions.sent_date >= ?", opts.SinceDate)
+	}
+
+	if opts.UntilDate != 0 {
+		query = query.Where("interactions.sent_date <= ?", opts.UntilDate)
+	}
+
+	if opts.RefCID != "" {
+		ref, err := d.getInteractionByCID(opts.RefCID)
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("unable to retrieve specified interaction: %w", err))
+		} else if err != nil {
+			return nil, nil, errcode.ErrDBRead.Wrap(fmt.Errorf("unable to retrieve specified interaction: %w", err))
+		}
+
+		query = query.Where("(interactions.sent_date, interactions.cid) < (?, ?)", ref.SentDate, ref.CID)
+	}
+
+	matches := []struct {
+		CID     string `gorm:"column:cid"`
+		Snippet string
+	}(nil)
+
+	if err := query.
+		Order("interactions.sent_date DESC, interactions.cid DESC").
+		Limit(amount).
+		Scan(&matches).
+		Error; err != nil {
+		return nil, nil, errcode.ErrDBRead.Wrap(fmt.Errorf("unable to search interactions: %w", err))
+	}
+
+	if len(matches) == 0 {
+		return nil, nil, nil
+	}
+
+	cids := make([]string, len(matches))
+	for i, m := range matches {
+		cids[i] = m.CID
+	}
+
+	interactions := []*messengertypes.Interaction(nil)
+	if err := d.db.
+		Preload(clause.Associations).
+		Find(&interactions, cids).
+		Error; err != nil {
+		return nil, nil, errcode.ErrDBRead.Wrap(fmt.Errorf("unable to fetch interactions: %w", err))
+	}
+
+	if err := d.attachReactionsToInteractions(interactions); err != nil {
+		return nil, nil, errcode.ErrDBRead.Wrap(fmt.Errorf("unable to fetch reactions: %w", err))
+	}
+
+	byCID := make(map[string]*messengertypes.Interaction, len(interactions))
+	for _, i := range interactions {
+		byCID[i.CID] = i
+	}
+
+	results := make([]*messengertypes.InteractionSearch_Result, 0, len(matches))
+	for _, m := range matches {
+		if i, ok := byCID[m.CID]; ok {
+			results = append(results, &messengertypes.InteractionSearch_Result{Interaction: i, Snippet: m.Snippet})
+		}
+	}
+
+	medias := []*messengertypes.Media(nil)
+	if err := d.db.
+		Model(&messengertypes.Media{}).
+		Where("media.interaction_cid IN (?)", cids).
+		Find(&medias).
+		Error; err != nil {
+		return nil, nil, errcode.ErrDBRead.Wrap(fmt.Errorf("unable to fetch medias: %w", err))
+	}
+
+	return results, medias, nil
+}
@@ -33,6 +33,14 @@ func keepDisplayName(db *gorm.DB, logger *zap.Logger) string {
 }
 
 func keepAutoReplicateFlag(db *gorm.DB, logger *zap.Logger) bool {
+	return keepAccountBoolField(db, "replicate_new_groups_automatically", true, logger)
+}
+
+func keepReadReceiptsFlag(db *gorm.DB, logger *zap.Logger) bool {
+	return keepAccountBoolField(db, "send_read_receipts", true, logger)
+}
+
+func keepAccountBoolField(db *gorm.DB, field string, defaultValue bool, logger *zap.Logger) bool {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -40,7 +48,7 @@ func keepAutoReplicateFlag(db *gorm.DB, logger *zap.Logger) bool {
 	result := int64(0)
 	count := int64(0)
 
-	if err := db.Table("accounts").Count(&count).Order("ROWID").Limit(1).Pluck("replicate_new_groups_automatically", &result).Error; err == nil {
+	if err := db.Table("accounts").Count(&count).Order("ROWID").Limit(1).Pluck(field, &result).Error; err == nil {
 		if count != 1 {
 			logger.Warn("expected one result", zap.Int64("count", count))
 		}
@@ -49,12 +57,12 @@ func keepAutoReplicateFlag(db *gorm.DB, logger *zap.Logger) bool {
 			return result != 0
 		}
 	} else {
-		logger.Warn("attempt at retrieving display name failed", zap.Error(err))
+		logger.Warn("attempt at retrieving field failed", zap.String("field-name", field), zap.Error(err))
 	}
 
 	logger.Warn("nothing found returning a default value")
 
-	return true
+	return defaultValue
 }
 
 func keepConversationsLocalData(db *gorm.DB, logger *zap.Logger) []*messengertypes.LocalConversationState {
@@ -105,6 +113,7 @@ func keepDatabaseLocalState(db *gorm.DB, logger *zap.Logger) *messengertypes.Loc
 		PublicKey:               keepAccountStringField(db, "public_key", logger),
 		DisplayName:             keepDisplayName(db, logger),
 		ReplicateFlag:           keepAutoReplicateFlag(db, logger),
+		ReadReceiptsFlag:        keepReadReceiptsFlag(db, logger),
 		LocalConversationsState: keepConversationsLocalData(db, logger),
 		AccountLink:             keepAccountStringField(db, "link", logger),
 	}
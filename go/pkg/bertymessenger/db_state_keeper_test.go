@@ -53,6 +53,26 @@ func Test_keepAutoReplicateFlag(t *testing.T) {
 	require.Equal(t, true, keepAutoReplicateFlag(db.db, log))
 }
 
+func Test_keepReadReceiptsFlag(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t, getInMemoryTestDBOptsNoInit)
+	defer dispose()
+
+	log := zap.NewNop()
+
+	require.Equal(t, true, keepReadReceiptsFlag(db.db, nil))
+
+	// table schema before read receipts
+	require.NoError(t, db.db.Exec("CREATE TABLE accounts (public_key text, display_name text, link text, replicate_new_groups_automatically numeric DEFAULT true,PRIMARY KEY (public_key))").Error)
+	require.NoError(t, db.db.Exec(`INSERT INTO accounts (public_key, display_name, link, replicate_new_groups_automatically) VALUES ("pk_1", "display_name_1", "http://display_name_1/", false)`).Error)
+	require.Equal(t, true, keepReadReceiptsFlag(db.db, log))
+
+	require.NoError(t, db.db.Exec("ALTER TABLE accounts ADD COLUMN send_read_receipts numeric DEFAULT true").Error)
+	require.Equal(t, true, keepReadReceiptsFlag(db.db, log))
+
+	require.NoError(t, db.db.Exec(`UPDATE accounts SET send_read_receipts = false WHERE public_key = "pk_1"`).Error)
+	require.Equal(t, false, keepReadReceiptsFlag(db.db, log))
+}
+
 func Test_keepConversationsUnreadCounts(t *testing.T) {
 	db, dispose := getInMemoryTestDB(t, getInMemoryTestDBOptsNoInit)
 	defer dispose()
@@ -6,6 +6,8 @@ import (
 	"testing"
 	"time"
 
+	// nolint:staticcheck // cannot use the new protobuf API while keeping gogoproto
+	"github.com/golang/protobuf/proto"
 	"github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -255,6 +257,120 @@ func Test_dbWrapper_addContactRequestIncomingAccepted(t *testing.T) {
 	t.Skip("complete test")
 }
 
+func Test_dbWrapper_discardContactRequest(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.discardContactRequest("")
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = db.discardContactRequest("contact_1")
+	require.Equal(t, gorm.ErrRecordNotFound, err)
+
+	_, err = db.addContactRequestIncomingReceived("contact_1", "name_1", "conversation_1")
+	require.NoError(t, err)
+
+	_, err = db.addConversationForContact("conversation_1", "contact_1")
+	require.NoError(t, err)
+
+	contact, err := db.discardContactRequest("contact_1")
+	require.NoError(t, err)
+	require.Equal(t, messengertypes.Contact_Discarded, contact.State)
+
+	conv, err := db.getConversationByPK("conversation_1")
+	require.NoError(t, err)
+	require.True(t, conv.IsArchived)
+
+	_, err = db.discardContactRequest("contact_1")
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	// a new request can be received
+	contact, err = db.addContactRequestIncomingReceived("contact_1", "name_2", "conversation_1")
+	require.NoError(t, err)
+	require.Equal(t, messengertypes.Contact_IncomingRequest, contact.State)
+	require.Equal(t, "name_2", contact.DisplayName)
+
+	conv, err = db.getConversationByPK("conversation_1")
+	require.NoError(t, err)
+	require.False(t, conv.IsArchived)
+}
+
+func Test_dbWrapper_blockContact(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, _, err := db.blockContact("")
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, _, err = db.unblockContact("")
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, _, err = db.blockContact("contact_1")
+	require.Equal(t, gorm.ErrRecordNotFound, err)
+
+	require.NoError(t, db.db.Create(&messengertypes.Contact{PublicKey: "contact_1", ConversationPublicKey: "conversation_1", State: messengertypes.Contact_Accepted}).Error)
+
+	_, err = db.addConversationForContact("conversation_1", "contact_1")
+	require.NoError(t, err)
+
+	// unblocking a contact which isn't blocked does nothing
+	_, updated, err := db.unblockContact("contact_1")
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	contact, updated, err := db.blockContact("contact_1")
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, messengertypes.Contact_Blocked, contact.State)
+	require.Equal(t, messengertypes.Contact_Accepted, contact.StateBeforeBlock)
+
+	conv, err := db.getConversationByPK("conversation_1")
+	require.NoError(t, err)
+	require.True(t, conv.IsArchived)
+
+	_, updated, err = db.blockContact("contact_1")
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	contact, updated, err = db.unblockContact("contact_1")
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, messengertypes.Contact_Accepted, contact.State)
+	require.Equal(t, messengertypes.Contact_Undefined, contact.StateBeforeBlock)
+
+	conv, err = db.getConversationByPK("conversation_1")
+	require.NoError(t, err)
+	require.False(t, conv.IsArchived)
+}
+
+func Test_dbWrapper_setConversationArchived(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	updated, err := db.setConversationArchived("", true)
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	updated, err = db.setConversationArchived("conversation_1", true)
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	_, err = db.addConversation("conversation_1")
+	require.NoError(t, err)
+
+	updated, err = db.setConversationArchived("conversation_1", true)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	updated, err = db.setConversationArchived("conversation_1", true)
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	updated, err = db.setConversationArchived("conversation_1", false)
+	require.NoError(t, err)
+	require.True(t, updated)
+}
+
 func Test_dbWrapper_addContactRequestOutgoingEnqueued(t *testing.T) {
 	var (
 		contactPK   = "contactPK1"
@@ -535,6 +651,7 @@ func Test_dbWrapper_addMember(t *testing.T) {
 	require.Equal(t, "conversation_1", member.ConversationPublicKey)
 	require.Equal(t, "Display3", member.DisplayName)
 	require.True(t, member.IsCreator)
+	require.True(t, member.IsAdmin)
 	require.False(t, member.IsMe)
 
 	member, err = db.addMember("member_4", "conversation_1", "Display4", "", true, false)
@@ -544,6 +661,7 @@ func Test_dbWrapper_addMember(t *testing.T) {
 	require.Equal(t, "conversation_1", member.ConversationPublicKey)
 	require.Equal(t, "Display4", member.DisplayName)
 	require.False(t, member.IsCreator)
+	require.False(t, member.IsAdmin)
 	require.True(t, member.IsMe)
 }
 
@@ -1044,6 +1162,14 @@ func Test_dbWrapper_getDBInfo(t *testing.T) {
 		db.db.Create(&messengertypes.ConversationReplicationInfo{CID: fmt.Sprintf("%d", i)})
 	}
 
+	for i := 0; i < 9; i++ {
+		db.db.Create(&messengertypes.Reaction{TargetCID: fmt.Sprintf("%d", i), MemberPublicKey: "member", Emoji: "+1"})
+	}
+
+	for i := 0; i < 10; i++ {
+		db.db.Create(&messengertypes.InteractionReceipt{InteractionCID: fmt.Sprintf("%d", i), MemberPublicKey: "member"})
+	}
+
 	info, err = db.getDBInfo()
 	require.NoError(t, err)
 	require.Equal(t, int64(1), info.Accounts)
@@ -1054,12 +1180,21 @@ func Test_dbWrapper_getDBInfo(t *testing.T) {
 	require.Equal(t, int64(6), info.Devices)
 	require.Equal(t, int64(7), info.ServiceTokens)
 	require.Equal(t, int64(8), info.ConversationReplicationInfo)
+	require.Equal(t, int64(9), info.Reactions)
+	require.Equal(t, int64(10), info.InteractionReceipts)
 
 	// Ensure all tables are in the debug data
 	tables := []string(nil)
 	err = db.db.Raw("SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'").Scan(&tables).Error
 	require.NoError(t, err)
-	require.Equal(t, 9, len(tables))
+
+	dataTables := []string(nil)
+	for _, table := range tables {
+		if !isSearchIndexTable(table) {
+			dataTables = append(dataTables, table)
+		}
+	}
+	require.Equal(t, 12, len(dataTables))
 }
 
 func Test_dbWrapper_getMemberByPK(t *testing.T) {
@@ -1380,6 +1515,70 @@ func Test_dbWrapper_addServiceToken(t *testing.T) {
 	require.Error(t, db.db.Model(&messengertypes.ServiceToken{}).Where(&messengertypes.ServiceToken{TokenID: tok2.TokenID(), ServiceType: "srv2"}).First(&tok).Error)
 }
 
+func Test_dbWrapper_deleteServiceToken(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	require.True(t, errcode.Is(db.deleteServiceToken(""), errcode.ErrInvalidInput))
+
+	tok1 := &protocoltypes.ServiceToken{
+		Token:             "tok1",
+		AuthenticationURL: "https://url1/",
+		SupportedServices: []*protocoltypes.ServiceTokenSupportedService{
+			{ServiceType: "srv1"},
+			{ServiceType: "srv2"},
+		},
+	}
+
+	tok2 := &protocoltypes.ServiceToken{
+		Token:             "tok2",
+		AuthenticationURL: "https://url2/",
+		SupportedServices: []*protocoltypes.ServiceTokenSupportedService{
+			{ServiceType: "srv1"},
+		},
+	}
+
+	require.NoError(t, db.addServiceToken("acc_1", tok1))
+	require.NoError(t, db.addServiceToken("acc_1", tok2))
+
+	require.NoError(t, db.deleteServiceToken(tok1.TokenID()))
+
+	// unknown tokens are ignored
+	require.NoError(t, db.deleteServiceToken("unknown"))
+
+	var toks []*messengertypes.ServiceToken
+	require.NoError(t, db.db.Find(&toks).Error)
+	require.Len(t, toks, 1)
+	require.Equal(t, tok2.TokenID(), toks[0].TokenID)
+}
+
+func Test_dbWrapper_deleteConversationReplicationInfo(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	require.True(t, errcode.Is(db.deleteConversationReplicationInfo("", "server1"), errcode.ErrInvalidInput))
+
+	for cid, info := range map[string][2]string{
+		"cid1": {"conv1", "server1"},
+		"cid2": {"conv1", "server2"},
+		"cid3": {"conv2", "server1"},
+	} {
+		require.NoError(t, db.saveConversationReplicationInfo(messengertypes.ConversationReplicationInfo{
+			CID:                   cid,
+			ConversationPublicKey: info[0],
+			ReplicationServer:     info[1],
+		}))
+	}
+
+	require.NoError(t, db.deleteConversationReplicationInfo("conv1", "server1"))
+
+	var infos []*messengertypes.ConversationReplicationInfo
+	require.NoError(t, db.db.Order("cid").Find(&infos).Error)
+	require.Len(t, infos, 2)
+	require.Equal(t, "cid2", infos[0].CID)
+	require.Equal(t, "cid3", infos[1].CID)
+}
+
 func Test_dbWrapper_getReplyOptionsCIDForConversation(t *testing.T) {
 	db, dispose := getInMemoryTestDB(t)
 	defer dispose()
@@ -1591,3 +1790,671 @@ func Test_dbWrapper_getLatestInteractionAndMediaPerConversation(t *testing.T) {
 
 	// TODO: check fetched items cids
 }
+
+func Test_dbWrapper_upsertReaction(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.upsertReaction(messengertypes.Reaction{MemberPublicKey: "member_1", Emoji: "+1", State: true})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", Emoji: "+1", State: true})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", MemberPublicKey: "member_1", State: true})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	changed, err := db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", MemberPublicKey: "member_1", Emoji: "+1", State: true, StateDate: 10})
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	// same state, more recent
+	changed, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", MemberPublicKey: "member_1", Emoji: "+1", State: true, StateDate: 11})
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// older removal is ignored
+	changed, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", MemberPublicKey: "member_1", Emoji: "+1", State: false, StateDate: 5})
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	changed, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", MemberPublicKey: "member_1", Emoji: "+1", State: false, StateDate: 12})
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	// removal received before the addition
+	changed, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", MemberPublicKey: "member_2", Emoji: "+1", State: false, StateDate: 20})
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	changed, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", MemberPublicKey: "member_2", Emoji: "+1", State: true, StateDate: 15})
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	count := int64(0)
+	require.NoError(t, db.db.Model(&messengertypes.Reaction{}).Where("state = true").Count(&count).Error)
+	require.Equal(t, int64(0), count)
+}
+
+func Test_dbWrapper_getReactionsViewsForInteractions(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	views, err := db.getReactionsViewsForInteractions(nil)
+	require.NoError(t, err)
+	require.Empty(t, views)
+
+	reactions := []messengertypes.Reaction{
+		{TargetCID: "Qm0001", MemberPublicKey: "member_1", Emoji: "+1", State: true, StateDate: 1, IsMine: true},
+		{TargetCID: "Qm0001", MemberPublicKey: "member_2", Emoji: "+1", State: true, StateDate: 2},
+		{TargetCID: "Qm0001", MemberPublicKey: "member_2", Emoji: "-1", State: true, StateDate: 3},
+		{TargetCID: "Qm0001", MemberPublicKey: "member_3", Emoji: "-1", State: false, StateDate: 4},
+		{TargetCID: "Qm0002", MemberPublicKey: "member_3", Emoji: "+1", State: true, StateDate: 5},
+	}
+	for _, r := range reactions {
+		_, err := db.upsertReaction(r)
+		require.NoError(t, err)
+	}
+
+	views, err = db.getReactionsViewsForInteractions([]string{"Qm0001", "Qm0003"})
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	require.Len(t, views["Qm0001"], 2)
+	require.Equal(t, &messengertypes.Interaction_ReactionView{Emoji: "+1", OwnState: true, Count: 2}, views["Qm0001"][0])
+	require.Equal(t, &messengertypes.Interaction_ReactionView{Emoji: "-1", OwnState: false, Count: 1}, views["Qm0001"][1])
+
+	_, _, err = db.addInteraction(messengertypes.Interaction{CID: "Qm0002"})
+	require.NoError(t, err)
+
+	i, err := db.getInteractionByCID("Qm0002")
+	require.NoError(t, err)
+	require.Len(t, i.Reactions, 1)
+	require.Equal(t, uint64(1), i.Reactions[0].Count)
+	require.False(t, i.Reactions[0].OwnState)
+}
+
+func Test_dbWrapper_updateConversationInfo(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.updateConversationInfo("", "name", "", false, 1)
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	updated, err := db.updateConversationInfo("conversation_1", "name", "", false, 1)
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	_, err = db.addConversation("conversation_1")
+	require.NoError(t, err)
+
+	updated, err = db.updateConversationInfo("conversation_1", "name_2", "avatar_2", false, 2)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	// older info is ignored
+	updated, err = db.updateConversationInfo("conversation_1", "name_1", "avatar_1", false, 1)
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	conv, err := db.getConversationByPK("conversation_1")
+	require.NoError(t, err)
+	require.Equal(t, "name_2", conv.DisplayName)
+	require.Equal(t, "avatar_2", conv.AvatarCID)
+	require.Equal(t, int64(2), conv.InfoDate)
+
+	updated, err = db.updateConversationInfo("conversation_1", "name_3", "", false, 3)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	conv, err = db.getConversationByPK("conversation_1")
+	require.NoError(t, err)
+	require.Equal(t, "name_3", conv.DisplayName)
+	require.Equal(t, "", conv.AvatarCID)
+	require.False(t, conv.InfoAdminOnly)
+
+	updated, err = db.updateConversationInfo("conversation_1", "name_3", "", true, 4)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	conv, err = db.getConversationByPK("conversation_1")
+	require.NoError(t, err)
+	require.True(t, conv.InfoAdminOnly)
+}
+
+func Test_dbWrapper_setMemberAdmin(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.setMemberAdmin("", "conversation_1")
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = db.setMemberAdmin("member_1", "")
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	// unknown members are not created
+	updated, err := db.setMemberAdmin("member_1", "conversation_1")
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	isAdmin, err := db.isMemberAdmin("member_1", "conversation_1")
+	require.NoError(t, err)
+	require.False(t, isAdmin)
+
+	_, err = db.addMember("member_1", "conversation_1", "", "", false, false)
+	require.NoError(t, err)
+	_, err = db.addMember("member_1", "conversation_2", "", "", false, false)
+	require.NoError(t, err)
+
+	updated, err = db.setMemberAdmin("member_1", "conversation_1")
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	updated, err = db.setMemberAdmin("member_1", "conversation_1")
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	isAdmin, err = db.isMemberAdmin("member_1", "conversation_1")
+	require.NoError(t, err)
+	require.True(t, isAdmin)
+
+	// the role is scoped to a conversation
+	isAdmin, err = db.isMemberAdmin("member_1", "conversation_2")
+	require.NoError(t, err)
+	require.False(t, isAdmin)
+}
+
+func Test_dbWrapper_updateConversationRetention(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.updateConversationRetention("", 1000, 1)
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = db.updateConversationRetention("conversation_1", -1, 1)
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = db.addConversation("conversation_1")
+	require.NoError(t, err)
+
+	updated, err := db.updateConversationRetention("conversation_1", 2000, 2)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	// older setting is ignored
+	updated, err = db.updateConversationRetention("conversation_1", 1000, 1)
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	conv, err := db.getConversationByPK("conversation_1")
+	require.NoError(t, err)
+	require.Equal(t, int64(2000), conv.RetentionPeriod)
+	require.Equal(t, int64(2), conv.RetentionDate)
+
+	_, _, err = db.addInteraction(messengertypes.Interaction{CID: "Qm0001", ConversationPublicKey: "conversation_1", SentDate: 10, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+
+	_, _, err = db.addInteraction(messengertypes.Interaction{CID: "Qm0002", ConversationPublicKey: "conversation_1", SentDate: 30, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+
+	expirationDate := func(cid string) int64 {
+		i, err := db.getInteractionByCID(cid)
+		require.NoError(t, err)
+		return i.ExpirationDate
+	}
+
+	require.Equal(t, int64(2010), expirationDate("Qm0001"))
+	require.Equal(t, int64(2030), expirationDate("Qm0002"))
+
+	// the stored interactions expire according to the new setting
+	updated, err = db.updateConversationRetention("conversation_1", 500, 20)
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, int64(0), expirationDate("Qm0001"))
+	require.Equal(t, int64(530), expirationDate("Qm0002"))
+
+	updated, err = db.updateConversationRetention("conversation_1", 0, 40)
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, int64(0), expirationDate("Qm0001"))
+	require.Equal(t, int64(0), expirationDate("Qm0002"))
+}
+
+func Test_dbWrapper_deleteExpiredInteractions(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.addConversation("conversation_1")
+	require.NoError(t, err)
+
+	// sent before the retention setting, doesn't expire
+	_, _, err = db.addInteraction(messengertypes.Interaction{CID: "Qm0001", ConversationPublicKey: "conversation_1", SentDate: 1, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+
+	next, err := db.getNextInteractionExpirationDate()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), next)
+
+	_, err = db.updateConversationRetention("conversation_1", 1000, 10)
+	require.NoError(t, err)
+
+	i, _, err := db.addInteraction(messengertypes.Interaction{CID: "Qm0002", ConversationPublicKey: "conversation_1", SentDate: 20, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+	require.Equal(t, int64(1020), i.ExpirationDate)
+
+	i, _, err = db.addInteraction(messengertypes.Interaction{CID: "Qm0003", ConversationPublicKey: "conversation_1", SentDate: 30, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+	require.Equal(t, int64(1030), i.ExpirationDate)
+
+	_, err = db.addMedias([]*messengertypes.Media{{CID: "EiBnLu1b0PFzPcVd_QPPfhzIs0kmzAH2g0VUfiAqvIXMLg", InteractionCID: "Qm0002"}})
+	require.NoError(t, err)
+
+	_, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0002", MemberPublicKey: "member_1", Emoji: "❤️", State: true, StateDate: 21})
+	require.NoError(t, err)
+
+	next, err = db.getNextInteractionExpirationDate()
+	require.NoError(t, err)
+	require.Equal(t, int64(1020), next)
+
+	cids, medias, err := db.deleteExpiredInteractions(1019)
+	require.NoError(t, err)
+	require.Empty(t, cids)
+	require.Empty(t, medias)
+
+	cids, medias, err = db.deleteExpiredInteractions(1020)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0002"}, cids)
+	require.Len(t, medias, 1)
+	require.Equal(t, "EiBnLu1b0PFzPcVd_QPPfhzIs0kmzAH2g0VUfiAqvIXMLg", medias[0].CID)
+
+	_, err = db.getInteractionByCID("Qm0002")
+	require.Equal(t, gorm.ErrRecordNotFound, err)
+
+	count := int64(0)
+	require.NoError(t, db.db.Model(&messengertypes.Media{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+
+	require.NoError(t, db.db.Model(&messengertypes.Reaction{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+
+	next, err = db.getNextInteractionExpirationDate()
+	require.NoError(t, err)
+	require.Equal(t, int64(1030), next)
+
+	_, err = db.getInteractionByCID("Qm0001")
+	require.NoError(t, err)
+}
+
+func Test_dbWrapper_deleteExpiredInteractions_sharedMedia(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	const mediaCID = "EiBnLu1b0PFzPcVd_QPPfhzIs0kmzAH2g0VUfiAqvIXMLg"
+
+	_, err := db.addConversation("conversation_1")
+	require.NoError(t, err)
+
+	_, err = db.addConversation("conversation_2")
+	require.NoError(t, err)
+
+	_, err = db.updateConversationRetention("conversation_1", 1000, 10)
+	require.NoError(t, err)
+
+	// the same media is received in an expiring and in a persistent conversation
+	_, _, err = db.addInteraction(messengertypes.Interaction{CID: "Qm0001", ConversationPublicKey: "conversation_1", SentDate: 20, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+
+	_, err = db.addMedias([]*messengertypes.Media{{CID: mediaCID, InteractionCID: "Qm0001"}})
+	require.NoError(t, err)
+
+	_, _, err = db.addInteraction(messengertypes.Interaction{CID: "Qm0002", ConversationPublicKey: "conversation_2", SentDate: 30, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+
+	_, err = db.addMedias([]*messengertypes.Media{{CID: mediaCID, InteractionCID: "Qm0002"}})
+	require.NoError(t, err)
+
+	cids, medias, err := db.deleteExpiredInteractions(1020)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0001"}, cids)
+	require.Empty(t, medias)
+
+	// the media is kept and attached to the remaining interaction
+	remaining, err := db.getMedias([]string{mediaCID})
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	require.Equal(t, "Qm0002", remaining[0].InteractionCID)
+
+	_, err = db.updateConversationRetention("conversation_2", 1000, 10)
+	require.NoError(t, err)
+
+	cids, medias, err = db.deleteExpiredInteractions(1030)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0002"}, cids)
+	require.Len(t, medias, 1)
+	require.Equal(t, mediaCID, medias[0].CID)
+
+	count := int64(0)
+	require.NoError(t, db.db.Model(&messengertypes.Media{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+
+	require.NoError(t, db.db.Model(&messengertypes.MediaReference{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+}
+
+func Test_dbWrapper_addInteractionEdit(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, _, err := db.addInteractionEdit(nil, messengertypes.InteractionEdit{CID: "Qm0002"})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	target, _, err := db.addInteraction(messengertypes.Interaction{CID: "Qm0001", Payload: []byte("v1"), SentDate: 1, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+
+	_, _, err = db.addInteractionEdit(target, messengertypes.InteractionEdit{})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	target, updated, err := db.addInteractionEdit(target, messengertypes.InteractionEdit{CID: "Qm0003", Payload: []byte("v3"), EditDate: 3})
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, []byte("v3"), target.Payload)
+	require.Equal(t, int64(3), target.EditedAt)
+
+	// older edit is kept in history only
+	target, updated, err = db.addInteractionEdit(target, messengertypes.InteractionEdit{CID: "Qm0002", Payload: []byte("v2"), EditDate: 2})
+	require.NoError(t, err)
+	require.False(t, updated)
+	require.Equal(t, []byte("v3"), target.Payload)
+
+	edits := []*messengertypes.InteractionEdit(nil)
+	require.NoError(t, db.db.Where(&messengertypes.InteractionEdit{TargetCID: "Qm0001"}).Order("edit_date asc").Find(&edits).Error)
+	require.Len(t, edits, 3)
+	require.Equal(t, []byte("v1"), edits[0].Payload)
+	require.Equal(t, []byte("v2"), edits[1].Payload)
+	require.Equal(t, []byte("v3"), edits[2].Payload)
+}
+
+func Test_dbWrapper_getMemberPKFromDevicePK(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.getMemberPKFromDevicePK("device_1")
+	require.True(t, errcode.Is(err, errcode.ErrNotFound))
+
+	_, err = db.addDevice("device_1", "member_1")
+	require.NoError(t, err)
+
+	mpk, err := db.getMemberPKFromDevicePK("device_1")
+	require.NoError(t, err)
+	require.Equal(t, "member_1", mpk)
+}
+
+func Test_dbWrapper_retractInteraction(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	require.True(t, errcode.Is(db.retractInteraction(""), errcode.ErrInvalidInput))
+	require.True(t, errcode.Is(db.retractInteraction("Qm0001"), errcode.ErrDBWrite))
+
+	target, _, err := db.addInteraction(messengertypes.Interaction{CID: "Qm0001", Payload: []byte("v1"), SentDate: 1, Type: messengertypes.AppMessage_TypeUserMessage})
+	require.NoError(t, err)
+
+	_, err = db.addMedias([]*messengertypes.Media{{CID: "EiBnLu1b0PFzPcVd_QPPfhzIs0kmzAH2g0VUfiAqvIXMLg", InteractionCID: "Qm0001"}})
+	require.NoError(t, err)
+
+	_, err = db.upsertReaction(messengertypes.Reaction{TargetCID: "Qm0001", MemberPublicKey: "member_1", Emoji: ":+1:", State: true, StateDate: 1})
+	require.NoError(t, err)
+
+	_, _, err = db.addInteractionEdit(target, messengertypes.InteractionEdit{CID: "Qm0002", Payload: []byte("v2"), EditDate: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, db.retractInteraction("Qm0001"))
+
+	target, err = db.getInteractionByCID("Qm0001")
+	require.NoError(t, err)
+	require.True(t, target.Retracted)
+	require.Empty(t, target.Payload)
+	require.Empty(t, target.Reactions)
+
+	count := int64(0)
+	require.NoError(t, db.db.Model(&messengertypes.Media{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+	require.NoError(t, db.db.Model(&messengertypes.Reaction{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+	require.NoError(t, db.db.Model(&messengertypes.InteractionEdit{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+
+	interactions, err := db.getAllInteractions()
+	require.NoError(t, err)
+	require.Len(t, interactions, 0)
+}
+
+func Test_searchQueryToMatchExpression(t *testing.T) {
+	require.Equal(t, "", searchQueryToMatchExpression("  "))
+	require.Equal(t, `"hello"* "world"*`, searchQueryToMatchExpression(" hello  world "))
+	require.Equal(t, `"say"* """hi"""* "OR"*`, searchQueryToMatchExpression(`say "hi" OR`))
+}
+
+func Test_dbWrapper_searchInteractions(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	if !db.searchIndex {
+		t.Skip("sqlite has been built without fts5")
+	}
+
+	_, _, err := db.searchInteractions(&messengertypes.InteractionSearch_Request{})
+	require.True(t, errcode.Is(err, errcode.ErrMissingInput))
+
+	addMessage := func(cid, conv, member string, sentDate int64, body string) {
+		payload, err := proto.Marshal(&messengertypes.AppMessage_UserMessage{Body: body})
+		require.NoError(t, err)
+
+		_, _, err = db.addInteraction(messengertypes.Interaction{
+			CID:                   cid,
+			Type:                  messengertypes.AppMessage_TypeUserMessage,
+			ConversationPublicKey: conv,
+			MemberPublicKey:       member,
+			SentDate:              sentDate,
+			Payload:               payload,
+		})
+		require.NoError(t, err)
+	}
+
+	addMessage("Qm0001", "conv_1", "member_1", 1, "hello world")
+	addMessage("Qm0002", "conv_1", "member_2", 2, "Hello there")
+	addMessage("Qm0003", "conv_2", "member_1", 3, "goodbye world")
+	addMessage("Qm0004", "conv_2", "member_1", 4, "nothing to see")
+
+	_, err = db.addMedias([]*messengertypes.Media{{CID: "EiBnLu1b0PFzPcVd_QPPfhzIs0kmzAH2g0VUfiAqvIXMLg", InteractionCID: "Qm0004", Filename: "holidays.jpg"}})
+	require.NoError(t, err)
+
+	cidsOf := func(results []*messengertypes.InteractionSearch_Result) []string {
+		cids := []string(nil)
+		for _, r := range results {
+			cids = append(cids, r.Interaction.CID)
+		}
+		return cids
+	}
+
+	results, _, err := db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "hel"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0002", "Qm0001"}, cidsOf(results))
+	require.Equal(t, "<b>Hello</b> there", results[0].Snippet)
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "world", ConversationPK: "conv_1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0001"}, cidsOf(results))
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "hello", MemberPK: "member_2"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0002"}, cidsOf(results))
+
+	// own messages and messages from contacts are stored without a member key
+	db.db.Create(&messengertypes.Account{PublicKey: "account_1"})
+	db.db.Create(&messengertypes.Conversation{PublicKey: "conv_3", Type: messengertypes.Conversation_MultiMemberType, AccountMemberPublicKey: "member_3"})
+	db.db.Create(&messengertypes.Conversation{PublicKey: "conv_4", Type: messengertypes.Conversation_ContactType, ContactPublicKey: "contact_1"})
+
+	addOwnMessage := func(cid, conv string, sentDate int64, body string) {
+		payload, err := proto.Marshal(&messengertypes.AppMessage_UserMessage{Body: body})
+		require.NoError(t, err)
+
+		_, _, err = db.addInteraction(messengertypes.Interaction{
+			CID:                   cid,
+			Type:                  messengertypes.AppMessage_TypeUserMessage,
+			ConversationPublicKey: conv,
+			IsMine:                true,
+			SentDate:              sentDate,
+			Payload:               payload,
+		})
+		require.NoError(t, err)
+	}
+
+	addOwnMessage("Qm0005", "conv_3", 5, "hello from me")
+	addOwnMessage("Qm0006", "conv_4", 6, "hello contact")
+	addMessage("Qm0007", "conv_4", "", 7, "hello back")
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "hello", MemberPK: "member_3"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0005"}, cidsOf(results))
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "hello", MemberPK: "account_1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0006"}, cidsOf(results))
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "hello", MemberPK: "contact_1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0007"}, cidsOf(results))
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "world", SinceDate: 2, UntilDate: 3})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0003"}, cidsOf(results))
+
+	results, medias, err := db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "holidays"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0004"}, cidsOf(results))
+	require.Len(t, medias, 1)
+
+	// pagination
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "world", Amount: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0003"}, cidsOf(results))
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "world", Amount: 1, RefCID: "Qm0003"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0001"}, cidsOf(results))
+
+	// retracted messages are not searchable anymore
+	require.NoError(t, db.retractInteraction("Qm0001"))
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "world"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0003"}, cidsOf(results))
+
+	// rebuilding the index keeps the same results
+	require.NoError(t, db.rebuildSearchIndex())
+
+	results, _, err = db.searchInteractions(&messengertypes.InteractionSearch_Request{Query: "world"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0003"}, cidsOf(results))
+}
+
+func Test_dbWrapper_upsertInteractionReceipt(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.upsertInteractionReceipt(messengertypes.InteractionReceipt{MemberPublicKey: "member_1", DeliveredDate: 1})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = db.upsertInteractionReceipt(messengertypes.InteractionReceipt{InteractionCID: "Qm0001", DeliveredDate: 1})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = db.upsertInteractionReceipt(messengertypes.InteractionReceipt{InteractionCID: "Qm0001", MemberPublicKey: "member_1"})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	changed, err := db.upsertInteractionReceipt(messengertypes.InteractionReceipt{InteractionCID: "Qm0001", MemberPublicKey: "member_1", DeliveredDate: 10})
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	// a later delivery is ignored
+	changed, err = db.upsertInteractionReceipt(messengertypes.InteractionReceipt{InteractionCID: "Qm0001", MemberPublicKey: "member_1", DeliveredDate: 12})
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	changed, err = db.upsertInteractionReceipt(messengertypes.InteractionReceipt{InteractionCID: "Qm0001", MemberPublicKey: "member_1", ReadDate: 20})
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	// a read interaction is delivered
+	changed, err = db.upsertInteractionReceipt(messengertypes.InteractionReceipt{InteractionCID: "Qm0001", MemberPublicKey: "member_2", ReadDate: 15})
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	receipts, err := db.getInteractionReceipts("Qm0001")
+	require.NoError(t, err)
+	require.Len(t, receipts, 2)
+	require.Equal(t, "member_2", receipts[0].MemberPublicKey)
+	require.Equal(t, int64(15), receipts[0].DeliveredDate)
+	require.Equal(t, int64(15), receipts[0].ReadDate)
+	require.Equal(t, "member_1", receipts[1].MemberPublicKey)
+	require.Equal(t, int64(10), receipts[1].DeliveredDate)
+	require.Equal(t, int64(20), receipts[1].ReadDate)
+}
+
+func Test_dbWrapper_getCIDsToMarkAsRead(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.getCIDsToMarkAsRead("", 10)
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	db.db.Create(&messengertypes.Interaction{CID: "Qm0001", ConversationPublicKey: "conv_1", Type: messengertypes.AppMessage_TypeUserMessage, SentDate: 1})
+	db.db.Create(&messengertypes.Interaction{CID: "Qm0002", ConversationPublicKey: "conv_1", Type: messengertypes.AppMessage_TypeUserMessage, SentDate: 2, IsMine: true})
+	db.db.Create(&messengertypes.Interaction{CID: "Qm0003", ConversationPublicKey: "conv_1", Type: messengertypes.AppMessage_TypeUserMessage, SentDate: 3})
+	db.db.Create(&messengertypes.Interaction{CID: "Qm0004", ConversationPublicKey: "conv_1", Type: messengertypes.AppMessage_TypeGroupInvitation, SentDate: 4})
+	db.db.Create(&messengertypes.Interaction{CID: "Qm0005", ConversationPublicKey: "conv_2", Type: messengertypes.AppMessage_TypeUserMessage, SentDate: 5})
+
+	cids, err := db.getCIDsToMarkAsRead("conv_1", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0001", "Qm0003"}, cids)
+
+	cids, err = db.getCIDsToMarkAsRead("conv_1", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0001"}, cids)
+
+	_, err = db.upsertInteractionReceipt(messengertypes.InteractionReceipt{InteractionCID: "Qm0001", MemberPublicKey: "device_1", IsMine: true, ReadDate: 6})
+	require.NoError(t, err)
+
+	// delivered only is not enough
+	_, err = db.upsertInteractionReceipt(messengertypes.InteractionReceipt{InteractionCID: "Qm0003", MemberPublicKey: "device_1", IsMine: true, DeliveredDate: 6})
+	require.NoError(t, err)
+
+	cids, err = db.getCIDsToMarkAsRead("conv_1", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Qm0003"}, cids)
+}
+
+func Test_dbWrapper_countInteractionRecipients(t *testing.T) {
+	db, dispose := getInMemoryTestDB(t)
+	defer dispose()
+
+	_, err := db.countInteractionRecipients(nil)
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	db.db.Create(&messengertypes.Conversation{PublicKey: "conv_1", Type: messengertypes.Conversation_ContactType})
+	db.db.Create(&messengertypes.Conversation{PublicKey: "conv_2", Type: messengertypes.Conversation_MultiMemberType})
+	db.db.Create(&messengertypes.Member{PublicKey: "member_1", ConversationPublicKey: "conv_2", IsMe: true})
+	db.db.Create(&messengertypes.Member{PublicKey: "member_2", ConversationPublicKey: "conv_2"})
+	db.db.Create(&messengertypes.Member{PublicKey: "member_3", ConversationPublicKey: "conv_2"})
+
+	count, err := db.countInteractionRecipients(&messengertypes.Interaction{ConversationPublicKey: "conv_1", IsMine: true})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	count, err = db.countInteractionRecipients(&messengertypes.Interaction{ConversationPublicKey: "conv_2", IsMine: true})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = db.countInteractionRecipients(&messengertypes.Interaction{ConversationPublicKey: "conv_2", MemberPublicKey: "member_2"})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+}
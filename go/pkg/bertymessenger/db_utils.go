@@ -45,6 +45,11 @@ func dropAllTables(db *gorm.DB) error {
 	}
 
 	for _, table := range tables {
+		// shadow tables are dropped along with the search index
+		if isSearchIndexTable(table) && table != searchIndexTable {
+			continue
+		}
+
 		if err := db.Migrator().DropTable(table); err != nil {
 			return errcode.ErrDBWrite.Wrap(err)
 		}
@@ -115,6 +120,7 @@ func restoreDatabaseLocalState(db *dbWrapper, state *messengertypes.LocalDatabas
 			"display_name":                       state.DisplayName,
 			"link":                               state.AccountLink,
 			"replicate_new_groups_automatically": state.ReplicateFlag,
+			"send_read_receipts":                 state.ReadReceiptsFlag,
 		}); res.Error != nil {
 		return errcode.ErrInternal.Wrap(fmt.Errorf("unable to update account: %w", res.Error))
 	} else if res.RowsAffected == 0 {
@@ -153,6 +159,10 @@ func getDBTablesSchemas(db *gorm.DB) (map[string][]*ColumnInfo, error) {
 	}
 
 	for _, tableNameAndSQL := range tableNamesAndSQL {
+		if isSearchIndexTable(tableNameAndSQL.Name) {
+			continue
+		}
+
 		tableInfos := []*ColumnInfo{}
 		rows, err := db.Raw("PRAGMA table_info(" + tableNameAndSQL.Name + ");").Rows()
 		if err != nil {
@@ -11,7 +11,33 @@ import (
 	"berty.tech/berty/v2/go/pkg/errcode"
 )
 
-const exportLocalDBState = "messenger/local_db_state"
+const (
+	exportLocalDBState = "messenger/local_db_state"
+
+	// size of the two empty blocks ending a tar archive
+	exportTarTrailerSize = 1024
+)
+
+// tarTrailerWriter forwards all the data written to it but the tar trailer, allowing more entries to be appended
+// to the archive
+type tarTrailerWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (t *tarTrailerWriter) Write(p []byte) (int, error) {
+	t.pending = append(t.pending, p...)
+
+	if extra := len(t.pending) - exportTarTrailerSize; extra > 0 {
+		if _, err := t.w.Write(t.pending[:extra]); err != nil {
+			return 0, err
+		}
+
+		t.pending = append(t.pending[:0], t.pending[extra:]...)
+	}
+
+	return len(p), nil
+}
 
 func exportMessengerData(writer io.Writer, db *gorm.DB, logger *zap.Logger) error {
 	tw := tar.NewWriter(writer)
@@ -0,0 +1,66 @@
+package bertymessenger
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarTrailerWriter(t *testing.T) {
+	writeEntry := func(tw *tar.Writer, name string, data []byte) {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     int64(len(data)),
+			Mode:     0o600,
+		}))
+
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+
+	archive := &bytes.Buffer{}
+	tw := tar.NewWriter(archive)
+	writeEntry(tw, "first", bytes.Repeat([]byte("a"), 3000))
+	require.NoError(t, tw.Close())
+
+	out := &bytes.Buffer{}
+	trimmer := &tarTrailerWriter{w: out}
+
+	// the archive is written using small chunks, as received from the protocol
+	for data := archive.Bytes(); len(data) > 0; {
+		n := 100
+		if n > len(data) {
+			n = len(data)
+		}
+
+		written, err := trimmer.Write(data[:n])
+		require.NoError(t, err)
+		require.Equal(t, n, written)
+
+		data = data[n:]
+	}
+
+	require.Equal(t, archive.Len()-exportTarTrailerSize, out.Len())
+
+	tw = tar.NewWriter(out)
+	writeEntry(tw, "second", []byte("b"))
+	require.NoError(t, tw.Close())
+
+	names := []string(nil)
+	tr := tar.NewReader(out)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		names = append(names, header.Name)
+	}
+
+	require.Equal(t, []string{"first", "second"}, names)
+}
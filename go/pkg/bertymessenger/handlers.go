@@ -47,26 +47,40 @@ func newEventHandler(ctx context.Context, db *dbWrapper, protocolClient protocol
 
 	h.metadataHandlers = map[protocoltypes.EventType]func(gme *protocoltypes.GroupMetadataEvent) error{
 		protocoltypes.EventTypeAccountGroupJoined:                     h.accountGroupJoined,
+		protocoltypes.EventTypeAccountGroupLeft:                       h.accountGroupLeft,
 		protocoltypes.EventTypeAccountContactRequestOutgoingEnqueued:  h.accountContactRequestOutgoingEnqueued,
 		protocoltypes.EventTypeAccountContactRequestOutgoingSent:      h.accountContactRequestOutgoingSent,
 		protocoltypes.EventTypeAccountContactRequestIncomingReceived:  h.accountContactRequestIncomingReceived,
 		protocoltypes.EventTypeAccountContactRequestIncomingAccepted:  h.accountContactRequestIncomingAccepted,
+		protocoltypes.EventTypeAccountContactRequestIncomingDiscarded: h.accountContactRequestIncomingDiscarded,
+		protocoltypes.EventTypeAccountContactBlocked:                  h.accountContactBlocked,
+		protocoltypes.EventTypeAccountContactUnblocked:                h.accountContactUnblocked,
 		protocoltypes.EventTypeGroupMemberDeviceAdded:                 h.groupMemberDeviceAdded,
 		protocoltypes.EventTypeGroupMetadataPayloadSent:               h.groupMetadataPayloadSent,
 		protocoltypes.EventTypeAccountServiceTokenAdded:               h.accountServiceTokenAdded,
+		protocoltypes.EventTypeAccountServiceTokenRemoved:             h.accountServiceTokenRemoved,
 		protocoltypes.EventTypeGroupReplicating:                       h.groupReplicating,
+		protocoltypes.EventTypeGroupReplicatingStopped:                h.groupReplicatingStopped,
 		protocoltypes.EventTypeMultiMemberGroupInitialMemberAnnounced: h.multiMemberGroupInitialMemberAnnounced,
+		protocoltypes.EventTypeMultiMemberGroupAdminRoleGranted:       h.multiMemberGroupAdminRoleGranted,
+		protocoltypes.EventTypeMultiMemberGroupMemberRemoved:          h.multiMemberGroupMemberRemoved,
 	}
 
 	h.appMessageHandlers = map[messengertypes.AppMessage_Type]struct {
 		handler        func(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error)
 		isVisibleEvent bool
 	}{
-		messengertypes.AppMessage_TypeAcknowledge:     {h.handleAppMessageAcknowledge, false},
-		messengertypes.AppMessage_TypeGroupInvitation: {h.handleAppMessageGroupInvitation, true},
-		messengertypes.AppMessage_TypeUserMessage:     {h.handleAppMessageUserMessage, true},
-		messengertypes.AppMessage_TypeSetUserInfo:     {h.handleAppMessageSetUserInfo, false},
-		messengertypes.AppMessage_TypeReplyOptions:    {h.handleAppMessageReplyOptions, true},
+		messengertypes.AppMessage_TypeAcknowledge:        {h.handleAppMessageAcknowledge, false},
+		messengertypes.AppMessage_TypeGroupInvitation:    {h.handleAppMessageGroupInvitation, true},
+		messengertypes.AppMessage_TypeUserMessage:        {h.handleAppMessageUserMessage, true},
+		messengertypes.AppMessage_TypeSetUserInfo:        {h.handleAppMessageSetUserInfo, false},
+		messengertypes.AppMessage_TypeReplyOptions:       {h.handleAppMessageReplyOptions, true},
+		messengertypes.AppMessage_TypeUserReaction:       {h.handleAppMessageUserReaction, false},
+		messengertypes.AppMessage_TypeSetGroupInfo:       {h.handleAppMessageSetGroupInfo, true},
+		messengertypes.AppMessage_TypeEditUserMessage:    {h.handleAppMessageEditUserMessage, false},
+		messengertypes.AppMessage_TypeRetractUserMessage: {h.handleAppMessageRetractUserMessage, false},
+		messengertypes.AppMessage_TypeReadReceipt:        {h.handleAppMessageReadReceipt, false},
+		messengertypes.AppMessage_TypeSetRetention:       {h.handleAppMessageSetRetention, true},
 	}
 
 	return h
@@ -109,7 +123,7 @@ func (h *eventHandler) handleAppMessage(gpk string, gme *protocoltypes.GroupMess
 	var mediasAdded []bool
 
 	// start a transaction
-	var isNew bool
+	var isNew, isBlocked bool
 	if err := h.db.tx(func(tx *dbWrapper) error {
 		if mediasAdded, err = tx.addMedias(medias); err != nil {
 			return err
@@ -119,6 +133,10 @@ func (h *eventHandler) handleAppMessage(gpk string, gme *protocoltypes.GroupMess
 			return err
 		}
 
+		if isBlocked, err = h.interactionFromBlockedContact(tx, i); err != nil {
+			return err
+		}
+
 		if err := h.interactionConsumeAck(tx, i); err != nil {
 			return err
 		}
@@ -139,7 +157,8 @@ func (h *eventHandler) handleAppMessage(gpk string, gme *protocoltypes.GroupMess
 		return err
 	}
 
-	if handler.isVisibleEvent && isNew {
+	// interactions from blocked contacts are kept, but they aren't counted as unread
+	if handler.isVisibleEvent && isNew && !isBlocked {
 		if err := h.dispatchVisibleInteraction(i); err != nil {
 			h.logger.Error("unable to dispatch notification for interaction", zap.String("cid", i.CID), zap.Error(err))
 		}
@@ -186,6 +205,31 @@ func (h *eventHandler) accountServiceTokenAdded(gme *protocoltypes.GroupMetadata
 	return nil
 }
 
+func (h *eventHandler) accountServiceTokenRemoved(gme *protocoltypes.GroupMetadataEvent) error {
+	var ev protocoltypes.AccountServiceTokenRemoved
+	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if err := h.db.deleteServiceToken(ev.TokenID); err != nil {
+		return err
+	}
+
+	// dispatch event
+	if h.svc != nil {
+		acc, err := h.db.getAccount()
+		if err != nil {
+			return errcode.ErrDBRead.Wrap(err)
+		}
+
+		if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeAccountUpdated, &messengertypes.StreamEvent_AccountUpdated{Account: acc}, false); err != nil {
+			return errcode.TODO.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
 func (h *eventHandler) groupReplicating(gme *protocoltypes.GroupMetadataEvent) error {
 	var ev protocoltypes.GroupReplicating
 	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
@@ -222,6 +266,31 @@ func (h *eventHandler) groupReplicating(gme *protocoltypes.GroupMetadataEvent) e
 	return nil
 }
 
+func (h *eventHandler) groupReplicatingStopped(gme *protocoltypes.GroupMetadataEvent) error {
+	var ev protocoltypes.GroupReplicatingStopped
+	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	convPK := b64EncodeBytes(gme.EventContext.GroupPK)
+
+	if err := h.db.deleteConversationReplicationInfo(convPK, ev.ReplicationServer); err != nil {
+		return err
+	}
+
+	if h.svc == nil {
+		return nil
+	}
+
+	if conv, err := h.db.getConversationByPK(convPK); err != nil {
+		h.logger.Warn("unknown conversation", zap.String("conversation-pk", convPK))
+	} else if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeConversationUpdated, &messengertypes.StreamEvent_ConversationUpdated{Conversation: conv}, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (h *eventHandler) groupMetadataPayloadSent(gme *protocoltypes.GroupMetadataEvent) error {
 	var appMetadata protocoltypes.AppMetadata
 	if err := proto.Unmarshal(gme.GetEvent(), &appMetadata); err != nil {
@@ -258,6 +327,11 @@ func (h *eventHandler) accountGroupJoined(gme *protocoltypes.GroupMetadataEvent)
 	switch {
 	case errcode.Is(err, errcode.ErrDBEntryAlreadyExists):
 		h.logger.Info("conversation already in db")
+
+		// the group might have been left previously
+		if err := h.dispatchConversationArchived(groupPK, false); err != nil {
+			return err
+		}
 	case err != nil:
 		return errcode.ErrDBAddConversation.Wrap(err)
 	default:
@@ -288,6 +362,48 @@ func (h *eventHandler) accountGroupJoined(gme *protocoltypes.GroupMetadataEvent)
 	return nil
 }
 
+func (h *eventHandler) accountGroupLeft(gme *protocoltypes.GroupMetadataEvent) error {
+	var ev protocoltypes.AccountGroupLeft
+	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
+		return err
+	}
+
+	if len(ev.GetGroupPK()) == 0 {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("group pk is empty"))
+	}
+
+	return h.dispatchConversationArchived(b64EncodeBytes(ev.GetGroupPK()), true)
+}
+
+// dispatchConversationArchived archives or unarchives a conversation and dispatches the change
+func (h *eventHandler) dispatchConversationArchived(pk string, archived bool) error {
+	updated, err := h.db.setConversationArchived(pk, archived)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return nil
+	}
+
+	return h.dispatchConversationUpdated(pk)
+}
+
+func (h *eventHandler) dispatchConversationUpdated(pk string) error {
+	if h.svc == nil || pk == "" {
+		return nil
+	}
+
+	conversation, err := h.db.getConversationByPK(pk)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeConversationUpdated, &messengertypes.StreamEvent_ConversationUpdated{Conversation: conversation}, false)
+}
+
 func (h *eventHandler) accountContactRequestOutgoingEnqueued(gme *protocoltypes.GroupMetadataEvent) error {
 	var ev protocoltypes.AccountContactRequestEnqueued
 	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
@@ -506,6 +622,82 @@ func (h *eventHandler) accountContactRequestIncomingAccepted(gme *protocoltypes.
 	return nil
 }
 
+func (h *eventHandler) accountContactRequestIncomingDiscarded(gme *protocoltypes.GroupMetadataEvent) error {
+	var ev protocoltypes.AccountContactRequestDiscarded
+	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
+		return err
+	}
+	if len(ev.GetContactPK()) == 0 {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("contact pk is empty"))
+	}
+	contactPK := b64EncodeBytes(ev.GetContactPK())
+
+	contact, err := h.db.discardContactRequest(contactPK)
+	if err == gorm.ErrRecordNotFound {
+		h.logger.Warn("discarded contact request not found", zap.String("contact-pk", contactPK))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return h.dispatchContactUpdated(contact)
+}
+
+func (h *eventHandler) accountContactBlocked(gme *protocoltypes.GroupMetadataEvent) error {
+	var ev protocoltypes.AccountContactBlocked
+	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
+		return err
+	}
+	if len(ev.GetContactPK()) == 0 {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("contact pk is empty"))
+	}
+	contactPK := b64EncodeBytes(ev.GetContactPK())
+
+	contact, updated, err := h.db.blockContact(contactPK)
+	if err == gorm.ErrRecordNotFound {
+		h.logger.Warn("blocked contact not found", zap.String("contact-pk", contactPK))
+		return nil
+	} else if err != nil || !updated {
+		return err
+	}
+
+	return h.dispatchContactUpdated(contact)
+}
+
+func (h *eventHandler) accountContactUnblocked(gme *protocoltypes.GroupMetadataEvent) error {
+	var ev protocoltypes.AccountContactUnblocked
+	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
+		return err
+	}
+	if len(ev.GetContactPK()) == 0 {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("contact pk is empty"))
+	}
+	contactPK := b64EncodeBytes(ev.GetContactPK())
+
+	contact, updated, err := h.db.unblockContact(contactPK)
+	if err == gorm.ErrRecordNotFound {
+		h.logger.Warn("unblocked contact not found", zap.String("contact-pk", contactPK))
+		return nil
+	} else if err != nil || !updated {
+		return err
+	}
+
+	return h.dispatchContactUpdated(contact)
+}
+
+// dispatchContactUpdated dispatches a contact and its conversation
+func (h *eventHandler) dispatchContactUpdated(contact *messengertypes.Contact) error {
+	if h.svc == nil {
+		return nil
+	}
+
+	if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeContactUpdated, &messengertypes.StreamEvent_ContactUpdated{Contact: contact}, false); err != nil {
+		return err
+	}
+
+	return h.dispatchConversationUpdated(contact.GetConversationPublicKey())
+}
+
 func (h *eventHandler) contactRequestAccepted(contact *messengertypes.Contact, memberPK []byte) error {
 	// someone you invited just accepted the invitation
 	// update contact
@@ -582,6 +774,7 @@ func (h *eventHandler) multiMemberGroupInitialMemberAnnounced(gme *protocoltypes
 			}
 		} else {
 			member.IsCreator = true
+			member.IsAdmin = true
 			if err := tx.db.Save(member).Error; err != nil {
 				return errcode.ErrDBWrite.Wrap(err)
 			}
@@ -612,6 +805,162 @@ func (h *eventHandler) multiMemberGroupInitialMemberAnnounced(gme *protocoltypes
 	return nil
 }
 
+func (h *eventHandler) multiMemberGroupAdminRoleGranted(gme *protocoltypes.GroupMetadataEvent) error {
+	var ev protocoltypes.MultiMemberGrantAdminRole
+	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
+		return err
+	}
+
+	mpkb := ev.GetGranteeMemberPK()
+	mpk := b64EncodeBytes(mpkb)
+	dpk := b64EncodeBytes(ev.GetDevicePK())
+	gpkb := gme.GetEventContext().GetGroupPK()
+	gpk := b64EncodeBytes(gpkb)
+
+	updated := false
+	if err := h.db.tx(func(tx *dbWrapper) error {
+		// only grants issued by an admin are applied
+		granterPK, err := tx.getMemberPKFromDevicePK(dpk)
+		if err != nil {
+			return err
+		}
+
+		isAdmin, err := tx.isMemberAdmin(granterPK, gpk)
+		if err != nil {
+			return err
+		}
+
+		if !isAdmin {
+			return errcode.ErrInvalidInput.Wrap(fmt.Errorf("admin role granted by a non admin member"))
+		}
+
+		// create the member if its device has not been seen yet
+		if _, err := tx.getMemberByPK(mpk, gpk); err == gorm.ErrRecordNotFound {
+			gi, err := h.protocolClient.GroupInfo(h.ctx, &protocoltypes.GroupInfo_Request{GroupPK: gpkb})
+			if err != nil {
+				return errcode.ErrGroupInfo.Wrap(err)
+			}
+			isMe := bytes.Equal(gi.GetMemberPK(), mpkb)
+
+			if _, err := tx.addMember(mpk, gpk, "", "", isMe, false); err != nil {
+				return errcode.ErrDBWrite.Wrap(err)
+			}
+		} else if err != nil {
+			return errcode.ErrDBRead.Wrap(err)
+		}
+
+		updated, err = tx.setMemberAdmin(mpk, gpk)
+		return err
+	}); err != nil {
+		if errcode.Is(err, errcode.ErrNotFound) || errcode.Is(err, errcode.ErrInvalidInput) {
+			h.logger.Warn("ignoring admin role grant", zap.String("member", mpk), zap.String("conv", gpk), zap.Error(err))
+			return nil
+		}
+		return err
+	}
+
+	if !updated || h.svc == nil {
+		return nil
+	}
+
+	member, err := h.db.getMemberByPK(mpk, gpk)
+	if err != nil {
+		return errcode.ErrDBRead.Wrap(err)
+	}
+
+	if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeMemberUpdated, &messengertypes.StreamEvent_MemberUpdated{Member: member}, false); err != nil {
+		return err
+	}
+
+	h.logger.Info("dispatched member admin role update", zap.String("member", mpk), zap.String("conv", gpk))
+
+	return nil
+}
+
+// multiMemberGroupMemberRemoved builds a member removed interaction from the protocol event, the conversation is
+// archived if the current member has been removed
+func (h *eventHandler) multiMemberGroupMemberRemoved(gme *protocoltypes.GroupMetadataEvent) error {
+	var ev protocoltypes.MultiMemberRemoveMember
+	if err := proto.Unmarshal(gme.GetEvent(), &ev); err != nil {
+		return err
+	}
+
+	mpkb := ev.GetRemovedMemberPK()
+	mpk := b64EncodeBytes(mpkb)
+	gpkb := gme.GetEventContext().GetGroupPK()
+	gpk := b64EncodeBytes(gpkb)
+
+	// only removals issued by an admin are applied
+	removerPK, err := h.db.getMemberPKFromDevicePK(b64EncodeBytes(ev.GetDevicePK()))
+	if errcode.Is(err, errcode.ErrNotFound) {
+		h.logger.Warn("ignoring member removal from an unknown device", zap.String("member", mpk), zap.String("conv", gpk))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	isAdmin, err := h.db.isMemberAdmin(removerPK, gpk)
+	if err != nil {
+		return err
+	}
+
+	if !isAdmin {
+		h.logger.Warn("ignoring member removal issued by a non admin member", zap.String("member", mpk), zap.String("conv", gpk))
+		return nil
+	}
+
+	// the interaction is built from the event itself, member removals sent as app messages are ignored
+	payload, err := proto.Marshal(&messengertypes.AppMessage_MemberRemoved{MemberPublicKey: mpk})
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	cid, err := ipfscid.Cast(gme.GetEventContext().GetID())
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	gi, err := h.protocolClient.GroupInfo(h.ctx, &protocoltypes.GroupInfo_Request{GroupPK: gpkb})
+	if err != nil {
+		return errcode.ErrGroupInfo.Wrap(err)
+	}
+
+	i := &messengertypes.Interaction{
+		CID:                   cid.String(),
+		Type:                  messengertypes.AppMessage_TypeMemberRemoved,
+		Payload:               payload,
+		IsMine:                bytes.Equal(gi.GetDevicePK(), ev.GetDevicePK()),
+		ConversationPublicKey: gpk,
+		SentDate:              ev.GetRemovedAt(),
+		DevicePublicKey:       b64EncodeBytes(ev.GetDevicePK()),
+		MemberPublicKey:       removerPK,
+	}
+
+	var isNew bool
+	if err := h.db.tx(func(tx *dbWrapper) error {
+		if err := h.interactionFetchRelations(tx, i); err != nil {
+			return err
+		}
+
+		i, isNew, err = h.addMemberRemovedInteraction(tx, i, mpk)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if isNew {
+		if err := h.dispatchVisibleInteraction(i); err != nil {
+			h.logger.Error("unable to dispatch notification for interaction", zap.String("cid", i.CID), zap.Error(err))
+		}
+	}
+
+	if !bytes.Equal(gi.GetMemberPK(), mpkb) {
+		return nil
+	}
+
+	return h.dispatchConversationArchived(gpk, true)
+}
+
 // groupMemberDeviceAdded is called at different moments
 // * on AccountGroup when you add a new device to your group
 // * on ContactGroup when you or your contact add a new device
@@ -732,6 +1081,26 @@ func (h *eventHandler) groupMemberDeviceAdded(gme *protocoltypes.GroupMetadataEv
 
 func (h *eventHandler) handleAppMessageAcknowledge(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error) {
 	payload := amPayload.(*messengertypes.AppMessage_Acknowledge)
+
+	if payload.GetTarget() != "" {
+		// the receipts of the different devices of a member are merged
+		memberPK, err := h.interactionMemberPK(i)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if memberPK == "" {
+			h.logger.Warn("ignoring receipt from an unknown device", zap.String("device", i.GetDevicePublicKey()), zap.String("cid", i.GetCID()))
+		} else if _, err := tx.upsertInteractionReceipt(messengertypes.InteractionReceipt{
+			InteractionCID:  payload.GetTarget(),
+			MemberPublicKey: memberPK,
+			IsMine:          i.GetIsMine(),
+			DeliveredDate:   receiptDate(i),
+		}); err != nil {
+			return nil, false, err
+		}
+	}
+
 	target, err := tx.markInteractionAsAcknowledged(payload.Target)
 
 	switch {
@@ -774,21 +1143,45 @@ func (h *eventHandler) handleAppMessageGroupInvitation(tx *dbWrapper, i *messeng
 	return i, isNew, err
 }
 
-func (h *eventHandler) handleAppMessageUserMessage(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error) {
+func (h *eventHandler) handleAppMessageUserMessage(tx *dbWrapper, i *messengertypes.Interaction, _ proto.Message) (*messengertypes.Interaction, bool, error) {
 	i, isNew, err := tx.addInteraction(*i)
 	if err != nil {
 		return nil, isNew, err
 	}
 
-	if h.svc == nil {
-		return i, isNew, nil
-	}
+	if isNew {
+		if i, err = h.interactionConsumeEdits(tx, i); err != nil {
+			return nil, isNew, err
+		}
 
-	if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionUpdated, &messengertypes.StreamEvent_InteractionUpdated{Interaction: i}, isNew); err != nil {
+		if i, err = h.interactionConsumeRetractions(tx, i); err != nil {
+			return nil, isNew, err
+		}
+	}
+
+	// the message has been retracted before being received
+	if i.GetRetracted() {
+		return i, false, nil
+	}
+
+	// fetch contact from db
+	var contact *messengertypes.Contact
+	if i.GetConversation().GetType() == messengertypes.Conversation_ContactType {
+		if contact, err = tx.getContactByPK(i.Conversation.ContactPublicKey); err != nil {
+			h.logger.Warn("1to1 message contact not found", zap.String("public-key", i.Conversation.ContactPublicKey), zap.Error(err))
+		}
+	}
+
+	if h.svc == nil {
+		return i, isNew, nil
+	}
+
+	if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionUpdated, &messengertypes.StreamEvent_InteractionUpdated{Interaction: i}, isNew); err != nil {
 		return nil, isNew, err
 	}
 
-	if i.IsMine || h.replay || !isNew {
+	// messages from blocked contacts are neither acknowledged nor notified, see handleAppMessage for the unread count
+	if i.IsMine || h.replay || !isNew || contact.GetState() == messengertypes.Contact_Blocked {
 		return i, isNew, nil
 	}
 
@@ -804,15 +1197,11 @@ func (h *eventHandler) handleAppMessageUserMessage(tx *dbWrapper, i *messengerty
 		return i, isNew, nil
 	}
 
-	// fetch contact from db
-	var contact *messengertypes.Contact
-	if i.Conversation.Type == messengertypes.Conversation_ContactType {
-		if contact, err = tx.getContactByPK(i.Conversation.ContactPublicKey); err != nil {
-			h.logger.Warn("1to1 message contact not found", zap.String("public-key", i.Conversation.ContactPublicKey), zap.Error(err))
-		}
+	var payload messengertypes.AppMessage_UserMessage
+	if err := proto.Unmarshal(i.GetPayload(), &payload); err != nil {
+		return nil, isNew, err
 	}
 
-	payload := amPayload.(*messengertypes.AppMessage_UserMessage)
 	var title string
 	body := payload.GetBody()
 	if contact != nil && i.Conversation.Type == messengertypes.Conversation_ContactType {
@@ -925,6 +1314,135 @@ func (h *eventHandler) handleAppMessageSetUserInfo(tx *dbWrapper, i *messengerty
 	return i, false, nil
 }
 
+func (h *eventHandler) handleAppMessageSetGroupInfo(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error) {
+	payload := amPayload.(*messengertypes.AppMessage_SetGroupInfo)
+
+	if i.GetConversation().GetType() != messengertypes.Conversation_MultiMemberType {
+		h.logger.Warn("ignoring group info for a non multi-member conversation", zap.String("conv", i.GetConversationPublicKey()))
+		return i, false, nil
+	}
+
+	// admin-only groups, and changes of the admin-only setting, only accept group info from admins
+	if i.GetConversation().GetInfoAdminOnly() || payload.GetAdminOnly() {
+		memberPK, err := h.interactionMemberPK(i)
+		if err != nil {
+			return nil, false, err
+		}
+
+		isAdmin, err := tx.isMemberAdmin(memberPK, i.GetConversationPublicKey())
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !isAdmin {
+			h.logger.Warn("ignoring group info sent by a non admin member", zap.String("member", memberPK), zap.String("conv", i.GetConversationPublicKey()))
+			return i, false, nil
+		}
+	}
+
+	updated, err := tx.updateConversationInfo(i.GetConversationPublicKey(), payload.GetDisplayName(), payload.GetAvatarCID(), payload.GetAdminOnly(), i.GetSentDate())
+	if err != nil {
+		return nil, false, err
+	}
+
+	// the interaction is kept to display the change in the conversation history, even if it was superseded
+	i, isNew, err := tx.addInteraction(*i)
+	if err != nil {
+		return nil, isNew, err
+	}
+
+	if h.svc == nil {
+		return i, isNew, nil
+	}
+
+	if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionUpdated, &messengertypes.StreamEvent_InteractionUpdated{Interaction: i}, isNew); err != nil {
+		return nil, isNew, err
+	}
+
+	if updated {
+		conv, err := tx.getConversationByPK(i.GetConversationPublicKey())
+		if err != nil {
+			return nil, isNew, err
+		}
+
+		if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeConversationUpdated, &messengertypes.StreamEvent_ConversationUpdated{Conversation: conv}, false); err != nil {
+			return nil, isNew, err
+		}
+
+		h.logger.Debug("dispatched conversation info update", zap.String("name", conv.GetDisplayName()), zap.String("conv", conv.GetPublicKey()))
+	}
+
+	return i, isNew, nil
+}
+
+// addMemberRemovedInteraction stores the interaction built by multiMemberGroupMemberRemoved
+func (h *eventHandler) addMemberRemovedInteraction(tx *dbWrapper, i *messengertypes.Interaction, mpk string) (*messengertypes.Interaction, bool, error) {
+	if i.GetConversation().GetType() != messengertypes.Conversation_MultiMemberType {
+		h.logger.Warn("ignoring member removal for a non multi-member conversation", zap.String("conv", i.GetConversationPublicKey()))
+		return i, false, nil
+	}
+
+	i, isNew, err := tx.addInteraction(*i)
+	if err != nil {
+		return nil, isNew, err
+	}
+
+	if h.svc == nil {
+		return i, isNew, nil
+	}
+
+	if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionUpdated, &messengertypes.StreamEvent_InteractionUpdated{Interaction: i}, isNew); err != nil {
+		return nil, isNew, err
+	}
+
+	h.logger.Debug("dispatched member removal", zap.String("member", mpk), zap.String("conv", i.GetConversationPublicKey()))
+
+	return i, isNew, nil
+}
+
+func (h *eventHandler) handleAppMessageSetRetention(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error) {
+	payload := amPayload.(*messengertypes.AppMessage_SetRetention)
+
+	if payload.GetPeriod() < 0 {
+		h.logger.Warn("ignoring invalid retention period", zap.Int64("period", payload.GetPeriod()), zap.String("conv", i.GetConversationPublicKey()))
+		return i, false, nil
+	}
+
+	updated, err := tx.updateConversationRetention(i.GetConversationPublicKey(), payload.GetPeriod(), i.GetSentDate())
+	if err != nil {
+		return nil, false, err
+	}
+
+	// the interaction is kept to display the change in the conversation history, even if it was superseded
+	i, isNew, err := tx.addInteraction(*i)
+	if err != nil {
+		return nil, isNew, err
+	}
+
+	if h.svc == nil {
+		return i, isNew, nil
+	}
+
+	if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionUpdated, &messengertypes.StreamEvent_InteractionUpdated{Interaction: i}, isNew); err != nil {
+		return nil, isNew, err
+	}
+
+	if updated {
+		conv, err := tx.getConversationByPK(i.GetConversationPublicKey())
+		if err != nil {
+			return nil, isNew, err
+		}
+
+		if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeConversationUpdated, &messengertypes.StreamEvent_ConversationUpdated{Conversation: conv}, false); err != nil {
+			return nil, isNew, err
+		}
+
+		h.logger.Debug("dispatched conversation retention update", zap.Int64("period", conv.GetRetentionPeriod()), zap.String("conv", conv.GetPublicKey()))
+	}
+
+	return i, isNew, nil
+}
+
 func interactionFromAppMessage(h *eventHandler, gpk string, gme *protocoltypes.GroupMessageEvent, am *messengertypes.AppMessage) (*messengertypes.Interaction, error) {
 	amt := am.GetType()
 	cid, err := ipfscid.Cast(gme.GetEventContext().GetID())
@@ -1007,6 +1525,23 @@ func (h *eventHandler) interactionFetchRelations(tx *dbWrapper, i *messengertype
 	return nil
 }
 
+// interactionFromBlockedContact returns whether an interaction has been received in the conversation of a blocked contact
+func (h *eventHandler) interactionFromBlockedContact(tx *dbWrapper, i *messengertypes.Interaction) (bool, error) {
+	if i.GetIsMine() || i.GetConversation().GetType() != messengertypes.Conversation_ContactType {
+		return false, nil
+	}
+
+	contact, err := tx.getContactByPK(i.GetConversation().GetContactPublicKey())
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	return contact.GetState() == messengertypes.Contact_Blocked, nil
+}
+
 func (h *eventHandler) dispatchVisibleInteraction(i *messengertypes.Interaction) error {
 	if h.svc == nil {
 		return nil
@@ -1048,7 +1583,7 @@ func (h *eventHandler) sendAck(cid, conversationPK string) error {
 
 	// Don't send ack if message is already acked to prevent spam in multimember groups
 	// Maybe wait a few seconds before checking since we're likely to receive the message before any ack
-	amp, err := messengertypes.AppMessage_TypeAcknowledge.MarshalPayload(0, nil, &messengertypes.AppMessage_Acknowledge{Target: cid})
+	amp, err := messengertypes.AppMessage_TypeAcknowledge.MarshalPayload(timestampMs(time.Now()), nil, &messengertypes.AppMessage_Acknowledge{Target: cid})
 	if err != nil {
 		return err
 	}
@@ -1112,3 +1647,369 @@ func (h *eventHandler) handleAppMessageReplyOptions(tx *dbWrapper, i *messengert
 
 	return i, isNew, nil
 }
+
+func (h *eventHandler) handleAppMessageUserReaction(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error) {
+	payload := amPayload.(*messengertypes.AppMessage_UserReaction)
+
+	if payload.GetTarget() == "" || payload.GetEmoji() == "" {
+		h.logger.Warn("ignoring invalid reaction", zap.String("cid", i.GetCID()), zap.String("target", payload.GetTarget()))
+		return i, false, nil
+	}
+
+	// reactions are keyed by member, so the devices of a member share the same reactions
+	memberPK, err := h.interactionMemberPK(i)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if memberPK == "" {
+		h.logger.Warn("ignoring reaction from an unknown member", zap.String("cid", i.GetCID()), zap.String("device", i.GetDevicePublicKey()))
+		return i, false, nil
+	}
+
+	changed, err := tx.upsertReaction(messengertypes.Reaction{
+		TargetCID:       payload.GetTarget(),
+		MemberPublicKey: memberPK,
+		Emoji:           payload.GetEmoji(),
+		IsMine:          i.GetIsMine(),
+		State:           !payload.GetRemove(),
+		StateDate:       i.GetSentDate(),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !changed || h.svc == nil {
+		return i, false, nil
+	}
+
+	target, err := tx.getInteractionByCID(payload.GetTarget())
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		// the target is not known yet, its reactions will be attached once it is received
+		h.logger.Debug("reaction target not found", zap.String("target", payload.GetTarget()), zap.String("cid", i.GetCID()))
+		return i, false, nil
+	case err != nil:
+		return nil, false, err
+	}
+
+	if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionUpdated, &messengertypes.StreamEvent_InteractionUpdated{Interaction: target}, false); err != nil {
+		return nil, false, err
+	}
+
+	return i, false, nil
+}
+
+func (h *eventHandler) handleAppMessageEditUserMessage(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error) {
+	payload := amPayload.(*messengertypes.AppMessage_EditUserMessage)
+
+	if payload.GetTarget() == "" {
+		h.logger.Warn("ignoring edit without target", zap.String("cid", i.GetCID()))
+		return i, false, nil
+	}
+
+	target, err := tx.getInteractionByCID(payload.GetTarget())
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		h.logger.Debug("added edit in backlog", zap.String("target", payload.GetTarget()), zap.String("cid", i.GetCID()))
+		i.TargetCID = payload.GetTarget()
+		i, _, err = tx.addInteraction(*i)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return i, false, nil
+
+	case err != nil:
+		return nil, false, err
+	}
+
+	target, updated, err := h.applyUserMessageEdit(tx, target, i, payload.GetBody())
+	if err != nil {
+		return nil, false, err
+	}
+
+	if updated && h.svc != nil {
+		if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionUpdated, &messengertypes.StreamEvent_InteractionUpdated{Interaction: target}, false); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return i, false, nil
+}
+
+// applyUserMessageEdit checks that an edit has been sent by the author of the edited message before applying it
+func (h *eventHandler) applyUserMessageEdit(tx *dbWrapper, target *messengertypes.Interaction, edit *messengertypes.Interaction, body string) (*messengertypes.Interaction, bool, error) {
+	if target.GetType() != messengertypes.AppMessage_TypeUserMessage || target.GetRetracted() {
+		h.logger.Warn("ignoring edit of a non user message", zap.String("target", target.GetCID()), zap.String("cid", edit.GetCID()))
+		return target, false, nil
+	}
+
+	if !h.interactionsHaveSameAuthor(tx, target, edit) {
+		h.logger.Warn("ignoring edit from another member", zap.String("target", target.GetCID()), zap.String("cid", edit.GetCID()))
+		return target, false, nil
+	}
+
+	payload, err := proto.Marshal(&messengertypes.AppMessage_UserMessage{Body: body})
+	if err != nil {
+		return nil, false, errcode.ErrSerialization.Wrap(err)
+	}
+
+	return tx.addInteractionEdit(target, messengertypes.InteractionEdit{
+		CID:      edit.GetCID(),
+		Payload:  payload,
+		EditDate: edit.GetSentDate(),
+	})
+}
+
+// interactionsHaveSameAuthor checks whether two interactions have been sent by devices of the same member
+func (h *eventHandler) interactionsHaveSameAuthor(tx *dbWrapper, a *messengertypes.Interaction, b *messengertypes.Interaction) bool {
+	if a.GetIsMine() || b.GetIsMine() {
+		return a.GetIsMine() == b.GetIsMine()
+	}
+
+	if a.GetDevicePublicKey() == b.GetDevicePublicKey() {
+		return true
+	}
+
+	// there is only one other member in a contact conversation
+	if a.GetConversation().GetType() == messengertypes.Conversation_ContactType {
+		return true
+	}
+
+	ampk, err := tx.getMemberPKFromDevicePK(a.GetDevicePublicKey())
+	if err != nil {
+		return false
+	}
+
+	bmpk, err := tx.getMemberPKFromDevicePK(b.GetDevicePublicKey())
+	if err != nil {
+		return false
+	}
+
+	return ampk == bmpk
+}
+
+// interactionConsumeEdits applies the edits received before the edited message
+func (h *eventHandler) interactionConsumeEdits(tx *dbWrapper, i *messengertypes.Interaction) (*messengertypes.Interaction, error) {
+	err := h.interactionConsumeBacklog(tx, i.GetCID(), messengertypes.AppMessage_TypeEditUserMessage, func(edit *messengertypes.Interaction) error {
+		var payload messengertypes.AppMessage_EditUserMessage
+		if err := proto.Unmarshal(edit.GetPayload(), &payload); err != nil {
+			return errcode.ErrDeserialization.Wrap(err)
+		}
+
+		var err error
+		i, _, err = h.applyUserMessageEdit(tx, i, edit, payload.GetBody())
+		return err
+	})
+
+	return i, err
+}
+
+// interactionConsumeRetractions applies the retractions received before the retracted message
+func (h *eventHandler) interactionConsumeRetractions(tx *dbWrapper, i *messengertypes.Interaction) (*messengertypes.Interaction, error) {
+	retracted := false
+	if err := h.interactionConsumeBacklog(tx, i.GetCID(), messengertypes.AppMessage_TypeRetractUserMessage, func(retraction *messengertypes.Interaction) error {
+		applied, err := h.applyUserMessageRetraction(tx, i, retraction)
+		retracted = retracted || applied
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if !retracted {
+		return i, nil
+	}
+
+	return tx.getInteractionByCID(i.GetCID())
+}
+
+// interactionConsumeBacklog applies then removes the interactions of a given type received before the interaction they target
+func (h *eventHandler) interactionConsumeBacklog(tx *dbWrapper, cid string, backlogType messengertypes.AppMessage_Type, apply func(*messengertypes.Interaction) error) error {
+	backlog, err := tx.getInteractionsTargeting(cid, backlogType)
+	if err != nil {
+		return err
+	}
+
+	if len(backlog) == 0 {
+		return nil
+	}
+
+	cids := make([]string, len(backlog))
+	for j, elem := range backlog {
+		h.logger.Debug("found elem in backlog", zap.String("type", backlogType.String()), zap.String("target", cid), zap.String("cid", elem.GetCID()))
+		cids[j] = elem.GetCID()
+
+		if err := apply(elem); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.deleteInteractions(cids); err != nil {
+		return err
+	}
+
+	if h.svc != nil {
+		for _, c := range cids {
+			if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionDeleted, &messengertypes.StreamEvent_InteractionDeleted{CID: c}, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (h *eventHandler) handleAppMessageRetractUserMessage(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error) {
+	payload := amPayload.(*messengertypes.AppMessage_RetractUserMessage)
+
+	if payload.GetTarget() == "" {
+		h.logger.Warn("ignoring retraction without target", zap.String("cid", i.GetCID()))
+		return i, false, nil
+	}
+
+	target, err := tx.getInteractionByCID(payload.GetTarget())
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		h.logger.Debug("added retraction in backlog", zap.String("target", payload.GetTarget()), zap.String("cid", i.GetCID()))
+		i.TargetCID = payload.GetTarget()
+		i, _, err = tx.addInteraction(*i)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return i, false, nil
+
+	case err != nil:
+		return nil, false, err
+	}
+
+	retracted, err := h.applyUserMessageRetraction(tx, target, i)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if retracted && h.svc != nil {
+		if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionDeleted, &messengertypes.StreamEvent_InteractionDeleted{CID: target.GetCID()}, false); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return i, false, nil
+}
+
+// applyUserMessageRetraction checks that a retraction has been sent by the author of the retracted message before applying it
+func (h *eventHandler) applyUserMessageRetraction(tx *dbWrapper, target *messengertypes.Interaction, retraction *messengertypes.Interaction) (bool, error) {
+	if target.GetRetracted() {
+		return false, nil
+	}
+
+	if target.GetType() != messengertypes.AppMessage_TypeUserMessage {
+		h.logger.Warn("ignoring retraction of a non user message", zap.String("target", target.GetCID()), zap.String("cid", retraction.GetCID()))
+		return false, nil
+	}
+
+	if !h.interactionsHaveSameAuthor(tx, target, retraction) {
+		h.logger.Warn("ignoring retraction from another member", zap.String("target", target.GetCID()), zap.String("cid", retraction.GetCID()))
+		return false, nil
+	}
+
+	if err := tx.retractInteraction(target.GetCID()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// interactionMemberPK returns the public key of the member who authored an interaction,
+// own interactions and interactions from contacts aren't attributed to a member by
+// interactionFetchRelations. It returns an empty string if the device isn't known yet.
+func (h *eventHandler) interactionMemberPK(i *messengertypes.Interaction) (string, error) {
+	switch {
+	case i.GetMemberPublicKey() != "":
+		return i.GetMemberPublicKey(), nil
+
+	case i.GetIsMine():
+		if pk := i.GetConversation().GetAccountMemberPublicKey(); pk != "" {
+			return pk, nil
+		}
+
+		gpkb, err := b64DecodeBytes(i.GetConversationPublicKey())
+		if err != nil {
+			return "", errcode.ErrDeserialization.Wrap(err)
+		}
+
+		gi, err := h.protocolClient.GroupInfo(h.ctx, &protocoltypes.GroupInfo_Request{GroupPK: gpkb})
+		if err != nil {
+			return "", errcode.ErrGroupInfo.Wrap(err)
+		}
+
+		return b64EncodeBytes(gi.GetMemberPK()), nil
+
+	case i.GetConversation().GetType() == messengertypes.Conversation_ContactType:
+		// the contact is the only other member of the group, its member key is its account key
+		return i.GetConversation().GetContactPublicKey(), nil
+
+	default:
+		return "", nil
+	}
+}
+
+// receiptDate returns the date of a receipt, acknowledgements used to be sent without a date
+func receiptDate(i *messengertypes.Interaction) int64 {
+	if i.GetSentDate() != 0 {
+		return i.GetSentDate()
+	}
+
+	return timestampMs(time.Now())
+}
+
+func (h *eventHandler) handleAppMessageReadReceipt(tx *dbWrapper, i *messengertypes.Interaction, amPayload proto.Message) (*messengertypes.Interaction, bool, error) {
+	payload := amPayload.(*messengertypes.AppMessage_ReadReceipt)
+
+	// the receipts of the different devices of a member are merged
+	memberPK, err := h.interactionMemberPK(i)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if memberPK == "" {
+		h.logger.Warn("ignoring read receipt from an unknown device", zap.String("device", i.GetDevicePublicKey()), zap.String("cid", i.GetCID()))
+		return i, false, nil
+	}
+
+	for _, cid := range payload.GetTargets() {
+		if cid == "" {
+			continue
+		}
+
+		changed, err := tx.upsertInteractionReceipt(messengertypes.InteractionReceipt{
+			InteractionCID:  cid,
+			MemberPublicKey: memberPK,
+			IsMine:          i.GetIsMine(),
+			ReadDate:        receiptDate(i),
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !changed || h.svc == nil {
+			continue
+		}
+
+		target, err := tx.getInteractionByCID(cid)
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			// the receipt will be retrieved along with the target once it is received
+			continue
+		case err != nil:
+			return nil, false, err
+		}
+
+		if err := h.svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionUpdated, &messengertypes.StreamEvent_InteractionUpdated{Interaction: target}, false); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return i, false, nil
+}
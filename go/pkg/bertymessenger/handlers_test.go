@@ -2,6 +2,7 @@ package bertymessenger
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -136,6 +137,78 @@ func Test_eventHandler_handleAppMessageUserMessage(t *testing.T) {
 	t.Skip("TODO")
 }
 
+func Test_eventHandler_handleAppMessageReadReceipt(t *testing.T) {
+	handler, dispose := getEventHandlerForTests(t)
+	defer dispose()
+
+	db := handler.db
+	db.db.Create(&messengertypes.Conversation{PublicKey: "conv_1", Type: messengertypes.Conversation_ContactType, ContactPublicKey: "contact_1", AccountMemberPublicKey: "member_me"})
+	db.db.Create(&messengertypes.Interaction{CID: "Qm0001", ConversationPublicKey: "conv_1", Type: messengertypes.AppMessage_TypeUserMessage, IsMine: true, SentDate: 1})
+	db.db.Create(&messengertypes.Interaction{CID: "Qm0002", ConversationPublicKey: "conv_1", Type: messengertypes.AppMessage_TypeUserMessage, SentDate: 2})
+
+	readReceipt := func(devicePK string, isMine bool, date int64, targets ...string) {
+		i := &messengertypes.Interaction{
+			CID:                   fmt.Sprintf("QmReceipt%s%d", devicePK, date),
+			Type:                  messengertypes.AppMessage_TypeReadReceipt,
+			ConversationPublicKey: "conv_1",
+			DevicePublicKey:       devicePK,
+			IsMine:                isMine,
+			SentDate:              date,
+		}
+		require.NoError(t, handler.interactionFetchRelations(db, i))
+
+		_, _, err := handler.handleAppMessageReadReceipt(db, i, &messengertypes.AppMessage_ReadReceipt{Targets: targets})
+		require.NoError(t, err)
+	}
+
+	// both devices of the contact read our message, the receipts are merged under the contact
+	readReceipt("contact_device_1", false, 10, "Qm0001")
+	readReceipt("contact_device_2", false, 5, "Qm0001")
+
+	receipts, err := db.getInteractionReceipts("Qm0001")
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	require.Equal(t, "contact_1", receipts[0].MemberPublicKey)
+	require.False(t, receipts[0].IsMine)
+	require.Equal(t, int64(5), receipts[0].ReadDate)
+
+	// both of our devices read the message of the contact, the receipts are merged under our member
+	readReceipt("my_device_1", true, 20, "Qm0002")
+	readReceipt("my_device_2", true, 30, "Qm0002")
+
+	receipts, err = db.getInteractionReceipts("Qm0002")
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	require.Equal(t, "member_me", receipts[0].MemberPublicKey)
+	require.True(t, receipts[0].IsMine)
+	require.Equal(t, int64(20), receipts[0].ReadDate)
+}
+
+func Test_eventHandler_interactionFromBlockedContact(t *testing.T) {
+	handler, dispose := getEventHandlerForTests(t)
+	defer dispose()
+
+	db := handler.db
+	db.db.Create(&messengertypes.Contact{PublicKey: "contact_1", State: messengertypes.Contact_Blocked})
+	db.db.Create(&messengertypes.Contact{PublicKey: "contact_2", State: messengertypes.Contact_Accepted})
+
+	for _, tc := range []struct {
+		name     string
+		i        *messengertypes.Interaction
+		expected bool
+	}{
+		{"blocked contact", &messengertypes.Interaction{Conversation: &messengertypes.Conversation{Type: messengertypes.Conversation_ContactType, ContactPublicKey: "contact_1"}}, true},
+		{"own interaction", &messengertypes.Interaction{IsMine: true, Conversation: &messengertypes.Conversation{Type: messengertypes.Conversation_ContactType, ContactPublicKey: "contact_1"}}, false},
+		{"accepted contact", &messengertypes.Interaction{Conversation: &messengertypes.Conversation{Type: messengertypes.Conversation_ContactType, ContactPublicKey: "contact_2"}}, false},
+		{"unknown contact", &messengertypes.Interaction{Conversation: &messengertypes.Conversation{Type: messengertypes.Conversation_ContactType, ContactPublicKey: "contact_3"}}, false},
+		{"group", &messengertypes.Interaction{Conversation: &messengertypes.Conversation{Type: messengertypes.Conversation_MultiMemberType}}, false},
+	} {
+		blocked, err := handler.interactionFromBlockedContact(db, tc.i)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, blocked, tc.name)
+	}
+}
+
 func Test_eventHandler_handleMetadataEvent(t *testing.T) {
 	// TODO
 	t.Skip("TODO")
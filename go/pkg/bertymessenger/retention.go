@@ -0,0 +1,131 @@
+package bertymessenger
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"berty.tech/berty/v2/go/pkg/errcode"
+	"berty.tech/berty/v2/go/pkg/messengertypes"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
+)
+
+// retentionSchedulerRetryDelay is the delay before retrying to expire interactions after a failure
+const retentionSchedulerRetryDelay = time.Minute
+
+// runRetentionScheduler deletes the interactions of the conversations with a retention period once they
+// expire, the expiration dates are stored in the database so the pending deletions survive restarts
+func (svc *service) runRetentionScheduler(ctx context.Context) {
+	wakeup := make(chan struct{}, 1)
+
+	// wake the scheduler up when an interaction expiring earlier than expected is added
+	unregister := svc.dispatcher.Register(&NotifieeBundle{StreamEventImpl: func(se *messengertypes.StreamEvent) error {
+		if se.GetType() != messengertypes.StreamEvent_TypeInteractionUpdated || !se.GetIsNew() {
+			return nil
+		}
+
+		payload, err := se.UnmarshalPayload()
+		if err != nil {
+			return nil
+		}
+
+		if payload.(*messengertypes.StreamEvent_InteractionUpdated).GetInteraction().GetExpirationDate() == 0 {
+			return nil
+		}
+
+		select {
+		case wakeup <- struct{}{}:
+		default:
+		}
+
+		return nil
+	}})
+	defer unregister()
+
+	for {
+		var (
+			delay     time.Duration
+			scheduled = true
+		)
+
+		if err := svc.expireInteractions(ctx); err != nil {
+			svc.logger.Error("unable to expire interactions", zap.Error(err))
+			delay = retentionSchedulerRetryDelay
+		} else if next, err := svc.db.getNextInteractionExpirationDate(); err != nil {
+			svc.logger.Error("unable to get next interaction expiration date", zap.Error(err))
+			delay = retentionSchedulerRetryDelay
+		} else if next != 0 {
+			delay = time.Until(time.Unix(0, next*int64(time.Millisecond)))
+		} else {
+			scheduled = false
+		}
+
+		// a nil channel blocks forever when nothing is scheduled
+		var (
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		if scheduled {
+			timer = time.NewTimer(delay)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-wakeup:
+		case <-timeout:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// expireInteractions deletes the expired interactions and their medias, the attachments secrets and data
+// are removed from the protocol layer
+func (svc *service) expireInteractions(ctx context.Context) error {
+	svc.handlerMutex.Lock()
+	cids, medias, err := svc.db.deleteExpiredInteractions(timestampMs(time.Now()))
+	svc.handlerMutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, cid := range cids {
+		if err := svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeInteractionDeleted, &messengertypes.StreamEvent_InteractionDeleted{CID: cid}, false); err != nil {
+			svc.logger.Error("unable to dispatch interaction deletion", zap.Error(err))
+		}
+	}
+
+	for _, media := range medias {
+		if err := svc.attachmentDelete(ctx, media.GetCID()); err != nil {
+			svc.logger.Warn("unable to delete expired attachment", zap.String("cid", media.GetCID()), zap.Error(err))
+		}
+	}
+
+	if len(cids) > 0 {
+		svc.logger.Debug("expired interactions deleted", zap.Int("interactions", len(cids)), zap.Int("medias", len(medias)))
+	}
+
+	return nil
+}
+
+func (svc *service) attachmentDelete(ctx context.Context, cid string) error {
+	cidBytes, err := b64DecodeBytes(cid)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if _, err := svc.protocolClient.AttachmentDelete(ctx, &protocoltypes.AttachmentDelete_Request{AttachmentCID: cidBytes}); err != nil {
+		return errcode.ErrAttachmentDelete.Wrap(err)
+	}
+
+	return nil
+}
@@ -52,6 +52,7 @@ type service struct {
 	notifmanager          notification.Manager
 	lcmanager             *lifecycle.Manager
 	eventHandler          *eventHandler
+	typing                *typingIndicators
 }
 
 type Opts struct {
@@ -127,8 +128,8 @@ func databaseStateRestoreAccountHandler(statePointer *messengertypes.LocalDataba
 	}
 }
 
-func RestoreFromAccountExport(ctx context.Context, reader io.Reader, coreAPI ipfs_interface.CoreAPI, odb *bertyprotocol.BertyOrbitDB, localDBState *messengertypes.LocalDatabaseState, logger *zap.Logger) error {
-	return bertyprotocol.RestoreAccountExport(ctx, reader, coreAPI, odb, logger, databaseStateRestoreAccountHandler(localDBState))
+func RestoreFromAccountExport(ctx context.Context, reader io.Reader, passphrase []byte, coreAPI ipfs_interface.CoreAPI, odb *bertyprotocol.BertyOrbitDB, localDBState *messengertypes.LocalDatabaseState, logger *zap.Logger) error {
+	return bertyprotocol.RestoreAccountExport(ctx, reader, passphrase, coreAPI, odb, logger, databaseStateRestoreAccountHandler(localDBState))
 }
 
 func New(client protocoltypes.ProtocolServiceClient, opts *Opts) (Service, error) {
@@ -160,6 +161,10 @@ func New(client protocoltypes.ProtocolServiceClient, opts *Opts) (Service, error
 		if err := restoreDatabaseLocalState(db, opts.StateBackup); err != nil {
 			return nil, errcode.ErrDBWrite.Wrap(fmt.Errorf("unable to restore database local state: %w", err))
 		}
+
+		if err := db.initSearchIndex(); err != nil {
+			return nil, errcode.ErrDBWrite.Wrap(fmt.Errorf("unable to create search index: %w", err))
+		}
 	} else if err := db.initDB(getEventsReplayerForDB(ctx, client)); err != nil {
 		return nil, errcode.TODO.Wrap(err)
 	}
@@ -183,6 +188,7 @@ func New(client protocoltypes.ProtocolServiceClient, opts *Opts) (Service, error
 	}
 
 	svc.eventHandler = newEventHandler(ctx, db, client, opts.Logger, &svc, false)
+	svc.typing = newTypingIndicators(typingIndicatorTTL, svc.dispatchTypingUpdated)
 
 	icr, err := client.InstanceGetConfiguration(ctx, &protocoltypes.InstanceGetConfiguration_Request{})
 	if err != nil {
@@ -217,6 +223,9 @@ func New(client protocoltypes.ProtocolServiceClient, opts *Opts) (Service, error
 	// monitor messenger lifecycle
 	go svc.monitorState(ctx)
 
+	// delete expired interactions
+	go svc.runRetentionScheduler(ctx)
+
 	// Dispatch app notifications to native manager
 	svc.dispatcher.Register(&NotifieeBundle{StreamEventImpl: func(se *messengertypes.StreamEvent) error {
 		if se.GetType() != messengertypes.StreamEvent_TypeNotified {
@@ -362,6 +371,61 @@ func (svc *service) subscribeToMessages(gpkb []byte) error {
 	return nil
 }
 
+func (svc *service) subscribeToEphemeralMessages(gpkb []byte) error {
+	s, err := svc.protocolClient.EphemeralMessageSubscribe(
+		svc.ctx,
+		&protocoltypes.EphemeralMessageSubscribe_Request{GroupPK: gpkb},
+	)
+	if err != nil {
+		return errcode.ErrEventListMessage.Wrap(err)
+	}
+	go func() {
+		for {
+			evt, err := s.Recv()
+			if err != nil {
+				svc.logStreamingError("group ephemeral message", err)
+				return
+			}
+
+			if err := svc.handleEphemeralMessage(evt); err != nil {
+				svc.logger.Debug("failed to handle ephemeral message", zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
+
+func (svc *service) handleEphemeralMessage(evt *protocoltypes.EphemeralMessageEvent) error {
+	payload, am, err := messengertypes.UnmarshalAppMessage(evt.GetPayload())
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	switch am.GetType() {
+	case messengertypes.AppMessage_TypeTyping:
+		// the protocol only delivers the ephemeral messages sent by the devices of the members
+		if len(evt.GetMemberPK()) == 0 {
+			return errcode.ErrInvalidInput.Wrap(fmt.Errorf("ephemeral message without a member public key"))
+		}
+
+		svc.typing.update(b64EncodeBytes(evt.GetGroupPK()), b64EncodeBytes(evt.GetMemberPK()), payload.(*messengertypes.AppMessage_Typing).GetState())
+	default:
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("unsupported ephemeral AppMessage type: %q", am.GetType()))
+	}
+
+	return nil
+}
+
+func (svc *service) dispatchTypingUpdated(conversationPK string, memberPK string, isTyping bool) {
+	if err := svc.dispatcher.StreamEvent(messengertypes.StreamEvent_TypeTypingUpdated, &messengertypes.StreamEvent_TypingUpdated{
+		ConversationPublicKey: conversationPK,
+		MemberPublicKey:       memberPK,
+		IsTyping:              isTyping,
+	}, false); err != nil {
+		svc.logger.Error("unable to dispatch typing update", zap.Error(err))
+	}
+}
+
 var monitorCounter uint64 = 0
 
 func (svc *service) subscribeToGroupMonitor(groupPK []byte) error {
@@ -429,7 +493,11 @@ func (svc *service) subscribeToGroup(gpkb []byte) error {
 		return err
 	}
 
-	return svc.subscribeToMessages(gpkb)
+	if err := svc.subscribeToMessages(gpkb); err != nil {
+		return err
+	}
+
+	return svc.subscribeToEphemeralMessages(gpkb)
 }
 
 func (svc *service) attachmentPrepare(attachment io.Reader) ([]byte, error) {
@@ -530,5 +598,6 @@ func (svc *service) Close() {
 	svc.logger.Debug("closing service")
 	svc.dispatcher.UnregisterAll()
 	svc.cancelFn()
+	svc.typing.close()
 	svc.optsCleanup()
 }
@@ -222,6 +222,61 @@ func TestServiceConversationCreateAsync(t *testing.T) {
 	}
 }
 
+func TestServiceConversationUpdateAdminOnly(t *testing.T) {
+	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
+	node, cleanup := testingNode(ctx, t)
+	defer cleanup()
+	node.ProcessWholeStream(t)
+
+	// create conversation, its creator is an admin
+	var createdConversationPK string
+	{
+		reply, err := node.GetClient().ConversationCreate(ctx, &messengertypes.ConversationCreate_Request{DisplayName: "Tasty"})
+		require.NoError(t, err)
+		require.NotEmpty(t, reply.GetPublicKey())
+		createdConversationPK = reply.GetPublicKey()
+	}
+
+	time.Sleep(time.Second)
+
+	// restrict the group info to the admins
+	{
+		_, err := node.GetClient().ConversationUpdate(ctx, &messengertypes.ConversationUpdate_Request{
+			ConversationPublicKey: createdConversationPK,
+			DisplayName:           "Tastier",
+			InfoAdminOnly:         messengertypes.ConversationUpdate_Enabled,
+		})
+		require.NoError(t, err)
+	}
+
+	time.Sleep(time.Second)
+
+	{
+		conversation := node.GetConversation(t, createdConversationPK)
+		require.NotNil(t, conversation)
+		require.Equal(t, "Tastier", conversation.GetDisplayName())
+		require.True(t, conversation.GetInfoAdminOnly())
+	}
+
+	// the admin can still edit the group
+	{
+		_, err := node.GetClient().ConversationUpdate(ctx, &messengertypes.ConversationUpdate_Request{
+			ConversationPublicKey: createdConversationPK,
+			DisplayName:           "Tastiest",
+		})
+		require.NoError(t, err)
+	}
+
+	time.Sleep(time.Second)
+
+	{
+		conversation := node.GetConversation(t, createdConversationPK)
+		require.NotNil(t, conversation)
+		require.Equal(t, "Tastiest", conversation.GetDisplayName())
+		require.True(t, conversation.GetInfoAdminOnly())
+	}
+}
+
 func TestBroken1To1AddContact(t *testing.T) {
 	testutil.FilterStabilityAndSpeed(t, testutil.Broken, testutil.Slow)
 
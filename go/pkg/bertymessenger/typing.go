@@ -0,0 +1,76 @@
+package bertymessenger
+
+import (
+	"sync"
+	"time"
+)
+
+// typingIndicatorTTL is the duration after which a member is no longer considered as typing
+// if no update has been received, clients should refresh their typing state before it expires
+const typingIndicatorTTL = 10 * time.Second
+
+// typingIndicators keeps track of the members currently typing in conversations, the state is
+// only kept in memory and each indicator expires automatically
+type typingIndicators struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	timers   map[string]*time.Timer
+	dispatch func(conversationPK string, memberPK string, isTyping bool)
+}
+
+func newTypingIndicators(ttl time.Duration, dispatch func(conversationPK string, memberPK string, isTyping bool)) *typingIndicators {
+	return &typingIndicators{
+		ttl:      ttl,
+		timers:   map[string]*time.Timer{},
+		dispatch: dispatch,
+	}
+}
+
+func typingIndicatorKey(conversationPK string, memberPK string) string {
+	return conversationPK + "/" + memberPK
+}
+
+// update sets the typing state of a member, an event is only dispatched when the state changes
+func (t *typingIndicators) update(conversationPK string, memberPK string, isTyping bool) {
+	key := typingIndicatorKey(conversationPK, memberPK)
+
+	t.mu.Lock()
+	timer, wasTyping := t.timers[key]
+	if wasTyping {
+		timer.Stop()
+		delete(t.timers, key)
+	}
+
+	if isTyping {
+		var expired *time.Timer
+		expired = time.AfterFunc(t.ttl, func() {
+			t.mu.Lock()
+			if t.timers[key] != expired {
+				// the indicator has been refreshed or removed in the meantime
+				t.mu.Unlock()
+				return
+			}
+			delete(t.timers, key)
+			t.mu.Unlock()
+
+			t.dispatch(conversationPK, memberPK, false)
+		})
+		t.timers[key] = expired
+	}
+	t.mu.Unlock()
+
+	if wasTyping != isTyping {
+		t.dispatch(conversationPK, memberPK, isTyping)
+	}
+}
+
+// close stops all the pending timers without dispatching any event
+func (t *typingIndicators) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, timer := range t.timers {
+		timer.Stop()
+		delete(t.timers, key)
+	}
+}
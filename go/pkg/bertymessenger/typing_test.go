@@ -0,0 +1,50 @@
+package bertymessenger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type typingUpdate struct {
+	conversationPK string
+	memberPK       string
+	isTyping       bool
+}
+
+func Test_typingIndicators(t *testing.T) {
+	updates := make(chan typingUpdate, 10)
+	ti := newTypingIndicators(50*time.Millisecond, func(conversationPK string, memberPK string, isTyping bool) {
+		updates <- typingUpdate{conversationPK: conversationPK, memberPK: memberPK, isTyping: isTyping}
+	})
+	defer ti.close()
+
+	ti.update("conv1", "member1", true)
+	require.Equal(t, typingUpdate{"conv1", "member1", true}, <-updates)
+
+	// refreshing the state doesn't dispatch anything
+	ti.update("conv1", "member1", true)
+	ti.update("conv2", "member1", true)
+	require.Equal(t, typingUpdate{"conv2", "member1", true}, <-updates)
+
+	ti.update("conv2", "member1", false)
+	require.Equal(t, typingUpdate{"conv2", "member1", false}, <-updates)
+
+	// a stopped indicator doesn't expire
+	ti.update("conv2", "member1", false)
+
+	// the indicator expires automatically
+	select {
+	case u := <-updates:
+		require.Equal(t, typingUpdate{"conv1", "member1", false}, u)
+	case <-time.After(time.Second):
+		require.FailNow(t, "typing indicator didn't expire")
+	}
+
+	select {
+	case u := <-updates:
+		require.FailNow(t, "unexpected update", "%v", u)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 
 	"github.com/ipfs/go-cid"
 	cbornode "github.com/ipfs/go-ipld-cbor"
 	ipfs_interface "github.com/ipfs/interface-go-ipfs-core"
+	ipfsoptions "github.com/ipfs/interface-go-ipfs-core/options"
 	"github.com/libp2p/go-libp2p-core/crypto"
 	crypto_pb "github.com/libp2p/go-libp2p-core/crypto/pb"
 	mh "github.com/multiformats/go-multihash"
@@ -20,6 +22,7 @@ import (
 
 	"berty.tech/berty/v2/go/pkg/errcode"
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
+	ipfslog "berty.tech/go-ipfs-log"
 	orbitdb "berty.tech/go-orbit-db"
 )
 
@@ -28,9 +31,13 @@ const (
 	exportAccountProofKeyFilename = "account_proof.key"
 	exportOrbitDBEntriesPrefix    = "entries/"
 	exportOrbitDBHeadsPrefix      = "heads/"
+	exportOrbitDBBaseHeadsPrefix  = "base_heads/"
 )
 
-func (s *service) export(ctx context.Context, output io.Writer) error {
+// export writes the account keys and the contents of the opened groups, when the heads of a previous export are
+// provided only the entries which were not reachable from them are written and the export can only be restored after
+// the previous ones
+func (s *service) export(ctx context.Context, output io.Writer, previousHeads []*protocoltypes.GroupHeadsExport) error {
 	tw := tar.NewWriter(output)
 	defer tw.Close()
 
@@ -51,8 +58,13 @@ func (s *service) export(ctx context.Context, output io.Writer) error {
 	}
 	s.lock.RUnlock()
 
+	previous := make(map[string]*protocoltypes.GroupHeadsExport, len(previousHeads))
+	for _, heads := range previousHeads {
+		previous[string(heads.PublicKey)] = heads
+	}
+
 	for _, gc := range groups {
-		if err := s.exportGroupContext(ctx, gc, tw); err != nil {
+		if err := s.exportGroupContext(ctx, gc, tw, previous[string(gc.group.PublicKey)]); err != nil {
 			return errcode.ErrInternal.Wrap(err)
 		}
 	}
@@ -60,42 +72,56 @@ func (s *service) export(ctx context.Context, output io.Writer) error {
 	return nil
 }
 
-func (s *service) exportGroupContext(ctx context.Context, gc *groupContext, tw *tar.Writer) error {
-	if err := s.exportOrbitDBStore(ctx, gc.metadataStore, tw); err != nil {
-		return errcode.ErrInternal.Wrap(err)
+func (s *service) exportGroupContext(ctx context.Context, gc *groupContext, tw *tar.Writer, previousHeads *protocoltypes.GroupHeadsExport) error {
+	var previousMetaCIDs, previousMessagesCIDs []cid.Cid
+
+	if previousHeads != nil {
+		var err error
+
+		previousMetaCIDs, previousMessagesCIDs, err = parseGroupHeadsExportCIDs(previousHeads)
+		if err != nil {
+			return errcode.ErrInvalidInput.Wrap(err)
+		}
+
+		if err := s.exportOrbitDBGroupHeads(previousHeads, exportOrbitDBBaseHeadsPrefix, tw); err != nil {
+			return errcode.ErrInternal.Wrap(err)
+		}
 	}
 
-	if err := s.exportOrbitDBStore(ctx, gc.messageStore, tw); err != nil {
+	if err := s.exportOrbitDBStore(ctx, gc.metadataStore, previousMetaCIDs, tw); err != nil {
 		return errcode.ErrInternal.Wrap(err)
 	}
 
-	metaRawHeads := gc.metadataStore.OpLog().RawHeads()
-	cidsMeta := make([]cid.Cid, metaRawHeads.Len())
-	for i, raw := range metaRawHeads.Slice() {
-		cidsMeta[i] = raw.GetHash()
+	if err := s.exportOrbitDBStore(ctx, gc.messageStore, previousMessagesCIDs, tw); err != nil {
+		return errcode.ErrInternal.Wrap(err)
 	}
 
-	messagesRawHeads := gc.messageStore.OpLog().RawHeads()
-	cidsMessages := make([]cid.Cid, messagesRawHeads.Len())
-	for i, raw := range messagesRawHeads.Slice() {
-		cidsMessages[i] = raw.GetHash()
+	headsExport, err := groupContextHeadsExport(gc)
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
 	}
 
-	if err := s.exportOrbitDBGroupHeads(gc, cidsMeta, cidsMessages, tw); err != nil {
+	if err := s.exportOrbitDBGroupHeads(headsExport, exportOrbitDBHeadsPrefix, tw); err != nil {
 		return errcode.ErrInternal.Wrap(err)
 	}
 
 	return nil
 }
 
-func (s *service) exportOrbitDBStore(ctx context.Context, store orbitdb.Store, tw *tar.Writer) error {
+func (s *service) exportOrbitDBStore(ctx context.Context, store orbitdb.Store, previousHeads []cid.Cid, tw *tar.Writer) error {
 	allCIDs := store.OpLog().GetEntries().Keys()
 
 	if len(allCIDs) == 0 {
 		return nil
 	}
 
+	previouslyExported := orbitDBEntriesReachableFrom(store.OpLog(), previousHeads)
+
 	for _, idStr := range allCIDs {
+		if _, ok := previouslyExported[idStr]; ok {
+			continue
+		}
+
 		if err := s.exportOrbitDBEntry(ctx, tw, idStr); err != nil {
 			if clErr := tw.Close(); clErr != nil {
 				err = multierr.Append(err, clErr)
@@ -108,6 +134,32 @@ func (s *service) exportOrbitDBStore(ctx context.Context, store orbitdb.Store, t
 	return nil
 }
 
+// orbitDBEntriesReachableFrom lists the entries of the log which can be reached from the given heads
+func orbitDBEntriesReachableFrom(log ipfslog.Log, heads []cid.Cid) map[string]struct{} {
+	reachable := map[string]struct{}{}
+	pending := append([]cid.Cid(nil), heads...)
+
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		idStr := id.String()
+		if _, ok := reachable[idStr]; ok {
+			continue
+		}
+
+		entry, ok := log.GetEntries().Get(idStr)
+		if !ok {
+			continue
+		}
+
+		reachable[idStr] = struct{}{}
+		pending = append(pending, entry.GetNext()...)
+	}
+
+	return reachable
+}
+
 func (s *service) exportAccountKey(tw *tar.Writer) error {
 	sk, err := s.deviceKeystore.AccountPrivKey()
 	if err != nil {
@@ -126,35 +178,39 @@ func (s *service) exportAccountProofKey(tw *tar.Writer) error {
 	return exportPrivateKey(tw, sk, exportAccountProofKeyFilename)
 }
 
-func (s *service) exportOrbitDBGroupHeads(gc *groupContext, headsMetadata []cid.Cid, headsMessages []cid.Cid, tw *tar.Writer) error {
-	cidsMeta := make([][]byte, len(headsMetadata))
-	for i, id := range headsMetadata {
-		cidsMeta[i] = id.Bytes()
+func groupContextHeadsExport(gc *groupContext) (*protocoltypes.GroupHeadsExport, error) {
+	metaRawHeads := gc.metadataStore.OpLog().RawHeads()
+	cidsMeta := make([][]byte, metaRawHeads.Len())
+	for i, raw := range metaRawHeads.Slice() {
+		cidsMeta[i] = raw.GetHash().Bytes()
 	}
 
-	cidsMessages := make([][]byte, len(headsMessages))
-	for i, id := range headsMessages {
-		cidsMessages[i] = id.Bytes()
+	messagesRawHeads := gc.messageStore.OpLog().RawHeads()
+	cidsMessages := make([][]byte, messagesRawHeads.Len())
+	for i, raw := range messagesRawHeads.Slice() {
+		cidsMessages[i] = raw.GetHash().Bytes()
 	}
 
 	spk, err := gc.group.GetSigningPubKey()
 	if err != nil {
-		return errcode.ErrSerialization.Wrap(err)
+		return nil, errcode.ErrSerialization.Wrap(err)
 	}
 
 	spkBytes, err := spk.Raw()
 	if err != nil {
-		return errcode.ErrSerialization.Wrap(err)
+		return nil, errcode.ErrSerialization.Wrap(err)
 	}
 
-	headsExport := &protocoltypes.GroupHeadsExport{
+	return &protocoltypes.GroupHeadsExport{
 		PublicKey:         gc.group.PublicKey,
 		SignPub:           spkBytes,
 		MetadataHeadsCIDs: cidsMeta,
 		MessagesHeadsCIDs: cidsMessages,
-	}
+	}, nil
+}
 
-	entryName := base64.RawURLEncoding.EncodeToString(gc.group.PublicKey)
+func (s *service) exportOrbitDBGroupHeads(headsExport *protocoltypes.GroupHeadsExport, prefix string, tw *tar.Writer) error {
+	entryName := base64.RawURLEncoding.EncodeToString(headsExport.PublicKey)
 
 	data, err := headsExport.Marshal()
 	if err != nil {
@@ -163,7 +219,7 @@ func (s *service) exportOrbitDBGroupHeads(gc *groupContext, headsMetadata []cid.
 
 	if err := tw.WriteHeader(&tar.Header{
 		Typeflag: tar.TypeReg,
-		Name:     fmt.Sprintf("%s%s", exportOrbitDBHeadsPrefix, entryName),
+		Name:     fmt.Sprintf("%s%s", prefix, entryName),
 		Mode:     0o600,
 		Size:     int64(len(data)),
 	}); err != nil {
@@ -290,11 +346,22 @@ func readExportOrbitDBGroupHeads(expectedSize int64, reader *tar.Reader) (*proto
 		return nil, nil, nil, errcode.ErrDeserialization.Wrap(err)
 	}
 
+	metaCIDs, messagesCIDs, err := parseGroupHeadsExportCIDs(groupHeads)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return groupHeads, metaCIDs, messagesCIDs, nil
+}
+
+func parseGroupHeadsExportCIDs(groupHeads *protocoltypes.GroupHeadsExport) ([]cid.Cid, []cid.Cid, error) {
+	var err error
+
 	messagesCIDs := make([]cid.Cid, len(groupHeads.MessagesHeadsCIDs))
 	for i, cidBytes := range groupHeads.MessagesHeadsCIDs {
 		messagesCIDs[i], err = cid.Parse(cidBytes)
 		if err != nil {
-			return nil, nil, nil, errcode.ErrDeserialization.Wrap(err)
+			return nil, nil, errcode.ErrDeserialization.Wrap(err)
 		}
 	}
 
@@ -302,11 +369,11 @@ func readExportOrbitDBGroupHeads(expectedSize int64, reader *tar.Reader) (*proto
 	for i, cidBytes := range groupHeads.MetadataHeadsCIDs {
 		metaCIDs[i], err = cid.Parse(cidBytes)
 		if err != nil {
-			return nil, nil, nil, errcode.ErrDeserialization.Wrap(err)
+			return nil, nil, errcode.ErrDeserialization.Wrap(err)
 		}
 	}
 
-	return groupHeads, metaCIDs, messagesCIDs, nil
+	return metaCIDs, messagesCIDs, nil
 }
 
 func readExportCBORNode(expectedSize int64, cidStr string, reader *tar.Reader) (*cbornode.Node, error) {
@@ -432,8 +499,40 @@ func restoreOrbitDBHeads(ctx context.Context, odb *BertyOrbitDB) RestoreAccountH
 	}
 }
 
-func RestoreAccountExport(ctx context.Context, reader io.Reader, coreAPI ipfs_interface.CoreAPI, odb *BertyOrbitDB, logger *zap.Logger, handlers ...RestoreAccountHandler) error {
-	tr := tar.NewReader(reader)
+// checkOrbitDBBaseHeads ensures the heads of the previous export an incremental export is based on are available
+// locally, the previous exports must be restored first, see RestoreAccountExport
+func checkOrbitDBBaseHeads(ctx context.Context, coreAPI ipfs_interface.CoreAPI) RestoreAccountHandler {
+	return RestoreAccountHandler{
+		Handler: func(header *tar.Header, reader *tar.Reader) (bool, error) {
+			if !strings.HasPrefix(header.Name, exportOrbitDBBaseHeadsPrefix) {
+				return false, nil
+			}
+
+			_, metaCIDs, messageCIDs, err := readExportOrbitDBGroupHeads(header.Size, reader)
+			if err != nil {
+				return true, errcode.ErrInternal.Wrap(err)
+			}
+
+			offlineAPI, err := coreAPI.WithOptions(ipfsoptions.Api.Offline(true))
+			if err != nil {
+				return true, errcode.ErrInternal.Wrap(err)
+			}
+
+			for _, c := range append(metaCIDs, messageCIDs...) {
+				if _, err := offlineAPI.Dag().Get(ctx, c); err != nil {
+					return true, errcode.ErrInvalidInput.Wrap(fmt.Errorf("entry %s of the previous export is missing, it must be restored first: %w", c.String(), err))
+				}
+			}
+
+			return true, nil
+		},
+	}
+}
+
+// RestoreAccountExport restores the account keys and the groups contents from an export, the passphrase is only
+// required if the export has been encrypted, see SealExport. An incremental export is restored by calling
+// RestoreAccountExport for each export of the chain, from the full export to the latest incremental one
+func RestoreAccountExport(ctx context.Context, reader io.Reader, passphrase []byte, coreAPI ipfs_interface.CoreAPI, odb *BertyOrbitDB, logger *zap.Logger, handlers ...RestoreAccountHandler) error {
 	state := restoreAccountState{
 		keys: map[string]crypto.PrivKey{},
 	}
@@ -445,10 +544,23 @@ func RestoreAccountExport(ctx context.Context, reader io.Reader, coreAPI ipfs_in
 			state.restoreKeys(odb),
 			restoreOrbitDBEntry(ctx, coreAPI),
 			restoreOrbitDBHeads(ctx, odb),
+			checkOrbitDBBaseHeads(ctx, coreAPI),
 		},
 		handlers...,
 	)
 
+	return walkAccountExport(reader, passphrase, logger, handlers)
+}
+
+// walkAccountExport opens an export and feeds each of its entries to the handlers
+func walkAccountExport(reader io.Reader, passphrase []byte, logger *zap.Logger, handlers []RestoreAccountHandler) error {
+	reader, err := OpenExport(reader, passphrase, logger)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(reader)
+
 	for {
 		header, err := tr.Next()
 
@@ -486,6 +598,11 @@ func RestoreAccountExport(ctx context.Context, reader io.Reader, coreAPI ipfs_in
 		}
 	}
 
+	// consume the padding after the end of the archive, ensuring encrypted exports are complete
+	if _, err := io.Copy(ioutil.Discard, reader); err != nil {
+		return errcode.ErrStreamRead.Wrap(err)
+	}
+
 	for _, h := range handlers {
 		if h.PostProcess == nil {
 			continue
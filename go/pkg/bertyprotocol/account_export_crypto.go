@@ -0,0 +1,210 @@
+package bertyprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/nacl/secretbox"
+
+	"berty.tech/berty/v2/go/internal/cryptoutil"
+	"berty.tech/berty/v2/go/internal/streamutil"
+	"berty.tech/berty/v2/go/pkg/errcode"
+)
+
+const (
+	exportEncryptionMagic           = "BERTYENC"
+	exportEncryptionVersion         = 1
+	exportEncryptionBlockSize       = 64 * 1024
+	exportEncryptionNoncePrefixSize = cryptoutil.NonceSize - 8
+	exportEncryptionFinalBlockFlag  = uint64(1) << 63
+)
+
+// exportEncryptionHeader is written in clear before the encrypted blocks of an export, it contains the parameters
+// required to derive the key from the passphrase
+type exportEncryptionHeader struct {
+	Version     uint8
+	ScryptN     uint32
+	ScryptR     uint32
+	ScryptP     uint32
+	Salt        [cryptoutil.ScryptKeyLen]byte
+	NoncePrefix [exportEncryptionNoncePrefixSize]byte
+}
+
+type exportCipher struct {
+	key         [cryptoutil.KeySize]byte
+	noncePrefix [exportEncryptionNoncePrefixSize]byte
+	counter     uint64
+}
+
+func newExportCipher(passphrase []byte, header *exportEncryptionHeader) (*exportCipher, error) {
+	if len(passphrase) == 0 {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("no passphrase specified"))
+	}
+
+	if header.ScryptN != cryptoutil.ScryptIterations || header.ScryptR != cryptoutil.ScryptR || header.ScryptP != cryptoutil.ScryptP {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("unsupported key derivation parameters"))
+	}
+
+	key, _, err := cryptoutil.DeriveKey(passphrase, header.Salt[:])
+	if err != nil {
+		return nil, errcode.ErrCryptoKeyDerivation.Wrap(err)
+	}
+
+	ec := &exportCipher{noncePrefix: header.NoncePrefix}
+	copy(ec.key[:], key)
+
+	return ec, nil
+}
+
+// nextNonce returns the nonce of the next block, the last block of the export uses a distinct nonce so a truncated
+// export can't be opened
+func (ec *exportCipher) nextNonce(final bool) *[cryptoutil.NonceSize]byte {
+	var nonce [cryptoutil.NonceSize]byte
+
+	counter := ec.counter
+	if final {
+		counter |= exportEncryptionFinalBlockFlag
+	}
+
+	copy(nonce[:], ec.noncePrefix[:])
+	binary.BigEndian.PutUint64(nonce[exportEncryptionNoncePrefixSize:], counter)
+	ec.counter++
+
+	return &nonce
+}
+
+// SealExport encrypts an export using a key derived from the passphrase, the output can be opened using OpenExport
+func SealExport(plaintext io.Reader, passphrase []byte, l *zap.Logger) (io.ReadCloser, error) {
+	header := &exportEncryptionHeader{
+		Version: exportEncryptionVersion,
+		ScryptN: cryptoutil.ScryptIterations,
+		ScryptR: cryptoutil.ScryptR,
+		ScryptP: cryptoutil.ScryptP,
+	}
+
+	salt, err := cryptoutil.GenerateNonceSize(cryptoutil.ScryptKeyLen)
+	if err != nil {
+		return nil, errcode.ErrCryptoRandomGeneration.Wrap(err)
+	}
+	copy(header.Salt[:], salt)
+
+	noncePrefix, err := cryptoutil.GenerateNonceSize(exportEncryptionNoncePrefixSize)
+	if err != nil {
+		return nil, errcode.ErrCryptoNonceGeneration.Wrap(err)
+	}
+	copy(header.NoncePrefix[:], noncePrefix)
+
+	ec, err := newExportCipher(passphrase, header)
+	if err != nil {
+		return nil, err
+	}
+
+	in, out := io.Pipe()
+
+	go func() {
+		err := func() error {
+			if _, err := out.Write([]byte(exportEncryptionMagic)); err != nil {
+				return errcode.ErrStreamWrite.Wrap(err)
+			}
+
+			if err := binary.Write(out, binary.BigEndian, header); err != nil {
+				return errcode.ErrStreamWrite.Wrap(err)
+			}
+
+			buf := make([]byte, exportEncryptionBlockSize)
+
+			for {
+				n, readErr := io.ReadFull(plaintext, buf)
+				if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+					return errcode.ErrStreamRead.Wrap(readErr)
+				}
+
+				// a full block is never the last one, an empty final block is added if needed
+				final := n < exportEncryptionBlockSize
+
+				if _, err := out.Write(secretbox.Seal(nil, buf[:n], ec.nextNonce(final), &ec.key)); err != nil {
+					return errcode.ErrStreamWrite.Wrap(err)
+				}
+
+				if final {
+					return nil
+				}
+			}
+		}()
+		streamutil.ClosePipeOut(out, err, "SealExport: close pipe out", l)
+	}()
+
+	return in, nil
+}
+
+// OpenExport detects if an export has been encrypted using SealExport and decrypts it using the passphrase, clear
+// exports are returned as is
+func OpenExport(reader io.Reader, passphrase []byte, l *zap.Logger) (io.Reader, error) {
+	br := bufio.NewReader(reader)
+
+	magic, err := br.Peek(len(exportEncryptionMagic))
+	if err != nil || !bytes.Equal(magic, []byte(exportEncryptionMagic)) {
+		return br, nil
+	}
+
+	if _, err := br.Discard(len(exportEncryptionMagic)); err != nil {
+		return nil, errcode.ErrStreamRead.Wrap(err)
+	}
+
+	header := &exportEncryptionHeader{}
+	if err := binary.Read(br, binary.BigEndian, header); err != nil {
+		return nil, errcode.ErrStreamHeaderRead.Wrap(err)
+	}
+
+	if header.Version != exportEncryptionVersion {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("unsupported export encryption version %d", header.Version))
+	}
+
+	if len(passphrase) == 0 {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("export is encrypted, a passphrase is required"))
+	}
+
+	ec, err := newExportCipher(passphrase, header)
+	if err != nil {
+		return nil, err
+	}
+
+	in, out := io.Pipe()
+
+	go func() {
+		err := func() error {
+			buf := make([]byte, exportEncryptionBlockSize+secretbox.Overhead)
+
+			for {
+				n, readErr := io.ReadFull(br, buf)
+				if readErr == io.EOF {
+					return errcode.ErrStreamRead.Wrap(fmt.Errorf("export is truncated"))
+				} else if readErr != nil && readErr != io.ErrUnexpectedEOF {
+					return errcode.ErrStreamRead.Wrap(readErr)
+				}
+
+				final := n < len(buf)
+
+				pt, ok := secretbox.Open(nil, buf[:n], ec.nextNonce(final), &ec.key)
+				if !ok {
+					return errcode.ErrCryptoDecrypt.Wrap(fmt.Errorf("invalid passphrase or corrupted export"))
+				}
+
+				if _, err := out.Write(pt); err != nil {
+					return errcode.ErrStreamWrite.Wrap(err)
+				}
+
+				if final {
+					return nil
+				}
+			}
+		}()
+		streamutil.ClosePipeOut(out, err, "OpenExport: close pipe out", l)
+	}()
+
+	return in, nil
+}
@@ -0,0 +1,63 @@
+package bertyprotocol
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"berty.tech/berty/v2/go/pkg/errcode"
+)
+
+func TestSealOpenExport(t *testing.T) {
+	logger := zap.NewNop()
+	passphrase := []byte("correct horse battery staple")
+
+	for _, size := range []int{0, 42, exportEncryptionBlockSize, exportEncryptionBlockSize*2 + 42} {
+		plaintext := make([]byte, size)
+		_, err := crand.Read(plaintext)
+		require.NoError(t, err)
+
+		sealed, err := SealExport(bytes.NewReader(plaintext), passphrase, logger)
+		require.NoError(t, err)
+
+		ciphertext, err := ioutil.ReadAll(sealed)
+		require.NoError(t, err)
+
+		opened, err := OpenExport(bytes.NewReader(ciphertext), passphrase, logger)
+		require.NoError(t, err)
+
+		decrypted, err := ioutil.ReadAll(opened)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+
+		// a passphrase is required
+		_, err = OpenExport(bytes.NewReader(ciphertext), nil, logger)
+		require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+		// invalid passphrase
+		opened, err = OpenExport(bytes.NewReader(ciphertext), []byte("invalid"), logger)
+		require.NoError(t, err)
+
+		_, err = ioutil.ReadAll(opened)
+		require.Error(t, err)
+
+		// truncated export
+		opened, err = OpenExport(bytes.NewReader(ciphertext[:len(ciphertext)-1]), passphrase, logger)
+		require.NoError(t, err)
+
+		_, err = ioutil.ReadAll(opened)
+		require.Error(t, err)
+	}
+
+	// clear exports are returned as is
+	opened, err := OpenExport(bytes.NewReader([]byte("clear export")), passphrase, logger)
+	require.NoError(t, err)
+
+	decrypted, err := ioutil.ReadAll(opened)
+	require.NoError(t, err)
+	require.Equal(t, []byte("clear export"), decrypted)
+}
@@ -2,6 +2,7 @@ package bertyprotocol
 
 import (
 	"archive/tar"
+	"bytes"
 	"io"
 	"io/ioutil"
 	"os"
@@ -181,7 +182,7 @@ func TestUnstableRestoreAccount(t *testing.T) {
 
 		expectedMessages[op.GetEntry().GetHash()] = testPayload4
 
-		require.NoError(t, serviceA.export(ctx, tmpFile))
+		require.NoError(t, serviceA.export(ctx, tmpFile, nil))
 
 		closeNodeA()
 		require.NoError(t, dsA.Close())
@@ -211,7 +212,7 @@ func TestUnstableRestoreAccount(t *testing.T) {
 		})
 		require.NoError(t, err)
 
-		err = RestoreAccountExport(ctx, tmpFile, ipfsNodeB.API(), odb, logger)
+		err = RestoreAccountExport(ctx, tmpFile, nil, ipfsNodeB.API(), odb, logger)
 		require.NoError(t, err)
 
 		nodeB, closeNodeB := NewTestingProtocol(ctx, t, &TestingOpts{
@@ -272,3 +273,172 @@ func TestUnstableRestoreAccount(t *testing.T) {
 	}
 	// TODO: test account metadata entries
 }
+
+func TestIncrementalExportVerify(t *testing.T) {
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	ctx, cancel, mn, rdvPeer := testHelperIPFSSetUp(t)
+	defer cancel()
+
+	nodeA, closeNodeA := NewTestingProtocol(ctx, t, &TestingOpts{
+		Mocknet: mn,
+		RDVPeer: rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+	}, nil)
+	defer closeNodeA()
+
+	serviceA, ok := nodeA.Service.(*service)
+	require.True(t, ok)
+
+	_, err := serviceA.accountGroup.messageStore.AddMessage(ctx, []byte("testMessage1"), nil)
+	require.NoError(t, err)
+
+	fullExport := new(bytes.Buffer)
+	require.NoError(t, serviceA.export(ctx, fullExport, nil))
+
+	report, err := VerifyAccountExport(bytes.NewReader(fullExport.Bytes()), nil, logger)
+	require.NoError(t, err)
+	require.True(t, report.Valid())
+	require.False(t, report.Incremental)
+	require.NotZero(t, report.Entries)
+
+	previousHeads, err := ReadAccountExportHeads(bytes.NewReader(fullExport.Bytes()), nil, logger)
+	require.NoError(t, err)
+	require.Len(t, previousHeads, len(report.Groups))
+
+	_, err = serviceA.accountGroup.messageStore.AddMessage(ctx, []byte("testMessage2"), nil)
+	require.NoError(t, err)
+
+	incrementalExport := new(bytes.Buffer)
+	require.NoError(t, serviceA.export(ctx, incrementalExport, previousHeads))
+
+	// entries of the previous export are missing
+	incrementalReport, err := VerifyAccountExport(bytes.NewReader(incrementalExport.Bytes()), nil, logger)
+	require.NoError(t, err)
+	require.True(t, incrementalReport.Incremental)
+	require.False(t, incrementalReport.Valid())
+	require.NotEmpty(t, incrementalReport.MissingEntries)
+	require.NotZero(t, incrementalReport.Entries)
+	require.Less(t, incrementalReport.Entries, report.Entries)
+
+	incrementalReport, err = VerifyAccountExport(bytes.NewReader(incrementalExport.Bytes()), nil, logger, bytes.NewReader(fullExport.Bytes()))
+	require.NoError(t, err)
+	require.True(t, incrementalReport.Valid())
+	require.Empty(t, incrementalReport.MissingEntries)
+}
+
+func TestUnstableRestoreIncrementalAccountExport(t *testing.T) {
+	testutil.FilterStability(t, testutil.Unstable)
+
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	ctx, cancel, mn, rdvPeer := testHelperIPFSSetUp(t)
+	defer cancel()
+
+	expectedMessages := map[cid.Cid][]byte{}
+	fullExport, incrementalExport := new(bytes.Buffer), new(bytes.Buffer)
+
+	var nodeAInstanceConfig *protocoltypes.InstanceGetConfiguration_Reply
+
+	{
+		dsA := dsync.MutexWrap(ds.NewMapDatastore())
+		nodeA, closeNodeA := NewTestingProtocol(ctx, t, &TestingOpts{
+			Mocknet: mn,
+			RDVPeer: rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+		}, dsA)
+
+		serviceA, ok := nodeA.Service.(*service)
+		require.True(t, ok)
+
+		var err error
+		nodeAInstanceConfig, err = nodeA.Client.InstanceGetConfiguration(ctx, &protocoltypes.InstanceGetConfiguration_Request{})
+		require.NoError(t, err)
+
+		testPayload1 := []byte("testMessage1")
+		op, err := serviceA.accountGroup.messageStore.AddMessage(ctx, testPayload1, nil)
+		require.NoError(t, err)
+
+		expectedMessages[op.GetEntry().GetHash()] = testPayload1
+
+		require.NoError(t, serviceA.export(ctx, fullExport, nil))
+
+		previousHeads, err := ReadAccountExportHeads(bytes.NewReader(fullExport.Bytes()), nil, logger)
+		require.NoError(t, err)
+
+		testPayload2 := []byte("testMessage2")
+		op, err = serviceA.accountGroup.messageStore.AddMessage(ctx, testPayload2, nil)
+		require.NoError(t, err)
+
+		expectedMessages[op.GetEntry().GetHash()] = testPayload2
+
+		require.NoError(t, serviceA.export(ctx, incrementalExport, previousHeads))
+
+		closeNodeA()
+		require.NoError(t, dsA.Close())
+	}
+
+	dsB := dsync.MutexWrap(ds.NewMapDatastore())
+	ipfsNodeB, cleanupNodeB := ipfsutil.TestingCoreAPIUsingMockNet(ctx, t, &ipfsutil.TestingAPIOpts{
+		Mocknet:   mn,
+		RDVPeer:   rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+		Datastore: dsB,
+	})
+	defer cleanupNodeB()
+
+	dksB := NewDeviceKeystore(ipfsutil.NewDatastoreKeystore(ipfsutil.NewNamespacedDatastore(dsB, ds.NewKey(NamespaceDeviceKeystore))))
+
+	odb, err := NewBertyOrbitDB(ctx, ipfsNodeB.API(), &NewOrbitDBOptions{
+		NewOrbitDBOptions: orbitdb.NewOrbitDBOptions{
+			PubSub: pubsubraw.NewPubSub(ipfsNodeB.PubSub(), ipfsNodeB.MockNode().PeerHost.ID(), logger, nil),
+			Logger: logger,
+		},
+		Datastore:      dsB,
+		DeviceKeystore: dksB,
+	})
+	require.NoError(t, err)
+
+	// the incremental export can't be restored before the export it is based on
+	require.Error(t, RestoreAccountExport(ctx, bytes.NewReader(incrementalExport.Bytes()), nil, ipfsNodeB.API(), odb, logger))
+
+	require.NoError(t, RestoreAccountExport(ctx, bytes.NewReader(fullExport.Bytes()), nil, ipfsNodeB.API(), odb, logger))
+	require.NoError(t, RestoreAccountExport(ctx, bytes.NewReader(incrementalExport.Bytes()), nil, ipfsNodeB.API(), odb, logger))
+
+	nodeB, closeNodeB := NewTestingProtocol(ctx, t, &TestingOpts{
+		Mocknet:        mn,
+		RDVPeer:        rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+		DeviceKeystore: dksB,
+		CoreAPIMock:    ipfsNodeB,
+		OrbitDB:        odb,
+	}, dsB)
+	defer closeNodeB()
+
+	nodeBInstanceConfig, err := nodeB.Client.InstanceGetConfiguration(ctx, &protocoltypes.InstanceGetConfiguration_Request{})
+	require.NoError(t, err)
+	require.Equal(t, nodeAInstanceConfig.AccountPK, nodeBInstanceConfig.AccountPK)
+
+	sub, err := nodeB.Client.GroupMessageList(ctx, &protocoltypes.GroupMessageList_Request{
+		GroupPK:  nodeBInstanceConfig.AccountGroupPK,
+		UntilNow: true,
+	})
+	require.NoError(t, err)
+
+	for {
+		evt, err := sub.Recv()
+		if err != nil {
+			require.Equal(t, io.EOF, err)
+			break
+		}
+
+		id, err := cid.Parse(evt.EventContext.ID)
+		require.NoError(t, err)
+
+		ref, ok := expectedMessages[id]
+		require.True(t, ok)
+		require.Equal(t, ref, evt.Message)
+
+		delete(expectedMessages, id)
+	}
+
+	require.Empty(t, expectedMessages)
+}
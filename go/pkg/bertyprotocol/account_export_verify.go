@@ -0,0 +1,348 @@
+package bertyprotocol
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"go.uber.org/zap"
+
+	"berty.tech/berty/v2/go/pkg/errcode"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
+	ipfslogentry "berty.tech/go-ipfs-log/entry"
+	ipliface "berty.tech/go-ipfs-log/iface"
+	ipfslogcbor "berty.tech/go-ipfs-log/io/cbor"
+)
+
+// AccountExportVerification is the outcome of VerifyAccountExport
+type AccountExportVerification struct {
+	// Keys lists the account keys found in the export
+	Keys []string
+
+	// Groups lists the groups for which heads have been exported
+	Groups []*protocoltypes.GroupHeadsExport
+
+	// Entries is the number of orbit-db entries found in the export
+	Entries int
+
+	// Incremental is true if the export only contains the entries added since a previous export
+	Incremental bool
+
+	// MissingEntries lists the CIDs of the entries reachable from the exported heads which are neither in the export
+	// nor in the previous exports
+	MissingEntries []string
+
+	// InvalidEntries lists the entries which can't be decoded, don't match their CID or are not signed by their group
+	InvalidEntries []string
+}
+
+// Valid returns true if the export can be restored
+func (v *AccountExportVerification) Valid() bool {
+	return len(v.Keys) == 2 && len(v.MissingEntries) == 0 && len(v.InvalidEntries) == 0
+}
+
+type exportVerifiedEntry struct {
+	links       []cid.Cid
+	identityID  string
+	identitySig []byte
+	logEntry    ipliface.IPFSLogEntry
+}
+
+type exportVerificationState struct {
+	report  *AccountExportVerification
+	entries map[string]*exportVerifiedEntry
+	logIO   ipliface.IO
+}
+
+func (state *exportVerificationState) invalidEntry(cidStr string, err error) {
+	state.report.InvalidEntries = append(state.report.InvalidEntries, fmt.Sprintf("%s: %v", cidStr, err))
+}
+
+func (state *exportVerificationState) readKey(keyName string) RestoreAccountHandler {
+	return RestoreAccountHandler{
+		Handler: func(header *tar.Header, reader *tar.Reader) (bool, error) {
+			if header.Name != keyName {
+				return false, nil
+			}
+
+			if _, err := readExportSecretKeyFile(header.Size, reader); err != nil {
+				return true, errcode.ErrInternal.Wrap(err)
+			}
+
+			state.report.Keys = append(state.report.Keys, keyName)
+
+			return true, nil
+		},
+	}
+}
+
+func (state *exportVerificationState) readEntry(count bool) RestoreAccountHandler {
+	return RestoreAccountHandler{
+		Handler: func(header *tar.Header, reader *tar.Reader) (bool, error) {
+			if !strings.HasPrefix(header.Name, exportOrbitDBEntriesPrefix) {
+				return false, nil
+			}
+
+			cidStr := strings.TrimPrefix(header.Name, exportOrbitDBEntriesPrefix)
+
+			if count {
+				state.report.Entries++
+			}
+
+			node, err := readExportCBORNode(header.Size, cidStr, reader)
+			if err != nil {
+				state.invalidEntry(cidStr, err)
+				return true, nil
+			}
+
+			entry, err := newExportVerifiedEntry(node, state.logIO)
+			if err != nil {
+				state.invalidEntry(cidStr, err)
+				return true, nil
+			}
+
+			state.entries[node.Cid().String()] = entry
+
+			return true, nil
+		},
+	}
+}
+
+func (state *exportVerificationState) readHeads() RestoreAccountHandler {
+	return RestoreAccountHandler{
+		Handler: func(header *tar.Header, reader *tar.Reader) (bool, error) {
+			if !strings.HasPrefix(header.Name, exportOrbitDBHeadsPrefix) {
+				return false, nil
+			}
+
+			heads, _, _, err := readExportOrbitDBGroupHeads(header.Size, reader)
+			if err != nil {
+				return true, errcode.ErrInternal.Wrap(err)
+			}
+
+			state.report.Groups = append(state.report.Groups, heads)
+
+			return true, nil
+		},
+	}
+}
+
+func (state *exportVerificationState) readBaseHeads() RestoreAccountHandler {
+	return RestoreAccountHandler{
+		Handler: func(header *tar.Header, _ *tar.Reader) (bool, error) {
+			if !strings.HasPrefix(header.Name, exportOrbitDBBaseHeadsPrefix) {
+				return false, nil
+			}
+
+			state.report.Incremental = true
+
+			return true, nil
+		},
+	}
+}
+
+// skipExportEntries ignores everything but the orbit-db entries, used when reading previous exports
+func skipExportEntries() RestoreAccountHandler {
+	return RestoreAccountHandler{
+		Handler: func(header *tar.Header, _ *tar.Reader) (bool, error) {
+			return !strings.HasPrefix(header.Name, exportOrbitDBEntriesPrefix), nil
+		},
+	}
+}
+
+func newExportVerifiedEntry(node *cbornode.Node, logIO ipliface.IO) (*exportVerifiedEntry, error) {
+	logEntry, err := logIO.DecodeRawEntry(node, node.Cid(), &bertySignedIdentityProvider{})
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	identityID, _, err := node.Resolve([]string{"identity", "id"})
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	identitySig, _, err := node.Resolve([]string{"identity", "signatures", "id"})
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	identityIDStr, ok := identityID.(string)
+	if !ok {
+		return nil, errcode.ErrDeserialization.Wrap(fmt.Errorf("invalid identity id"))
+	}
+
+	identitySigStr, ok := identitySig.(string)
+	if !ok {
+		return nil, errcode.ErrDeserialization.Wrap(fmt.Errorf("invalid identity signature"))
+	}
+
+	identitySigBytes, err := hex.DecodeString(identitySigStr)
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	links := node.Links()
+	entry := &exportVerifiedEntry{
+		links:       make([]cid.Cid, len(links)),
+		identityID:  identityIDStr,
+		identitySig: identitySigBytes,
+		logEntry:    logEntry,
+	}
+
+	for i, l := range links {
+		entry.links[i] = l.Cid
+	}
+
+	return entry, nil
+}
+
+// verifyEntrySignature checks the signature of the entry itself, it must have been made using the group signing key
+func (state *exportVerificationState) verifyEntrySignature(entry *exportVerifiedEntry, signPub crypto.PubKey) error {
+	key, err := crypto.UnmarshalPublicKey(entry.logEntry.GetKey())
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if !key.Equals(signPub) {
+		return errcode.ErrCryptoSignatureVerification.Wrap(fmt.Errorf("entry is not signed by the group"))
+	}
+
+	if err := entry.logEntry.Verify(&bertySignedIdentityProvider{}, state.logIO); err != nil {
+		return errcode.ErrCryptoSignatureVerification.Wrap(err)
+	}
+
+	return nil
+}
+
+// verifyGroupEntries walks the entries reachable from the heads of a group, entries and their identity must be
+// signed by the group signing key as done by the orbit-db identity provider
+func (state *exportVerificationState) verifyGroupEntries(heads *protocoltypes.GroupHeadsExport, missing map[string]struct{}, visited map[string]struct{}) error {
+	signPub, err := crypto.UnmarshalEd25519PublicKey(heads.SignPub)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	metaCIDs, messagesCIDs, err := parseGroupHeadsExportCIDs(heads)
+	if err != nil {
+		return err
+	}
+
+	expectedID := hex.EncodeToString(heads.SignPub)
+	pending := append(metaCIDs, messagesCIDs...)
+
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		cidStr := id.String()
+		if _, ok := visited[cidStr]; ok {
+			continue
+		}
+		visited[cidStr] = struct{}{}
+
+		entry, ok := state.entries[cidStr]
+		if !ok {
+			missing[cidStr] = struct{}{}
+			continue
+		}
+
+		if entry.identityID != expectedID {
+			state.invalidEntry(cidStr, fmt.Errorf("entry is not signed by the group"))
+		} else if valid, err := signPub.Verify([]byte(entry.identityID), entry.identitySig); err != nil || !valid {
+			state.invalidEntry(cidStr, errcode.ErrCryptoSignatureVerification)
+		} else if err := state.verifyEntrySignature(entry, signPub); err != nil {
+			state.invalidEntry(cidStr, err)
+		}
+
+		pending = append(pending, entry.links...)
+	}
+
+	return nil
+}
+
+// VerifyAccountExport checks an export without restoring it: the account keys must be present, each entry must
+// match its CID and be signed by its group, and every entry reachable from the exported heads must be either in the
+// export or in one of the previous exports an incremental export is based on. The signatures of the messages
+// contained in the entries payloads are checked when the groups are opened and are not verified here.
+func VerifyAccountExport(reader io.Reader, passphrase []byte, logger *zap.Logger, previous ...io.Reader) (*AccountExportVerification, error) {
+	logIO, err := ipfslogcbor.IO(&ipfslogentry.Entry{}, &ipfslogentry.LamportClock{})
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	state := &exportVerificationState{
+		report:  &AccountExportVerification{},
+		entries: map[string]*exportVerifiedEntry{},
+		logIO:   logIO,
+	}
+
+	for _, r := range previous {
+		if err := walkAccountExport(r, passphrase, logger, []RestoreAccountHandler{
+			state.readEntry(false),
+			skipExportEntries(),
+		}); err != nil {
+			return nil, errcode.ErrInternal.Wrap(fmt.Errorf("unable to read previous export: %w", err))
+		}
+	}
+
+	if err := walkAccountExport(reader, passphrase, logger, []RestoreAccountHandler{
+		state.readKey(exportAccountKeyFilename),
+		state.readKey(exportAccountProofKeyFilename),
+		state.readEntry(true),
+		state.readHeads(),
+		state.readBaseHeads(),
+	}); err != nil {
+		return nil, err
+	}
+
+	missing := map[string]struct{}{}
+	visited := map[string]struct{}{}
+
+	for _, heads := range state.report.Groups {
+		if err := state.verifyGroupEntries(heads, missing, visited); err != nil {
+			return nil, errcode.ErrInternal.Wrap(err)
+		}
+	}
+
+	for cidStr := range missing {
+		state.report.MissingEntries = append(state.report.MissingEntries, cidStr)
+	}
+	sort.Strings(state.report.MissingEntries)
+
+	return state.report, nil
+}
+
+// ReadAccountExportHeads returns the heads of the groups of an export, they can be used as the previous heads of an
+// incremental export
+func ReadAccountExportHeads(reader io.Reader, passphrase []byte, logger *zap.Logger) ([]*protocoltypes.GroupHeadsExport, error) {
+	var groupsHeads []*protocoltypes.GroupHeadsExport
+
+	if err := walkAccountExport(reader, passphrase, logger, []RestoreAccountHandler{
+		{
+			Handler: func(header *tar.Header, reader *tar.Reader) (bool, error) {
+				if !strings.HasPrefix(header.Name, exportOrbitDBHeadsPrefix) {
+					return true, nil
+				}
+
+				heads, _, _, err := readExportOrbitDBGroupHeads(header.Size, reader)
+				if err != nil {
+					return true, errcode.ErrInternal.Wrap(err)
+				}
+
+				groupsHeads = append(groupsHeads, heads)
+
+				return true, nil
+			},
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return groupsHeads, nil
+}
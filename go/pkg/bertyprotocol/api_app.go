@@ -84,6 +84,15 @@ func (s *service) AppMessageSend(ctx context.Context, req *protocoltypes.AppMess
 		)...,
 	)
 
+	// a secret known by a removed member is replaced before sending a new message with it
+	if rotate, err := gc.MetadataStore().SecretNeedsRotation(); err != nil {
+		return nil, err
+	} else if rotate {
+		if err := gc.MetadataStore().RotateSecret(ctx); err != nil {
+			return nil, errcode.ErrCryptoKeyGeneration.Wrap(err)
+		}
+	}
+
 	if _, err := gc.MessageStore().AddMessage(ctx, req.Payload, req.GetAttachmentCIDs()); err != nil {
 		s.logger.Error(
 			"Writing metadata on metadata store failed",
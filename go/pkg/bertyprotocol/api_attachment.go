@@ -1,6 +1,7 @@
 package bertyprotocol
 
 import (
+	"context"
 	"errors"
 
 	ipfscid "github.com/ipfs/go-cid"
@@ -105,6 +106,29 @@ func (s *service) AttachmentRetrieve(req *protocoltypes.AttachmentRetrieve_Reque
 	return nil
 }
 
+func (s *service) AttachmentDelete(ctx context.Context, req *protocoltypes.AttachmentDelete_Request) (*protocoltypes.AttachmentDelete_Reply, error) {
+	cid, err := ipfscid.Cast(req.GetAttachmentCID())
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if err := s.deviceKeystore.AttachmentSecretDelete(req.GetAttachmentCID()); err != nil {
+		return nil, err
+	}
+
+	// received attachments are not always pinned
+	p := ipfspath.IpfsPath(cid)
+	if _, pinned, err := s.ipfsCoreAPI.Pin().IsPinned(ctx, p); err != nil {
+		return nil, errcode.ErrIPFSGet.Wrap(err)
+	} else if pinned {
+		if err := s.ipfsCoreAPI.Pin().Rm(ctx, p); err != nil {
+			return nil, errcode.ErrIPFSUnpin.Wrap(err)
+		}
+	}
+
+	return &protocoltypes.AttachmentDelete_Reply{}, nil
+}
+
 func attachmentForcePin(settings *ipfsoptions.UnixfsAddSettings) error {
 	if settings == nil {
 		return errcode.ErrInvalidInput.Wrap(errors.New("nil ipfs settings"))
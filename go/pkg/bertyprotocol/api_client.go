@@ -5,13 +5,25 @@ import (
 	"io"
 	"sync"
 
+	"github.com/libp2p/go-libp2p-core/crypto"
+
 	"berty.tech/berty/v2/go/pkg/errcode"
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
 )
 
-func (s *service) InstanceExportData(_ *protocoltypes.InstanceExportData_Request, server protocoltypes.ProtocolService_InstanceExportDataServer) error {
+func (s *service) InstanceExportData(req *protocoltypes.InstanceExportData_Request, server protocoltypes.ProtocolService_InstanceExportDataServer) error {
 	r, w := io.Pipe()
 
+	var exported io.Reader = r
+	if len(req.Passphrase) > 0 {
+		sealed, err := SealExport(r, req.Passphrase, s.logger)
+		if err != nil {
+			return err
+		}
+
+		exported = sealed
+	}
+
 	var exportErr error
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -22,7 +34,7 @@ func (s *service) InstanceExportData(_ *protocoltypes.InstanceExportData_Request
 
 		for {
 			contents := make([]byte, 4096)
-			l, err := r.Read(contents)
+			l, err := exported.Read(contents)
 
 			if err == io.EOF {
 				break
@@ -38,7 +50,7 @@ func (s *service) InstanceExportData(_ *protocoltypes.InstanceExportData_Request
 		}
 	}()
 
-	if err := s.export(server.Context(), w); err != nil {
+	if err := s.export(server.Context(), w, req.PreviousHeads); err != nil {
 		return errcode.ErrInternal.Wrap(err)
 	}
 	_ = w.Close()
@@ -86,3 +98,65 @@ func (s *service) InstanceGetConfiguration(ctx context.Context, req *protocoltyp
 		Listeners:      listeners,
 	}, nil
 }
+
+func (s *service) DeviceLinkInvitationCreate(ctx context.Context, req *protocoltypes.DeviceLinkInvitationCreate_Request) (*protocoltypes.DeviceLinkInvitationCreate_Reply, error) {
+	invitation, err := s.createDeviceLinkInvitation(ctx)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	return &protocoltypes.DeviceLinkInvitationCreate_Reply{
+		Invitation: invitation,
+	}, nil
+}
+
+func (s *service) AccountDeviceRevoke(ctx context.Context, req *protocoltypes.AccountDeviceRevoke_Request) (*protocoltypes.AccountDeviceRevoke_Reply, error) {
+	devicePK, err := crypto.UnmarshalEd25519PublicKey(req.DevicePK)
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if _, err := s.accountGroup.MetadataStore().RevokeDevice(ctx, devicePK); err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	groups := make([]*groupContext, 0, len(s.openedGroups))
+	for _, gc := range s.openedGroups {
+		groups = append(groups, gc)
+	}
+	s.lock.Unlock()
+
+	for _, gc := range groups {
+		if gc == s.accountGroup {
+			continue
+		}
+
+		if err := s.revokeDevicesInGroup(ctx, gc); err != nil {
+			return nil, err
+		}
+	}
+
+	return &protocoltypes.AccountDeviceRevoke_Reply{}, nil
+}
+
+func (s *service) DeviceList(ctx context.Context, req *protocoltypes.DeviceList_Request) (*protocoltypes.DeviceList_Reply, error) {
+	devices := s.accountGroup.MetadataStore().ListDevices()
+	reply := &protocoltypes.DeviceList_Reply{
+		Devices: make([]*protocoltypes.DeviceList_Device, len(devices)),
+	}
+
+	for i, device := range devices {
+		devicePK, err := device.Raw()
+		if err != nil {
+			return nil, errcode.ErrSerialization.Wrap(err)
+		}
+
+		reply.Devices[i] = &protocoltypes.DeviceList_Device{
+			DevicePK: devicePK,
+			Current:  device.Equals(s.accountGroup.DevicePubKey()),
+		}
+	}
+
+	return reply, nil
+}
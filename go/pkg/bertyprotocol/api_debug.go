@@ -3,6 +3,7 @@ package bertyprotocol
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"go.uber.org/zap"
 
 	"berty.tech/berty/v2/go/internal/sysutil"
+	"berty.tech/berty/v2/go/internal/tinder"
 	"berty.tech/berty/v2/go/pkg/errcode"
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
 	"berty.tech/go-orbit-db/stores/operation"
@@ -201,6 +203,11 @@ func (s *service) SystemInfo(ctx context.Context, request *protocoltypes.SystemI
 			errs = multierr.Append(errs, fmt.Errorf("no such IPFS core API"))
 		}
 
+		// discovery metrics
+		if sp, ok := s.tinderDriver.(tinder.StatsProvider); ok {
+			reply.P2P.DiscoveryDrivers = discoveryDriversInfo(sp.DriversStats())
+		}
+
 		// pubsub metrics
 		// TODO
 
@@ -230,6 +237,39 @@ func (s *service) SystemInfo(ctx context.Context, request *protocoltypes.SystemI
 	return &reply, nil
 }
 
+func discoveryDriversInfo(stats []*tinder.DriverStats) []*protocoltypes.SystemInfo_DiscoveryDriver {
+	drivers := make([]*protocoltypes.SystemInfo_DiscoveryDriver, len(stats))
+	for i, stat := range stats {
+		driver := &protocoltypes.SystemInfo_DiscoveryDriver{
+			Name:                stat.Name,
+			AdvertiseSuccess:    stat.AdvertiseSuccess,
+			AdvertiseFailure:    stat.AdvertiseFailure,
+			FindPeersSuccess:    stat.FindPeersSuccess,
+			FindPeersFailure:    stat.FindPeersFailure,
+			AverageLatencyMS:    stat.AverageLatency.Milliseconds(),
+			ConsecutiveFailures: stat.ConsecutiveFailures,
+			LastError:           stat.LastError,
+			Score:               stat.Score(),
+		}
+
+		if !stat.BackoffUntil.IsZero() {
+			driver.BackoffUntil = stat.BackoffUntil.UnixNano() / int64(time.Millisecond)
+		}
+
+		for ns, peers := range stat.PeersFound {
+			driver.PeersFound = append(driver.PeersFound, &protocoltypes.SystemInfo_NamespacePeers{
+				Namespace: ns,
+				Peers:     peers,
+			})
+		}
+		sort.Slice(driver.PeersFound, func(i, j int) bool { return driver.PeersFound[i].Peers > driver.PeersFound[j].Peers })
+
+		drivers[i] = driver
+	}
+
+	return drivers
+}
+
 func (s *service) PeerList(ctx context.Context, request *protocoltypes.PeerList_Request) (*protocoltypes.PeerList_Reply, error) {
 	reply := protocoltypes.PeerList_Reply{}
 	api := s.IpfsCoreAPI()
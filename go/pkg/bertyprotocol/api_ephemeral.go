@@ -0,0 +1,97 @@
+package bertyprotocol
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"berty.tech/berty/v2/go/pkg/errcode"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
+)
+
+func (s *service) EphemeralMessageSend(ctx context.Context, req *protocoltypes.EphemeralMessageSend_Request) (*protocoltypes.EphemeralMessageSend_Reply, error) {
+	gc, err := s.getContextGroupForID(req.GroupPK)
+	if err != nil {
+		return nil, errcode.ErrGroupMissing.Wrap(err)
+	}
+
+	topic, err := ephemeralTopicForGroup(gc.Group())
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := sealEphemeralMessage(gc.Group(), gc.memberDevice.device, req.Payload, time.Now())
+	if err != nil {
+		return nil, errcode.ErrCryptoEncrypt.Wrap(err)
+	}
+
+	if err := s.ipfsCoreAPI.PubSub().Publish(ctx, topic, env); err != nil {
+		return nil, errcode.ErrStreamWrite.Wrap(err)
+	}
+
+	return &protocoltypes.EphemeralMessageSend_Reply{}, nil
+}
+
+func (s *service) EphemeralMessageSubscribe(req *protocoltypes.EphemeralMessageSubscribe_Request, sub protocoltypes.ProtocolService_EphemeralMessageSubscribeServer) error {
+	gc, err := s.getContextGroupForID(req.GroupPK)
+	if err != nil {
+		return errcode.ErrGroupMissing.Wrap(err)
+	}
+
+	topic, err := ephemeralTopicForGroup(gc.Group())
+	if err != nil {
+		return err
+	}
+
+	ps, err := s.ipfsCoreAPI.PubSub().Subscribe(sub.Context(), topic)
+	if err != nil {
+		return errcode.ErrStreamRead.Wrap(err)
+	}
+	defer ps.Close()
+
+	for {
+		msg, err := ps.Next(sub.Context())
+		if err != nil {
+			if sub.Context().Err() != nil {
+				return nil
+			}
+
+			return errcode.ErrStreamRead.Wrap(err)
+		}
+
+		em, devicePK, err := openEphemeralMessage(gc.Group(), msg.Data(), time.Now())
+		if err != nil {
+			gc.logger.Debug("ignoring invalid ephemeral message", zap.Error(err))
+			continue
+		}
+
+		// messages sent by the current device are delivered back by pubsub
+		if devicePK.Equals(gc.DevicePubKey()) {
+			continue
+		}
+
+		// anyone knowing the group secret can sign a message, only the devices of the members are trusted
+		memberPK, err := gc.MetadataStore().GetMemberByDevice(devicePK)
+		if err != nil {
+			gc.logger.Debug("ignoring ephemeral message from an unknown device", zap.Error(err))
+			continue
+		}
+
+		mpkb, err := memberPK.Raw()
+		if err != nil {
+			return errcode.ErrSerialization.Wrap(err)
+		}
+
+		evt := &protocoltypes.EphemeralMessageEvent{
+			GroupPK:  req.GroupPK,
+			DevicePK: em.DevicePK,
+			MemberPK: mpkb,
+			Payload:  em.Payload,
+		}
+
+		if err := sub.Send(evt); err != nil {
+			return errcode.ErrStreamWrite.Wrap(err)
+		}
+	}
+}
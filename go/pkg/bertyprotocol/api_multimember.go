@@ -89,8 +89,41 @@ func (s *service) MultiMemberGroupAliasResolverDisclose(ctx context.Context, req
 }
 
 // MultiMemberGroupAdminRoleGrant grants admin role to another member of the group
-func (s *service) MultiMemberGroupAdminRoleGrant(context.Context, *protocoltypes.MultiMemberGroupAdminRoleGrant_Request) (*protocoltypes.MultiMemberGroupAdminRoleGrant_Reply, error) {
-	return nil, errcode.ErrNotImplemented
+func (s *service) MultiMemberGroupAdminRoleGrant(ctx context.Context, req *protocoltypes.MultiMemberGroupAdminRoleGrant_Request) (*protocoltypes.MultiMemberGroupAdminRoleGrant_Reply, error) {
+	cg, err := s.getContextGroupForID(req.GroupPK)
+	if err != nil {
+		return nil, errcode.ErrGroupMemberUnknownGroupID.Wrap(err)
+	}
+
+	memberPK, err := crypto.UnmarshalEd25519PublicKey(req.MemberPK)
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if _, err := cg.MetadataStore().GrantAdminRole(ctx, memberPK); err != nil {
+		return nil, err
+	}
+
+	return &protocoltypes.MultiMemberGroupAdminRoleGrant_Reply{}, nil
+}
+
+// MultiMemberGroupMemberRemove removes a member from a multi-member group, the remaining members rotate their device secrets
+func (s *service) MultiMemberGroupMemberRemove(ctx context.Context, req *protocoltypes.MultiMemberGroupMemberRemove_Request) (*protocoltypes.MultiMemberGroupMemberRemove_Reply, error) {
+	cg, err := s.getContextGroupForID(req.GroupPK)
+	if err != nil {
+		return nil, errcode.ErrGroupMemberUnknownGroupID.Wrap(err)
+	}
+
+	memberPK, err := crypto.UnmarshalEd25519PublicKey(req.MemberPK)
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if _, err := cg.MetadataStore().RemoveMember(ctx, memberPK); err != nil {
+		return nil, err
+	}
+
+	return &protocoltypes.MultiMemberGroupMemberRemove_Reply{}, nil
 }
 
 // MultiMemberGroupInvitationCreate creates a group invitation
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -13,24 +14,19 @@ import (
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
 )
 
-func (s *service) ReplicationServiceRegisterGroup(ctx context.Context, request *protocoltypes.ReplicationServiceRegisterGroup_Request) (*protocoltypes.ReplicationServiceRegisterGroup_Reply, error) {
-	gc, err := s.getContextGroupForID(request.GroupPK)
-	if err != nil {
-		return nil, errcode.ErrInvalidInput.Wrap(err)
-	}
-
-	replGroup, err := gc.group.FilterForReplication()
+// dialReplicationService connects to the replication server of a service token, the endpoint of the server is returned
+func (s *service) dialReplicationService(tokenID string) (*grpc.ClientConn, *protocoltypes.ServiceToken, string, error) {
+	token, err := s.accountGroup.metadataStore.getServiceToken(tokenID)
 	if err != nil {
-		return nil, errcode.TODO.Wrap(err)
+		return nil, nil, "", errcode.ErrInvalidInput.Wrap(err)
 	}
 
-	token, err := s.accountGroup.metadataStore.getServiceToken(request.TokenID)
-	if err != nil {
-		return nil, errcode.ErrInvalidInput.Wrap(err)
+	if token == nil {
+		return nil, nil, "", errcode.ErrInvalidInput.Wrap(fmt.Errorf("invalid token"))
 	}
 
-	if token == nil {
-		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("invalid token"))
+	if token.Expired(time.Now()) {
+		return nil, nil, "", errcode.ErrServicesAuthTokenExpired
 	}
 
 	endpoint := ""
@@ -44,7 +40,7 @@ func (s *service) ReplicationServiceRegisterGroup(ctx context.Context, request *
 	}
 
 	if endpoint == "" {
-		return nil, errcode.ErrServiceReplicationMissingEndpoint
+		return nil, nil, "", errcode.ErrServiceReplicationMissingEndpoint
 	}
 
 	cc, err := grpc.Dial(endpoint, []grpc.DialOption{
@@ -52,9 +48,30 @@ func (s *service) ReplicationServiceRegisterGroup(ctx context.Context, request *
 		grpc.WithInsecure(), // TODO: remove this, enforce security
 	}...)
 	if err != nil {
-		return nil, errcode.ErrStreamWrite.Wrap(err)
+		return nil, nil, "", errcode.ErrStreamWrite.Wrap(err)
 	}
 
+	return cc, token, endpoint, nil
+}
+
+func (s *service) ReplicationServiceRegisterGroup(ctx context.Context, request *protocoltypes.ReplicationServiceRegisterGroup_Request) (*protocoltypes.ReplicationServiceRegisterGroup_Reply, error) {
+	gc, err := s.getContextGroupForID(request.GroupPK)
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	replGroup, err := gc.group.FilterForReplication()
+	if err != nil {
+		return nil, errcode.TODO.Wrap(err)
+	}
+
+	cc, token, endpoint, err := s.dialReplicationService(request.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = cc.Close() }()
+
 	client := NewReplicationServiceClient(cc)
 
 	if _, err = client.ReplicateGroup(ctx, &protocoltypes.ReplicationServiceReplicateGroup_Request{
@@ -71,3 +88,51 @@ func (s *service) ReplicationServiceRegisterGroup(ctx context.Context, request *
 
 	return &protocoltypes.ReplicationServiceRegisterGroup_Reply{}, nil
 }
+
+func (s *service) ReplicationServiceUnregisterGroup(ctx context.Context, request *protocoltypes.ReplicationServiceUnregisterGroup_Request) (*protocoltypes.ReplicationServiceUnregisterGroup_Reply, error) {
+	gc, err := s.getContextGroupForID(request.GroupPK)
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	cc, _, endpoint, err := s.dialReplicationService(request.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = cc.Close() }()
+
+	client := NewReplicationServiceClient(cc)
+
+	if _, err = client.UnreplicateGroup(ctx, &protocoltypes.ReplicationServiceUnreplicateGroup_Request{
+		GroupPK: request.GroupPK,
+	}); err != nil {
+		return nil, errcode.ErrServiceReplicationServer.Wrap(err)
+	}
+
+	s.logger.Info("group won't be replicated anymore", zap.String("public-key", base64.RawURLEncoding.EncodeToString(request.GroupPK)))
+
+	if _, err := gc.metadataStore.SendGroupReplicatingStopped(ctx, endpoint); err != nil {
+		s.logger.Error("error while notifying group about replication", zap.Error(err))
+	}
+
+	return &protocoltypes.ReplicationServiceUnregisterGroup_Reply{}, nil
+}
+
+func (s *service) ReplicationServiceListGroups(ctx context.Context, request *protocoltypes.ReplicationServiceListGroups_Request) (*protocoltypes.ReplicationServiceListGroups_Reply, error) {
+	cc, _, _, err := s.dialReplicationService(request.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = cc.Close() }()
+
+	client := NewReplicationServiceClient(cc)
+
+	res, err := client.ListReplicatedGroups(ctx, &protocoltypes.ReplicationServiceListReplicatedGroups_Request{})
+	if err != nil {
+		return nil, errcode.ErrServiceReplicationServer.Wrap(err)
+	}
+
+	return &protocoltypes.ReplicationServiceListGroups_Reply{GroupPKs: res.GroupPKs}, nil
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"golang.org/x/net/context/ctxhttp"
 
@@ -32,6 +33,7 @@ type authExchangeResponse struct {
 	Error            string            `json:"error"`
 	ErrorDescription string            `json:"error_description"`
 	Services         map[string]string `json:"services"`
+	ExpiresIn        int64             `json:"expires_in"`
 }
 
 type authSession struct {
@@ -205,11 +207,16 @@ func (s *service) AuthServiceCompleteFlow(ctx context.Context, request *protocol
 		i++
 	}
 
+	expiration := int64(-1)
+	if resMsg.ExpiresIn > 0 {
+		expiration = time.Now().Add(time.Duration(resMsg.ExpiresIn)*time.Second).UnixNano() / int64(time.Millisecond)
+	}
+
 	if _, err := s.accountGroup.metadataStore.SendAccountServiceTokenAdded(ctx, &protocoltypes.ServiceToken{
 		Token:             resMsg.AccessToken,
 		AuthenticationURL: auth.baseURL,
 		SupportedServices: services,
-		Expiration:        -1,
+		Expiration:        expiration,
 	}); err != nil {
 		return nil, err
 	}
@@ -230,11 +237,17 @@ func (s *service) AuthServiceInitFlow(ctx context.Context, request *protocoltype
 }
 
 func (s *service) ServicesTokenList(request *protocoltypes.ServicesTokenList_Request, server protocoltypes.ProtocolService_ServicesTokenListServer) error {
+	now := time.Now()
+
 	for _, t := range s.accountGroup.metadataStore.listServiceTokens() {
 		if server.Context().Err() != nil {
 			break
 		}
 
+		if t.Expired(now) {
+			continue
+		}
+
 		if err := server.Send(&protocoltypes.ServicesTokenList_Reply{
 			TokenID: t.TokenID(),
 			Service: t,
@@ -245,3 +258,15 @@ func (s *service) ServicesTokenList(request *protocoltypes.ServicesTokenList_Req
 
 	return nil
 }
+
+func (s *service) ServicesTokenRemove(ctx context.Context, request *protocoltypes.ServicesTokenRemove_Request) (*protocoltypes.ServicesTokenRemove_Reply, error) {
+	if request.TokenID == "" {
+		return nil, errcode.ErrMissingInput
+	}
+
+	if _, err := s.accountGroup.metadataStore.SendAccountServiceTokenRemoved(ctx, request.TokenID); err != nil {
+		return nil, err
+	}
+
+	return &protocoltypes.ServicesTokenRemove_Reply{}, nil
+}
@@ -10,6 +10,7 @@ const (
 	NamespaceOrbitDBDatastore = "orbitdb_datastore"
 	NamespaceOrbitDBDirectory = "orbitdb"
 	NamespaceIPFSDatastore    = "ipfs_datastore"
+	NamespacePeerCache        = "peer_cache"
 )
 
 var InMemoryDirectory = cacheleveldown.InMemoryDirectory
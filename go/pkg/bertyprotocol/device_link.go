@@ -0,0 +1,364 @@
+package bertyprotocol
+
+import (
+	"context"
+	"crypto/ed25519"
+	crand "crypto/rand"
+	"fmt"
+	"time"
+
+	ggio "github.com/gogo/protobuf/io"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/nacl/box"
+
+	"berty.tech/berty/v2/go/internal/cryptoutil"
+	"berty.tech/berty/v2/go/internal/handshake"
+	"berty.tech/berty/v2/go/internal/ipfsutil"
+	"berty.tech/berty/v2/go/pkg/errcode"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
+)
+
+const (
+	deviceLinkV1             = "/berty/device_link/1.0.0"
+	deviceLinkInvitationTTL  = 10 * time.Minute
+	deviceLinkMaxMessageSize = 1024 * 1024
+)
+
+type deviceLinkInvitation struct {
+	responderSK crypto.PrivKey
+	requesterPK crypto.PubKey
+	expiresAt   time.Time
+}
+
+// createDeviceLinkInvitation replaces the pending invitation, if any, by a
+// new one and waits for the new device to connect
+func (s *service) createDeviceLinkInvitation(ctx context.Context) (*protocoltypes.DeviceLinkInvitation, error) {
+	responderSK, responderPK, err := crypto.GenerateEd25519Key(crand.Reader)
+	if err != nil {
+		return nil, errcode.ErrCryptoKeyGeneration.Wrap(err)
+	}
+
+	responderPKBytes, err := responderPK.Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	secret := make([]byte, ed25519.SeedSize)
+	if _, err := crand.Read(secret); err != nil {
+		return nil, errcode.ErrCryptoRandomGeneration.Wrap(err)
+	}
+
+	key, err := s.ipfsCoreAPI.Key().Self(ctx)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	maddrs, err := s.ipfsCoreAPI.Swarm().ListenAddrs(ctx)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	addrs := make([]string, len(maddrs))
+	for i, addr := range maddrs {
+		addrs[i] = addr.String()
+	}
+
+	invitation := &protocoltypes.DeviceLinkInvitation{
+		PeerID:      key.ID().Pretty(),
+		Addrs:       addrs,
+		ResponderPK: responderPKBytes,
+		Secret:      secret,
+	}
+
+	requesterSK, err := invitation.GetRequesterPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	s.deviceLinkLock.Lock()
+	defer s.deviceLinkLock.Unlock()
+
+	s.deviceLinkInvitation = &deviceLinkInvitation{
+		responderSK: responderSK,
+		requesterPK: requesterSK.GetPublic(),
+		expiresAt:   time.Now().Add(deviceLinkInvitationTTL),
+	}
+
+	s.ipfsCoreAPI.SetStreamHandler(deviceLinkV1, s.deviceLinkHandler)
+
+	return invitation, nil
+}
+
+// consumeDeviceLinkInvitation removes the given invitation, it returns false
+// if the invitation has already been used or replaced
+func (s *service) consumeDeviceLinkInvitation(invitation *deviceLinkInvitation) bool {
+	s.deviceLinkLock.Lock()
+	defer s.deviceLinkLock.Unlock()
+
+	if s.deviceLinkInvitation != invitation {
+		return false
+	}
+
+	s.deviceLinkInvitation = nil
+	s.ipfsCoreAPI.RemoveStreamHandler(deviceLinkV1)
+
+	return true
+}
+
+func (s *service) deviceLinkHandler(stream network.Stream) {
+	defer func() {
+		if err := ipfsutil.FullClose(stream); err != nil {
+			s.logger.Warn("error while closing stream with other peer", zap.Error(err))
+		}
+	}()
+
+	s.deviceLinkLock.Lock()
+	invitation := s.deviceLinkInvitation
+	s.deviceLinkLock.Unlock()
+
+	if invitation == nil {
+		s.logger.Warn("received a device link request without any pending invitation")
+		return
+	}
+
+	if time.Now().After(invitation.expiresAt) {
+		s.consumeDeviceLinkInvitation(invitation)
+		s.logger.Warn("received a device link request for an expired invitation")
+		return
+	}
+
+	reader := ggio.NewDelimitedReader(stream, deviceLinkMaxMessageSize)
+	writer := ggio.NewDelimitedWriter(stream)
+
+	requesterPK, err := handshake.ResponseUsingReaderWriter(reader, writer, invitation.responderSK)
+	if err != nil {
+		s.logger.Error("an error occurred during handshake", zap.Error(err))
+		return
+	}
+
+	if !requesterPK.Equals(invitation.requesterPK) {
+		s.logger.Error("device link requester doesn't match the pending invitation")
+		return
+	}
+
+	if !s.consumeDeviceLinkInvitation(invitation) {
+		s.logger.Warn("device link invitation has already been used")
+		return
+	}
+
+	payload, err := s.deviceLinkPayload()
+	if err != nil {
+		s.logger.Error("unable to prepare device link payload", zap.Error(err))
+		return
+	}
+
+	sealed, err := sealDeviceLinkPayload(payload, invitation.responderSK, requesterPK)
+	if err != nil {
+		s.logger.Error("unable to seal device link payload", zap.Error(err))
+		return
+	}
+
+	if err := writer.WriteMsg(sealed); err != nil {
+		s.logger.Error("an error occurred while sending device link payload", zap.Error(err))
+		return
+	}
+
+	s.logger.Info("a new device has been linked to the account")
+}
+
+func (s *service) deviceLinkPayload() (*protocoltypes.DeviceLinkPayload, error) {
+	accountSK, err := s.deviceKeystore.AccountPrivKey()
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	accountProofSK, err := s.deviceKeystore.AccountProofPrivKey()
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	accountSKBytes, err := accountSK.Bytes()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	accountProofSKBytes, err := accountProofSK.Bytes()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	s.lock.RLock()
+	groups := make([]*groupContext, 0, len(s.openedGroups))
+	for _, gc := range s.openedGroups {
+		groups = append(groups, gc)
+	}
+	s.lock.RUnlock()
+
+	groupsHeads := make([]*protocoltypes.GroupHeadsExport, len(groups))
+	for i, gc := range groups {
+		if groupsHeads[i], err = groupContextHeadsExport(gc); err != nil {
+			return nil, errcode.ErrInternal.Wrap(err)
+		}
+	}
+
+	return &protocoltypes.DeviceLinkPayload{
+		AccountSK:      accountSKBytes,
+		AccountProofSK: accountProofSKBytes,
+		GroupsHeads:    groupsHeads,
+	}, nil
+}
+
+func sealDeviceLinkPayload(payload *protocoltypes.DeviceLinkPayload, ownSK crypto.PrivKey, peerPK crypto.PubKey) (*protocoltypes.DeviceLinkSealedPayload, error) {
+	data, err := payload.Marshal()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	mongPriv, mongPub, err := cryptoutil.EdwardsToMontgomery(ownSK, peerPK)
+	if err != nil {
+		return nil, errcode.ErrCryptoKeyConversion.Wrap(err)
+	}
+
+	nonce, err := cryptoutil.GenerateNonce()
+	if err != nil {
+		return nil, errcode.ErrCryptoNonceGeneration.Wrap(err)
+	}
+
+	return &protocoltypes.DeviceLinkSealedPayload{
+		Nonce: nonce[:],
+		Box:   box.Seal(nil, data, nonce, mongPub, mongPriv),
+	}, nil
+}
+
+func openDeviceLinkPayload(sealed *protocoltypes.DeviceLinkSealedPayload, ownSK crypto.PrivKey, peerPK crypto.PubKey) (*protocoltypes.DeviceLinkPayload, error) {
+	nonce, err := cryptoutil.NonceSliceToArray(sealed.Nonce)
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	mongPriv, mongPub, err := cryptoutil.EdwardsToMontgomery(ownSK, peerPK)
+	if err != nil {
+		return nil, errcode.ErrCryptoKeyConversion.Wrap(err)
+	}
+
+	data, ok := box.Open(nil, sealed.Box, nonce, mongPub, mongPriv)
+	if !ok {
+		return nil, errcode.ErrCryptoDecrypt.Wrap(fmt.Errorf("unable to open device link payload"))
+	}
+
+	payload := &protocoltypes.DeviceLinkPayload{}
+	if err := payload.Unmarshal(data); err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	return payload, nil
+}
+
+// LinkDevice uses an invitation created by another device to join its account.
+// The account keys are stored in the device keystore of odb, which must not
+// contain any account yet, and the heads of the groups of the account are
+// loaded so their contents can be replicated. The device is added to each
+// group via AddDeviceToGroup once the group is activated.
+func LinkDevice(ctx context.Context, invitation *protocoltypes.DeviceLinkInvitation, ipfsCoreAPI ipfsutil.ExtendedCoreAPI, odb *BertyOrbitDB, logger *zap.Logger) error {
+	if invitation == nil {
+		return errcode.ErrMissingInput
+	}
+
+	if err := invitation.CheckFormat(); err != nil {
+		return err
+	}
+
+	pid, err := peer.Decode(invitation.PeerID)
+	if err != nil {
+		return errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	addrInfo := peer.AddrInfo{ID: pid}
+	for _, addr := range invitation.Addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			logger.Warn("ignoring invalid device link address", zap.String("addr", addr), zap.Error(err))
+			continue
+		}
+
+		addrInfo.Addrs = append(addrInfo.Addrs, maddr)
+	}
+
+	responderPK, err := invitation.GetResponderPubKey()
+	if err != nil {
+		return err
+	}
+
+	requesterSK, err := invitation.GetRequesterPrivKey()
+	if err != nil {
+		return err
+	}
+
+	if err := ipfsCoreAPI.Swarm().Connect(ctx, addrInfo); err != nil {
+		return errcode.ErrInternal.Wrap(fmt.Errorf("unable to connect to the device which created the invitation: %w", err))
+	}
+
+	stream, err := ipfsCoreAPI.NewStream(ctx, pid, deviceLinkV1)
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	defer func() {
+		if err := ipfsutil.FullClose(stream); err != nil {
+			logger.Warn("error while closing stream with other peer", zap.Error(err))
+		}
+	}()
+
+	reader := ggio.NewDelimitedReader(stream, deviceLinkMaxMessageSize)
+	writer := ggio.NewDelimitedWriter(stream)
+
+	if err := handshake.RequestUsingReaderWriter(reader, writer, requesterSK, responderPK); err != nil {
+		return errcode.ErrInternal.Wrap(fmt.Errorf("an error occurred during handshake: %w", err))
+	}
+
+	sealed := &protocoltypes.DeviceLinkSealedPayload{}
+	if err := reader.ReadMsg(sealed); err != nil {
+		return errcode.ErrStreamRead.Wrap(err)
+	}
+
+	payload, err := openDeviceLinkPayload(sealed, requesterSK, responderPK)
+	if err != nil {
+		return err
+	}
+
+	accountSK, err := crypto.UnmarshalPrivateKey(payload.AccountSK)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	accountProofSK, err := crypto.UnmarshalPrivateKey(payload.AccountProofSK)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if err := odb.deviceKeystore.RestoreAccountKeys(accountSK, accountProofSK); err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	for _, heads := range payload.GroupsHeads {
+		metaCIDs, messageCIDs, err := parseGroupHeadsExportCIDs(heads)
+		if err != nil {
+			return errcode.ErrInternal.Wrap(err)
+		}
+
+		if err := odb.setHeadsForGroup(ctx, &protocoltypes.Group{
+			PublicKey: heads.PublicKey,
+			SignPub:   heads.SignPub,
+		}, metaCIDs, messageCIDs); err != nil {
+			return errcode.ErrOrbitDBAppend.Wrap(fmt.Errorf("error while loading group heads: %w", err))
+		}
+	}
+
+	logger.Info("device linked to account", zap.String("peer", pid.Pretty()))
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package bertyprotocol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	dsync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/host"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"berty.tech/berty/v2/go/internal/ipfsutil"
+	"berty.tech/berty/v2/go/internal/testutil"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
+	orbitdb "berty.tech/go-orbit-db"
+	"berty.tech/go-orbit-db/pubsub/pubsubraw"
+)
+
+func testHelperNewDeviceLinkNode(ctx context.Context, t *testing.T, mn mocknet.Mocknet, rdvPeer host.Host, logger *zap.Logger) (ipfsutil.CoreAPIMock, *BertyOrbitDB, ds.Batching, func()) {
+	t.Helper()
+
+	datastore := dsync.MutexWrap(ds.NewMapDatastore())
+	api, cleanup := ipfsutil.TestingCoreAPIUsingMockNet(ctx, t, &ipfsutil.TestingAPIOpts{
+		Mocknet:   mn,
+		RDVPeer:   rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+		Datastore: datastore,
+	})
+
+	require.NoError(t, mn.LinkAll())
+
+	dks := NewDeviceKeystore(ipfsutil.NewDatastoreKeystore(ipfsutil.NewNamespacedDatastore(datastore, ds.NewKey(NamespaceDeviceKeystore))))
+
+	odb, err := NewBertyOrbitDB(ctx, api.API(), &NewOrbitDBOptions{
+		NewOrbitDBOptions: orbitdb.NewOrbitDBOptions{
+			PubSub: pubsubraw.NewPubSub(api.PubSub(), api.MockNode().PeerHost.ID(), logger, nil),
+			Logger: logger,
+		},
+		Datastore:      datastore,
+		DeviceKeystore: dks,
+	})
+	require.NoError(t, err)
+
+	return api, odb, datastore, cleanup
+}
+
+func TestLinkDevice(t *testing.T) {
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	ctx, cancel, mn, rdvPeer := testHelperIPFSSetUp(t)
+	defer cancel()
+
+	nodeA, closeNodeA := NewTestingProtocol(ctx, t, &TestingOpts{
+		Mocknet: mn,
+		RDVPeer: rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+	}, nil)
+	defer closeNodeA()
+
+	configA, err := nodeA.Client.InstanceGetConfiguration(ctx, &protocoltypes.InstanceGetConfiguration_Request{})
+	require.NoError(t, err)
+
+	res, err := nodeA.Client.DeviceLinkInvitationCreate(ctx, &protocoltypes.DeviceLinkInvitationCreate_Request{})
+	require.NoError(t, err)
+	require.NoError(t, res.Invitation.CheckFormat())
+
+	ipfsNodeB, odbB, dsB, cleanupNodeB := testHelperNewDeviceLinkNode(ctx, t, mn, rdvPeer, logger)
+	defer cleanupNodeB()
+
+	ipfsNodeC, odbC, _, cleanupNodeC := testHelperNewDeviceLinkNode(ctx, t, mn, rdvPeer, logger)
+	defer cleanupNodeC()
+
+	require.NoError(t, LinkDevice(ctx, res.Invitation, ipfsNodeB.API(), odbB, logger))
+
+	// invitations can only be used once
+	require.Error(t, LinkDevice(ctx, res.Invitation, ipfsNodeC.API(), odbC, logger))
+
+	nodeB, closeNodeB := NewTestingProtocol(ctx, t, &TestingOpts{
+		Mocknet:        mn,
+		RDVPeer:        rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+		DeviceKeystore: odbB.deviceKeystore,
+		CoreAPIMock:    ipfsNodeB,
+		OrbitDB:        odbB,
+	}, dsB)
+	defer closeNodeB()
+
+	configB, err := nodeB.Client.InstanceGetConfiguration(ctx, &protocoltypes.InstanceGetConfiguration_Request{})
+	require.NoError(t, err)
+
+	require.Equal(t, configA.AccountPK, configB.AccountPK)
+	require.Equal(t, configA.AccountGroupPK, configB.AccountGroupPK)
+	require.NotEqual(t, configA.DevicePK, configB.DevicePK)
+}
+
+func TestAccountDeviceRevoke(t *testing.T) {
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+
+	ctx, cancel, mn, rdvPeer := testHelperIPFSSetUp(t)
+	defer cancel()
+
+	nodeA, closeNodeA := NewTestingProtocol(ctx, t, &TestingOpts{
+		Mocknet: mn,
+		RDVPeer: rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+	}, nil)
+	defer closeNodeA()
+
+	configA, err := nodeA.Client.InstanceGetConfiguration(ctx, &protocoltypes.InstanceGetConfiguration_Request{})
+	require.NoError(t, err)
+
+	res, err := nodeA.Client.DeviceLinkInvitationCreate(ctx, &protocoltypes.DeviceLinkInvitationCreate_Request{})
+	require.NoError(t, err)
+
+	ipfsNodeB, odbB, dsB, cleanupNodeB := testHelperNewDeviceLinkNode(ctx, t, mn, rdvPeer, logger)
+	defer cleanupNodeB()
+
+	require.NoError(t, LinkDevice(ctx, res.Invitation, ipfsNodeB.API(), odbB, logger))
+
+	nodeB, closeNodeB := NewTestingProtocol(ctx, t, &TestingOpts{
+		Mocknet:        mn,
+		RDVPeer:        rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+		DeviceKeystore: odbB.deviceKeystore,
+		CoreAPIMock:    ipfsNodeB,
+		OrbitDB:        odbB,
+	}, dsB)
+	defer closeNodeB()
+
+	configB, err := nodeB.Client.InstanceGetConfiguration(ctx, &protocoltypes.InstanceGetConfiguration_Request{})
+	require.NoError(t, err)
+
+	// wait for the device of B to be replicated on A
+	require.Eventually(t, func() bool {
+		list, err := nodeA.Client.DeviceList(ctx, &protocoltypes.DeviceList_Request{})
+		return err == nil && len(list.Devices) == 2
+	}, time.Second*10, time.Millisecond*100)
+
+	// the current device can't be revoked
+	_, err = nodeA.Client.AccountDeviceRevoke(ctx, &protocoltypes.AccountDeviceRevoke_Request{DevicePK: configA.DevicePK})
+	require.Error(t, err)
+
+	_, err = nodeA.Client.AccountDeviceRevoke(ctx, &protocoltypes.AccountDeviceRevoke_Request{DevicePK: configB.DevicePK})
+	require.NoError(t, err)
+
+	list, err := nodeA.Client.DeviceList(ctx, &protocoltypes.DeviceList_Request{})
+	require.NoError(t, err)
+	require.Len(t, list.Devices, 1)
+	require.Equal(t, configA.DevicePK, list.Devices[0].DevicePK)
+	require.True(t, list.Devices[0].Current)
+}
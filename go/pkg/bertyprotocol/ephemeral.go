@@ -0,0 +1,140 @@
+package bertyprotocol
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/sha3"
+
+	"berty.tech/berty/v2/go/internal/cryptoutil"
+	"berty.tech/berty/v2/go/pkg/errcode"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
+)
+
+// ephemeralMessageMaxAge is the maximum clock drift tolerated between the sender and the receiver
+// of an ephemeral message, outdated messages are discarded to prevent them from being replayed
+const ephemeralMessageMaxAge = time.Minute
+
+// ephemeralTopicForGroup returns the pubsub topic used by the ephemeral channel of a group,
+// it is derived from the group secret so the group public key is not disclosed
+func ephemeralTopicForGroup(g *protocoltypes.Group) (string, error) {
+	topic := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha3.New256, g.GetSecret(), nil, []byte("ephemeral topic v0")), topic); err != nil {
+		return "", errcode.ErrCryptoKeyDerivation.Wrap(err)
+	}
+
+	return "berty/ephemeral/" + hex.EncodeToString(topic), nil
+}
+
+func ephemeralKeyForGroup(g *protocoltypes.Group) (*[cryptoutil.KeySize]byte, error) {
+	var key [cryptoutil.KeySize]byte
+	if _, err := io.ReadFull(hkdf.New(sha3.New256, g.GetSecret(), nil, []byte("ephemeral encryption v0")), key[:]); err != nil {
+		return nil, errcode.ErrCryptoKeyDerivation.Wrap(err)
+	}
+
+	return &key, nil
+}
+
+func sealEphemeralMessage(g *protocoltypes.Group, deviceSK crypto.PrivKey, payload []byte, now time.Time) ([]byte, error) {
+	devicePKRaw, err := deviceSK.GetPublic().Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	msg := &protocoltypes.EphemeralMessage{
+		DevicePK:  devicePKRaw,
+		Payload:   payload,
+		Timestamp: now.UnixNano(),
+	}
+
+	unsigned, err := msg.Marshal()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	if msg.Sig, err = deviceSK.Sign(unsigned); err != nil {
+		return nil, errcode.ErrCryptoSignature.Wrap(err)
+	}
+
+	msgBytes, err := msg.Marshal()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	key, err := ephemeralKeyForGroup(g)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := cryptoutil.GenerateNonce()
+	if err != nil {
+		return nil, errcode.ErrCryptoNonceGeneration.Wrap(err)
+	}
+
+	env, err := (&protocoltypes.EphemeralMessageEnvelope{
+		Message: secretbox.Seal(nil, msgBytes, nonce, key),
+		Nonce:   nonce[:],
+	}).Marshal()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	return env, nil
+}
+
+func openEphemeralMessage(g *protocoltypes.Group, data []byte, now time.Time) (*protocoltypes.EphemeralMessage, crypto.PubKey, error) {
+	env := &protocoltypes.EphemeralMessageEnvelope{}
+	if err := env.Unmarshal(data); err != nil {
+		return nil, nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	nonce, err := cryptoutil.NonceSliceToArray(env.Nonce)
+	if err != nil {
+		return nil, nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	key, err := ephemeralKeyForGroup(g)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msgBytes, ok := secretbox.Open(nil, env.Message, nonce, key)
+	if !ok {
+		return nil, nil, errcode.ErrCryptoDecrypt.Wrap(fmt.Errorf("secretbox failed to open ephemeral message"))
+	}
+
+	msg := &protocoltypes.EphemeralMessage{}
+	if err := msg.Unmarshal(msgBytes); err != nil {
+		return nil, nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	devicePK, err := crypto.UnmarshalEd25519PublicKey(msg.DevicePK)
+	if err != nil {
+		return nil, nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	sig := msg.Sig
+	msg.Sig = nil
+
+	unsigned, err := msg.Marshal()
+	if err != nil {
+		return nil, nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	if ok, err := devicePK.Verify(unsigned, sig); err != nil || !ok {
+		return nil, nil, errcode.ErrCryptoSignatureVerification.Wrap(fmt.Errorf("invalid ephemeral message signature"))
+	}
+
+	msg.Sig = sig
+
+	if drift := now.Sub(time.Unix(0, msg.Timestamp)); drift > ephemeralMessageMaxAge || drift < -ephemeralMessageMaxAge {
+		return nil, nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("outdated ephemeral message"))
+	}
+
+	return msg, devicePK, nil
+}
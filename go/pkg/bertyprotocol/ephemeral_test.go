@@ -0,0 +1,89 @@
+package bertyprotocol
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/secretbox"
+
+	"berty.tech/berty/v2/go/internal/cryptoutil"
+	"berty.tech/berty/v2/go/pkg/errcode"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
+)
+
+func Test_ephemeralTopicForGroup(t *testing.T) {
+	g1, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	g2, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	topic1, err := ephemeralTopicForGroup(g1)
+	require.NoError(t, err)
+
+	topic1bis, err := ephemeralTopicForGroup(g1)
+	require.NoError(t, err)
+
+	topic2, err := ephemeralTopicForGroup(g2)
+	require.NoError(t, err)
+
+	require.Equal(t, topic1, topic1bis)
+	require.NotEqual(t, topic1, topic2)
+}
+
+func Test_sealEphemeralMessage(t *testing.T) {
+	g, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	otherGroup, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	deviceSK, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	env, err := sealEphemeralMessage(g, deviceSK, []byte("typing"), now)
+	require.NoError(t, err)
+
+	msg, devicePK, err := openEphemeralMessage(g, env, now.Add(time.Second))
+	require.NoError(t, err)
+	require.True(t, devicePK.Equals(deviceSK.GetPublic()))
+	require.Equal(t, []byte("typing"), msg.Payload)
+
+	// another group can't read the message
+	_, _, err = openEphemeralMessage(otherGroup, env, now)
+	require.True(t, errcode.Is(err, errcode.ErrCryptoDecrypt))
+
+	// outdated messages are discarded
+	_, _, err = openEphemeralMessage(g, env, now.Add(2*ephemeralMessageMaxAge))
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	// tampered messages are discarded
+	otherSK, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+
+	msg.DevicePK, err = otherSK.GetPublic().Raw()
+	require.NoError(t, err)
+
+	msgBytes, err := msg.Marshal()
+	require.NoError(t, err)
+
+	key, err := ephemeralKeyForGroup(g)
+	require.NoError(t, err)
+
+	nonce, err := cryptoutil.GenerateNonce()
+	require.NoError(t, err)
+
+	tampered, err := (&protocoltypes.EphemeralMessageEnvelope{
+		Message: secretbox.Seal(nil, msgBytes, nonce, key),
+		Nonce:   nonce[:],
+	}).Marshal()
+	require.NoError(t, err)
+
+	_, _, err = openEphemeralMessage(g, tampered, now)
+	require.True(t, errcode.Is(err, errcode.ErrCryptoSignatureVerification))
+}
@@ -6,6 +6,7 @@ import (
 	crand "crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -26,6 +27,9 @@ import (
 
 const CurrentGroupVersion = 1
 
+// deviceSecretRenewalCheckInterval is the interval between two checks of the age and usage of the secret of the current device
+const deviceSecretRenewalCheckInterval = time.Minute * 10
+
 // NewGroupMultiMember creates a new Group object and an invitation to be used by
 // the first member of the group
 func NewGroupMultiMember() (*protocoltypes.Group, crypto.PrivKey, error) {
@@ -154,7 +158,6 @@ func metadataStoreListSecrets(ctx context.Context, gc *groupContext) map[crypto.
 	publishedSecrets := map[crypto.PubKey]*protocoltypes.DeviceSecret{}
 
 	m := gc.MetadataStore()
-	ownSK := gc.getMemberPrivKey()
 	g := gc.Group()
 
 	metadatas, err := m.ListEvents(ctx, nil, nil, false)
@@ -166,7 +169,7 @@ func metadataStoreListSecrets(ctx context.Context, gc *groupContext) map[crypto.
 			continue
 		}
 
-		pk, ds, err := openDeviceSecret(metadata.Metadata, ownSK, g)
+		pk, ds, err := openDeviceSecret(metadata.Metadata, gc.memberDevice, g)
 		if errcode.Is(err, errcode.ErrInvalidInput) || errcode.Is(err, errcode.ErrGroupSecretOtherDestMember) {
 			continue
 		}
@@ -198,7 +201,7 @@ func FillMessageKeysHolderUsingNewData(ctx context.Context, gc *groupContext) <-
 				continue
 			}
 
-			pk, ds, err := openDeviceSecret(e.Metadata, gc.getMemberPrivKey(), gc.Group())
+			pk, ds, err := openDeviceSecret(e.Metadata, gc.memberDevice, gc.Group())
 			if errcode.Is(err, errcode.ErrInvalidInput) {
 				continue
 			}
@@ -292,6 +295,17 @@ func activateGroupContext(ctx context.Context, gc *groupContext, contact crypto.
 
 	wg.Wait()
 
+	WatchRemovedMembersAndRotateSecrets(ctx, gc.logger, gc)
+
+	// members or devices might have been removed while the group was not active
+	forgetRemovedDevices(gc.logger, gc)
+
+	if gc.MetadataStore().MinSecretGeneration() > 0 {
+		if err := gc.MetadataStore().RotateSecret(ctx); err != nil {
+			gc.logger.Error("unable to rotate device secret", zap.Error(err))
+		}
+	}
+
 	start := time.Now()
 	ch := FillMessageKeysHolderUsingPreviousData(ctx, gc)
 	for pk := range ch {
@@ -318,6 +332,8 @@ func activateGroupContext(ctx context.Context, gc *groupContext, contact crypto.
 
 	gc.logger.Info(fmt.Sprintf("SendSecretsToExistingMembers took %s", time.Since(start)))
 
+	ScheduleDeviceSecretRenewal(ctx, gc.logger, gc)
+
 	if selfAnnouncement {
 		start = time.Now()
 		op, err := gc.MetadataStore().AddDeviceToGroup(ctx)
@@ -456,7 +472,121 @@ func WatchNewMembersAndSendSecrets(ctx context.Context, logger *zap.Logger, gctx
 	return ch
 }
 
-func openDeviceSecret(m *protocoltypes.GroupMetadata, localMemberPrivateKey crypto.PrivKey, group *protocoltypes.Group) (crypto.PubKey, *protocoltypes.DeviceSecret, error) {
+// WatchRemovedMembersAndRotateSecrets rotates the secret of the current device each time a member is removed
+// from the group or a device is revoked, so the removed member can't open the messages sent afterwards
+func WatchRemovedMembersAndRotateSecrets(ctx context.Context, logger *zap.Logger, gctx *groupContext) {
+	sub := gctx.MetadataStore().Subscribe(ctx)
+
+	go func() {
+		for evt := range sub {
+			e, ok := evt.(*protocoltypes.GroupMetadataEvent)
+			if !ok {
+				continue
+			}
+
+			switch e.Metadata.EventType {
+			case protocoltypes.EventTypeMultiMemberGroupMemberRemoved:
+				event := &protocoltypes.MultiMemberRemoveMember{}
+				if err := event.Unmarshal(e.Event); err != nil {
+					logger.Error("unable to unmarshal payload", zap.Error(err))
+					continue
+				}
+
+				removedPK, err := crypto.UnmarshalEd25519PublicKey(event.RemovedMemberPK)
+				if err != nil {
+					logger.Error("unable to unmarshal removed member pk", zap.Error(err))
+					continue
+				}
+
+				if gctx.MemberPubKey().Equals(removedPK) {
+					logger.Info("current member has been removed from the group")
+					continue
+				}
+
+				forgetRemovedDevices(logger, gctx)
+
+			case protocoltypes.EventTypeGroupDeviceRevoked:
+				event := &protocoltypes.GroupRevokeDevice{}
+				if err := event.Unmarshal(e.Event); err != nil {
+					logger.Error("unable to unmarshal payload", zap.Error(err))
+					continue
+				}
+
+				revokedPK, err := crypto.UnmarshalEd25519PublicKey(event.RevokedDevicePK)
+				if err != nil {
+					logger.Error("unable to unmarshal revoked device pk", zap.Error(err))
+					continue
+				}
+
+				if gctx.DevicePubKey().Equals(revokedPK) {
+					logger.Info("current device has been revoked")
+					continue
+				}
+
+				forgetRemovedDevices(logger, gctx)
+
+			default:
+				continue
+			}
+
+			if err := gctx.MetadataStore().RotateSecret(ctx); err != nil {
+				logger.Error("unable to rotate device secret", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// forgetRemovedDevices drops the chain keys of the revoked devices and of the devices of the members removed from
+// the group, their messages can't be opened anymore
+func forgetRemovedDevices(logger *zap.Logger, gctx *groupContext) {
+	devices := append(gctx.MetadataStore().ListRemovedMembersDevices(), gctx.MetadataStore().ListRevokedDevices()...)
+
+	for _, devicePK := range devices {
+		if gctx.DevicePubKey().Equals(devicePK) {
+			continue
+		}
+
+		if err := gctx.MessageKeystore().RemoveDevice(gctx.Group(), devicePK); err != nil {
+			logger.Error("unable to remove device chain keys", zap.Error(err))
+		}
+	}
+}
+
+// ScheduleDeviceSecretRenewal periodically renews the secret of the current device once it has been used for too
+// long or for too many messages, limiting the messages exposed by a leaked secret
+func ScheduleDeviceSecretRenewal(ctx context.Context, logger *zap.Logger, gctx *groupContext) {
+	go func() {
+		ticker := time.NewTicker(deviceSecretRenewalCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			renew, err := gctx.MetadataStore().SecretNeedsRenewal()
+			if err != nil {
+				logger.Error("unable to check device secret renewal", zap.Error(err))
+				continue
+			}
+
+			if !renew {
+				continue
+			}
+
+			if err := gctx.MetadataStore().RenewSecret(ctx); err != nil {
+				logger.Error("unable to renew device secret", zap.Error(err))
+				continue
+			}
+
+			logger.Info("device secret renewed")
+		}
+	}()
+}
+
+func openDeviceSecret(m *protocoltypes.GroupMetadata, localMemberDevice *ownMemberDevice, group *protocoltypes.Group) (crypto.PubKey, *protocoltypes.DeviceSecret, error) {
 	if m == nil || m.EventType != protocoltypes.EventTypeGroupDeviceSecretAdded {
 		return nil, nil, errcode.ErrInvalidInput
 	}
@@ -476,16 +606,31 @@ func openDeviceSecret(m *protocoltypes.GroupMetadata, localMemberPrivateKey cryp
 		return nil, nil, errcode.ErrDeserialization.Wrap(err)
 	}
 
-	if !localMemberPrivateKey.GetPublic().Equals(destMemberPubKey) {
+	if !localMemberDevice.member.GetPublic().Equals(destMemberPubKey) {
 		return nil, nil, errcode.ErrGroupSecretOtherDestMember
 	}
 
-	mongPriv, mongPub, err := cryptoutil.EdwardsToMontgomery(localMemberPrivateKey, senderDevicePubKey)
+	// the secret is sent to a single device of the member
+	localPrivateKey := localMemberDevice.member
+	if len(s.DestDevicePK) > 0 {
+		destDevicePubKey, err := crypto.UnmarshalEd25519PublicKey(s.DestDevicePK)
+		if err != nil {
+			return nil, nil, errcode.ErrDeserialization.Wrap(err)
+		}
+
+		if !localMemberDevice.device.GetPublic().Equals(destDevicePubKey) {
+			return nil, nil, errcode.ErrGroupSecretOtherDestMember
+		}
+
+		localPrivateKey = localMemberDevice.device
+	}
+
+	mongPriv, mongPub, err := cryptoutil.EdwardsToMontgomery(localPrivateKey, senderDevicePubKey)
 	if err != nil {
 		return nil, nil, errcode.ErrCryptoKeyConversion.Wrap(err)
 	}
 
-	nonce := groupIDToNonce(group)
+	nonce := deviceSecretNonce(group, s.Generation)
 	decryptedSecret := &protocoltypes.DeviceSecret{}
 	decryptedMessage, ok := box.Open(nil, s.Payload, nonce, mongPub, mongPriv)
 	if !ok {
@@ -497,6 +642,10 @@ func openDeviceSecret(m *protocoltypes.GroupMetadata, localMemberPrivateKey cryp
 		return nil, nil, errcode.ErrDeserialization
 	}
 
+	if decryptedSecret.Generation != s.Generation {
+		return nil, nil, errcode.ErrCryptoDecrypt.Wrap(fmt.Errorf("device secret generation mismatch"))
+	}
+
 	return senderDevicePubKey, decryptedSecret, nil
 }
 
@@ -517,3 +666,21 @@ func groupIDToNonce(group *protocoltypes.Group) *[cryptoutil.NonceSize]byte {
 
 	return &nonce
 }
+
+// deviceSecretNonce returns the nonce used to send a device secret, a rotated secret is sent again to the
+// same {sender, receiver} set so the generation is mixed into the nonce to keep it unique
+func deviceSecretNonce(group *protocoltypes.Group, generation uint64) *[cryptoutil.NonceSize]byte {
+	if generation == 0 {
+		return groupIDToNonce(group)
+	}
+
+	var nonce [cryptoutil.NonceSize]byte
+
+	generationBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(generationBytes, generation)
+
+	h := sha256.Sum256(append(append([]byte{}, group.GetPublicKey()...), generationBytes...))
+	copy(nonce[:], h[:])
+
+	return &nonce
+}
@@ -23,10 +23,6 @@ func (gc *groupContext) MessageKeystore() *messageKeystore {
 	return gc.messageKeystore
 }
 
-func (gc *groupContext) getMemberPrivKey() crypto.PrivKey {
-	return gc.memberDevice.member
-}
-
 func (gc *groupContext) MessageStore() *messageStore {
 	return gc.messageStore
 }
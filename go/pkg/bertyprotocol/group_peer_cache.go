@@ -0,0 +1,61 @@
+package bertyprotocol
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"go.uber.org/zap"
+
+	"berty.tech/berty/v2/go/internal/ipfsutil"
+	"berty.tech/berty/v2/go/internal/tinder"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
+)
+
+// recordGroupPeers saves the peers joining the group topics into the peer cache, they are served by the peer cache
+// driver on the next start
+func recordGroupPeers(ctx context.Context, logger *zap.Logger, h host.Host, cache *tinder.PeerCache) error {
+	sub, err := h.EventBus().Subscribe(new(ipfsutil.EvtPubSubTopic))
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer sub.Close()
+
+		for {
+			var evt interface{}
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok = <-sub.Out():
+				if !ok {
+					return
+				}
+			}
+
+			e := evt.(ipfsutil.EvtPubSubTopic)
+			monitorEvent := monitorHandlePubsubEvent(&e, h)
+			if monitorEvent.Type != protocoltypes.TypeEventMonitorPeerJoin || monitorEvent.PeerJoin.IsSelf {
+				continue
+			}
+
+			info := peer.AddrInfo{ID: e.PeerID}
+			for _, addr := range monitorEvent.PeerJoin.Maddrs {
+				maddr, err := ma.NewMultiaddr(addr)
+				if err != nil {
+					continue
+				}
+				info.Addrs = append(info.Addrs, maddr)
+			}
+
+			if err := cache.Record(monitorEvent.PeerJoin.Topic, info); err != nil {
+				logger.Warn("unable to record group peer", zap.String("topic", monitorEvent.PeerJoin.Topic), zap.Error(err))
+			}
+		}
+	}()
+
+	return nil
+}
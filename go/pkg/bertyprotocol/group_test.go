@@ -7,6 +7,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/stretchr/testify/require"
 
+	"berty.tech/berty/v2/go/pkg/errcode"
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
 )
 
@@ -33,3 +34,116 @@ func TestGetKeysForGroupOfContact(t *testing.T) {
 	require.NotNil(t, sk2)
 	require.False(t, sk1.Equals(sk2))
 }
+
+func TestOpenDeviceSecretGeneration(t *testing.T) {
+	g, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	deviceSK, _, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	memberSK, _, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	devicePK, err := deviceSK.GetPublic().Raw()
+	require.NoError(t, err)
+
+	memberPK, err := memberSK.GetPublic().Raw()
+	require.NoError(t, err)
+
+	memberDeviceSK, _, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	local := &ownMemberDevice{member: memberSK, device: memberDeviceSK}
+
+	require.NotEqual(t, deviceSecretNonce(g, 0), deviceSecretNonce(g, 1))
+	require.Equal(t, groupIDToNonce(g), deviceSecretNonce(g, 0))
+
+	newMetadata := func(ds *protocoltypes.DeviceSecret, generation uint64) *protocoltypes.GroupMetadata {
+		payload, err := newSecretEntryPayload(deviceSK, memberSK.GetPublic(), ds, g)
+		require.NoError(t, err)
+
+		evt, err := (&protocoltypes.GroupAddDeviceSecret{
+			DevicePK:     devicePK,
+			DestMemberPK: memberPK,
+			Payload:      payload,
+			Generation:   generation,
+		}).Marshal()
+		require.NoError(t, err)
+
+		return &protocoltypes.GroupMetadata{EventType: protocoltypes.EventTypeGroupDeviceSecretAdded, Payload: evt}
+	}
+
+	for _, generation := range []uint64{0, 1, 2} {
+		ds, err := newDeviceSecret()
+		require.NoError(t, err)
+		ds.Generation = generation
+
+		pk, opened, err := openDeviceSecret(newMetadata(ds, generation), local, g)
+		require.NoError(t, err)
+		require.True(t, pk.Equals(deviceSK.GetPublic()))
+		require.Equal(t, ds, opened)
+	}
+
+	// the generation can't be altered
+	ds, err := newDeviceSecret()
+	require.NoError(t, err)
+	ds.Generation = 1
+
+	_, _, err = openDeviceSecret(newMetadata(ds, 2), local, g)
+	require.Error(t, err)
+}
+
+func TestOpenDeviceSecretForDevice(t *testing.T) {
+	g, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	deviceSK, _, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	memberSK, _, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	destDeviceSK, _, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	revokedDeviceSK, _, err := crypto.GenerateEd25519Key(crand.Reader)
+	require.NoError(t, err)
+
+	devicePK, err := deviceSK.GetPublic().Raw()
+	require.NoError(t, err)
+
+	memberPK, err := memberSK.GetPublic().Raw()
+	require.NoError(t, err)
+
+	destDevicePK, err := destDeviceSK.GetPublic().Raw()
+	require.NoError(t, err)
+
+	ds, err := newDeviceSecret()
+	require.NoError(t, err)
+	ds.Generation = 1
+
+	payload, err := newSecretEntryPayload(deviceSK, destDeviceSK.GetPublic(), ds, g)
+	require.NoError(t, err)
+
+	evt, err := (&protocoltypes.GroupAddDeviceSecret{
+		DevicePK:     devicePK,
+		DestMemberPK: memberPK,
+		DestDevicePK: destDevicePK,
+		Payload:      payload,
+		Generation:   ds.Generation,
+	}).Marshal()
+	require.NoError(t, err)
+
+	metadata := &protocoltypes.GroupMetadata{EventType: protocoltypes.EventTypeGroupDeviceSecretAdded, Payload: evt}
+
+	pk, opened, err := openDeviceSecret(metadata, &ownMemberDevice{member: memberSK, device: destDeviceSK}, g)
+	require.NoError(t, err)
+	require.True(t, pk.Equals(deviceSK.GetPublic()))
+	require.Equal(t, ds, opened)
+
+	// another device of the member, owning the member key, can't open it
+	_, _, err = openDeviceSecret(metadata, &ownMemberDevice{member: memberSK, device: revokedDeviceSK}, g)
+	require.Error(t, err)
+	require.True(t, errcode.Is(err, errcode.ErrGroupSecretOtherDestMember))
+}
@@ -34,6 +34,7 @@ type DeviceKeystore interface {
 	AttachmentSecretPut(cid []byte, secret []byte) error
 	AttachmentSecretSlice(cids [][]byte) ([][]byte, error)
 	AttachmentSecretSlicePut(cids, secrets [][]byte) error
+	AttachmentSecretDelete(cid []byte) error
 }
 
 type deviceKeystore struct {
@@ -222,33 +223,49 @@ func (a *deviceKeystore) RestoreAccountKeys(sk crypto.PrivKey, proofSK crypto.Pr
 		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("missing account proof key"))
 	}
 
-	ok, err := a.ks.Has(keyAccount)
+	// the keys of the same account can be restored again, e.g. from the successive exports of an account
+	hasAccountKey, err := a.hasKey(keyAccount, sk)
 	if err != nil {
 		return err
 	}
 
-	if ok {
-		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("an account key is already set in this keystore"))
-	}
-
-	ok, err = a.ks.Has(keyAccountProof)
+	hasAccountProofKey, err := a.hasKey(keyAccountProof, proofSK)
 	if err != nil {
 		return err
 	}
 
-	if ok {
-		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("an account proof key is already set in this keystore"))
+	if !hasAccountKey {
+		if err := a.ks.Put(keyAccount, sk); err != nil {
+			return err
+		}
 	}
 
-	if err := a.ks.Put(keyAccount, sk); err != nil {
-		return err
+	if !hasAccountProofKey {
+		if err := a.ks.Put(keyAccountProof, proofSK); err != nil {
+			return err
+		}
 	}
 
-	if err := a.ks.Put(keyAccountProof, proofSK); err != nil {
-		return err
+	return nil
+}
+
+// hasKey returns whether the keystore already holds the given key under this name, it fails if another key is set
+func (a *deviceKeystore) hasKey(name string, sk crypto.PrivKey) (bool, error) {
+	ok, err := a.ks.Has(name)
+	if err != nil || !ok {
+		return false, err
 	}
 
-	return nil
+	existing, err := a.ks.Get(name)
+	if err != nil {
+		return false, err
+	}
+
+	if !existing.Equals(sk) {
+		return false, errcode.ErrInvalidInput.Wrap(fmt.Errorf("another key is already set in this keystore for %s", name))
+	}
+
+	return true, nil
 }
 
 func (a *deviceKeystore) AttachmentPrivKey(cidBytes []byte) (crypto.PrivKey, error) {
@@ -341,6 +358,29 @@ func (a *deviceKeystore) AttachmentSecretSlicePut(cids, secrets [][]byte) error
 	return nil
 }
 
+// AttachmentSecretDelete removes the secret of an attachment, it is a no-op if the secret is unknown
+func (a *deviceKeystore) AttachmentSecretDelete(cidBytes []byte) error {
+	id, err := attachmentKeyIDFromCID(cidBytes)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if has, err := a.ks.Has(id); err != nil {
+		return errcode.ErrKeystoreGet.Wrap(err)
+	} else if !has {
+		return nil
+	}
+
+	if err := a.ks.Delete(id); err != nil {
+		return errcode.ErrKeystoreDelete.Wrap(err)
+	}
+
+	return nil
+}
+
 // ownMemberDevice is own local device part of a group
 type ownMemberDevice struct {
 	member crypto.PrivKey
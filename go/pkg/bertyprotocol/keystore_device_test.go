@@ -71,6 +71,38 @@ func Test_NewWithExistingKeys_AccountPrivKey_AccountProofPrivKey(t *testing.T) {
 	assert.False(t, sk2.Equals(skProof2))
 }
 
+func Test_RestoreAccountKeys(t *testing.T) {
+	acc1 := NewDeviceKeystore(keystore.NewMemKeystore())
+
+	sk1, err := acc1.AccountPrivKey()
+	assert.NoError(t, err)
+
+	skProof1, err := acc1.AccountProofPrivKey()
+	assert.NoError(t, err)
+
+	acc2 := NewDeviceKeystore(keystore.NewMemKeystore())
+	assert.NoError(t, acc2.RestoreAccountKeys(sk1, skProof1))
+
+	// the keys of the same account can be restored again
+	assert.NoError(t, acc2.RestoreAccountKeys(sk1, skProof1))
+
+	sk2, err := acc2.AccountPrivKey()
+	assert.NoError(t, err)
+	assert.True(t, sk1.Equals(sk2))
+
+	// the keys of another account are rejected
+	acc3 := NewDeviceKeystore(keystore.NewMemKeystore())
+
+	sk3, err := acc3.AccountPrivKey()
+	assert.NoError(t, err)
+
+	skProof3, err := acc3.AccountProofPrivKey()
+	assert.NoError(t, err)
+
+	assert.Error(t, acc2.RestoreAccountKeys(sk3, skProof3))
+	assert.Error(t, acc2.RestoreAccountKeys(sk1, skProof3))
+}
+
 func Test_DevicePrivKey(t *testing.T) {
 	ks1 := keystore.NewMemKeystore()
 	acc1 := NewDeviceKeystore(ks1)
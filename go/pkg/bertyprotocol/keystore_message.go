@@ -2,11 +2,14 @@ package bertyprotocol
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
 	dssync "github.com/ipfs/go-datastore/sync"
 	"github.com/libp2p/go-libp2p-core/crypto"
 	"golang.org/x/crypto/nacl/secretbox"
@@ -16,10 +19,20 @@ import (
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
 )
 
+const (
+	// defaultSecretRenewalPeriod is the maximum lifetime of the secret of the current device in a group
+	defaultSecretRenewalPeriod = time.Hour * 24 * 7
+
+	// defaultSecretRenewalMessageCount is the maximum number of messages sent by the current device using the same secret
+	defaultSecretRenewalMessageCount = 1000
+)
+
 type messageKeystore struct {
-	lock                 sync.Mutex
-	preComputedKeysCount int
-	store                datastore.Datastore
+	lock                      sync.Mutex
+	preComputedKeysCount      int
+	secretRenewalPeriod       time.Duration
+	secretRenewalMessageCount uint64
+	store                     datastore.Datastore
 }
 
 type decryptInfo struct {
@@ -118,7 +131,11 @@ func (m *messageKeystore) postDecryptActions(di *decryptInfo, g *protocoltypes.G
 		return errcode.ErrInternal.Wrap(err)
 	}
 
-	if ds, err = m.getDeviceChainKey(groupPK, pk); err != nil {
+	ds, err = m.getDeviceChainKeyForGeneration(groupPK, pk, headers.Generation)
+	if errcode.Is(err, errcode.ErrMissingInput) && headers.Generation > 0 {
+		// the chain of an outdated generation isn't kept, only its precomputed keys remain
+		return nil
+	} else if err != nil {
 		return errcode.ErrInvalidInput.Wrap(err)
 	}
 
@@ -168,6 +185,10 @@ func (m *messageKeystore) GetDeviceSecret(g *protocoltypes.Group, acc DeviceKeys
 			return nil, errcode.ErrMessageKeyPersistencePut.Wrap(err)
 		}
 
+		if err = m.putOwnSecretStart(groupPK, md.device.GetPublic(), ds.Counter, time.Now()); err != nil {
+			return nil, errcode.ErrMessageKeyPersistencePut.Wrap(err)
+		}
+
 		return ds, nil
 	}
 	if err != nil {
@@ -177,6 +198,180 @@ func (m *messageKeystore) GetDeviceSecret(g *protocoltypes.Group, acc DeviceKeys
 	return ds, nil
 }
 
+// RotateDeviceSecret replaces the secret of the current device by a new one if its generation is lower than
+// the given one, the new secret is at least of this generation. Keys precomputed using the previous secret are
+// kept so messages sent before the rotation can still be opened.
+func (m *messageKeystore) RotateDeviceSecret(g *protocoltypes.Group, acc DeviceKeystore, minGeneration uint64) (*protocoltypes.DeviceSecret, error) {
+	if m == nil {
+		return nil, errcode.ErrInvalidInput
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	md, err := acc.MemberDeviceForGroup(g)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	groupPK, err := g.GetPubKey()
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	current, err := m.getDeviceChainKey(groupPK, md.device.GetPublic())
+	switch {
+	case err == nil && current.Generation >= minGeneration:
+		return current, nil
+	case err != nil && !errcode.Is(err, errcode.ErrMissingInput):
+		return nil, errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	return m.rotateDeviceSecret(groupPK, md.device.GetPublic(), current, minGeneration)
+}
+
+// RenewDeviceSecret replaces the secret of the current device by a new one of the next generation, a leaked
+// secret doesn't allow to open the messages sent after its renewal
+func (m *messageKeystore) RenewDeviceSecret(g *protocoltypes.Group, acc DeviceKeystore) (*protocoltypes.DeviceSecret, error) {
+	if m == nil {
+		return nil, errcode.ErrInvalidInput
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	md, err := acc.MemberDeviceForGroup(g)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	groupPK, err := g.GetPubKey()
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	current, err := m.getDeviceChainKey(groupPK, md.device.GetPublic())
+	if err != nil {
+		return nil, errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	return m.rotateDeviceSecret(groupPK, md.device.GetPublic(), current, current.Generation+1)
+}
+
+func (m *messageKeystore) rotateDeviceSecret(groupPK, device crypto.PubKey, current *protocoltypes.DeviceSecret, generation uint64) (*protocoltypes.DeviceSecret, error) {
+	if current != nil {
+		if err := m.putPreviousDeviceChainKey(groupPK, device, current); err != nil {
+			return nil, err
+		}
+	}
+
+	ds, err := newDeviceSecret()
+	if err != nil {
+		return nil, errcode.ErrCryptoKeyGeneration.Wrap(err)
+	}
+	ds.Generation = generation
+
+	if err := m.putDeviceChainKey(groupPK, device, ds); err != nil {
+		return nil, errcode.ErrMessageKeyPersistencePut.Wrap(err)
+	}
+
+	if err := m.putOwnSecretStart(groupPK, device, ds.Counter, time.Now()); err != nil {
+		return nil, errcode.ErrMessageKeyPersistencePut.Wrap(err)
+	}
+
+	return ds, nil
+}
+
+// DeviceSecretNeedsRenewal checks if the secret of the current device has been used for too long or for too many
+// messages, see RenewDeviceSecret
+func (m *messageKeystore) DeviceSecretNeedsRenewal(g *protocoltypes.Group, acc DeviceKeystore) (bool, error) {
+	if m == nil {
+		return false, errcode.ErrInvalidInput
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	md, err := acc.MemberDeviceForGroup(g)
+	if err != nil {
+		return false, errcode.ErrInternal.Wrap(err)
+	}
+
+	groupPK, err := g.GetPubKey()
+	if err != nil {
+		return false, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	ds, err := m.getDeviceChainKey(groupPK, md.device.GetPublic())
+	if errcode.Is(err, errcode.ErrMissingInput) {
+		return false, nil
+	} else if err != nil {
+		return false, errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	startCounter, startDate, err := m.getOwnSecretStart(groupPK, md.device.GetPublic())
+	if errcode.Is(err, errcode.ErrMissingInput) {
+		// secrets created before their start was recorded are considered as new
+		return false, m.putOwnSecretStart(groupPK, md.device.GetPublic(), ds.Counter, time.Now())
+	} else if err != nil {
+		return false, err
+	}
+
+	// the counter starts at a random value and might overflow
+	if ds.Counter-startCounter >= m.secretRenewalMessageCount {
+		return true, nil
+	}
+
+	return time.Since(startDate) >= m.secretRenewalPeriod, nil
+}
+
+func (m *messageKeystore) putOwnSecretStart(groupPK, device crypto.PubKey, counter uint64, date time.Time) error {
+	deviceRaw, err := device.Raw()
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	groupRaw, err := groupPK.Raw()
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[:8], counter)
+	binary.BigEndian.PutUint64(data[8:], uint64(date.Unix()))
+
+	if err := m.store.Put(idForOwnSecretStart(groupRaw, deviceRaw), data); err != nil {
+		return errcode.ErrMessageKeyPersistencePut.Wrap(err)
+	}
+
+	return nil
+}
+
+func (m *messageKeystore) getOwnSecretStart(groupPK, device crypto.PubKey) (uint64, time.Time, error) {
+	deviceRaw, err := device.Raw()
+	if err != nil {
+		return 0, time.Time{}, errcode.ErrSerialization.Wrap(err)
+	}
+
+	groupRaw, err := groupPK.Raw()
+	if err != nil {
+		return 0, time.Time{}, errcode.ErrSerialization.Wrap(err)
+	}
+
+	data, err := m.store.Get(idForOwnSecretStart(groupRaw, deviceRaw))
+	if err == datastore.ErrNotFound {
+		return 0, time.Time{}, errcode.ErrMissingInput.Wrap(err)
+	} else if err != nil {
+		return 0, time.Time{}, errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	if len(data) != 16 {
+		return 0, time.Time{}, errcode.ErrDeserialization
+	}
+
+	return binary.BigEndian.Uint64(data[:8]), time.Unix(int64(binary.BigEndian.Uint64(data[8:])), 0), nil
+}
+
 func (m *messageKeystore) RegisterChainKey(g *protocoltypes.Group, devicePK crypto.PubKey, ds *protocoltypes.DeviceSecret, isOwnPK bool) error {
 	if m == nil {
 		return errcode.ErrInvalidInput
@@ -198,9 +393,22 @@ func (m *messageKeystore) registerChainKey(g *protocoltypes.Group, devicePK cryp
 		return errcode.ErrDeserialization.Wrap(err)
 	}
 
-	if _, err := m.getDeviceChainKey(groupPK, devicePK); err == nil {
-		// device is already registered, ignore it
-		return nil
+	if removed, err := m.isDeviceRemoved(groupPK, devicePK); err != nil {
+		return err
+	} else if removed {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("device has been removed from the group"))
+	}
+
+	if current, err := m.getDeviceChainKey(groupPK, devicePK); err == nil {
+		if current.Generation >= ds.Generation {
+			// device is already registered with a secret as recent, ignore it
+			return nil
+		}
+
+		// the previous generation is kept to open the messages sent before the rotation
+		if err := m.putPreviousDeviceChainKey(groupPK, devicePK, current); err != nil {
+			return errcode.ErrInternal.Wrap(err)
+		}
 	}
 
 	// If own device store key as is, no need to precompute future keys
@@ -223,6 +431,78 @@ func (m *messageKeystore) registerChainKey(g *protocoltypes.Group, devicePK cryp
 	return nil
 }
 
+// RemoveDevice forgets the chain keys of a device removed from a group, including the keys precomputed for its
+// next messages. The device can't register a new secret afterwards and its messages are rejected.
+func (m *messageKeystore) RemoveDevice(g *protocoltypes.Group, devicePK crypto.PubKey) error {
+	if m == nil {
+		return errcode.ErrInvalidInput
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	groupPK, err := g.GetPubKey()
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	groupRaw, err := groupPK.Raw()
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	deviceRaw, err := devicePK.Raw()
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	if err := m.store.Put(idForRemovedDevice(groupRaw, deviceRaw), []byte{}); err != nil {
+		return errcode.ErrMessageKeyPersistencePut.Wrap(err)
+	}
+
+	res, err := m.store.Query(query.Query{Prefix: idForCachedKey(groupRaw, deviceRaw, 0).Parent().String(), KeysOnly: true})
+	if err != nil {
+		return errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	entries, err := res.Rest()
+	if err != nil {
+		return errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	keys := []datastore.Key{idForCurrentCK(groupRaw, deviceRaw), idForPreviousCK(groupRaw, deviceRaw)}
+	for _, entry := range entries {
+		keys = append(keys, datastore.NewKey(entry.Key))
+	}
+
+	for _, key := range keys {
+		if err := m.store.Delete(key); err != nil && err != datastore.ErrNotFound {
+			return errcode.ErrMessageKeyPersistencePut.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func (m *messageKeystore) isDeviceRemoved(groupPK, devicePK crypto.PubKey) (bool, error) {
+	groupRaw, err := groupPK.Raw()
+	if err != nil {
+		return false, errcode.ErrSerialization.Wrap(err)
+	}
+
+	deviceRaw, err := devicePK.Raw()
+	if err != nil {
+		return false, errcode.ErrSerialization.Wrap(err)
+	}
+
+	removed, err := m.store.Has(idForRemovedDevice(groupRaw, deviceRaw))
+	if err != nil {
+		return false, errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	return removed, nil
+}
+
 func (m *messageKeystore) preComputeKeys(device crypto.PubKey, g *protocoltypes.Group, ds *protocoltypes.DeviceSecret) (*protocoltypes.DeviceSecret, error) {
 	if m == nil {
 		return nil, errcode.ErrInvalidInput
@@ -236,7 +516,7 @@ func (m *messageKeystore) preComputeKeys(device crypto.PubKey, g *protocoltypes.
 		return nil, errcode.ErrDeserialization.Wrap(err)
 	}
 
-	knownCK, err := m.getDeviceChainKey(groupPK, device)
+	knownCK, err := m.getDeviceChainKeyForGeneration(groupPK, device, ds.Generation)
 	if err != nil && !errcode.Is(err, errcode.ErrMissingInput) {
 		return nil, errcode.ErrInternal.Wrap(err)
 	}
@@ -270,8 +550,9 @@ func (m *messageKeystore) preComputeKeys(device crypto.PubKey, g *protocoltypes.
 	}
 
 	return &protocoltypes.DeviceSecret{
-		Counter:  counter,
-		ChainKey: ck,
+		Counter:    counter,
+		ChainKey:   ck,
+		Generation: ds.Generation,
 	}, nil
 }
 
@@ -368,6 +649,18 @@ func (m *messageKeystore) OpenEnvelope(ctx context.Context, g *protocoltypes.Gro
 		return nil, nil, nil, errcode.ErrCryptoDecrypt.Wrap(err)
 	}
 
+	devicePK, err := crypto.UnmarshalEd25519PublicKey(headers.DevicePK)
+	if err != nil {
+		return nil, nil, nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	// the messages of a removed device are rejected rather than cached waiting for a secret
+	if removed, err := m.isDeviceRemoved(gPK, devicePK); err != nil {
+		return nil, nil, nil, err
+	} else if removed {
+		return nil, nil, nil, errcode.ErrCryptoDecrypt.Wrap(fmt.Errorf("message sent by a device removed from the group"))
+	}
+
 	msgBytes, decryptInfo, err := m.openPayload(id, gPK, env.Message, headers)
 	if err != nil {
 		return headers, nil, nil, errcode.ErrCryptoDecryptPayload.Wrap(err)
@@ -547,8 +840,9 @@ func (m *messageKeystore) deriveDeviceSecret(g *protocoltypes.Group, deviceSK cr
 	}
 
 	if err = m.putDeviceChainKey(groupPK, deviceSK.GetPublic(), &protocoltypes.DeviceSecret{
-		ChainKey: ck,
-		Counter:  ds.Counter + 1,
+		ChainKey:   ck,
+		Counter:    ds.Counter + 1,
+		Generation: ds.Generation,
 	}); err != nil {
 		return errcode.ErrCryptoKeyGeneration.Wrap(err)
 	}
@@ -565,7 +859,7 @@ func (m *messageKeystore) updateCurrentKey(groupPK, pk crypto.PubKey, ds *protoc
 		return errcode.ErrInvalidInput
 	}
 
-	currentCK, err := m.getDeviceChainKey(groupPK, pk)
+	currentCK, err := m.getDeviceChainKeyForGeneration(groupPK, pk, ds.Generation)
 	if err != nil {
 		return errcode.ErrInternal.Wrap(err)
 	}
@@ -574,18 +868,106 @@ func (m *messageKeystore) updateCurrentKey(groupPK, pk crypto.PubKey, ds *protoc
 		return nil
 	}
 
-	if err = m.putDeviceChainKey(groupPK, pk, ds); err != nil {
+	// messages sent using the previous generation only update its chain key
+	latestCK, err := m.getDeviceChainKey(groupPK, pk)
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	if latestCK.Generation != ds.Generation {
+		err = m.putPreviousDeviceChainKey(groupPK, pk, ds)
+	} else {
+		err = m.putDeviceChainKey(groupPK, pk, ds)
+	}
+
+	if err != nil {
 		return errcode.ErrInternal.Wrap(err)
 	}
 
 	return nil
 }
 
+// getDeviceChainKeyForGeneration returns the chain key of a device for the given generation, only the current
+// and the previous generations are kept
+func (m *messageKeystore) getDeviceChainKeyForGeneration(groupPK, pk crypto.PubKey, generation uint64) (*protocoltypes.DeviceSecret, error) {
+	ds, err := m.getDeviceChainKey(groupPK, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	if ds.Generation == generation {
+		return ds, nil
+	}
+
+	previous, err := m.getPreviousDeviceChainKey(groupPK, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous.Generation != generation {
+		return nil, errcode.ErrMissingInput.Wrap(fmt.Errorf("unknown device secret generation"))
+	}
+
+	return previous, nil
+}
+
+func (m *messageKeystore) getPreviousDeviceChainKey(groupPK, pk crypto.PubKey) (*protocoltypes.DeviceSecret, error) {
+	pkB, err := pk.Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	groupRaw, err := groupPK.Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	dsBytes, err := m.store.Get(idForPreviousCK(groupRaw, pkB))
+	if err == datastore.ErrNotFound {
+		return nil, errcode.ErrMissingInput.Wrap(err)
+	}
+	if err != nil {
+		return nil, errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	ds := &protocoltypes.DeviceSecret{}
+	if err := ds.Unmarshal(dsBytes); err != nil {
+		return nil, errcode.ErrInvalidInput
+	}
+
+	return ds, nil
+}
+
+func (m *messageKeystore) putPreviousDeviceChainKey(groupPK, device crypto.PubKey, ds *protocoltypes.DeviceSecret) error {
+	deviceRaw, err := device.Raw()
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	groupRaw, err := groupPK.Raw()
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	data, err := ds.Marshal()
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	if err := m.store.Put(idForPreviousCK(groupRaw, deviceRaw), data); err != nil {
+		return errcode.ErrMessageKeyPersistencePut.Wrap(err)
+	}
+
+	return nil
+}
+
 // newMessageKeystore instantiate a new messageKeystore
 func newMessageKeystore(s datastore.Datastore) *messageKeystore {
 	return &messageKeystore{
-		preComputedKeysCount: 100,
-		store:                s,
+		preComputedKeysCount:      100,
+		secretRenewalPeriod:       defaultSecretRenewalPeriod,
+		secretRenewalMessageCount: defaultSecretRenewalMessageCount,
+		store:                     s,
 	}
 }
 
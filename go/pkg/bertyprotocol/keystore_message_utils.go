@@ -52,9 +52,10 @@ func sealEnvelopeInternal(ctx context.Context, payload []byte, ds *protocoltypes
 	}
 
 	h := &protocoltypes.MessageHeaders{
-		Counter:  ds.Counter + 1,
-		DevicePK: devicePKRaw,
-		Sig:      sig,
+		Counter:    ds.Counter + 1,
+		DevicePK:   devicePKRaw,
+		Sig:        sig,
+		Generation: ds.Generation,
 	}
 
 	tracer.InjectSpanContextToMessageHeaders(ctx, h)
@@ -158,6 +159,18 @@ func idForCurrentCK(groupPK, pk []byte) datastore.Key {
 	return datastore.KeyWithNamespaces([]string{"currentCKs", hex.EncodeToString(groupPK), hex.EncodeToString(pk)})
 }
 
+func idForPreviousCK(groupPK, pk []byte) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{"previousCKs", hex.EncodeToString(groupPK), hex.EncodeToString(pk)})
+}
+
+func idForRemovedDevice(groupPK, pk []byte) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{"removedDevices", hex.EncodeToString(groupPK), hex.EncodeToString(pk)})
+}
+
+func idForOwnSecretStart(groupPK, pk []byte) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{"ownSecretStarts", hex.EncodeToString(groupPK), hex.EncodeToString(pk)})
+}
+
 func idForCID(id cid.Cid) datastore.Key {
 	// TODO: specify the id
 	return datastore.KeyWithNamespaces([]string{"cid", id.String()})
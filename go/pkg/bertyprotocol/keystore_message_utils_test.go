@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"berty.tech/berty/v2/go/internal/testutil"
+	"berty.tech/berty/v2/go/pkg/errcode"
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
 )
 
@@ -33,7 +34,7 @@ func addDummyMemberInMetadataStore(ctx context.Context, t testing.TB, ms *metada
 		assert.NoError(t, err)
 	}
 
-	_, err = metadataStoreSendSecret(ctx, ms, g, md, memberPK, ds)
+	_, err = metadataStoreSendSecret(ctx, ms, g, md, memberPK, nil, ds)
 	assert.NoError(t, err)
 
 	return md.device.GetPublic(), ds
@@ -508,3 +509,198 @@ func TestMessageKeyHolderSubscription(t *testing.T) {
 		testMessageKeyHolderSubscription(t, testCase.expectedNewDevices, testCase.slow)
 	}
 }
+
+func TestMessageKeystoreRotateDeviceSecret(t *testing.T) {
+	mks, cleanup := newInMemMessageKeystore()
+	defer cleanup()
+
+	g, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	groupPK, err := g.GetPubKey()
+	require.NoError(t, err)
+
+	acc := NewDeviceKeystore(keystore.NewMemKeystore())
+
+	ds0, err := mks.GetDeviceSecret(g, acc)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), ds0.Generation)
+
+	// the current secret is kept if it is already at the expected generation
+	ds, err := mks.RotateDeviceSecret(g, acc, 0)
+	require.NoError(t, err)
+	require.Equal(t, ds0, ds)
+
+	ds1, err := mks.RotateDeviceSecret(g, acc, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), ds1.Generation)
+	require.NotEqual(t, ds0.ChainKey, ds1.ChainKey)
+
+	ds, err = mks.GetDeviceSecret(g, acc)
+	require.NoError(t, err)
+	require.Equal(t, ds1, ds)
+
+	// secrets of another device are replaced only by a more recent generation
+	otherAcc := NewDeviceKeystore(keystore.NewMemKeystore())
+	otherMD, err := otherAcc.MemberDeviceForGroup(g)
+	require.NoError(t, err)
+	otherPK := otherMD.device.GetPublic()
+
+	otherDS1, err := newDeviceSecret()
+	require.NoError(t, err)
+	otherDS1.Generation = 1
+
+	require.NoError(t, mks.RegisterChainKey(g, otherPK, otherDS1, false))
+
+	otherDS0, err := newDeviceSecret()
+	require.NoError(t, err)
+
+	require.NoError(t, mks.RegisterChainKey(g, otherPK, otherDS0, false))
+
+	ds, err = mks.getDeviceChainKey(groupPK, otherPK)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), ds.Generation)
+	require.Equal(t, otherDS1.Counter+uint64(mks.getPrecomputedKeyExpectedCount()), ds.Counter)
+
+	otherDS2, err := newDeviceSecret()
+	require.NoError(t, err)
+	otherDS2.Generation = 2
+
+	require.NoError(t, mks.RegisterChainKey(g, otherPK, otherDS2, false))
+
+	ds, err = mks.getDeviceChainKey(groupPK, otherPK)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), ds.Generation)
+	require.Equal(t, otherDS2.Counter+uint64(mks.getPrecomputedKeyExpectedCount()), ds.Counter)
+
+	// keys precomputed from the previous secret are kept for the messages sent before the rotation
+	_, err = mks.getPrecomputedKey(groupPK, otherPK, otherDS1.Counter+1)
+	require.NoError(t, err)
+}
+
+func TestMessageKeystoreRemoveDevice(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	groupPK, err := g.GetPubKey()
+	require.NoError(t, err)
+
+	acc1 := NewDeviceKeystore(keystore.NewMemKeystore())
+	acc2 := NewDeviceKeystore(keystore.NewMemKeystore())
+
+	omd1, err := acc1.MemberDeviceForGroup(g)
+	require.NoError(t, err)
+
+	omd2, err := acc2.MemberDeviceForGroup(g)
+	require.NoError(t, err)
+
+	mkh1, cleanup := newInMemMessageKeystore()
+	defer cleanup()
+
+	mkh2, cleanup := newInMemMessageKeystore()
+	defer cleanup()
+
+	ds, err := mkh1.GetDeviceSecret(g, acc1)
+	require.NoError(t, err)
+	require.NoError(t, mkh2.RegisterChainKey(g, omd1.device.GetPublic(), ds, false))
+
+	payload, err := (&protocoltypes.EncryptedMessage{Plaintext: []byte("test")}).Marshal()
+	require.NoError(t, err)
+
+	env, err := mkh1.SealEnvelope(ctx, g, omd1.device, payload, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, mkh2.RemoveDevice(g, omd1.device.GetPublic()))
+
+	_, err = mkh2.getDeviceChainKey(groupPK, omd1.device.GetPublic())
+	require.True(t, errcode.Is(err, errcode.ErrMissingInput))
+
+	_, err = mkh2.getPrecomputedKey(groupPK, omd1.device.GetPublic(), ds.Counter+1)
+	require.True(t, errcode.Is(err, errcode.ErrMissingInput))
+
+	// the messages of the removed device are rejected, not cached
+	_, _, _, err = mkh2.OpenEnvelope(ctx, g, omd2.device.GetPublic(), env, cid.Undef)
+	require.Error(t, err)
+	require.False(t, errcode.Is(err, errcode.ErrCryptoDecryptPayload))
+
+	// a new secret of the removed device is refused
+	newDS, err := newDeviceSecret()
+	require.NoError(t, err)
+	newDS.Generation = ds.Generation + 1
+
+	require.Error(t, mkh2.RegisterChainKey(g, omd1.device.GetPublic(), newDS, false))
+}
+
+func TestMessageKeystoreRenewDeviceSecret(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	acc1 := NewDeviceKeystore(keystore.NewMemKeystore())
+	acc2 := NewDeviceKeystore(keystore.NewMemKeystore())
+
+	omd1, err := acc1.MemberDeviceForGroup(g)
+	require.NoError(t, err)
+
+	omd2, err := acc2.MemberDeviceForGroup(g)
+	require.NoError(t, err)
+
+	mkh1, cleanup := newInMemMessageKeystore()
+	defer cleanup()
+	mkh1.secretRenewalMessageCount = 2
+
+	mkh2, cleanup := newInMemMessageKeystore()
+	defer cleanup()
+
+	seal := func(body string) []byte {
+		payload, err := (&protocoltypes.EncryptedMessage{Plaintext: []byte(body)}).Marshal()
+		require.NoError(t, err)
+
+		env, err := mkh1.SealEnvelope(ctx, g, omd1.device, payload, nil)
+		require.NoError(t, err)
+
+		return env
+	}
+
+	open := func(env []byte, body string) {
+		_, msg, _, err := mkh2.OpenEnvelope(ctx, g, omd2.device.GetPublic(), env, cid.Undef)
+		require.NoError(t, err)
+		require.Equal(t, []byte(body), msg.Plaintext)
+	}
+
+	ds0, err := mkh1.GetDeviceSecret(g, acc1)
+	require.NoError(t, err)
+	require.NoError(t, mkh2.RegisterChainKey(g, omd1.device.GetPublic(), ds0, false))
+
+	renew, err := mkh1.DeviceSecretNeedsRenewal(g, acc1)
+	require.NoError(t, err)
+	require.False(t, renew)
+
+	env1 := seal("message 1")
+	env2 := seal("message 2")
+
+	renew, err = mkh1.DeviceSecretNeedsRenewal(g, acc1)
+	require.NoError(t, err)
+	require.True(t, renew)
+
+	ds1, err := mkh1.RenewDeviceSecret(g, acc1)
+	require.NoError(t, err)
+	require.Equal(t, ds0.Generation+1, ds1.Generation)
+
+	renew, err = mkh1.DeviceSecretNeedsRenewal(g, acc1)
+	require.NoError(t, err)
+	require.False(t, renew)
+
+	require.NoError(t, mkh2.RegisterChainKey(g, omd1.device.GetPublic(), ds1, false))
+
+	// messages of the previous generation can still be opened after the renewal
+	env3 := seal("message 3")
+	open(env3, "message 3")
+	open(env2, "message 2")
+	open(env1, "message 1")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ipfs/go-datastore"
 	ds_sync "github.com/ipfs/go-datastore/sync"
 	coreapi "github.com/ipfs/interface-go-ipfs-core"
+	ipfspath "github.com/ipfs/interface-go-ipfs-core/path"
 	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/api/trace"
@@ -299,38 +300,76 @@ func (s *BertyOrbitDB) openGroup(ctx context.Context, g *protocoltypes.Group, op
 	return gc, nil
 }
 
-func (s *BertyOrbitDB) openGroupReplication(ctx context.Context, g *protocoltypes.Group, options *orbitdb.CreateDBOptions) error {
+// openGroupReplication opens the stores of a group without being able to read their contents, the opened stores are
+// returned, nothing is returned if the group has already been opened using openGroup
+func (s *BertyOrbitDB) openGroupReplication(ctx context.Context, g *protocoltypes.Group, options *orbitdb.CreateDBOptions) ([]iface.Store, error) {
 	if g == nil || len(g.PublicKey) == 0 {
-		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("missing group or group pubkey"))
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("missing group or group pubkey"))
 	}
 
 	id := g.GroupIDAsString()
 
 	_, err := s.getGroupContext(id)
 	if err != nil && !errcode.Is(err, errcode.ErrMissingMapKey) {
-		return errcode.ErrInternal.Wrap(err)
+		return nil, errcode.ErrInternal.Wrap(err)
 	}
 	if err == nil {
-		return nil
+		return nil, nil
 	}
 
 	groupID := g.GroupIDAsString()
 	s.groups.Store(groupID, g)
 
 	if err := s.registerGroupSigningPubKey(g); err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = s.storeForGroup(ctx, s, g, options, groupMetadataStoreType, GroupOpenModeReplicate)
+	metadataStore, err := s.storeForGroup(ctx, s, g, options, groupMetadataStoreType, GroupOpenModeReplicate)
 	if err != nil {
-		return errors.Wrap(err, "unable to open database")
+		return nil, errors.Wrap(err, "unable to open database")
 	}
 
-	_, err = s.storeForGroup(ctx, s, g, options, groupMessageStoreType, GroupOpenModeReplicate)
+	messageStore, err := s.storeForGroup(ctx, s, g, options, groupMessageStoreType, GroupOpenModeReplicate)
 	if err != nil {
-		return errors.Wrap(err, "unable to open database")
+		return nil, errors.Wrap(err, "unable to open database")
+	}
+
+	return []iface.Store{metadataStore, messageStore}, nil
+}
+
+// closeGroupReplication closes the replicated stores of a group and removes their entries from the blockstore, groups
+// opened using openGroup are left untouched
+func (s *BertyOrbitDB) closeGroupReplication(ctx context.Context, g *protocoltypes.Group, stores []iface.Store) error {
+	id := g.GroupIDAsString()
+
+	if _, err := s.getGroupContext(id); err == nil {
+		return nil
+	} else if !errcode.Is(err, errcode.ErrMissingMapKey) {
+		return errcode.ErrInternal.Wrap(err)
 	}
 
+	for _, store := range stores {
+		entries := store.OpLog().GetEntries().Keys()
+
+		if err := store.Drop(); err != nil {
+			return errcode.ErrInternal.Wrap(err)
+		}
+
+		for _, cidStr := range entries {
+			c, err := cid.Parse(cidStr)
+			if err != nil {
+				return errcode.ErrDeserialization.Wrap(err)
+			}
+
+			if err := s.IPFS().Block().Rm(ctx, ipfspath.IpfsPath(c)); err != nil {
+				s.Logger().Warn("unable to remove replicated entry", zap.String("cid", cidStr), zap.Error(err))
+			}
+		}
+	}
+
+	s.groups.Delete(id)
+	s.groupsSigPubKey.Delete(id)
+
 	return nil
 }
 
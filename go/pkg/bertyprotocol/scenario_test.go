@@ -295,13 +295,12 @@ func TestScenario_ReplicateMessage(t *testing.T) {
 	// Create MultiMember Group
 	group := createMultiMemberGroupInstance(ctx, t, nodeA, nodeB)
 
-	// TODO: handle services auth
-	_ = tokenSK
-	// issuer, err := NewAuthTokenIssuer(tokenSecret, tokenSK)
-	// require.NoError(t, err)
-	// token, err := issuer.IssueToken([]string{ServiceReplicationID})
-	// require.NoError(t, err)
-	//
+	issuer, err := NewAuthTokenIssuer(tokenSecret, tokenSK)
+	require.NoError(t, err)
+	token, err := issuer.IssueToken([]string{ServiceReplicationID})
+	require.NoError(t, err)
+
+	// TODO: register the token in the account group and use ReplicationServiceRegisterGroup
 	// _, err = nodeA.Service.(*service).accountGroup.metadataStore.SendAccountServiceTokenAdded(ctx, &protocoltypes.ServiceToken{
 	// 	Token: token,
 	// 	SupportedServices: []*protocoltypes.ServiceTokenSupportedService{
@@ -316,12 +315,10 @@ func TestScenario_ReplicateMessage(t *testing.T) {
 	groupReplicable, err := group.FilterForReplication()
 	require.NoError(t, err)
 
-	// TODO: handle auth
-	_, err = replPeer.Service.ReplicateGroup(ctx, &protocoltypes.ReplicationServiceReplicateGroup_Request{
+	_, err = replPeer.Service.ReplicateGroup(testHelperContextWithAuthToken(ctx, token), &protocoltypes.ReplicationServiceReplicateGroup_Request{
 		Group: groupReplicable,
 	})
 	require.NoError(t, err)
-
 	_, err = nodeA.Service.AppMessageSend(ctx, &protocoltypes.AppMessageSend_Request{
 		GroupPK: group.PublicKey,
 		Payload: []byte("test1"),
@@ -50,6 +50,10 @@ type service struct {
 	close          func() error
 	startedAt      time.Time
 	host           host.Host
+	tinderDriver   tinder.Driver
+
+	deviceLinkLock       sync.Mutex
+	deviceLinkInvitation *deviceLinkInvitation
 }
 
 // Opts contains optional configuration flags for building a new Client
@@ -61,6 +65,7 @@ type Opts struct {
 	RootDatastore          ds.Batching
 	OrbitDB                *BertyOrbitDB
 	TinderDriver           tinder.Driver
+	PeerCache              *tinder.PeerCache
 	RendezvousRotationBase time.Duration
 	Host                   host.Host
 	PubSub                 *pubsub.PubSub
@@ -184,9 +189,16 @@ func New(ctx context.Context, opts Opts) (Service, error) {
 		opts.Logger.Warn("no tinder driver provided, incoming and outgoing contact requests won't be enabled")
 	}
 
+	if opts.PeerCache != nil && opts.Host != nil {
+		if err := recordGroupPeers(ctx, opts.Logger, opts.Host, opts.PeerCache); err != nil {
+			return nil, errcode.TODO.Wrap(err)
+		}
+	}
+
 	return &service{
 		ctx:            ctx,
 		host:           opts.Host,
+		tinderDriver:   opts.TinderDriver,
 		ipfsCoreAPI:    opts.IpfsCoreAPI,
 		logger:         opts.Logger,
 		odb:            opts.OrbitDB,
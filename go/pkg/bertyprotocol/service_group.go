@@ -1,6 +1,7 @@
 package bertyprotocol
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/libp2p/go-libp2p-core/crypto"
@@ -180,6 +181,17 @@ func (s *service) activateGroup(pk crypto.PubKey, localOnly bool) error {
 
 		TagGroupContextPeers(s.ctx, gc, s.ipfsCoreAPI, 42)
 
+		if g.GroupType == protocoltypes.GroupTypeMultiMember {
+			if _, err := s.accountGroup.MetadataStore().AddDeviceGroupKey(s.ctx, g); err != nil {
+				s.logger.Error("unable to announce group device key", zap.Error(err))
+			}
+		}
+
+		// devices might have been revoked while the group was not active
+		if err := s.revokeDevicesInGroup(s.ctx, gc); err != nil {
+			s.logger.Error("unable to revoke devices in group", zap.Error(err))
+		}
+
 		return nil
 	case protocoltypes.GroupTypeAccount:
 		return errcode.ErrInternal.Wrap(fmt.Errorf("deviceKeystore group should already be opened"))
@@ -188,6 +200,33 @@ func (s *service) activateGroup(pk crypto.PubKey, localOnly bool) error {
 	return errcode.ErrInternal.Wrap(fmt.Errorf("unknown group type"))
 }
 
+// revokeDevicesInGroup publishes in the given group the revocation of the devices revoked from the account
+func (s *service) revokeDevicesInGroup(ctx context.Context, gc *groupContext) error {
+	for _, devicePK := range s.accountGroup.MetadataStore().ListRevokedDevices() {
+		groupDevicePK := devicePK
+
+		// devices are using a dedicated key in each multi-member group
+		if gc.Group().GroupType == protocoltypes.GroupTypeMultiMember {
+			pk, err := s.accountGroup.MetadataStore().GetDeviceGroupKey(gc.Group(), devicePK)
+			if err != nil {
+				continue
+			}
+
+			groupDevicePK = pk
+		}
+
+		if _, err := gc.MetadataStore().GetMemberByDevice(groupDevicePK); err != nil {
+			continue
+		}
+
+		if _, err := gc.MetadataStore().RevokeDevice(ctx, groupDevicePK); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *service) getContextGroupForID(id []byte) (*groupContext, error) {
 	if len(id) == 0 {
 		return nil, errcode.ErrInternal.Wrap(fmt.Errorf("no group id provided"))
@@ -5,6 +5,8 @@ import (
 	stdcrypto "crypto"
 	"crypto/ed25519"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
@@ -28,7 +30,11 @@ const (
 
 type AuthTokenVerifier struct {
 	secret *[32]byte
-	pk     stdcrypto.PublicKey
+	pks    []stdcrypto.PublicKey
+
+	revokedLock    sync.RWMutex
+	revoked        map[string]struct{}
+	revocationList AuthTokenRevocationList
 }
 
 type AuthTokenIssuer struct {
@@ -36,9 +42,20 @@ type AuthTokenIssuer struct {
 	signer jose.Signer
 }
 
-func NewAuthTokenVerifier(secret []byte, pk ed25519.PublicKey) (*AuthTokenVerifier, error) {
-	if pk == nil {
-		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("pk is nil"))
+// NewAuthTokenVerifier creates a verifier accepting the tokens signed by any of the given keys, several keys are active
+// while the signing key of the issuer is rotated
+func NewAuthTokenVerifier(secret []byte, pks ...ed25519.PublicKey) (*AuthTokenVerifier, error) {
+	if len(pks) == 0 {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("no pk specified"))
+	}
+
+	verifierPKs := make([]stdcrypto.PublicKey, len(pks))
+	for i, pk := range pks {
+		if pk == nil {
+			return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("pk is nil"))
+		}
+
+		verifierPKs[i] = pk
 	}
 
 	secretArr, err := cryptoutil.KeySliceToArray(secret)
@@ -47,17 +64,20 @@ func NewAuthTokenVerifier(secret []byte, pk ed25519.PublicKey) (*AuthTokenVerifi
 	}
 
 	return &AuthTokenVerifier{
-		secret: secretArr,
-		pk:     pk,
+		secret:  secretArr,
+		pks:     verifierPKs,
+		revoked: map[string]struct{}{},
 	}, nil
 }
 
-func NewAuthTokenIssuer(secret []byte, sk ed25519.PrivateKey) (*AuthTokenIssuer, error) {
+// NewAuthTokenIssuer creates an issuer signing with sk, the codes and tokens signed with the previous keys are still
+// accepted
+func NewAuthTokenIssuer(secret []byte, sk ed25519.PrivateKey, previousPKs ...ed25519.PublicKey) (*AuthTokenIssuer, error) {
 	if sk == nil {
 		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("sk is nil"))
 	}
 
-	tokVerifier, err := NewAuthTokenVerifier(secret, sk.Public().(ed25519.PublicKey))
+	tokVerifier, err := NewAuthTokenVerifier(secret, append([]ed25519.PublicKey{sk.Public().(ed25519.PublicKey)}, previousPKs...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -129,11 +149,25 @@ func (r *AuthTokenVerifier) decryptVerify(token string) ([]byte, error) {
 		return nil, err
 	}
 
-	data, err := parsed.Verify(r.pk)
+	var (
+		data     []byte
+		signerPK stdcrypto.PublicKey
+	)
+	for _, pk := range r.pks {
+		if data, err = parsed.Verify(pk); err == nil {
+			signerPK = pk
+			break
+		}
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
+	if r.isSigningKeyRetired(signerPK) {
+		return nil, errcode.ErrServicesAuthTokenRevoked
+	}
+
 	if len(data) < secretbox.Overhead+cryptoutil.NonceSize {
 		return nil, errcode.ErrCryptoDecrypt
 	}
@@ -170,26 +204,38 @@ func (r *AuthTokenVerifier) VerifyCode(code, codeVerifier string) (*protocoltype
 }
 
 func (r *AuthTokenIssuer) IssueToken(services []string) (string, error) {
+	token, _, err := r.issueToken(services, 0)
+	return token, err
+}
+
+// issueToken issues a token expiring at the given unix timestamp in milliseconds, or never if zero
+func (r *AuthTokenIssuer) issueToken(services []string, expiration int64) (string, *protocoltypes.ServicesTokenCode, error) {
 	tokenID, err := uuid.NewV4()
 	if err != nil {
-		return "", errcode.ErrInternal.Wrap(err)
+		return "", nil, errcode.ErrInternal.Wrap(err)
 	}
 
 	if len(services) == 0 {
-		return "", errcode.ErrInvalidInput.Wrap(fmt.Errorf("no services specified"))
+		return "", nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("no services specified"))
 	}
 
 	tokenPayload := &protocoltypes.ServicesTokenCode{
-		Services: services,
-		TokenID:  tokenID.String(),
+		Services:   services,
+		TokenID:    tokenID.String(),
+		Expiration: expiration,
 	}
 
 	payload, err := tokenPayload.Marshal()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return r.encryptSign(payload)
+	token, err := r.encryptSign(payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, tokenPayload, nil
 }
 
 func (r *AuthTokenVerifier) VerifyToken(token, serviceID string) (*protocoltypes.ServicesTokenCode, error) {
@@ -207,6 +253,14 @@ func (r *AuthTokenVerifier) VerifyToken(token, serviceID string) (*protocoltypes
 		return nil, errcode.ErrServicesAuthServiceInvalidToken
 	}
 
+	if r.IsTokenRevoked(tokenObj.TokenID) {
+		return nil, errcode.ErrServicesAuthTokenRevoked
+	}
+
+	if tokenObj.Expiration > 0 && tokenObj.Expiration <= time.Now().UnixNano()/int64(time.Millisecond) {
+		return nil, errcode.ErrServicesAuthTokenExpired
+	}
+
 	for _, s := range tokenObj.Services {
 		if s == serviceID {
 			return tokenObj, nil
@@ -216,6 +270,56 @@ func (r *AuthTokenVerifier) VerifyToken(token, serviceID string) (*protocoltypes
 	return nil, errcode.ErrServicesAuthServiceNotSupported
 }
 
+// RevokeToken adds a token to the revocation list, it will be rejected by VerifyToken
+func (r *AuthTokenVerifier) RevokeToken(tokenID string) {
+	r.revokedLock.Lock()
+	r.revoked[tokenID] = struct{}{}
+	r.revokedLock.Unlock()
+}
+
+// SetRevocationList makes the verifier consult an external revocation list, such as the store of an AuthTokenServer,
+// in addition to the tokens revoked using RevokeToken, the codes and tokens signed by a key retired in the list are
+// rejected
+func (r *AuthTokenVerifier) SetRevocationList(list AuthTokenRevocationList) {
+	r.revokedLock.Lock()
+	r.revocationList = list
+	r.revokedLock.Unlock()
+}
+
+// IsTokenRevoked returns true if the token is in the revocation list, tokens are considered revoked if the external
+// revocation list can't be consulted
+func (r *AuthTokenVerifier) IsTokenRevoked(tokenID string) bool {
+	r.revokedLock.RLock()
+	_, ok := r.revoked[tokenID]
+	list := r.revocationList
+	r.revokedLock.RUnlock()
+
+	if ok || list == nil {
+		return ok
+	}
+
+	revoked, err := list.IsTokenRevoked(tokenID)
+
+	return err != nil || revoked
+}
+
+// isSigningKeyRetired returns true if the external revocation list retired the key, keys are considered retired if the
+// list can't be consulted
+func (r *AuthTokenVerifier) isSigningKeyRetired(pk stdcrypto.PublicKey) bool {
+	r.revokedLock.RLock()
+	list := r.revocationList
+	r.revokedLock.RUnlock()
+
+	edPK, ok := pk.(ed25519.PublicKey)
+	if list == nil || !ok {
+		return false
+	}
+
+	retired, err := list.IsSigningKeyRetired(edPK)
+
+	return err != nil || retired
+}
+
 func (r *AuthTokenVerifier) GRPCAuthInterceptor(serviceID string) func(ctx context.Context) (context.Context, error) {
 	return func(ctx context.Context) (context.Context, error) {
 		token, err := grpc_auth.AuthFromMD(ctx, "bearer")
@@ -8,36 +8,78 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
 	"berty.tech/berty/v2/go/pkg/errcode"
 )
 
+// AuthTokenServerOpts are the optional parameters of an AuthTokenServer
+type AuthTokenServerOpts struct {
+	// Store records the issued tokens, when set the signing keys are loaded from it and sk is only used to initialize
+	// an empty store
+	Store AuthTokenStore
+
+	// TokenTTL is the validity duration of the issued tokens, they don't expire if zero
+	TokenTTL time.Duration
+}
+
 type AuthTokenServer struct {
-	issuer   *AuthTokenIssuer
-	services map[string]string
-	logger   *zap.Logger
+	issuer     *AuthTokenIssuer
+	signingKey string
+	services   map[string]string
+	logger     *zap.Logger
+	opts       AuthTokenServerOpts
 }
 
-func NewAuthTokenServer(secret []byte, sk ed25519.PrivateKey, services map[string]string, logger *zap.Logger) (*AuthTokenServer, error) {
+func NewAuthTokenServer(secret []byte, sk ed25519.PrivateKey, services map[string]string, logger *zap.Logger, opts *AuthTokenServerOpts) (*AuthTokenServer, error) {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 
+	if opts == nil {
+		opts = &AuthTokenServerOpts{}
+	}
+
 	if len(services) == 0 {
 		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("missing services list"))
 	}
 
-	issuer, err := NewAuthTokenIssuer(secret, sk)
+	var previousPKs []ed25519.PublicKey
+	if opts.Store != nil {
+		sks, err := opts.Store.ActiveSigningKeys()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(sks) == 0 && sk != nil {
+			if err := opts.Store.AddSigningKey(sk); err != nil {
+				return nil, err
+			}
+
+			sks = []ed25519.PrivateKey{sk}
+		}
+
+		if len(sks) > 0 {
+			sk = sks[0]
+			for _, previous := range sks[1:] {
+				previousPKs = append(previousPKs, previous.Public().(ed25519.PublicKey))
+			}
+		}
+	}
+
+	issuer, err := NewAuthTokenIssuer(secret, sk, previousPKs...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &AuthTokenServer{
-		issuer:   issuer,
-		services: services,
-		logger:   logger,
+		issuer:     issuer,
+		signingKey: authStoreEncodeKey(sk.Public().(ed25519.PublicKey)),
+		services:   services,
+		logger:     logger,
+		opts:       *opts,
 	}, nil
 }
 
@@ -155,16 +197,41 @@ func (a *AuthTokenServer) authTokenServerHTTPOAuthToken(w http.ResponseWriter, r
 		return
 	}
 
-	token, err := a.issuer.IssueToken(codeData.Services)
+	expiration := int64(0)
+	if a.opts.TokenTTL > 0 {
+		expiration = time.Now().Add(a.opts.TokenTTL).UnixNano() / int64(time.Millisecond)
+	}
+
+	token, tokenData, err := a.issuer.issueToken(codeData.Services, expiration)
 	if err != nil {
 		a.authTokenServerJSONError(w, "server_error", "unable to issue token", a.logger)
 		return
 	}
 
-	a.authTokenServerJSONResponse(w, map[string]interface{}{
+	if a.opts.Store != nil {
+		if err := a.opts.Store.AddIssuedToken(&AuthIssuedToken{
+			TokenID:    tokenData.TokenID,
+			Services:   strings.Join(tokenData.Services, ","),
+			IssuedAt:   authStoreNow(),
+			Expiration: expiration,
+			SigningKey: a.signingKey,
+		}); err != nil {
+			a.logger.Error("unable to record issued token", zap.Error(err))
+			a.authTokenServerJSONError(w, "server_error", "unable to issue token", a.logger)
+			return
+		}
+	}
+
+	response := map[string]interface{}{
 		"access_token": token,
 		"token_type":   "bearer",
 		"scope":        strings.Join(codeData.Services, ","),
 		"services":     a.services,
-	}, 200, a.logger)
+	}
+
+	if a.opts.TokenTTL > 0 {
+		response["expires_in"] = int64(a.opts.TokenTTL / time.Second)
+	}
+
+	a.authTokenServerJSONResponse(w, response, 200, a.logger)
 }
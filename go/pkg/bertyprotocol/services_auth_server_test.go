@@ -27,27 +27,27 @@ func TestNewAuthTokenServer(t *testing.T) {
 	}
 	secret, _, sk := helperGenerateTokenIssuerSecrets(t)
 
-	ats, err := NewAuthTokenServer(secret, nil, services, zap.NewNop())
+	ats, err := NewAuthTokenServer(secret, nil, services, zap.NewNop(), nil)
 	require.Error(t, err)
 	require.Nil(t, ats)
 
-	ats, err = NewAuthTokenServer(secret, sk, nil, zap.NewNop())
+	ats, err = NewAuthTokenServer(secret, sk, nil, zap.NewNop(), nil)
 	require.Error(t, err)
 	require.Nil(t, ats)
 
-	ats, err = NewAuthTokenServer(secret, sk, map[string]string{}, zap.NewNop())
+	ats, err = NewAuthTokenServer(secret, sk, map[string]string{}, zap.NewNop(), nil)
 	require.Error(t, err)
 	require.Nil(t, ats)
 
-	ats, err = NewAuthTokenServer(nil, sk, services, zap.NewNop())
+	ats, err = NewAuthTokenServer(nil, sk, services, zap.NewNop(), nil)
 	require.Error(t, err)
 	require.Nil(t, ats)
 
-	ats, err = NewAuthTokenServer(secret, sk, services, nil)
+	ats, err = NewAuthTokenServer(secret, sk, services, nil, nil)
 	require.NoError(t, err)
 	require.NotNil(t, ats)
 
-	ats, err = NewAuthTokenServer(secret, sk, services, zap.NewNop())
+	ats, err = NewAuthTokenServer(secret, sk, services, zap.NewNop(), nil)
 	require.NoError(t, err)
 	require.NotNil(t, ats)
 
@@ -0,0 +1,279 @@
+package bertyprotocol
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"moul.io/zapgorm2"
+
+	"berty.tech/berty/v2/go/pkg/errcode"
+)
+
+// AuthTokenRevocationList is consulted by AuthTokenVerifier when verifying a token
+type AuthTokenRevocationList interface {
+	IsTokenRevoked(tokenID string) (bool, error)
+
+	// IsSigningKeyRetired returns true if the key has been retired, keys unknown to the list are not retired
+	IsSigningKeyRetired(pk ed25519.PublicKey) (bool, error)
+}
+
+// AuthTokenStore persists the tokens issued by an AuthTokenServer and its signing keys, the seeds of the signing keys
+// are stored unencrypted and the store must only be readable by the server
+type AuthTokenStore interface {
+	AuthTokenRevocationList
+
+	// AddIssuedToken records a token issued by the server
+	AddIssuedToken(token *AuthIssuedToken) error
+
+	// ListIssuedTokens returns the issued tokens, most recent first
+	ListIssuedTokens() ([]*AuthIssuedToken, error)
+
+	// RevokeToken marks an issued token as revoked
+	RevokeToken(tokenID string) error
+
+	// AddSigningKey adds a key used to sign the codes and tokens, the last added key is used to sign
+	AddSigningKey(sk ed25519.PrivateKey) error
+
+	// ActiveSigningKeys returns the keys which haven't been retired, most recent first
+	ActiveSigningKeys() ([]ed25519.PrivateKey, error)
+
+	// RetireSigningKey removes a key from the active keys, the codes and tokens it signed are rejected afterwards by the
+	// server and by the verifiers using this store as their revocation list, verifiers only configured with the public
+	// key keep accepting them until their configuration is updated
+	RetireSigningKey(pk ed25519.PublicKey) error
+
+	Close() error
+}
+
+// AuthIssuedToken is a token issued by an AuthTokenServer, dates are unix timestamps in milliseconds
+type AuthIssuedToken struct {
+	TokenID string `gorm:"primaryKey"`
+
+	// Services is the comma separated list of the services granted by the token
+	Services string
+
+	IssuedAt int64
+
+	// Expiration is zero if the token doesn't expire
+	Expiration int64
+
+	// RevokedAt is zero if the token hasn't been revoked
+	RevokedAt int64
+
+	// SigningKey is the base64 encoded public key of the key used to sign the token
+	SigningKey string
+}
+
+// AuthSigningKey is a key used by an AuthTokenServer to sign codes and tokens
+type AuthSigningKey struct {
+	// PublicKey is the base64 encoded public key
+	PublicKey string `gorm:"primaryKey"`
+
+	// Seed is the unencrypted seed of the private key
+	Seed []byte
+
+	AddedAt int64
+
+	// RetiredAt is zero if the key is still active
+	RetiredAt int64
+}
+
+func authStoreEncodeKey(pk ed25519.PublicKey) string {
+	return base64.RawStdEncoding.EncodeToString(pk)
+}
+
+func authStoreNow() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+type authTokenSQLiteStore struct {
+	db *gorm.DB
+}
+
+// NewAuthTokenSQLiteStore opens the SQLite database at the given path, ":memory:" can be used for a volatile store.
+// The signing keys are stored unencrypted, the database file is created readable by its owner only.
+func NewAuthTokenSQLiteStore(path string, logger *zap.Logger) (AuthTokenStore, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	if path != ":memory:" {
+		f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, errcode.ErrDBRead.Wrap(err)
+		}
+		_ = f.Close()
+	}
+
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: zapgorm2.New(logger.Named("gorm")),
+	})
+	if err != nil {
+		return nil, errcode.ErrDBRead.Wrap(err)
+	}
+
+	// a volatile database is bound to its connection
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, errcode.ErrDBRead.Wrap(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&AuthIssuedToken{}, &AuthSigningKey{}); err != nil {
+		_ = sqlDB.Close()
+		return nil, errcode.ErrDBWrite.Wrap(err)
+	}
+
+	return &authTokenSQLiteStore{db: db}, nil
+}
+
+func (s *authTokenSQLiteStore) AddIssuedToken(token *AuthIssuedToken) error {
+	if token == nil || token.TokenID == "" {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("a token id is required"))
+	}
+
+	if err := s.db.Create(token).Error; err != nil {
+		return errcode.ErrDBWrite.Wrap(err)
+	}
+
+	return nil
+}
+
+func (s *authTokenSQLiteStore) ListIssuedTokens() ([]*AuthIssuedToken, error) {
+	var tokens []*AuthIssuedToken
+	if err := s.db.Order("issued_at DESC").Find(&tokens).Error; err != nil {
+		return nil, errcode.ErrDBRead.Wrap(err)
+	}
+
+	return tokens, nil
+}
+
+func (s *authTokenSQLiteStore) RevokeToken(tokenID string) error {
+	res := s.db.Model(&AuthIssuedToken{}).Where("token_id = ? AND revoked_at = 0", tokenID).Update("revoked_at", authStoreNow())
+	if res.Error != nil {
+		return errcode.ErrDBWrite.Wrap(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		if revoked, err := s.IsTokenRevoked(tokenID); err != nil {
+			return err
+		} else if revoked {
+			return errcode.ErrInvalidInput.Wrap(fmt.Errorf("token already revoked"))
+		}
+
+		return errcode.ErrServicesAuthUnknownToken
+	}
+
+	return nil
+}
+
+func (s *authTokenSQLiteStore) IsTokenRevoked(tokenID string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&AuthIssuedToken{}).Where("token_id = ? AND revoked_at != 0", tokenID).Count(&count).Error; err != nil {
+		return false, errcode.ErrDBRead.Wrap(err)
+	}
+
+	return count > 0, nil
+}
+
+func (s *authTokenSQLiteStore) AddSigningKey(sk ed25519.PrivateKey) error {
+	if len(sk) != ed25519.PrivateKeySize {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("invalid sk size"))
+	}
+
+	if err := s.db.Create(&AuthSigningKey{
+		PublicKey: authStoreEncodeKey(sk.Public().(ed25519.PublicKey)),
+		Seed:      sk.Seed(),
+		AddedAt:   authStoreNow(),
+	}).Error; err != nil {
+		return errcode.ErrDBWrite.Wrap(err)
+	}
+
+	return nil
+}
+
+func (s *authTokenSQLiteStore) ActiveSigningKeys() ([]ed25519.PrivateKey, error) {
+	var keys []*AuthSigningKey
+	// keys added during the same millisecond are ordered by insertion
+	if err := s.db.Where("retired_at = 0").Order("added_at DESC, rowid DESC").Find(&keys).Error; err != nil {
+		return nil, errcode.ErrDBRead.Wrap(err)
+	}
+
+	sks := make([]ed25519.PrivateKey, len(keys))
+	for i, k := range keys {
+		if len(k.Seed) != ed25519.SeedSize {
+			return nil, errcode.ErrDeserialization.Wrap(fmt.Errorf("invalid seed size for key %s", k.PublicKey))
+		}
+
+		sks[i] = ed25519.NewKeyFromSeed(k.Seed)
+	}
+
+	return sks, nil
+}
+
+func (s *authTokenSQLiteStore) IsSigningKeyRetired(pk ed25519.PublicKey) (bool, error) {
+	var count int64
+	if err := s.db.Model(&AuthSigningKey{}).Where("public_key = ? AND retired_at != 0", authStoreEncodeKey(pk)).Count(&count).Error; err != nil {
+		return false, errcode.ErrDBRead.Wrap(err)
+	}
+
+	return count > 0, nil
+}
+
+func (s *authTokenSQLiteStore) RetireSigningKey(pk ed25519.PublicKey) error {
+	res := s.db.Model(&AuthSigningKey{}).Where("public_key = ? AND retired_at = 0", authStoreEncodeKey(pk)).Update("retired_at", authStoreNow())
+	if res.Error != nil {
+		return errcode.ErrDBWrite.Wrap(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("unknown or already retired key"))
+	}
+
+	return nil
+}
+
+func (s *authTokenSQLiteStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	return sqlDB.Close()
+}
+
+// AuthTokenStoreRotateKey adds a new signing key to the store and retires the active keys but the previous one, so the
+// tokens signed before the rotation remain valid until the next one
+func AuthTokenStoreRotateKey(store AuthTokenStore) (ed25519.PrivateKey, error) {
+	active, err := store.ActiveSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, errcode.ErrCryptoKeyGeneration.Wrap(err)
+	}
+
+	if err := store.AddSigningKey(sk); err != nil {
+		return nil, err
+	}
+
+	for i, k := range active {
+		if i == 0 {
+			continue
+		}
+
+		if err := store.RetireSigningKey(k.Public().(ed25519.PublicKey)); err != nil {
+			return nil, err
+		}
+	}
+
+	return sk, nil
+}
@@ -0,0 +1,197 @@
+package bertyprotocol
+
+import (
+	"crypto/ed25519"
+	crand "crypto/rand"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"berty.tech/berty/v2/go/pkg/errcode"
+)
+
+func helperNewAuthTokenStore(t *testing.T) AuthTokenStore {
+	t.Helper()
+
+	store, err := NewAuthTokenSQLiteStore(":memory:", zap.NewNop())
+	require.NoError(t, err)
+
+	return store
+}
+
+func TestAuthTokenSQLiteStoreTokens(t *testing.T) {
+	store := helperNewAuthTokenStore(t)
+	defer store.Close()
+
+	require.Error(t, store.AddIssuedToken(nil))
+	require.Error(t, store.AddIssuedToken(&AuthIssuedToken{}))
+
+	require.NoError(t, store.AddIssuedToken(&AuthIssuedToken{TokenID: "tok1", Services: "srv1", IssuedAt: 1}))
+	require.NoError(t, store.AddIssuedToken(&AuthIssuedToken{TokenID: "tok2", Services: "srv1,srv2", IssuedAt: 2}))
+
+	// tokens can only be recorded once
+	require.Error(t, store.AddIssuedToken(&AuthIssuedToken{TokenID: "tok1", IssuedAt: 3}))
+
+	tokens, err := store.ListIssuedTokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.Equal(t, "tok2", tokens[0].TokenID)
+	require.Equal(t, "srv1,srv2", tokens[0].Services)
+	require.Equal(t, "tok1", tokens[1].TokenID)
+
+	revoked, err := store.IsTokenRevoked("tok1")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, store.RevokeToken("tok1"))
+
+	revoked, err = store.IsTokenRevoked("tok1")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsTokenRevoked("tok2")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.True(t, errcode.Is(store.RevokeToken("tok1"), errcode.ErrInvalidInput))
+	require.True(t, errcode.Is(store.RevokeToken("unknown"), errcode.ErrServicesAuthUnknownToken))
+
+	tokens, err = store.ListIssuedTokens()
+	require.NoError(t, err)
+	require.NotZero(t, tokens[1].RevokedAt)
+}
+
+func TestAuthTokenStoreRotateKey(t *testing.T) {
+	store := helperNewAuthTokenStore(t)
+	defer store.Close()
+
+	secret, _, sk1 := helperGenerateTokenIssuerSecrets(t)
+	require.NoError(t, store.AddSigningKey(sk1))
+
+	sk2, err := AuthTokenStoreRotateKey(store)
+	require.NoError(t, err)
+
+	active, err := store.ActiveSigningKeys()
+	require.NoError(t, err)
+	require.Equal(t, []ed25519.PrivateKey{sk2, sk1}, active)
+
+	sk3, err := AuthTokenStoreRotateKey(store)
+	require.NoError(t, err)
+
+	active, err = store.ActiveSigningKeys()
+	require.NoError(t, err)
+	require.Equal(t, []ed25519.PrivateKey{sk3, sk2}, active)
+
+	require.Error(t, store.RetireSigningKey(sk1.Public().(ed25519.PublicKey)))
+
+	// tokens signed by the previous key are accepted, the retired ones are rejected
+	issuer, err := NewAuthTokenIssuer(secret, active[0], active[1].Public().(ed25519.PublicKey))
+	require.NoError(t, err)
+
+	// a verifier still configured with the retired key rejects its tokens when consulting the store
+	verifier, err := NewAuthTokenVerifier(secret, sk3.Public().(ed25519.PublicKey), sk2.Public().(ed25519.PublicKey), sk1.Public().(ed25519.PublicKey))
+	require.NoError(t, err)
+	verifier.SetRevocationList(store)
+
+	for _, c := range []struct {
+		sk    ed25519.PrivateKey
+		valid bool
+	}{
+		{sk3, true},
+		{sk2, true},
+		{sk1, false},
+	} {
+		signer, err := NewAuthTokenIssuer(secret, c.sk)
+		require.NoError(t, err)
+
+		token, err := signer.IssueToken([]string{"service"})
+		require.NoError(t, err)
+
+		_, err = issuer.VerifyToken(token, "service")
+		if c.valid {
+			require.NoError(t, err)
+		} else {
+			require.Error(t, err)
+		}
+
+		_, err = verifier.VerifyToken(token, "service")
+		if c.valid {
+			require.NoError(t, err)
+		} else {
+			require.True(t, errcode.Is(err, errcode.ErrServicesAuthTokenRevoked))
+		}
+	}
+}
+
+func TestAuthTokenServerStore(t *testing.T) {
+	store := helperNewAuthTokenStore(t)
+	defer store.Close()
+
+	secret, pk, sk := helperGenerateTokenIssuerSecrets(t)
+
+	ats, err := NewAuthTokenServer(secret, sk, map[string]string{ServiceReplicationID: "servicehost:1234"}, zap.NewNop(), &AuthTokenServerOpts{
+		Store:    store,
+		TokenTTL: time.Hour,
+	})
+	require.NoError(t, err)
+
+	// the key used to initialize the store is saved
+	active, err := store.ActiveSigningKeys()
+	require.NoError(t, err)
+	require.Equal(t, []ed25519.PrivateKey{sk}, active)
+
+	// once initialized, the keys are loaded from the store
+	_, otherSK, err := ed25519.GenerateKey(crand.Reader)
+	require.NoError(t, err)
+
+	otherATS, err := NewAuthTokenServer(secret, otherSK, map[string]string{ServiceReplicationID: "servicehost:1234"}, zap.NewNop(), &AuthTokenServerOpts{Store: store})
+	require.NoError(t, err)
+	require.Equal(t, ats.signingKey, otherATS.signingKey)
+
+	server := httptest.NewServer(ats.serveMux())
+	defer server.Close()
+
+	codeVerifier, codeChallenge, err := authSessionCodeVerifierAndChallenge()
+	require.NoError(t, err)
+
+	code, err := ats.issuer.IssueCode(codeChallenge, []string{ServiceReplicationID})
+	require.NoError(t, err)
+
+	res, err := server.Client().PostForm(server.URL+AuthHTTPPathTokenExchange, url.Values{
+		"grant_type":    {AuthGrantType},
+		"code":          {code},
+		"code_verifier": {codeVerifier},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 200, res.StatusCode)
+
+	resMsg := &authExchangeResponse{}
+	require.NoError(t, json.Unmarshal(mustReadAllBytes(t, res.Body), resMsg))
+	require.Equal(t, int64(3600), resMsg.ExpiresIn)
+
+	tokens, err := store.ListIssuedTokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, ServiceReplicationID, tokens[0].Services)
+	require.Equal(t, authStoreEncodeKey(pk), tokens[0].SigningKey)
+	require.NotZero(t, tokens[0].Expiration)
+
+	verifier, err := NewAuthTokenVerifier(secret, pk)
+	require.NoError(t, err)
+	verifier.SetRevocationList(store)
+
+	tokenData, err := verifier.VerifyToken(resMsg.AccessToken, ServiceReplicationID)
+	require.NoError(t, err)
+	require.Equal(t, tokens[0].TokenID, tokenData.TokenID)
+	require.Equal(t, tokens[0].Expiration, tokenData.Expiration)
+
+	require.NoError(t, store.RevokeToken(tokenData.TokenID))
+
+	_, err = verifier.VerifyToken(resMsg.AccessToken, ServiceReplicationID)
+	require.True(t, errcode.Is(err, errcode.ErrServicesAuthTokenRevoked))
+}
@@ -3,12 +3,16 @@ package bertyprotocol
 import (
 	"crypto/ed25519"
 	crand "crypto/rand"
+	"io"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"berty.tech/berty/v2/go/internal/cryptoutil"
+	"berty.tech/berty/v2/go/pkg/errcode"
+	"berty.tech/berty/v2/go/pkg/protocoltypes"
 )
 
 func helperGenerateTokenIssuerSecrets(t *testing.T) ([]byte, ed25519.PublicKey, ed25519.PrivateKey) {
@@ -207,6 +211,56 @@ func TestVerifyToken(t *testing.T) {
 	require.Empty(t, tokenCode)
 }
 
+func TestVerifyTokenRevoked(t *testing.T) {
+	secret, pk, sk := helperGenerateTokenIssuerSecrets(t)
+	issuer, err := NewAuthTokenIssuer(secret, sk)
+	require.NoError(t, err)
+
+	verifier, err := NewAuthTokenVerifier(secret, pk)
+	require.NoError(t, err)
+
+	token, err := issuer.IssueToken([]string{"service"})
+	require.NoError(t, err)
+
+	otherToken, err := issuer.IssueToken([]string{"service"})
+	require.NoError(t, err)
+
+	tokenCode, err := verifier.VerifyToken(token, "service")
+	require.NoError(t, err)
+	require.False(t, verifier.IsTokenRevoked(tokenCode.TokenID))
+
+	verifier.RevokeToken(tokenCode.TokenID)
+	require.True(t, verifier.IsTokenRevoked(tokenCode.TokenID))
+
+	_, err = verifier.VerifyToken(token, "service")
+	require.True(t, errcode.Is(err, errcode.ErrServicesAuthTokenRevoked))
+
+	// other tokens are still accepted
+	_, err = verifier.VerifyToken(otherToken, "service")
+	require.NoError(t, err)
+}
+
+func TestVerifyTokenExpired(t *testing.T) {
+	secret, _, sk := helperGenerateTokenIssuerSecrets(t)
+	issuer, err := NewAuthTokenIssuer(secret, sk)
+	require.NoError(t, err)
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	token, _, err := issuer.issueToken([]string{"service"}, now+int64(time.Hour/time.Millisecond))
+	require.NoError(t, err)
+
+	tokenCode, err := issuer.VerifyToken(token, "service")
+	require.NoError(t, err)
+	require.NotZero(t, tokenCode.Expiration)
+
+	token, _, err = issuer.issueToken([]string{"service"}, now-1)
+	require.NoError(t, err)
+
+	_, err = issuer.VerifyToken(token, "service")
+	require.True(t, errcode.Is(err, errcode.ErrServicesAuthTokenExpired))
+}
+
 func TestServiceAuthServiceInitFlow(t *testing.T) {
 	s := service{}
 
@@ -256,3 +310,72 @@ func TestServiceAuthServiceInitFlow(t *testing.T) {
 	require.NotEqual(t, state, s.authSession.Load().(*authSession).state)
 	require.NotEqual(t, codeVerifier, s.authSession.Load().(*authSession).codeVerifier)
 }
+
+func TestServicesTokenRemove(t *testing.T) {
+	ctx, cancel, mn, rdvPeer := testHelperIPFSSetUp(t)
+	defer cancel()
+
+	node, closeNode := NewTestingProtocol(ctx, t, &TestingOpts{
+		Mocknet: mn,
+		RDVPeer: rdvPeer.Peerstore().PeerInfo(rdvPeer.ID()),
+	}, nil)
+	defer closeNode()
+
+	listTokens := func() []string {
+		cl, err := node.Client.ServicesTokenList(ctx, &protocoltypes.ServicesTokenList_Request{})
+		require.NoError(t, err)
+
+		tokenIDs := []string(nil)
+		for {
+			item, err := cl.Recv()
+			if err == io.EOF {
+				return tokenIDs
+			}
+			require.NoError(t, err)
+
+			tokenIDs = append(tokenIDs, item.TokenID)
+		}
+	}
+
+	services := []*protocoltypes.ServiceTokenSupportedService{{ServiceType: ServiceReplicationID, ServiceEndpoint: "localhost:1234"}}
+	tok := &protocoltypes.ServiceToken{
+		Token:             "tok1",
+		AuthenticationURL: "https://url1/",
+		SupportedServices: services,
+		Expiration:        -1,
+	}
+	expiredTok := &protocoltypes.ServiceToken{
+		Token:             "tok2",
+		AuthenticationURL: "https://url1/",
+		SupportedServices: services,
+		Expiration:        time.Now().Add(-time.Minute).UnixNano() / int64(time.Millisecond),
+	}
+
+	metadataStore := node.Service.(*service).accountGroup.metadataStore
+
+	_, err := metadataStore.SendAccountServiceTokenAdded(ctx, tok)
+	require.NoError(t, err)
+
+	_, err = metadataStore.SendAccountServiceTokenAdded(ctx, expiredTok)
+	require.NoError(t, err)
+
+	// expired tokens are not listed and can't be used
+	require.Equal(t, []string{tok.TokenID()}, listTokens())
+
+	_, err = node.Client.ReplicationServiceRegisterGroup(ctx, &protocoltypes.ReplicationServiceRegisterGroup_Request{
+		TokenID: expiredTok.TokenID(),
+		GroupPK: metadataStore.g.PublicKey,
+	})
+	require.Error(t, err)
+
+	_, err = node.Client.ServicesTokenRemove(ctx, &protocoltypes.ServicesTokenRemove_Request{TokenID: tok.TokenID()})
+	require.NoError(t, err)
+	require.Empty(t, listTokens())
+
+	// tokens can only be removed once
+	_, err = node.Client.ServicesTokenRemove(ctx, &protocoltypes.ServicesTokenRemove_Request{TokenID: tok.TokenID()})
+	require.Error(t, err)
+
+	_, err = node.Client.ServicesTokenRemove(ctx, &protocoltypes.ServicesTokenRemove_Request{TokenID: "unknown"})
+	require.Error(t, err)
+}
@@ -2,58 +2,450 @@ package bertyprotocol
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"sync"
 
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	ds "github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/query"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 
 	"berty.tech/berty/v2/go/pkg/errcode"
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
+	"berty.tech/go-orbit-db/iface"
+	"berty.tech/go-orbit-db/stores"
 )
 
 const (
 	serviceReplicationKeyGroupPrefix = "group"
 	ServiceReplicationID             = "rpl"
+
+	ReplicationServiceDefaultMaxGroupsPerToken  = 100
+	ReplicationServiceDefaultMaxStoragePerToken = 1024 * 1024 * 1024
 )
 
+// ReplicationServiceOpts configures the replication service
+type ReplicationServiceOpts struct {
+	// TokenVerifier verifies the bearer tokens sent along the replication requests
+	TokenVerifier *AuthTokenVerifier
+
+	// MaxGroupsPerToken is the number of groups which can be replicated using a single token
+	MaxGroupsPerToken int
+
+	// MaxStoragePerToken is the size in bytes of the entries which can be replicated using a single token, it is
+	// checked when a new group is registered and when entries are replicated, the replication of a group is paused
+	// once all the tokens used to register it have exceeded it
+	MaxStoragePerToken int64
+}
+
+func (opts *ReplicationServiceOpts) applyDefaults() {
+	if opts.MaxGroupsPerToken == 0 {
+		opts.MaxGroupsPerToken = ReplicationServiceDefaultMaxGroupsPerToken
+	}
+
+	if opts.MaxStoragePerToken == 0 {
+		opts.MaxStoragePerToken = ReplicationServiceDefaultMaxStoragePerToken
+	}
+}
+
 type replicationService struct {
 	odb    *BertyOrbitDB
 	ds     ds.Datastore
 	logger *zap.Logger
 	ctx    context.Context
+	opts   ReplicationServiceOpts
+
+	lock   sync.Mutex
+	stores map[string][]iface.Store // group public key -> replicated stores
+	paused map[string]int64         // group public key -> size of the entries replicated before the pause
+}
+
+func replicationGroupKey(tokenID string, groupPK []byte) ds.Key {
+	return ds.KeyWithNamespaces([]string{serviceReplicationKeyGroupPrefix, tokenID, base64.RawURLEncoding.EncodeToString(groupPK)})
+}
+
+func (s *replicationService) GroupRegister(tokenID string, group *protocoltypes.Group) error {
+	if tokenID == "" {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("missing token id"))
+	}
+
+	if group == nil || len(group.PublicKey) == 0 {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("missing group or group pubkey"))
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := replicationGroupKey(tokenID, group.PublicKey)
+
+	registered, err := s.ds.Has(key)
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	if !registered {
+		if err := s.checkTokenQuota(tokenID); err != nil {
+			return err
+		}
+
+		// the token has enough storage left to resume the replication of the group
+		delete(s.paused, string(group.PublicKey))
+
+		data, err := group.Marshal()
+		if err != nil {
+			s.logger.Error("error while marshaling request", zap.Error(err))
+			return errcode.ErrSerialization.Wrap(err)
+		}
+
+		if err := s.ds.Put(key, data); err != nil {
+			s.logger.Error("error while registering group", zap.Error(err))
+			return errcode.ErrInternal.Wrap(err)
+		}
+
+		s.logger.Info("registering group", zap.Binary("group_pk", group.PublicKey), zap.String("token_id", tokenID))
+	}
+
+	return s.groupSubscribe(group)
+}
+
+func (s *replicationService) GroupUnregister(tokenID string, groupPK []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := replicationGroupKey(tokenID, groupPK)
+
+	data, err := s.ds.Get(key)
+	if err == ds.ErrNotFound {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("group is not replicated for this token"))
+	} else if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	group := &protocoltypes.Group{}
+	if err := group.Unmarshal(data); err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if err := s.ds.Delete(key); err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	s.logger.Info("unregistering group", zap.Binary("group_pk", groupPK), zap.String("token_id", tokenID))
+
+	// the group might still be replicated for another token
+	res, err := s.ds.Query(query.Query{Prefix: serviceReplicationKeyGroupPrefix, KeysOnly: true})
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	entries, err := res.Rest()
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	for _, e := range entries {
+		if ds.RawKey(e.Key).BaseNamespace() == key.BaseNamespace() {
+			return nil
+		}
+	}
+
+	// the stores of a paused group are opened again to remove their entries
+	groupStores := s.stores[string(groupPK)]
+	if _, ok := s.paused[string(groupPK)]; ok {
+		if groupStores, err = s.odb.openGroupReplication(s.ctx, group, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := s.odb.closeGroupReplication(s.ctx, group, groupStores); err != nil {
+		return err
+	}
+
+	delete(s.stores, string(groupPK))
+	delete(s.paused, string(groupPK))
+
+	return nil
+}
+
+// tokenGroups lists the public keys of the groups registered using the token
+func (s *replicationService) tokenGroups(tokenID string) ([][]byte, error) {
+	res, err := s.ds.Query(query.Query{
+		Prefix:   ds.KeyWithNamespaces([]string{serviceReplicationKeyGroupPrefix, tokenID}).String(),
+		KeysOnly: true,
+	})
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	entries, err := res.Rest()
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	groupPKs := make([][]byte, len(entries))
+	for i, e := range entries {
+		groupPKs[i], err = base64.RawURLEncoding.DecodeString(ds.RawKey(e.Key).BaseNamespace())
+		if err != nil {
+			return nil, errcode.ErrDeserialization.Wrap(err)
+		}
+	}
+
+	return groupPKs, nil
+}
+
+// groupTokens lists the ids of the tokens used to register the group
+func (s *replicationService) groupTokens(groupPK []byte) ([]string, error) {
+	res, err := s.ds.Query(query.Query{Prefix: serviceReplicationKeyGroupPrefix, KeysOnly: true})
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	entries, err := res.Rest()
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	groupKey := base64.RawURLEncoding.EncodeToString(groupPK)
+	tokenIDs := []string(nil)
+
+	for _, e := range entries {
+		key := ds.RawKey(e.Key)
+		if key.BaseNamespace() == groupKey {
+			tokenIDs = append(tokenIDs, key.Parent().BaseNamespace())
+		}
+	}
+
+	return tokenIDs, nil
+}
+
+// groupStorage returns the size of the entries replicated for the group
+func (s *replicationService) groupStorage(groupPK []byte) int64 {
+	if size, ok := s.paused[string(groupPK)]; ok {
+		return size
+	}
+
+	size := int64(0)
+	for _, store := range s.stores[string(groupPK)] {
+		size += replicatedStoreSize(store)
+	}
+
+	return size
+}
+
+// tokenStorage returns the size of the entries replicated for the groups registered using the token
+func (s *replicationService) tokenStorage(tokenID string) (int64, error) {
+	groupPKs, err := s.tokenGroups(tokenID)
+	if err != nil {
+		return 0, err
+	}
+
+	usedStorage := int64(0)
+	for _, groupPK := range groupPKs {
+		usedStorage += s.groupStorage(groupPK)
+	}
+
+	return usedStorage, nil
 }
 
-func (s *replicationService) GroupRegister(token string, group *protocoltypes.Group) error {
-	data, err := group.Marshal()
+// checkTokenQuota ensures a new group can be registered using the token
+func (s *replicationService) checkTokenQuota(tokenID string) error {
+	groupPKs, err := s.tokenGroups(tokenID)
 	if err != nil {
-		s.logger.Error("error while marshaling request", zap.Error(err))
 		return err
 	}
 
-	if err := s.ds.Put(ds.KeyWithNamespaces([]string{serviceReplicationKeyGroupPrefix, token, string(group.PublicKey)}), data); err != nil {
-		s.logger.Error("error while registering group", zap.Error(err))
+	if len(groupPKs) >= s.opts.MaxGroupsPerToken {
+		return errcode.ErrServiceReplicationGroupQuotaExceeded.Wrap(fmt.Errorf("a token can't be used to replicate more than %d groups", s.opts.MaxGroupsPerToken))
+	}
+
+	usedStorage, err := s.tokenStorage(tokenID)
+	if err != nil {
 		return err
 	}
 
-	s.logger.Info("registering group", zap.Binary("group_pk", group.PublicKey))
+	if usedStorage >= s.opts.MaxStoragePerToken {
+		return errcode.ErrServiceReplicationStorageQuotaExceeded.Wrap(fmt.Errorf("a token can't be used to replicate more than %d bytes", s.opts.MaxStoragePerToken))
+	}
+
+	return nil
+}
+
+// replicatedStoreSize returns the size of the payloads of the entries of a store
+func replicatedStoreSize(store iface.Store) int64 {
+	size := int64(0)
+	for _, e := range store.OpLog().GetEntries().Slice() {
+		size += int64(len(e.GetPayload()))
+	}
 
-	return s.GroupSubscribe(group)
+	return size
 }
 
 func (s *replicationService) GroupSubscribe(group *protocoltypes.Group) error {
-	return s.odb.openGroupReplication(s.ctx, group, nil)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.groupSubscribe(group)
+}
+
+func (s *replicationService) groupSubscribe(group *protocoltypes.Group) error {
+	if _, ok := s.paused[string(group.PublicKey)]; ok {
+		return nil
+	}
+
+	groupStores, err := s.odb.openGroupReplication(s.ctx, group, nil)
+	if err != nil {
+		return err
+	}
+
+	s.stores[string(group.PublicKey)] = groupStores
+
+	for _, store := range groupStores {
+		s.watchStorageQuota(group.PublicKey, store)
+	}
+
+	return nil
+}
+
+// watchStorageQuota checks the storage quota of the tokens used to register the group each time entries are
+// replicated in one of its stores
+func (s *replicationService) watchStorageQuota(groupPK []byte, store iface.Store) {
+	sub := store.Subscribe(s.ctx)
+
+	go func() {
+		for evt := range sub {
+			if _, ok := evt.(*stores.EventReplicated); !ok {
+				continue
+			}
+
+			// the subscription must be drained while the stores are closed
+			go func() {
+				if err := s.enforceStorageQuota(groupPK); err != nil {
+					s.logger.Error("unable to enforce storage quota", zap.Binary("group_pk", groupPK), zap.Error(err))
+				}
+			}()
+		}
+	}()
+}
+
+// enforceStorageQuota pauses the replication of the group once all the tokens used to register it have exceeded their
+// storage quota, the entries already replicated are kept until the group is unregistered
+func (s *replicationService) enforceStorageQuota(groupPK []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.paused[string(groupPK)]; ok {
+		return nil
+	}
+
+	tokenIDs, err := s.groupTokens(groupPK)
+	if err != nil {
+		return err
+	}
+
+	if len(tokenIDs) == 0 {
+		return nil
+	}
+
+	for _, tokenID := range tokenIDs {
+		usedStorage, err := s.tokenStorage(tokenID)
+		if err != nil {
+			return err
+		}
+
+		if usedStorage <= s.opts.MaxStoragePerToken {
+			return nil
+		}
+	}
+
+	s.paused[string(groupPK)] = s.groupStorage(groupPK)
+
+	var errs error
+	for _, store := range s.stores[string(groupPK)] {
+		errs = multierr.Append(errs, store.Close())
+	}
+
+	delete(s.stores, string(groupPK))
+
+	s.logger.Warn("storage quota exceeded, pausing group replication", zap.Binary("group_pk", groupPK))
+
+	return errs
+}
+
+// tokenIDFromContext verifies the bearer token of a request and returns its id
+func (s *replicationService) tokenIDFromContext(ctx context.Context) (string, error) {
+	token, err := grpc_auth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		return "", errcode.ErrServicesAuthServiceInvalidToken.Wrap(err)
+	}
+
+	tokenData, err := s.opts.TokenVerifier.VerifyToken(token, ServiceReplicationID)
+	if err != nil {
+		return "", errcode.ErrServicesAuthServiceInvalidToken.Wrap(err)
+	}
+
+	return tokenData.TokenID, nil
 }
 
-func (s *replicationService) ReplicateGroup(_ context.Context, req *protocoltypes.ReplicationServiceReplicateGroup_Request) (*protocoltypes.ReplicationServiceReplicateGroup_Reply, error) {
-	// TODO: retrieve auth token
-	err := s.GroupRegister("TODO", req.Group)
+func (s *replicationService) ReplicateGroup(ctx context.Context, req *protocoltypes.ReplicationServiceReplicateGroup_Request) (*protocoltypes.ReplicationServiceReplicateGroup_Reply, error) {
+	tokenID, err := s.tokenIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.GroupRegister(tokenID, req.Group); err != nil {
+		return nil, err
+	}
 
-	return &protocoltypes.ReplicationServiceReplicateGroup_Reply{}, err
+	return &protocoltypes.ReplicationServiceReplicateGroup_Reply{}, nil
+}
+
+func (s *replicationService) UnreplicateGroup(ctx context.Context, req *protocoltypes.ReplicationServiceUnreplicateGroup_Request) (*protocoltypes.ReplicationServiceUnreplicateGroup_Reply, error) {
+	tokenID, err := s.tokenIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.GroupUnregister(tokenID, req.GroupPK); err != nil {
+		return nil, err
+	}
+
+	return &protocoltypes.ReplicationServiceUnreplicateGroup_Reply{}, nil
+}
+
+func (s *replicationService) ListReplicatedGroups(ctx context.Context, _ *protocoltypes.ReplicationServiceListReplicatedGroups_Request) (*protocoltypes.ReplicationServiceListReplicatedGroups_Reply, error) {
+	tokenID, err := s.tokenIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	groupPKs, err := s.tokenGroups(tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protocoltypes.ReplicationServiceListReplicatedGroups_Reply{GroupPKs: groupPKs}, nil
 }
 
 func (s *replicationService) Close() error {
-	return nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var errs error
+
+	for groupPK, stores := range s.stores {
+		for _, store := range stores {
+			errs = multierr.Append(errs, store.Close())
+		}
+
+		delete(s.stores, groupPK)
+	}
+
+	return errs
 }
 
 var _ ReplicationService = (*replicationService)(nil)
@@ -65,7 +457,7 @@ type ReplicationService interface {
 	Close() error
 }
 
-func NewReplicationService(ctx context.Context, store ds.Datastore, odb *BertyOrbitDB, logger *zap.Logger) (ReplicationService, error) {
+func NewReplicationService(ctx context.Context, store ds.Datastore, odb *BertyOrbitDB, logger *zap.Logger, opts *ReplicationServiceOpts) (ReplicationService, error) {
 	if store == nil {
 		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("store should not be nil"))
 	}
@@ -74,6 +466,10 @@ func NewReplicationService(ctx context.Context, store ds.Datastore, odb *BertyOr
 		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("odb should not be nil"))
 	}
 
+	if opts == nil || opts.TokenVerifier == nil {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("a token verifier is required"))
+	}
+
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -83,7 +479,11 @@ func NewReplicationService(ctx context.Context, store ds.Datastore, odb *BertyOr
 		logger: logger,
 		odb:    odb,
 		ds:     store,
+		opts:   *opts,
+		stores: map[string][]iface.Store{},
+		paused: map[string]int64{},
 	}
+	r.opts.applyDefaults()
 
 	res, err := store.Query(query.Query{Prefix: serviceReplicationKeyGroupPrefix})
 	if err != nil {
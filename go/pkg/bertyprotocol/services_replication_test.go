@@ -14,9 +14,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
 
 	"berty.tech/berty/v2/go/internal/ipfsutil"
 	"berty.tech/berty/v2/go/internal/testutil"
+	"berty.tech/berty/v2/go/pkg/errcode"
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
 	orbitdb "berty.tech/go-orbit-db"
 )
@@ -33,6 +35,10 @@ func testHelperIPFSSetUp(t *testing.T) (context.Context, context.CancelFunc, moc
 	return ctx, cancel, mn, rdvp
 }
 
+func testHelperContextWithAuthToken(ctx context.Context, token string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "bearer "+token))
+}
+
 func TestNewReplicationService(t *testing.T) {
 	ctx, cancel, mn, rdvp := testHelperIPFSSetUp(t)
 	defer cancel()
@@ -54,7 +60,15 @@ func TestNewReplicationService(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	repl, err := NewReplicationService(ctx, ds, odb, zap.NewNop())
+	repl, err := NewReplicationService(ctx, ds, odb, zap.NewNop(), nil)
+	require.Error(t, err)
+	require.Nil(t, repl)
+
+	secret, pk, _ := helperGenerateTokenIssuerSecrets(t)
+	tokenVerifier, err := NewAuthTokenVerifier(secret, pk)
+	require.NoError(t, err)
+
+	repl, err = NewReplicationService(ctx, ds, odb, zap.NewNop(), &ReplicationServiceOpts{TokenVerifier: tokenVerifier})
 	require.NoError(t, err)
 	require.NotNil(t, repl)
 }
@@ -63,7 +77,7 @@ func TestReplicationService_GroupSubscribe(t *testing.T) {
 	ctx, cancel, mn, rdvp := testHelperIPFSSetUp(t)
 	defer cancel()
 
-	repl, cancel := testHelperNewReplicationService(ctx, t, nil, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), nil)
+	repl, cancel := testHelperNewReplicationService(ctx, t, nil, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), nil, nil)
 	defer cancel()
 
 	g, _, err := NewGroupMultiMember()
@@ -92,7 +106,7 @@ func TestReplicationService_GroupRegister(t *testing.T) {
 	ctx, cancel, mn, rdvp := testHelperIPFSSetUp(t)
 	defer cancel()
 
-	repl, cancel := testHelperNewReplicationService(ctx, t, nil, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), ds)
+	repl, cancel := testHelperNewReplicationService(ctx, t, nil, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), ds, nil)
 	cancel()
 
 	g, _, err := NewGroupMultiMember()
@@ -109,7 +123,7 @@ func TestReplicationService_GroupRegister(t *testing.T) {
 	cancel()
 
 	// Test reopening the replication manager, the previously registered group should be present
-	repl, cancel = testHelperNewReplicationService(ctx, t, nil, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), ds)
+	repl, cancel = testHelperNewReplicationService(ctx, t, nil, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), ds, nil)
 	defer cancel()
 
 	gc, ok := repl.odb.groups.Load(g.GroupIDAsString())
@@ -117,6 +131,223 @@ func TestReplicationService_GroupRegister(t *testing.T) {
 	require.NotNil(t, gc)
 }
 
+func TestReplicationService_ReplicateGroupToken(t *testing.T) {
+	ctx, cancel, mn, rdvp := testHelperIPFSSetUp(t)
+	defer cancel()
+
+	secret, pk, sk := helperGenerateTokenIssuerSecrets(t)
+	tokenVerifier, err := NewAuthTokenVerifier(secret, pk)
+	require.NoError(t, err)
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	repl, cancel := testHelperNewReplicationService(ctx, t, nil, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), ds, &ReplicationServiceOpts{
+		TokenVerifier:     tokenVerifier,
+		MaxGroupsPerToken: 1,
+	})
+	defer cancel()
+
+	issuer, err := NewAuthTokenIssuer(secret, sk)
+	require.NoError(t, err)
+
+	token, err := issuer.IssueToken([]string{ServiceReplicationID})
+	require.NoError(t, err)
+
+	tokenData, err := tokenVerifier.VerifyToken(token, ServiceReplicationID)
+	require.NoError(t, err)
+
+	otherServiceToken, err := issuer.IssueToken([]string{"other"})
+	require.NoError(t, err)
+
+	newReplicationRequest := func() *protocoltypes.ReplicationServiceReplicateGroup_Request {
+		g, _, err := NewGroupMultiMember()
+		require.NoError(t, err)
+
+		replGroup, err := g.FilterForReplication()
+		require.NoError(t, err)
+
+		return &protocoltypes.ReplicationServiceReplicateGroup_Request{Group: replGroup}
+	}
+
+	req := newReplicationRequest()
+
+	// a valid token for the replication service is required
+	_, err = repl.ReplicateGroup(ctx, req)
+	require.True(t, errcode.Is(err, errcode.ErrServicesAuthServiceInvalidToken))
+
+	_, err = repl.ReplicateGroup(testHelperContextWithAuthToken(ctx, "invalid"), req)
+	require.True(t, errcode.Is(err, errcode.ErrServicesAuthServiceInvalidToken))
+
+	_, err = repl.ReplicateGroup(testHelperContextWithAuthToken(ctx, otherServiceToken), req)
+	require.True(t, errcode.Is(err, errcode.ErrServicesAuthServiceInvalidToken))
+
+	_, err = repl.ReplicateGroup(testHelperContextWithAuthToken(ctx, token), req)
+	require.NoError(t, err)
+
+	// the group is registered under the token id
+	registered, err := ds.Has(replicationGroupKey(tokenData.TokenID, req.Group.PublicKey))
+	require.NoError(t, err)
+	require.True(t, registered)
+
+	// registering the same group again doesn't count toward the quota
+	_, err = repl.ReplicateGroup(testHelperContextWithAuthToken(ctx, token), req)
+	require.NoError(t, err)
+
+	_, err = repl.ReplicateGroup(testHelperContextWithAuthToken(ctx, token), newReplicationRequest())
+	require.True(t, errcode.Is(err, errcode.ErrServiceReplicationGroupQuotaExceeded))
+
+	// other tokens have their own quota
+	otherToken, err := issuer.IssueToken([]string{ServiceReplicationID})
+	require.NoError(t, err)
+
+	_, err = repl.ReplicateGroup(testHelperContextWithAuthToken(ctx, otherToken), newReplicationRequest())
+	require.NoError(t, err)
+}
+
+func TestReplicationService_UnreplicateGroup(t *testing.T) {
+	ctx, cancel, mn, rdvp := testHelperIPFSSetUp(t)
+	defer cancel()
+
+	secret, pk, sk := helperGenerateTokenIssuerSecrets(t)
+	tokenVerifier, err := NewAuthTokenVerifier(secret, pk)
+	require.NoError(t, err)
+
+	repl, cancel := testHelperNewReplicationService(ctx, t, nil, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), nil, &ReplicationServiceOpts{
+		TokenVerifier: tokenVerifier,
+	})
+	defer cancel()
+
+	issuer, err := NewAuthTokenIssuer(secret, sk)
+	require.NoError(t, err)
+
+	token1, err := issuer.IssueToken([]string{ServiceReplicationID})
+	require.NoError(t, err)
+
+	token2, err := issuer.IssueToken([]string{ServiceReplicationID})
+	require.NoError(t, err)
+
+	ctx1 := testHelperContextWithAuthToken(ctx, token1)
+	ctx2 := testHelperContextWithAuthToken(ctx, token2)
+
+	g, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	replGroup, err := g.FilterForReplication()
+	require.NoError(t, err)
+
+	_, err = repl.ReplicateGroup(ctx1, &protocoltypes.ReplicationServiceReplicateGroup_Request{Group: replGroup})
+	require.NoError(t, err)
+
+	list, err := repl.ListReplicatedGroups(ctx1, &protocoltypes.ReplicationServiceListReplicatedGroups_Request{})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{g.PublicKey}, list.GroupPKs)
+
+	// groups are scoped to the token used to register them
+	list, err = repl.ListReplicatedGroups(ctx2, &protocoltypes.ReplicationServiceListReplicatedGroups_Request{})
+	require.NoError(t, err)
+	require.Empty(t, list.GroupPKs)
+
+	_, err = repl.UnreplicateGroup(ctx2, &protocoltypes.ReplicationServiceUnreplicateGroup_Request{GroupPK: g.PublicKey})
+	require.True(t, errcode.Is(err, errcode.ErrInvalidInput))
+
+	_, err = repl.UnreplicateGroup(ctx, &protocoltypes.ReplicationServiceUnreplicateGroup_Request{GroupPK: g.PublicKey})
+	require.True(t, errcode.Is(err, errcode.ErrServicesAuthServiceInvalidToken))
+
+	// the group is still replicated while another token has registered it
+	_, err = repl.ReplicateGroup(ctx2, &protocoltypes.ReplicationServiceReplicateGroup_Request{Group: replGroup})
+	require.NoError(t, err)
+
+	_, err = repl.UnreplicateGroup(ctx1, &protocoltypes.ReplicationServiceUnreplicateGroup_Request{GroupPK: g.PublicKey})
+	require.NoError(t, err)
+
+	list, err = repl.ListReplicatedGroups(ctx1, &protocoltypes.ReplicationServiceListReplicatedGroups_Request{})
+	require.NoError(t, err)
+	require.Empty(t, list.GroupPKs)
+
+	_, ok := repl.odb.groups.Load(g.GroupIDAsString())
+	require.True(t, ok)
+
+	_, err = repl.UnreplicateGroup(ctx2, &protocoltypes.ReplicationServiceUnreplicateGroup_Request{GroupPK: g.PublicKey})
+	require.NoError(t, err)
+
+	_, ok = repl.odb.groups.Load(g.GroupIDAsString())
+	require.False(t, ok)
+}
+
+func TestReplicationService_StorageQuota(t *testing.T) {
+	testutil.FilterStabilityAndSpeed(t, testutil.Unstable, testutil.Slow)
+
+	logger, cleanup := testutil.Logger(t)
+	defer cleanup()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	rdvp, err := mn.GenPeer()
+	require.NoError(t, err, "failed to generate mocked peer")
+
+	defer rdvp.Close()
+
+	_, cleanrdvp := ipfsutil.TestingRDVP(ctx, t, rdvp)
+	defer cleanrdvp()
+
+	ipfsOpts := &ipfsutil.TestingAPIOpts{
+		Logger:    logger,
+		Mocknet:   mn,
+		RDVPeer:   rdvp.Peerstore().PeerInfo(rdvp.ID()),
+		Datastore: dssync.MutexWrap(datastore.NewMapDatastore()),
+	}
+
+	api, cleanupAPI := ipfsutil.TestingCoreAPIUsingMockNet(ctx, t, ipfsOpts)
+	defer cleanupAPI()
+
+	odb := newTestOrbitDB(ctx, t, logger, api, ipfsOpts.Datastore)
+	defer odb.Close()
+
+	repl, cancel := testHelperNewReplicationService(ctx, t, logger, mn, rdvp.Peerstore().PeerInfo(rdvp.ID()), nil, &ReplicationServiceOpts{
+		MaxStoragePerToken: 1,
+	})
+	defer cancel()
+
+	require.NoError(t, mn.LinkAll())
+	require.NoError(t, mn.ConnectAllButSelf())
+
+	g, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	gc, err := odb.openGroup(ctx, g, nil)
+	require.NoError(t, err)
+	require.NoError(t, ActivateGroupContext(ctx, gc, nil))
+
+	replGroup, err := g.FilterForReplication()
+	require.NoError(t, err)
+	require.NoError(t, repl.GroupRegister("token", replGroup))
+
+	_, err = gc.MetadataStore().SendAppMetadata(ctx, []byte("over quota"), nil)
+	require.NoError(t, err)
+
+	// the replication is paused once the replicated entries exceed the quota of the token
+	require.Eventually(t, func() bool {
+		repl.lock.Lock()
+		defer repl.lock.Unlock()
+
+		_, ok := repl.paused[string(g.PublicKey)]
+		return ok
+	}, 5*time.Second, 50*time.Millisecond)
+
+	otherGroup, _, err := NewGroupMultiMember()
+	require.NoError(t, err)
+
+	otherReplGroup, err := otherGroup.FilterForReplication()
+	require.NoError(t, err)
+
+	err = repl.GroupRegister("token", otherReplGroup)
+	require.True(t, errcode.Is(err, errcode.ErrServiceReplicationStorageQuotaExceeded))
+
+	// unregistering the group releases the storage used by the token
+	require.NoError(t, repl.GroupUnregister("token", g.PublicKey))
+	require.NoError(t, repl.GroupRegister("token", otherReplGroup))
+}
+
 func TestReplicationService_Flow(t *testing.T) {
 	testutil.FilterStabilityAndSpeed(t, testutil.Unstable, testutil.Slow)
 
@@ -170,7 +401,7 @@ func TestReplicationService_Flow(t *testing.T) {
 	api2, cleanupAPI2 := ipfsutil.TestingCoreAPIUsingMockNet(ctx, t, ipfsOpts2)
 	odb2 := newTestOrbitDB(ctx, t, logger, api2, ipfsOpts2.Datastore)
 
-	tokenSecret, tokenPK, _ := helperGenerateTokenIssuerSecrets(t)
+	tokenSecret, tokenPK, tokenSK := helperGenerateTokenIssuerSecrets(t)
 	replPeer, cancel := NewReplicationMockedPeer(ctx, t, tokenSecret, tokenPK, &TestingOpts{
 		Mocknet: mn,
 		RDVPeer: rdvp.Peerstore().PeerInfo(rdvp.ID()),
@@ -198,8 +429,13 @@ func TestReplicationService_Flow(t *testing.T) {
 	groupReplicable, err := gA.FilterForReplication()
 	require.NoError(t, err)
 
-	// TODO: handle auth
-	_, err = replPeer.Service.ReplicateGroup(ctx, &protocoltypes.ReplicationServiceReplicateGroup_Request{
+	issuer, err := NewAuthTokenIssuer(tokenSecret, tokenSK)
+	require.NoError(t, err)
+
+	token, err := issuer.IssueToken([]string{ServiceReplicationID})
+	require.NoError(t, err)
+
+	_, err = replPeer.Service.ReplicateGroup(testHelperContextWithAuthToken(ctx, token), &protocoltypes.ReplicationServiceReplicateGroup_Request{
 		Group: groupReplicable,
 	})
 	require.NoError(t, err)
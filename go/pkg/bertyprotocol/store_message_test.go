@@ -7,10 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ipfs/go-cid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"berty.tech/berty/v2/go/internal/testutil"
+	"berty.tech/berty/v2/go/pkg/errcode"
 	"berty.tech/berty/v2/go/pkg/protocoltypes"
 	ipfslog "berty.tech/go-ipfs-log"
 )
@@ -170,3 +172,68 @@ func Test_Add_Messages_To_Cache(t *testing.T) {
 
 	require.Equal(t, 0, bufferCount(peers[1].GC.MessageStore().cache[string(dPK0Raw)]))
 }
+
+func Test_Revoked_Device_Messages_Rejected(t *testing.T) {
+	testutil.FilterSpeed(t, testutil.Slow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger, cleanupLogger := testutil.Logger(t)
+	defer cleanupLogger()
+
+	// two members with two devices each, the second device of the first member is revoked
+	peers, groupSK, cleanup := createPeersWithGroup(ctx, t, "/tmp/message_test", 2, 2)
+	defer cleanup()
+
+	inviteAllPeersToGroup(ctx, t, peers, groupSK)
+
+	revoked, reader := peers[1], peers[2]
+	revokedPK := revoked.GC.DevicePubKey()
+
+	ds, err := revoked.MKS.GetDeviceSecret(revoked.GC.Group(), revoked.DevKS)
+	require.NoError(t, err)
+	require.NoError(t, reader.MKS.RegisterChainKey(reader.GC.Group(), revokedPK, ds, false))
+
+	countReaderEvents := func() int {
+		out, err := reader.GC.MessageStore().ListEvents(ctx, nil, nil, false)
+		require.NoError(t, err)
+
+		return countEntries(out)
+	}
+
+	// the messages sent before the revocation are opened
+	_, err = revoked.GC.MessageStore().AddMessage(ctx, []byte("before revocation"), nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return countReaderEvents() == 1 }, time.Second*5, time.Millisecond*100)
+
+	WatchRemovedMembersAndRotateSecrets(ctx, logger, reader.GC)
+
+	_, err = peers[0].GC.MetadataStore().RevokeDevice(ctx, revokedPK)
+	require.NoError(t, err)
+
+	groupPK, err := reader.GC.Group().GetPubKey()
+	require.NoError(t, err)
+
+	// the chain key of the revoked device is dropped once the revocation is received
+	require.Eventually(t, func() bool {
+		_, err := reader.MKS.getDeviceChainKey(groupPK, revokedPK)
+		return errcode.Is(err, errcode.ErrMissingInput)
+	}, time.Second*5, time.Millisecond*100)
+
+	// the messages sent by the revoked device afterwards are rejected
+	_, err = revoked.GC.MessageStore().AddMessage(ctx, []byte("after revocation"), nil)
+	require.NoError(t, err)
+
+	payload, err := (&protocoltypes.EncryptedMessage{Plaintext: []byte("after revocation")}).Marshal()
+	require.NoError(t, err)
+
+	env, err := revoked.MKS.SealEnvelope(ctx, revoked.GC.Group(), revoked.GC.memberDevice.device, payload, nil)
+	require.NoError(t, err)
+
+	_, _, _, err = reader.MKS.OpenEnvelope(ctx, reader.GC.Group(), reader.GC.DevicePubKey(), env, cid.Undef)
+	require.Error(t, err)
+
+	<-time.After(time.Millisecond * 500)
+	require.Equal(t, 1, countReaderEvents())
+}
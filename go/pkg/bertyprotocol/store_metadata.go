@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	coreapi "github.com/ipfs/interface-go-ipfs-core"
@@ -173,7 +174,19 @@ func (m *metadataStore) SendSecret(ctx context.Context, memberPK crypto.PubKey)
 		return nil, errcode.ErrInternal.Wrap(err)
 	}
 
-	ok, err := m.Index().(*metadataStoreIndex).areSecretsAlreadySent(memberPK)
+	ds, err := m.mks.GetDeviceSecret(m.g, m.devKS)
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(err)
+	}
+
+	idx := m.Index().(*metadataStoreIndex)
+
+	// the revoked devices still own the member key, the secret is sent to each remaining device instead
+	if idx.hasRevokedDevices(memberPK) {
+		return m.sendSecretToDevices(ctx, md, memberPK, ds)
+	}
+
+	ok, err := idx.areSecretsAlreadySent(memberPK, ds.Generation)
 	if err != nil {
 		return nil, errcode.ErrInvalidInput.Wrap(err)
 	}
@@ -186,16 +199,50 @@ func (m *metadataStore) SendSecret(ctx context.Context, memberPK crypto.PubKey)
 		m.logger.Warn("sending secret to an unknown group member")
 	}
 
-	ds, err := m.mks.GetDeviceSecret(m.g, m.devKS)
+	return metadataStoreSendSecret(ctx, m, m.g, md, memberPK, nil, ds)
+}
+
+func (m *metadataStore) sendSecretToDevices(ctx context.Context, md *ownMemberDevice, memberPK crypto.PubKey, ds *protocoltypes.DeviceSecret) (operation.Operation, error) {
+	devices, err := m.GetDevicesForMember(memberPK)
 	if err != nil {
 		return nil, errcode.ErrInvalidInput.Wrap(err)
 	}
 
-	return metadataStoreSendSecret(ctx, m, m.g, md, memberPK, ds)
+	var op operation.Operation
+	for _, devicePK := range devices {
+		if devicePK.Equals(md.device.GetPublic()) {
+			continue
+		}
+
+		ok, err := m.Index().(*metadataStoreIndex).areSecretsAlreadySent(devicePK, ds.Generation)
+		if err != nil {
+			return nil, errcode.ErrInvalidInput.Wrap(err)
+		}
+
+		if ok {
+			continue
+		}
+
+		if op, err = metadataStoreSendSecret(ctx, m, m.g, md, memberPK, devicePK, ds); err != nil {
+			return nil, err
+		}
+	}
+
+	if op == nil {
+		return nil, errcode.ErrGroupSecretAlreadySentToMember
+	}
+
+	return op, nil
 }
 
-func metadataStoreSendSecret(ctx context.Context, m *metadataStore, g *protocoltypes.Group, md *ownMemberDevice, memberPK crypto.PubKey, ds *protocoltypes.DeviceSecret) (operation.Operation, error) {
-	payload, err := newSecretEntryPayload(md.device, memberPK, ds, g)
+// metadataStoreSendSecret sends the device secret to a member, or only to one of their devices if destDevicePK is set
+func metadataStoreSendSecret(ctx context.Context, m *metadataStore, g *protocoltypes.Group, md *ownMemberDevice, memberPK crypto.PubKey, destDevicePK crypto.PubKey, ds *protocoltypes.DeviceSecret) (operation.Operation, error) {
+	destPK := memberPK
+	if destDevicePK != nil {
+		destPK = destDevicePK
+	}
+
+	payload, err := newSecretEntryPayload(md.device, destPK, ds, g)
 	if err != nil {
 		return nil, errcode.ErrInternal.Wrap(err)
 	}
@@ -214,6 +261,13 @@ func metadataStoreSendSecret(ctx context.Context, m *metadataStore, g *protocolt
 		DevicePK:     devicePKRaw,
 		DestMemberPK: memberPKRaw,
 		Payload:      payload,
+		Generation:   ds.Generation,
+	}
+
+	if destDevicePK != nil {
+		if event.DestDevicePK, err = destDevicePK.Raw(); err != nil {
+			return nil, errcode.ErrSerialization.Wrap(err)
+		}
 	}
 
 	sig, err := signProto(event, md.device)
@@ -251,6 +305,248 @@ func (m *metadataStore) ClaimGroupOwnership(ctx context.Context, groupSK crypto.
 	return metadataStoreAddEvent(ctx, m, m.g, protocoltypes.EventTypeMultiMemberGroupInitialMemberAnnounced, event, sig, nil)
 }
 
+// GrantAdminRole grants the admin role to a member of the group, the current member must be an admin
+func (m *metadataStore) GrantAdminRole(ctx context.Context, memberPK crypto.PubKey) (operation.Operation, error) {
+	if !m.typeChecker(isMultiMemberGroup) {
+		return nil, errcode.ErrGroupInvalidType
+	}
+
+	if memberPK == nil {
+		return nil, errcode.ErrInvalidInput
+	}
+
+	md, err := m.devKS.MemberDeviceForGroup(m.g)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	idx := m.Index().(*metadataStoreIndex)
+
+	if !idx.isAdmin(md.member.GetPublic()) {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("only admins can grant the admin role"))
+	}
+
+	if idx.isAdmin(memberPK) {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("member is already an admin"))
+	}
+
+	if _, err := idx.getDevicesForMember(memberPK); err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("unknown member: %w", err))
+	}
+
+	granteePK, err := memberPK.Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	return m.attributeSignAndAddEvent(ctx, &protocoltypes.MultiMemberGrantAdminRole{
+		GranteeMemberPK: granteePK,
+	}, protocoltypes.EventTypeMultiMemberGroupAdminRoleGranted, nil)
+}
+
+// RemoveMember removes a member from the group, the current member must be an admin. The remaining members
+// rotate their device secrets once the event is received if they were sent to the removed member, see RotateSecret.
+func (m *metadataStore) RemoveMember(ctx context.Context, memberPK crypto.PubKey) (operation.Operation, error) {
+	if !m.typeChecker(isMultiMemberGroup) {
+		return nil, errcode.ErrGroupInvalidType
+	}
+
+	if memberPK == nil {
+		return nil, errcode.ErrInvalidInput
+	}
+
+	md, err := m.devKS.MemberDeviceForGroup(m.g)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	idx := m.Index().(*metadataStoreIndex)
+
+	if !idx.isAdmin(md.member.GetPublic()) {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("only admins can remove members"))
+	}
+
+	if md.member.GetPublic().Equals(memberPK) {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("can't remove self from the group, leave it instead"))
+	}
+
+	if _, err := idx.getDevicesForMember(memberPK); err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("unknown member: %w", err))
+	}
+
+	removedPK, err := memberPK.Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	return m.attributeSignAndAddEvent(ctx, &protocoltypes.MultiMemberRemoveMember{
+		RemovedMemberPK: removedPK,
+		RemovedAt:       time.Now().UnixNano() / int64(time.Millisecond),
+	}, protocoltypes.EventTypeMultiMemberGroupMemberRemoved, nil)
+}
+
+// RevokeDevice revokes another device of the current member, its events won't be accepted anymore. The devices
+// having sent their secret to the member rotate it once the event is received, see RotateSecret. As the revoked
+// device still owns the member keys, the new secrets are sent to each remaining device of the member.
+func (m *metadataStore) RevokeDevice(ctx context.Context, devicePK crypto.PubKey) (operation.Operation, error) {
+	if devicePK == nil {
+		return nil, errcode.ErrInvalidInput
+	}
+
+	md, err := m.devKS.MemberDeviceForGroup(m.g)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	idx := m.Index().(*metadataStoreIndex)
+
+	if idx.isRevokedDevice(devicePK) {
+		return nil, nil
+	}
+
+	if md.device.GetPublic().Equals(devicePK) {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("can't revoke the current device"))
+	}
+
+	memberPK, err := idx.getMemberByDevice(devicePK)
+	if err != nil {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("unknown device: %w", err))
+	}
+
+	if !memberPK.Equals(md.member.GetPublic()) {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("only devices of the current member can be revoked"))
+	}
+
+	revokedPK, err := devicePK.Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	return m.attributeSignAndAddEvent(ctx, &protocoltypes.GroupRevokeDevice{
+		RevokedDevicePK: revokedPK,
+	}, protocoltypes.EventTypeGroupDeviceRevoked, nil)
+}
+
+// AddDeviceGroupKey announces in the account group the device key used by the current device in a multi-member
+// group, allowing the other devices of the account to revoke it
+func (m *metadataStore) AddDeviceGroupKey(ctx context.Context, g *protocoltypes.Group) (operation.Operation, error) {
+	if !m.typeChecker(isAccountGroup) || g.GroupType != protocoltypes.GroupTypeMultiMember {
+		return nil, errcode.ErrGroupInvalidType
+	}
+
+	md, err := m.devKS.MemberDeviceForGroup(m.g)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	groupMD, err := m.devKS.MemberDeviceForGroup(g)
+	if err != nil {
+		return nil, errcode.ErrInternal.Wrap(err)
+	}
+
+	if pk, err := m.GetDeviceGroupKey(g, md.device.GetPublic()); err == nil && pk.Equals(groupMD.device.GetPublic()) {
+		return nil, nil
+	}
+
+	device, err := md.device.GetPublic().Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	groupDevice, err := groupMD.device.GetPublic().Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	groupDeviceSig, err := groupMD.device.Sign(device)
+	if err != nil {
+		return nil, errcode.ErrCryptoSignature.Wrap(err)
+	}
+
+	return m.attributeSignAndAddEvent(ctx, &protocoltypes.AccountDeviceGroupKeyAdded{
+		GroupPK:        g.PublicKey,
+		GroupDevicePK:  groupDevice,
+		GroupDeviceSig: groupDeviceSig,
+	}, protocoltypes.EventTypeAccountDeviceGroupKeyAdded, nil)
+}
+
+// RotateSecret replaces the secret of the current device if it has been sent to a member removed from the group,
+// or to a member having revoked one of their devices, and sends the new one to the remaining members. The removed
+// members can't open the messages sent using the new secret.
+func (m *metadataStore) RotateSecret(ctx context.Context) error {
+	md, err := m.devKS.MemberDeviceForGroup(m.g)
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	idx := m.Index().(*metadataStoreIndex)
+
+	if idx.isRemovedMember(md.member.GetPublic()) {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("member has been removed from the group"))
+	}
+
+	if idx.isRevokedDevice(md.device.GetPublic()) {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("device has been revoked"))
+	}
+
+	if _, err := m.mks.RotateDeviceSecret(m.g, m.devKS, idx.getMinSecretGeneration()); err != nil {
+		return errcode.ErrCryptoKeyGeneration.Wrap(err)
+	}
+
+	return m.sendSecretToMembers(ctx)
+}
+
+// RenewSecret replaces the secret of the current device by a new one and sends it to the members of the group,
+// a leaked secret can't be used to open the messages sent afterwards
+func (m *metadataStore) RenewSecret(ctx context.Context) error {
+	md, err := m.devKS.MemberDeviceForGroup(m.g)
+	if err != nil {
+		return errcode.ErrInternal.Wrap(err)
+	}
+
+	if m.Index().(*metadataStoreIndex).isRemovedMember(md.member.GetPublic()) {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("member has been removed from the group"))
+	}
+
+	if _, err := m.mks.RenewDeviceSecret(m.g, m.devKS); err != nil {
+		return errcode.ErrCryptoKeyGeneration.Wrap(err)
+	}
+
+	return m.sendSecretToMembers(ctx)
+}
+
+// SecretNeedsRotation checks if the secret of the current device is known by a member removed from the group,
+// see RotateSecret
+func (m *metadataStore) SecretNeedsRotation() (bool, error) {
+	ds, err := m.mks.GetDeviceSecret(m.g, m.devKS)
+	if err != nil {
+		return false, errcode.ErrMessageKeyPersistenceGet.Wrap(err)
+	}
+
+	return ds.Generation < m.Index().(*metadataStoreIndex).getMinSecretGeneration(), nil
+}
+
+// SecretNeedsRenewal checks if the secret of the current device has been used for too long or for too many messages
+func (m *metadataStore) SecretNeedsRenewal() (bool, error) {
+	return m.mks.DeviceSecretNeedsRenewal(m.g, m.devKS)
+}
+
+func (m *metadataStore) sendSecretToMembers(ctx context.Context) error {
+	for _, memberPK := range m.ListMembers() {
+		if _, err := m.SendSecret(ctx, memberPK); err != nil && !errcode.Is(err, errcode.ErrGroupSecretAlreadySentToMember) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MinSecretGeneration returns the minimum generation of the secret of the current device, older secrets have
+// been sent to members removed from the group
+func (m *metadataStore) MinSecretGeneration() uint64 {
+	return m.Index().(*metadataStoreIndex).getMinSecretGeneration()
+}
+
 func signProto(message proto.Message, sk crypto.PrivKey) ([]byte, error) {
 	data, err := proto.Marshal(message)
 	if err != nil {
@@ -351,6 +647,24 @@ func (m *metadataStore) ListDevices() []crypto.PubKey {
 	return m.Index().(*metadataStoreIndex).listDevices()
 }
 
+func (m *metadataStore) ListRevokedDevices() []crypto.PubKey {
+	return m.Index().(*metadataStoreIndex).listRevokedDevices()
+}
+
+// ListRemovedMembersDevices returns the devices of the members removed from the group
+func (m *metadataStore) ListRemovedMembersDevices() []crypto.PubKey {
+	return m.Index().(*metadataStoreIndex).listRemovedMembersDevices()
+}
+
+// GetDeviceGroupKey returns the device key used in a multi-member group by a device of the account
+func (m *metadataStore) GetDeviceGroupKey(g *protocoltypes.Group, devicePK crypto.PubKey) (crypto.PubKey, error) {
+	if !m.typeChecker(isAccountGroup) {
+		return nil, errcode.ErrGroupInvalidType
+	}
+
+	return m.Index().(*metadataStoreIndex).getDeviceGroupKey(g.PublicKey, devicePK)
+}
+
 func (m *metadataStore) ListMultiMemberGroups() []*protocoltypes.Group {
 	if !m.typeChecker(isAccountGroup) {
 		return nil
@@ -800,6 +1114,12 @@ func (m *metadataStore) SendGroupReplicating(ctx context.Context, t *protocoltyp
 	}, protocoltypes.EventTypeGroupReplicating, nil)
 }
 
+func (m *metadataStore) SendGroupReplicatingStopped(ctx context.Context, endpoint string) (operation.Operation, error) {
+	return m.attributeSignAndAddEvent(ctx, &protocoltypes.GroupReplicatingStopped{
+		ReplicationServer: endpoint,
+	}, protocoltypes.EventTypeGroupReplicatingStopped, nil)
+}
+
 type accountSignableEvent interface {
 	proto.Message
 	proto.Marshaler
@@ -898,7 +1218,7 @@ func (m *metadataStore) getServiceToken(tokenID string) (*protocoltypes.ServiceT
 	defer m.Index().(*metadataStoreIndex).lock.RUnlock()
 
 	token, ok := m.Index().(*metadataStoreIndex).serviceTokens[tokenID]
-	if !ok {
+	if !ok || token == nil {
 		return nil, errcode.ErrServicesAuthUnknownToken
 	}
 
@@ -1000,18 +1320,18 @@ func constructorFactoryGroupMetadata(s *BertyOrbitDB) iface.StoreConstructor {
 	}
 }
 
-func newSecretEntryPayload(localDevicePrivKey crypto.PrivKey, remoteMemberPubKey crypto.PubKey, secret *protocoltypes.DeviceSecret, group *protocoltypes.Group) ([]byte, error) {
+func newSecretEntryPayload(localDevicePrivKey crypto.PrivKey, remotePubKey crypto.PubKey, secret *protocoltypes.DeviceSecret, group *protocoltypes.Group) ([]byte, error) {
 	message, err := secret.Marshal()
 	if err != nil {
 		return nil, errcode.ErrSerialization.Wrap(err)
 	}
 
-	mongPriv, mongPub, err := cryptoutil.EdwardsToMontgomery(localDevicePrivKey, remoteMemberPubKey)
+	mongPriv, mongPub, err := cryptoutil.EdwardsToMontgomery(localDevicePrivKey, remotePubKey)
 	if err != nil {
 		return nil, errcode.ErrCryptoKeyConversion.Wrap(err)
 	}
 
-	nonce := groupIDToNonce(group)
+	nonce := deviceSecretNonce(group, secret.Generation)
 	encryptedSecret := box.Seal(nil, message, nonce, mongPub, mongPriv)
 
 	return encryptedSecret, nil
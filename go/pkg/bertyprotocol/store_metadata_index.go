@@ -1,6 +1,7 @@
 package bertyprotocol
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"sync"
@@ -21,8 +22,13 @@ type metadataStoreIndex struct {
 	members                  map[string][]*memberDevice
 	devices                  map[string]*memberDevice
 	handledEvents            map[string]struct{}
-	sentSecrets              map[string]struct{}
-	admins                   map[crypto.PubKey]struct{}
+	sentSecrets              map[string]uint64
+	admins                   map[string]crypto.PubKey
+	removedMembers           map[string][]crypto.PubKey
+	revokedDevices           map[string]struct{}
+	revokedDevicesMembers    map[string]struct{}
+	deviceGroupKeys          map[string]map[string][]byte
+	minSecretGeneration      uint64
 	contacts                 map[string]*accountContact
 	contactsFromGroupPK      map[string]*accountContact
 	groups                   map[string]*accountGroup
@@ -87,6 +93,15 @@ func (m *metadataStoreIndex) UpdateIndex(log ipfslog.Log, _ []ipfslog.Entry) err
 			continue
 		}
 
+		// entries accepted before the revocation of their device are kept
+		if signed, ok := event.(eventDeviceSigned); ok && !alreadyHandledEvent {
+			if _, ok := m.revokedDevices[string(signed.GetDevicePK())]; ok {
+				m.handledEvents[e.GetHash().String()] = struct{}{}
+				m.logger.Warn("ignoring entry from a revoked device", zap.String("event-type", metaEvent.Metadata.EventType.String()))
+				continue
+			}
+		}
+
 		handlers, ok := m.eventHandlers[metaEvent.Metadata.EventType]
 		if !ok {
 			m.handledEvents[e.GetHash().String()] = struct{}{}
@@ -141,6 +156,14 @@ func (m *metadataStoreIndex) handleGroupAddMemberDevice(event proto.Message) err
 		return nil
 	}
 
+	if _, ok := m.revokedDevices[string(e.DevicePK)]; ok {
+		return nil
+	}
+
+	if _, ok := m.removedMembers[string(e.MemberPK)]; ok {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("device added by a removed member"))
+	}
+
 	m.devices[string(e.DevicePK)] = &memberDevice{
 		member: member,
 		device: device,
@@ -165,13 +188,33 @@ func (m *metadataStoreIndex) handleGroupAddDeviceSecret(event proto.Message) err
 		return errcode.ErrDeserialization.Wrap(err)
 	}
 
+	// secrets sent to a single device are tracked using the device key
+	destPK := e.DestMemberPK
+	if len(e.DestDevicePK) > 0 {
+		if _, err := crypto.UnmarshalEd25519PublicKey(e.DestDevicePK); err != nil {
+			return errcode.ErrDeserialization.Wrap(err)
+		}
+
+		destPK = e.DestDevicePK
+	}
+
 	senderPK, err := crypto.UnmarshalEd25519PublicKey(e.DevicePK)
 	if err != nil {
 		return errcode.ErrDeserialization.Wrap(err)
 	}
 
 	if m.ownMemberDevice.device.Equals(senderPK) {
-		m.sentSecrets[string(e.DestMemberPK)] = struct{}{}
+		if sent, ok := m.sentSecrets[string(destPK)]; !ok || sent < e.Generation {
+			m.sentSecrets[string(destPK)] = e.Generation
+		}
+
+		if _, ok := m.removedMembers[string(e.DestMemberPK)]; ok {
+			m.unsafeDiscardSecretsSentTo(destPK)
+		}
+
+		if _, ok := m.revokedDevices[string(destPK)]; ok {
+			m.unsafeDiscardSecretsSentTo(destPK)
+		}
 	}
 
 	return nil
@@ -284,7 +327,8 @@ func (m *metadataStoreIndex) listDevices() []crypto.PubKey {
 	return devices
 }
 
-func (m *metadataStoreIndex) areSecretsAlreadySent(pk crypto.PubKey) (bool, error) {
+// areSecretsAlreadySent checks if a device secret of the given generation, or a more recent one, has been sent to a member
+func (m *metadataStoreIndex) areSecretsAlreadySent(pk crypto.PubKey, generation uint64) (bool, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
@@ -293,8 +337,8 @@ func (m *metadataStoreIndex) areSecretsAlreadySent(pk crypto.PubKey) (bool, erro
 		return false, errcode.ErrInvalidInput.Wrap(err)
 	}
 
-	_, ok := m.sentSecrets[string(key)]
-	return ok, nil
+	sent, ok := m.sentSecrets[string(key)]
+	return ok && sent >= generation, nil
 }
 
 type accountGroupJoinedState uint32
@@ -673,21 +717,336 @@ func (m *metadataStoreIndex) handleMultiMemberInitialMember(event proto.Message)
 		return errcode.ErrDeserialization.Wrap(err)
 	}
 
-	if _, ok := m.admins[pk]; ok {
+	if _, ok := m.admins[string(e.MemberPK)]; ok {
 		return errcode.ErrInternal
 	}
 
-	m.admins[pk] = struct{}{}
+	m.admins[string(e.MemberPK)] = pk
 
 	return nil
 }
 
 func (m *metadataStoreIndex) handleMultiMemberGrantAdminRole(event proto.Message) error {
-	// TODO:
+	e, ok := event.(*protocoltypes.MultiMemberGrantAdminRole)
+	if !ok {
+		return errcode.ErrInvalidInput
+	}
+
+	devicePK, err := crypto.UnmarshalEd25519PublicKey(e.DevicePK)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	granteePK, err := crypto.UnmarshalEd25519PublicKey(e.GranteeMemberPK)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	granterPK, err := m.unsafeGetMemberByDevice(devicePK)
+	if err != nil {
+		return errcode.ErrGroupMemberUnknownGroupID.Wrap(err)
+	}
+
+	if !m.unsafeIsAdmin(granterPK) {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("admin role granted by a non admin member"))
+	}
+
+	m.admins[string(e.GranteeMemberPK)] = granteePK
+
+	return nil
+}
+
+func (m *metadataStoreIndex) handleMultiMemberRemoveMember(event proto.Message) error {
+	e, ok := event.(*protocoltypes.MultiMemberRemoveMember)
+	if !ok {
+		return errcode.ErrInvalidInput
+	}
+
+	devicePK, err := crypto.UnmarshalEd25519PublicKey(e.DevicePK)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if _, err := crypto.UnmarshalEd25519PublicKey(e.RemovedMemberPK); err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	removerPK, err := m.unsafeGetMemberByDevice(devicePK)
+	if err != nil {
+		return errcode.ErrGroupMemberUnknownGroupID.Wrap(err)
+	}
+
+	if !m.unsafeIsAdmin(removerPK) {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("member removed by a non admin member"))
+	}
+
+	if _, ok := m.removedMembers[string(e.RemovedMemberPK)]; ok {
+		return nil
+	}
+
+	devices := []crypto.PubKey(nil)
+	for _, md := range m.members[string(e.RemovedMemberPK)] {
+		device, err := md.device.Raw()
+		if err != nil {
+			return errcode.ErrSerialization.Wrap(err)
+		}
+
+		delete(m.devices, string(device))
+		devices = append(devices, md.device)
+		m.unsafeDiscardSecretsSentTo(device)
+	}
+
+	delete(m.members, string(e.RemovedMemberPK))
+	delete(m.admins, string(e.RemovedMemberPK))
+
+	// the devices are kept to forget their chain keys
+	m.removedMembers[string(e.RemovedMemberPK)] = devices
+	m.unsafeDiscardSecretsSentTo(e.RemovedMemberPK)
+
+	return nil
+}
+
+func (m *metadataStoreIndex) handleGroupDeviceRevoked(event proto.Message) error {
+	e, ok := event.(*protocoltypes.GroupRevokeDevice)
+	if !ok {
+		return errcode.ErrInvalidInput
+	}
+
+	devicePK, err := crypto.UnmarshalEd25519PublicKey(e.DevicePK)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if _, err := crypto.UnmarshalEd25519PublicKey(e.RevokedDevicePK); err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	if _, ok := m.revokedDevices[string(e.RevokedDevicePK)]; ok {
+		return nil
+	}
+
+	if bytes.Equal(e.DevicePK, e.RevokedDevicePK) {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("a device can't revoke itself"))
+	}
+
+	revokerPK, err := m.unsafeGetMemberByDevice(devicePK)
+	if err != nil {
+		return errcode.ErrGroupMemberUnknownGroupID.Wrap(err)
+	}
+
+	revoked, ok := m.devices[string(e.RevokedDevicePK)]
+	if !ok {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("unknown revoked device"))
+	}
+
+	if !revoked.member.Equals(revokerPK) {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("device revoked by another member"))
+	}
+
+	memberPK, err := revokerPK.Raw()
+	if err != nil {
+		return errcode.ErrSerialization.Wrap(err)
+	}
+
+	remaining := []*memberDevice(nil)
+	for _, md := range m.members[string(memberPK)] {
+		if !md.device.Equals(revoked.device) {
+			remaining = append(remaining, md)
+		}
+	}
+
+	if len(remaining) == 0 {
+		delete(m.members, string(memberPK))
+	} else {
+		m.members[string(memberPK)] = remaining
+	}
+
+	delete(m.devices, string(e.RevokedDevicePK))
+
+	m.revokedDevices[string(e.RevokedDevicePK)] = struct{}{}
+	m.revokedDevicesMembers[string(memberPK)] = struct{}{}
+	m.unsafeDiscardSecretsSentTo(memberPK)
+	m.unsafeDiscardSecretsSentTo(e.RevokedDevicePK)
+
+	return nil
+}
+
+func (m *metadataStoreIndex) handleAccountDeviceGroupKeyAdded(event proto.Message) error {
+	e, ok := event.(*protocoltypes.AccountDeviceGroupKeyAdded)
+	if !ok {
+		return errcode.ErrInvalidInput
+	}
+
+	if _, err := crypto.UnmarshalEd25519PublicKey(e.GroupPK); err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	groupDevicePK, err := crypto.UnmarshalEd25519PublicKey(e.GroupDevicePK)
+	if err != nil {
+		return errcode.ErrDeserialization.Wrap(err)
+	}
+
+	ok, err = groupDevicePK.Verify(e.DevicePK, e.GroupDeviceSig)
+	if err != nil {
+		return errcode.ErrCryptoSignatureVerification.Wrap(err)
+	}
+
+	if !ok {
+		return errcode.ErrCryptoSignatureVerification
+	}
+
+	keys, ok := m.deviceGroupKeys[string(e.GroupPK)]
+	if !ok {
+		keys = map[string][]byte{}
+		m.deviceGroupKeys[string(e.GroupPK)] = keys
+	}
+
+	for devicePK, key := range keys {
+		if devicePK != string(e.DevicePK) && bytes.Equal(key, e.GroupDevicePK) {
+			return errcode.ErrInvalidInput.Wrap(fmt.Errorf("group device key already used by another device"))
+		}
+	}
+
+	keys[string(e.DevicePK)] = e.GroupDevicePK
 
 	return nil
 }
 
+// unsafeDiscardSecretsSentTo ensures the secrets of the current device known by a removed member or a revoked
+// device won't be used anymore
+func (m *metadataStoreIndex) unsafeDiscardSecretsSentTo(pk []byte) {
+	sent, ok := m.sentSecrets[string(pk)]
+	if !ok {
+		return
+	}
+
+	if sent+1 > m.minSecretGeneration {
+		m.minSecretGeneration = sent + 1
+	}
+}
+
+// isRemovedMember returns true if the member has been removed from the group by an admin
+func (m *metadataStoreIndex) isRemovedMember(pk crypto.PubKey) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	id, err := pk.Raw()
+	if err != nil {
+		return false
+	}
+
+	_, ok := m.removedMembers[string(id)]
+	return ok
+}
+
+// listRemovedMembersDevices returns the devices of the members removed from the group
+func (m *metadataStoreIndex) listRemovedMembersDevices() []crypto.PubKey {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	devices := []crypto.PubKey(nil)
+	for _, memberDevices := range m.removedMembers {
+		devices = append(devices, memberDevices...)
+	}
+
+	return devices
+}
+
+// isRevokedDevice returns true if the device has been revoked by its member
+func (m *metadataStoreIndex) isRevokedDevice(pk crypto.PubKey) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	id, err := pk.Raw()
+	if err != nil {
+		return false
+	}
+
+	_, ok := m.revokedDevices[string(id)]
+	return ok
+}
+
+// hasRevokedDevices returns true if the member has revoked at least one of their devices
+func (m *metadataStoreIndex) hasRevokedDevices(memberPK crypto.PubKey) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	id, err := memberPK.Raw()
+	if err != nil {
+		return false
+	}
+
+	_, ok := m.revokedDevicesMembers[string(id)]
+	return ok
+}
+
+func (m *metadataStoreIndex) listRevokedDevices() []crypto.PubKey {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	devices := []crypto.PubKey(nil)
+
+	for id := range m.revokedDevices {
+		pk, err := crypto.UnmarshalEd25519PublicKey([]byte(id))
+		if err != nil {
+			continue
+		}
+
+		devices = append(devices, pk)
+	}
+
+	return devices
+}
+
+// getDeviceGroupKey returns the device key used in a multi-member group by a device of the account
+func (m *metadataStoreIndex) getDeviceGroupKey(groupPK []byte, pk crypto.PubKey) (crypto.PubKey, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	id, err := pk.Raw()
+	if err != nil {
+		return nil, errcode.ErrSerialization.Wrap(err)
+	}
+
+	key, ok := m.deviceGroupKeys[string(groupPK)][string(id)]
+	if !ok {
+		return nil, errcode.ErrMissingMapKey
+	}
+
+	groupDevicePK, err := crypto.UnmarshalEd25519PublicKey(key)
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	return groupDevicePK, nil
+}
+
+// getMinSecretGeneration returns the minimum generation of the secret of the current device, older secrets
+// have been sent to removed members
+func (m *metadataStoreIndex) getMinSecretGeneration() uint64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.minSecretGeneration
+}
+
+func (m *metadataStoreIndex) isAdmin(pk crypto.PubKey) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.unsafeIsAdmin(pk)
+}
+
+func (m *metadataStoreIndex) unsafeIsAdmin(pk crypto.PubKey) bool {
+	id, err := pk.Raw()
+	if err != nil {
+		return false
+	}
+
+	_, ok := m.admins[string(id)]
+	return ok
+}
+
 func (m *metadataStoreIndex) listAdmins() []crypto.PubKey {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
@@ -695,7 +1054,7 @@ func (m *metadataStoreIndex) listAdmins() []crypto.PubKey {
 	admins := make([]crypto.PubKey, len(m.admins))
 	i := 0
 
-	for admin := range m.admins {
+	for _, admin := range m.admins {
 		admins[i] = admin
 		i++
 	}
@@ -769,8 +1128,12 @@ func newMetadataIndex(ctx context.Context, eventEmitter events.EmitterInterface,
 		m := &metadataStoreIndex{
 			members:                map[string][]*memberDevice{},
 			devices:                map[string]*memberDevice{},
-			admins:                 map[crypto.PubKey]struct{}{},
-			sentSecrets:            map[string]struct{}{},
+			admins:                 map[string]crypto.PubKey{},
+			sentSecrets:            map[string]uint64{},
+			removedMembers:         map[string][]crypto.PubKey{},
+			revokedDevices:         map[string]struct{}{},
+			revokedDevicesMembers:  map[string]struct{}{},
+			deviceGroupKeys:        map[string]map[string][]byte{},
 			handledEvents:          map[string]struct{}{},
 			contacts:               map[string]*accountContact{},
 			contactsFromGroupPK:    map[string]*accountContact{},
@@ -796,13 +1159,16 @@ func newMetadataIndex(ctx context.Context, eventEmitter events.EmitterInterface,
 			protocoltypes.EventTypeAccountContactRequestOutgoingSent:      {m.handleContactRequestOutgoingSent},
 			protocoltypes.EventTypeAccountContactRequestReferenceReset:    {m.handleContactRequestReferenceReset},
 			protocoltypes.EventTypeAccountContactUnblocked:                {m.handleContactUnblocked},
+			protocoltypes.EventTypeAccountDeviceGroupKeyAdded:             {m.handleAccountDeviceGroupKeyAdded},
 			protocoltypes.EventTypeAccountGroupJoined:                     {m.handleGroupJoined},
 			protocoltypes.EventTypeAccountGroupLeft:                       {m.handleGroupLeft},
 			protocoltypes.EventTypeContactAliasKeyAdded:                   {m.handleContactAliasKeyAdded},
+			protocoltypes.EventTypeGroupDeviceRevoked:                     {m.handleGroupDeviceRevoked},
 			protocoltypes.EventTypeGroupDeviceSecretAdded:                 {m.handleGroupAddDeviceSecret},
 			protocoltypes.EventTypeGroupMemberDeviceAdded:                 {m.handleGroupAddMemberDevice},
 			protocoltypes.EventTypeMultiMemberGroupAdminRoleGranted:       {m.handleMultiMemberGrantAdminRole},
 			protocoltypes.EventTypeMultiMemberGroupInitialMemberAnnounced: {m.handleMultiMemberInitialMember},
+			protocoltypes.EventTypeMultiMemberGroupMemberRemoved:          {m.handleMultiMemberRemoveMember},
 			protocoltypes.EventTypeAccountServiceTokenAdded:               {m.handleAccountServiceTokenAdded},
 			protocoltypes.EventTypeAccountServiceTokenRemoved:             {m.handleAccountServiceTokenRemoved},
 		}
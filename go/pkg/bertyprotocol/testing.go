@@ -3,6 +3,7 @@ package bertyprotocol
 import (
 	"context"
 	"crypto/ed25519"
+	crand "crypto/rand"
 	"fmt"
 	"testing"
 
@@ -20,6 +21,7 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 
+	"berty.tech/berty/v2/go/internal/cryptoutil"
 	"berty.tech/berty/v2/go/internal/ipfsutil"
 	"berty.tech/berty/v2/go/internal/tracer"
 	orbitdb "berty.tech/go-orbit-db"
@@ -160,13 +162,29 @@ func (opts *TestingOpts) applyDefaults(ctx context.Context) {
 	}
 }
 
-func testHelperNewReplicationService(ctx context.Context, t *testing.T, logger *zap.Logger, mn libp2p_mocknet.Mocknet, rdvp peer.AddrInfo, ds datastore.Batching) (*replicationService, context.CancelFunc) {
+func testHelperNewReplicationService(ctx context.Context, t *testing.T, logger *zap.Logger, mn libp2p_mocknet.Mocknet, rdvp peer.AddrInfo, ds datastore.Batching, opts *ReplicationServiceOpts) (*replicationService, context.CancelFunc) {
 	t.Helper()
 
 	if ds == nil {
 		ds = ds_sync.MutexWrap(datastore.NewMapDatastore())
 	}
 
+	if opts == nil {
+		opts = &ReplicationServiceOpts{}
+	}
+
+	if opts.TokenVerifier == nil {
+		pk, _, err := ed25519.GenerateKey(crand.Reader)
+		require.NoError(t, err)
+
+		secret := make([]byte, cryptoutil.KeySize)
+		_, err = crand.Read(secret)
+		require.NoError(t, err)
+
+		opts.TokenVerifier, err = NewAuthTokenVerifier(secret, pk)
+		require.NoError(t, err)
+	}
+
 	api, cleanup := ipfsutil.TestingCoreAPIUsingMockNet(ctx, t, &ipfsutil.TestingAPIOpts{
 		Logger:    logger,
 		Mocknet:   mn,
@@ -181,7 +199,7 @@ func testHelperNewReplicationService(ctx context.Context, t *testing.T, logger *
 	})
 	require.NoError(t, err)
 
-	repl, err := NewReplicationService(ctx, ds, odb, logger)
+	repl, err := NewReplicationService(ctx, ds, odb, logger, opts)
 	require.NoError(t, err)
 	require.NotNil(t, repl)
 
@@ -192,11 +210,12 @@ func testHelperNewReplicationService(ctx context.Context, t *testing.T, logger *
 }
 
 func NewReplicationMockedPeer(ctx context.Context, t *testing.T, secret []byte, sk ed25519.PublicKey, opts *TestingOpts) (*TestingReplicationPeer, func()) {
-	// TODO: handle auth
-	_ = secret
-	_ = sk
+	tokenVerifier, err := NewAuthTokenVerifier(secret, sk)
+	require.NoError(t, err)
 
-	replServ, cleanupReplMan := testHelperNewReplicationService(ctx, t, nil, opts.Mocknet, opts.RDVPeer, nil)
+	replServ, cleanupReplMan := testHelperNewReplicationService(ctx, t, nil, opts.Mocknet, opts.RDVPeer, nil, &ReplicationServiceOpts{
+		TokenVerifier: tokenVerifier,
+	})
 
 	return &TestingReplicationPeer{
 			Service: replServ,
@@ -47,6 +47,12 @@ func (link *BertyLink) IsValid() error {
 			return errcode.ErrInvalidInput
 		}
 		return nil
+
+	case BertyLink_DeviceLinkV1Kind:
+		if link.DeviceLink == nil {
+			return errcode.ErrMissingInput
+		}
+		return link.DeviceLink.CheckFormat()
 	}
 	return errcode.ErrInvalidInput
 }
@@ -61,6 +67,11 @@ func (link *BertyLink) IsGroup() bool {
 		link.IsValid() == nil
 }
 
+func (link *BertyLink) IsDeviceLink() bool {
+	return link.Kind == BertyLink_DeviceLinkV1Kind &&
+		link.IsValid() == nil
+}
+
 func (id *BertyID) GetBertyLink() *BertyLink {
 	return &BertyLink{
 		Kind:    BertyLink_ContactInviteV1Kind,
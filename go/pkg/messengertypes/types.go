@@ -82,8 +82,20 @@ func (am AppMessage) UnmarshalPayload() (proto.Message, error) {
 		message = &AppMessage_SetUserInfo{}
 	case AppMessage_TypeReplyOptions:
 		message = &AppMessage_ReplyOptions{}
+	case AppMessage_TypeEditUserMessage:
+		message = &AppMessage_EditUserMessage{}
+	case AppMessage_TypeRetractUserMessage:
+		message = &AppMessage_RetractUserMessage{}
+	case AppMessage_TypeReadReceipt:
+		message = &AppMessage_ReadReceipt{}
+	case AppMessage_TypeSetRetention:
+		message = &AppMessage_SetRetention{}
+	case AppMessage_TypeTyping:
+		message = &AppMessage_Typing{}
 	case AppMessage_TypeMonitorMetadata:
 		message = &AppMessage_MonitorMetadata{}
+	case AppMessage_TypeMemberRemoved:
+		message = &AppMessage_MemberRemoved{}
 
 	default:
 		return nil, errcode.TODO.Wrap(fmt.Errorf("unsupported AppMessage type: %q", am.GetType()))
@@ -126,6 +138,8 @@ func (event *StreamEvent) UnmarshalPayload() (proto.Message, error) {
 		message = &StreamEvent_DeviceUpdated{}
 	case StreamEvent_TypeMediaUpdated:
 		message = &StreamEvent_MediaUpdated{}
+	case StreamEvent_TypeTypingUpdated:
+		message = &StreamEvent_TypingUpdated{}
 	case StreamEvent_TypeNotified:
 		message = &StreamEvent_Notified{}
 	case StreamEvent_TypeListEnded:
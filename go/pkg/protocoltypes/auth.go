@@ -2,6 +2,7 @@ package protocoltypes
 
 import (
 	fmt "fmt"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -9,3 +10,8 @@ import (
 func (m *ServiceToken) TokenID() string {
 	return uuid.NewV5(uuid.NamespaceURL, fmt.Sprintf("%s/%s", m.AuthenticationURL, m.Token)).String()
 }
+
+// Expired returns true if the token has an expiration date and it is before now
+func (m *ServiceToken) Expired(now time.Time) bool {
+	return m.Expiration > 0 && m.Expiration <= now.UnixNano()/int64(time.Millisecond)
+}
@@ -0,0 +1,56 @@
+package protocoltypes
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"berty.tech/berty/v2/go/pkg/errcode"
+)
+
+func (m *DeviceLinkInvitation) CheckFormat() error {
+	if _, err := peer.Decode(m.PeerID); err != nil {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("invalid peer id: %w", err))
+	}
+
+	if len(m.Addrs) == 0 {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("no address specified"))
+	}
+
+	if _, err := m.GetResponderPubKey(); err != nil {
+		return err
+	}
+
+	if l := len(m.Secret); l != ed25519.SeedSize {
+		return errcode.ErrInvalidInput.Wrap(fmt.Errorf("secret length should not be %d", l))
+	}
+
+	return nil
+}
+
+func (m *DeviceLinkInvitation) GetResponderPubKey() (crypto.PubKey, error) {
+	pk, err := crypto.UnmarshalEd25519PublicKey(m.ResponderPK)
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	return pk, nil
+}
+
+// GetRequesterPrivKey returns the one-time key used by the new device to
+// authenticate itself
+func (m *DeviceLinkInvitation) GetRequesterPrivKey() (crypto.PrivKey, error) {
+	if l := len(m.Secret); l != ed25519.SeedSize {
+		return nil, errcode.ErrInvalidInput.Wrap(fmt.Errorf("secret length should not be %d", l))
+	}
+
+	stdSK := ed25519.NewKeyFromSeed(m.Secret)
+	sk, _, err := crypto.KeyPairFromStdKey(&stdSK)
+	if err != nil {
+		return nil, errcode.ErrDeserialization.Wrap(err)
+	}
+
+	return sk, nil
+}
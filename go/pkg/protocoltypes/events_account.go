@@ -84,6 +84,18 @@ func (m *MultiMemberGrantAdminRole) SetDevicePK(pk []byte) {
 	m.DevicePK = pk
 }
 
+func (m *MultiMemberRemoveMember) SetDevicePK(pk []byte) {
+	m.DevicePK = pk
+}
+
+func (m *GroupRevokeDevice) SetDevicePK(pk []byte) {
+	m.DevicePK = pk
+}
+
+func (m *AccountDeviceGroupKeyAdded) SetDevicePK(pk []byte) {
+	m.DevicePK = pk
+}
+
 func (m *AppMetadata) SetDevicePK(pk []byte) {
 	m.DevicePK = pk
 }
@@ -99,3 +111,7 @@ func (m *AccountServiceTokenRemoved) SetDevicePK(pk []byte) {
 func (m *GroupReplicating) SetDevicePK(pk []byte) {
 	m.DevicePK = pk
 }
+
+func (m *GroupReplicatingStopped) SetDevicePK(pk []byte) {
+	m.DevicePK = pk
+}